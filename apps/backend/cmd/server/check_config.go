@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/server"
+)
+
+// runCheckConfig implements `server check-config`. It exits non-zero if
+// any check fails, so it can gate deployments.
+func runCheckConfig(cfg *server.Config) {
+	checks := server.CheckConfig(cfg)
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range checks {
+		if c.Status == server.CheckFail {
+			failed++
+		}
+		fmt.Fprintf(w, "[%s]\t%s\t%s\n", strings.ToUpper(c.Status), c.Name, c.Detail)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		fmt.Printf("\n%d check(s) failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("\nConfiguration OK")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/server"
+)
+
+// runCreateAccount implements `server create-admin` and `server
+// create-user`, which differ only in the role of the new account
+func runCreateAccount(cfg *server.Config, role string, args []string) {
+	fs := flag.NewFlagSet("create-"+role, flag.ExitOnError)
+	email := fs.String("email", "", role+" email address (required)")
+	passwordHelp := role + " password"
+	if role == model.RoleAdmin {
+		passwordHelp += "; defaults to $ADMIN_PASSWORD to keep it out of shell history"
+	}
+	password := fs.String("password", "", passwordHelp)
+	fs.Parse(args)
+
+	if *password == "" && role == model.RoleAdmin {
+		*password = cfg.AdminPassword
+	}
+	if *email == "" || *password == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if cfg.Database.Driver == "" || cfg.Database.Driver == "memory" {
+		log.Println("Warning: the in-memory store does not persist; accounts created here are lost when the command exits")
+	}
+	s, err := server.OpenStore(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to open store: ", err)
+	}
+	accounts, ok := server.OpenAccounts(s)
+	if !ok {
+		log.Fatalf("The %q storage driver does not support accounts", cfg.Database.Driver)
+	}
+
+	account, err := server.CreateAccount(context.Background(), accounts, *email, *password, role)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", role, err)
+	}
+	log.Printf("Created %s account %s", role, account.Email)
+}
+
+// runCreateAdmin implements `server create-admin`
+func runCreateAdmin(cfg *server.Config, args []string) {
+	runCreateAccount(cfg, model.RoleAdmin, args)
+}
+
+// runCreateUser implements `server create-user`
+func runCreateUser(cfg *server.Config, args []string) {
+	runCreateAccount(cfg, model.RoleUser, args)
+}
@@ -0,0 +1,10 @@
+package main
+
+// Third-party store and blob drivers register themselves from an init
+// function, the same way database/sql drivers do. To compile one in, add a
+// blank import here and select it with DB_DRIVER or BLOB_DRIVER:
+//
+//	import _ "example.com/web-collector-sqlite"
+//
+// Driver-specific settings are passed through DB_OPTION_* and
+// BLOB_OPTION_* variables.
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+	"github.com/hereisth/web-collector/apps/backend/internal/server"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// runGenerate implements `server generate`, which fills the store with
+// synthetic data for load testing
+func runGenerate(cfg *server.Config, args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	users := fs.Int("users", 10, "number of fake user accounts to create")
+	bookmarks := fs.Int("bookmarks", 1000, "number of fake bookmarks to create")
+	randSeed := fs.Int64("seed", 1, "random seed; the same seed produces the same data")
+	fs.Parse(args)
+
+	if cfg.Database.Driver == "" || cfg.Database.Driver == "memory" {
+		log.Println("Warning: the in-memory store does not persist; generated data is discarded on exit")
+	}
+	s, err := server.OpenStore(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to open store: ", err)
+	}
+
+	if *users > 0 {
+		accounts, ok := server.OpenAccounts(s)
+		if !ok {
+			log.Fatalf("The %q storage driver does not support accounts", cfg.Database.Driver)
+		}
+		created := 0
+		for i := 1; i <= *users; i++ {
+			// "!" is not a valid bcrypt hash, so fake users can never log in.
+			_, err := accounts.CreateAccount(context.Background(), fmt.Sprintf("user%d@example.test", i), "!", model.RoleUser)
+			if errors.Is(err, storage.ErrAccountExists) {
+				continue
+			}
+			if err != nil {
+				log.Fatal("Failed to create user: ", err)
+			}
+			created++
+		}
+		log.Printf("Created %d of %d users (%d already present)", created, *users, *users-created)
+	}
+
+	start := time.Now()
+	fixtures := seed.Generate(*bookmarks, *randSeed)
+	created, err := seed.Apply(context.Background(), s, fixtures)
+	if err != nil {
+		log.Fatal("Failed to create bookmarks, none were saved: ", err)
+	}
+	elapsed := time.Since(start)
+	rate := float64(created) / elapsed.Seconds()
+	log.Printf("Created %d of %d bookmarks in %v (%.0f/s)", created, len(fixtures), elapsed.Round(time.Millisecond), rate)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/importer"
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+	"github.com/hereisth/web-collector/apps/backend/internal/server"
+)
+
+// runImport implements `server import`
+func runImport(cfg *server.Config, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "", "bookmark file to import (required)")
+	format := fs.String("format", "netscape", "file format: "+strings.Join(importer.Names(), ", "))
+	dryRun := fs.Bool("dry-run", false, "parse the file and report what would be imported without writing")
+	minVisits := fs.Int("min-visits", importer.DefaultHistoryFilter.MinVisits, "history: skip pages visited fewer times, unless starred")
+	domains := fs.String("domains", "", "history: comma-separated domains to import pages from (default all)")
+	excludeDomains := fs.String("exclude-domains", "", "history: comma-separated domains to skip")
+	since := fs.String("since", "", "history: skip pages last visited before this date (YYYY-MM-DD)")
+	until := fs.String("until", "", "history: skip pages last visited on or after this date (YYYY-MM-DD)")
+	fs.Parse(args)
+
+	if *file == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	parse, ok := importer.Lookup(*format)
+	if !ok {
+		log.Fatalf("Unknown format %q (supported: %s)", *format, strings.Join(importer.Names(), ", "))
+	}
+	if *format == "history" {
+		filter := importer.HistoryFilter{
+			MinVisits:      *minVisits,
+			Domains:        splitList(*domains),
+			ExcludeDomains: splitList(*excludeDomains),
+			Since:          parseDate("since", *since),
+			Until:          parseDate("until", *until),
+		}
+		parse = func(r io.Reader) ([]seed.Fixture, error) {
+			return importer.ParseHistory(r, filter)
+		}
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal("Failed to open file: ", err)
+	}
+	defer f.Close()
+
+	bookmarks, err := parse(f)
+	if err != nil {
+		log.Fatalf("Failed to parse %s: %v", *file, err)
+	}
+	log.Printf("Parsed %d bookmarks from %s", len(bookmarks), *file)
+	if *dryRun {
+		return
+	}
+
+	if cfg.Database.Driver == "" || cfg.Database.Driver == "memory" {
+		log.Println("Warning: the in-memory store does not persist; imported bookmarks are discarded on exit")
+	}
+	s, err := server.OpenStore(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to open store: ", err)
+	}
+
+	created, err := seed.Apply(context.Background(), s, bookmarks)
+	if err != nil {
+		log.Fatal("Failed to import bookmarks, none were saved: ", err)
+	}
+	log.Printf("Imported %d of %d bookmarks (%d already present)", created, len(bookmarks), len(bookmarks)-created)
+}
+
+// splitList splits a comma-separated flag value
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// parseDate parses a YYYY-MM-DD flag value; an empty one is the zero time
+func parseDate(name, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		log.Fatalf("Invalid -%s %q: want YYYY-MM-DD", name, value)
+	}
+	return t
+}
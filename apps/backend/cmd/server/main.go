@@ -1,8 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/hereisth/web-collector/apps/backend/internal/server"
 
@@ -10,6 +18,14 @@ import (
 )
 
 func main() {
+	// Remember what the real environment sets, so reloading .env later
+	// never overrides it
+	for _, kv := range os.Environ() {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			processEnv[kv[:i]] = true
+		}
+	}
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("Warning: .env file not found, using environment variables")
@@ -18,17 +34,156 @@ func main() {
 	// Load configuration
 	cfg := server.LoadConfig()
 
+	// Dispatch subcommands; without one, run the HTTP server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+		case "check-config":
+			runCheckConfig(cfg)
+			return
+		case "create-admin":
+			runCreateAdmin(cfg, os.Args[2:])
+			return
+		case "create-user":
+			runCreateUser(cfg, os.Args[2:])
+			return
+		case "generate":
+			runGenerate(cfg, os.Args[2:])
+			return
+		case "import":
+			runImport(cfg, os.Args[2:])
+			return
+		case "migrate":
+			runMigrate(cfg, os.Args[2:])
+			return
+		case "seed":
+			runSeed(cfg, os.Args[2:])
+			return
+		case "help", "-h", "--help":
+			usage()
+			return
+		default:
+			usage()
+			os.Exit(2)
+		}
+	}
+
+	serve(cfg)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: server [command]
+
+Commands:
+  serve         Run the HTTP server (default)
+  check-config  Validate configuration and connectivity without serving
+  create-admin  Create an admin account
+  create-user   Create a regular account, e.g. to share collections with
+  generate      Create synthetic users and bookmarks for load testing
+  import        Import bookmarks from a Netscape, Pocket, CSV, XBEL, Wallabag or Shaarli file
+  migrate       Apply, roll back or list database schema migrations
+  seed          Load sample or fixture bookmarks into the configured store
+  help          Show this help
+
+Run "server <command> -h" for command options.
+`)
+}
+
+// serve runs the HTTP server until SIGINT/SIGTERM
+func serve(cfg *server.Config) {
 	// Setup router
 	r := server.SetupRouter(cfg)
 
-	// Start server
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8080"
+	srv := &http.Server{
+		Handler: r.Handler(),
+	}
+	ln, addr, err := server.Listen(cfg)
+	if err != nil {
+		log.Fatal("Failed to listen: ", err)
+	}
+
+	// Operational endpoints on an internal-only listener, if configured
+	var adminSrv *http.Server
+	if cfg.AdminAddr != "" {
+		adminSrv = &http.Server{
+			Addr:    cfg.AdminAddr,
+			Handler: server.SetupAdminRouter(cfg).Handler(),
+		}
+		go func() {
+			log.Printf("Admin listener starting on %s", cfg.AdminAddr)
+			if err := adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal("Failed to start admin listener:", err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start background maintenance jobs
+	stopJobs := server.StartJobs(ctx)
+	defer stopJobs()
+
+	// Reload tunable settings on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reloadEnvFile()
+				if err := server.Reload(server.LoadConfig()); err != nil {
+					log.Println("Configuration reload failed, keeping current settings:", err)
+				}
+			}
+		}
+	}()
+
+	go func() {
+		// With a certificate configured, net/http negotiates HTTP/2 via ALPN;
+		// otherwise the router falls back to h2c for plaintext HTTP/2.
+		var err error
+		if cfg.TLSCertFile != "" && cfg.TLSKeyFile != "" {
+			log.Printf("Server starting on %s (TLS)", addr)
+			err = srv.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			log.Printf("Server starting on %s", addr)
+			err = srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server forced to shutdown:", err)
+	}
+	if adminSrv != nil {
+		adminSrv.Shutdown(shutdownCtx)
 	}
+}
+
+// processEnv holds the variables set by the process environment at startup
+var processEnv = map[string]bool{}
 
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+// reloadEnvFile re-reads .env, updating every variable that didn't come
+// from the process environment
+func reloadEnvFile() {
+	values, err := godotenv.Read()
+	if err != nil {
+		return
+	}
+	for key, value := range values {
+		if !processEnv[key] {
+			os.Setenv(key, value)
+		}
 	}
 }
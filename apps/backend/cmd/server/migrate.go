@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/server"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// runMigrate implements `server migrate up|down|status`
+func runMigrate(cfg *server.Config, args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: server migrate <up|down|status>
+
+  up      Apply all pending migrations
+  down    Roll back the most recently applied migration
+  status  List migrations and whether they have been applied
+`)
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if cfg.Database.Driver != "postgres" {
+		log.Fatalf("Migrations require DB_DRIVER=postgres (current driver: %q)", cfg.Database.Driver)
+	}
+
+	m, db, err := storage.OpenMigrator(cfg.Database.DSN())
+	if err != nil {
+		log.Fatal("Failed to open database: ", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch fs.Arg(0) {
+	case "up":
+		ran, err := m.Up(ctx)
+		for _, mig := range ran {
+			log.Printf("Applied %d_%s", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		if len(ran) == 0 {
+			log.Println("Schema is up to date")
+		}
+	case "down":
+		mig, ok, err := m.Down(ctx)
+		if err != nil {
+			log.Fatal("Rollback failed: ", err)
+		}
+		if !ok {
+			log.Println("No migrations to roll back")
+			return
+		}
+		log.Printf("Rolled back %d_%s", mig.Version, mig.Name)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			log.Fatal("Failed to read migration status: ", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		w.Flush()
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
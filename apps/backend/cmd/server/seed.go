@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+	"github.com/hereisth/web-collector/apps/backend/internal/server"
+)
+
+// runSeed implements `server seed`
+func runSeed(cfg *server.Config, args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	file := fs.String("file", "sample", `fixture JSON file, or "sample" for the built-in demo data`)
+	fs.Parse(args)
+
+	if cfg.Database.Driver == "" || cfg.Database.Driver == "memory" {
+		log.Println(`Warning: the in-memory store does not persist; set SEED_DATA to seed it at startup instead`)
+	}
+
+	fixtures, err := seed.Load(*file)
+	if err != nil {
+		log.Fatal("Failed to load fixtures: ", err)
+	}
+	s, err := server.OpenStore(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to open store: ", err)
+	}
+
+	created, err := seed.Apply(context.Background(), s, fixtures)
+	if err != nil {
+		log.Fatal("Failed to seed bookmarks, none were saved: ", err)
+	}
+	log.Printf("Seeded %d of %d bookmarks (%d already present)", created, len(fixtures), len(fixtures)-created)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/spf13/cobra"
+)
+
+func newAddCmd() *cobra.Command {
+	var (
+		title string
+		tags  []string
+	)
+	cmd := &cobra.Command{
+		Use:   "add <url>",
+		Short: "Save a bookmark",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if title == "" {
+				title = args[0]
+			}
+			b, err := newClient().CreateBookmark(cmd.Context(), model.CreateBookmarkRequest{
+				Title: title,
+				URL:   args[0],
+				Tags:  tags,
+			})
+			if err != nil {
+				return err
+			}
+			return printBookmarks(b)
+		},
+	}
+	cmd.Flags().StringVarP(&title, "title", "t", "", "bookmark title (defaults to the URL)")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "tag to attach (repeatable or comma-separated)")
+	return cmd
+}
+
+func newListCmd() *cobra.Command {
+	var tag string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List bookmarks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookmarks, err := newClient().ListBookmarks(cmd.Context(), "", tag)
+			if err != nil {
+				return err
+			}
+			return printBookmarks(bookmarks...)
+		},
+	}
+	cmd.Flags().StringVar(&tag, "tag", "", "only list bookmarks with this tag")
+	return cmd
+}
+
+func newSearchCmd() *cobra.Command {
+	var tag string
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search bookmarks by title, URL or tag",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookmarks, err := newClient().ListBookmarks(cmd.Context(), args[0], tag)
+			if err != nil {
+				return err
+			}
+			return printBookmarks(bookmarks...)
+		},
+	}
+	cmd.Flags().StringVar(&tag, "tag", "", "restrict results to this tag")
+	return cmd
+}
+
+func newTagCmd() *cobra.Command {
+	var add, remove []string
+	cmd := &cobra.Command{
+		Use:   "tag <id>",
+		Short: "Add or remove tags on a bookmark",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(add) == 0 && len(remove) == 0 {
+				return fmt.Errorf("nothing to do: pass --add and/or --remove")
+			}
+			c := newClient()
+			b, err := c.GetBookmark(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			drop := make(map[string]bool, len(remove))
+			for _, t := range model.NormalizeTags(remove) {
+				drop[t] = true
+			}
+			tags := make([]string, 0, len(b.Tags)+len(add))
+			for _, t := range append(b.Tags, add...) {
+				if !drop[t] {
+					tags = append(tags, t)
+				}
+			}
+
+			tags = model.NormalizeTags(tags)
+			b, err = c.UpdateBookmark(cmd.Context(), args[0], model.UpdateBookmarkRequest{
+				Tags: &tags,
+			})
+			if err != nil {
+				return err
+			}
+			return printBookmarks(b)
+		},
+	}
+	cmd.Flags().StringSliceVar(&add, "add", nil, "tags to add")
+	cmd.Flags().StringSliceVar(&remove, "remove", nil, "tags to remove")
+	return cmd
+}
+
+func newDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <id>...",
+		Aliases: []string{"rm"},
+		Short:   "Delete bookmarks",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			for _, id := range args {
+				if err := c.DeleteBookmark(cmd.Context(), id); err != nil {
+					return fmt.Errorf("delete %s: %w", id, err)
+				}
+				fmt.Printf("Deleted %s\n", id)
+			}
+			return nil
+		},
+	}
+}
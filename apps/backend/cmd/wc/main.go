@@ -0,0 +1,89 @@
+// Command wc is a terminal client for a web-collector server.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/client"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serverURL  string
+	token      string
+	jsonOutput bool
+)
+
+func main() {
+	root := &cobra.Command{
+		Use:           "wc",
+		Short:         "Manage web-collector bookmarks from the terminal",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&serverURL, "server", envOr("WC_SERVER", "http://localhost:8080"), "server URL (env WC_SERVER)")
+	root.PersistentFlags().StringVar(&token, "token", os.Getenv("WC_TOKEN"), "API token (env WC_TOKEN)")
+	root.PersistentFlags().BoolVar(&jsonOutput, "json", false, "print raw JSON")
+
+	root.AddCommand(
+		newAddCmd(),
+		newListCmd(),
+		newSearchCmd(),
+		newTagCmd(),
+		newDeleteCmd(),
+		newNativeHostCmd(),
+	)
+
+	// Started by the browser, run the host whatever arguments it passed
+	if nativeHostLaunch(os.Args[1:]) {
+		root.SetArgs([]string{"native-host"})
+	}
+
+	if err := root.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func newClient() *client.Client {
+	return client.New(serverURL, token)
+}
+
+// printBookmarks renders bookmarks as a table, or JSON with --json
+func printBookmarks(bookmarks ...model.Bookmark) error {
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if len(bookmarks) == 1 {
+			return enc.Encode(bookmarks[0])
+		}
+		return enc.Encode(bookmarks)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tURL\tTAGS")
+	for _, b := range bookmarks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.ID, truncate(b.Title, 50), b.URL, strings.Join(b.Tags, ","))
+	}
+	return tw.Flush()
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
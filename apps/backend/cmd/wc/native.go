@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/nativemsg"
+	"github.com/spf13/cobra"
+)
+
+// nativeHostName is the name the extension connects to the host by
+const nativeHostName = "com.webcollector.host"
+
+// nativeRequest is a message from the extension. ID is echoed in the
+// response so the extension can match them up.
+type nativeRequest struct {
+	ID        string   `json:"id"`
+	Action    string   `json:"action"`
+	URL       string   `json:"url"`
+	Title     string   `json:"title"`
+	Tags      []string `json:"tags"`
+	Selection string   `json:"selection"`
+	Query     string   `json:"query"`
+	Tag       string   `json:"tag"`
+}
+
+// nativeResponse answers a nativeRequest
+type nativeResponse struct {
+	ID    string      `json:"id,omitempty"`
+	OK    bool        `json:"ok"`
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// nativeHostLaunch reports whether the browser started wc as a native
+// messaging host. Browsers run the manifest's path without a way to add
+// arguments of our own: Chrome passes the caller's origin, Firefox the
+// manifest path and the extension ID.
+func nativeHostLaunch(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	return strings.HasPrefix(args[0], "chrome-extension://") ||
+		(len(args) == 2 && strings.HasSuffix(args[0], ".json") && !strings.HasPrefix(args[1], "-"))
+}
+
+func newNativeHostCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "native-host",
+		Short: "Serve the browser extension over native messaging",
+		Long: `Serve the browser extension over native messaging, forwarding its
+requests to the server. The extension talks to this process over stdin and
+stdout, so it needs neither CORS nor credentials of its own; the server URL
+and token come from WC_SERVER and WC_TOKEN as usual.
+
+Install the host with "wc native-host manifest", which prints the manifest
+to place in the browser's NativeMessagingHosts directory.`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serveNativeHost(cmd.Context(), os.Stdin, os.Stdout)
+		},
+	}
+	cmd.AddCommand(newNativeManifestCmd())
+	return cmd
+}
+
+func newNativeManifestCmd() *cobra.Command {
+	var (
+		browser     string
+		extensionID string
+	)
+	cmd := &cobra.Command{
+		Use:   "manifest",
+		Short: "Print the native messaging host manifest",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			if path, err = filepath.Abs(path); err != nil {
+				return err
+			}
+			m := nativemsg.Manifest{
+				Name:        nativeHostName,
+				Description: "web-collector",
+				Path:        path,
+				Type:        "stdio",
+			}
+			switch browser {
+			case "chrome", "chromium", "edge":
+				m.AllowedOrigins = []string{"chrome-extension://" + extensionID + "/"}
+			case "firefox":
+				m.AllowedExtensions = []string{extensionID}
+			default:
+				return fmt.Errorf("unknown browser %q (chrome, chromium, edge or firefox)", browser)
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(m)
+		},
+	}
+	cmd.Flags().StringVar(&browser, "browser", "chrome", "browser the manifest is for: chrome, chromium, edge or firefox")
+	cmd.Flags().StringVar(&extensionID, "extension-id", "", "ID of the extension allowed to connect")
+	cmd.MarkFlagRequired("extension-id")
+	return cmd
+}
+
+// serveNativeHost answers the extension's messages until it disconnects.
+// Anything written to stdout is read as a message, so errors are reported
+// in responses and logs go to stderr.
+func serveNativeHost(ctx context.Context, in io.Reader, out io.Writer) error {
+	for {
+		var req nativeRequest
+		if err := nativemsg.Read(in, &req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				if err := nativemsg.Write(out, nativeResponse{Error: "message is not valid JSON"}); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+
+		resp := handleNativeRequest(ctx, req)
+		resp.ID = req.ID
+		err := nativemsg.Write(out, resp)
+		if errors.Is(err, nativemsg.ErrTooLarge) {
+			err = nativemsg.Write(out, nativeResponse{ID: req.ID, Error: "response is too large for native messaging; narrow the request"})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func handleNativeRequest(ctx context.Context, req nativeRequest) nativeResponse {
+	var (
+		data interface{}
+		err  error
+	)
+	switch req.Action {
+	case "ping":
+		data = map[string]string{"server": serverURL}
+	case "save":
+		title := req.Title
+		if title == "" {
+			title = req.URL
+		}
+		data, err = newClient().CreateBookmark(ctx, model.CreateBookmarkRequest{
+			Title:     title,
+			URL:       req.URL,
+			Tags:      req.Tags,
+			Selection: req.Selection,
+		})
+	case "lookup":
+		data, err = newClient().LookupBookmark(ctx, req.URL)
+	case "list":
+		data, err = newClient().ListBookmarks(ctx, req.Query, req.Tag)
+	default:
+		err = fmt.Errorf("unknown action %q (ping, save, lookup or list)", req.Action)
+	}
+	if err != nil {
+		return nativeResponse{Error: err.Error()}
+	}
+	return nativeResponse{OK: true, Data: data}
+}
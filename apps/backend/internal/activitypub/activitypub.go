@@ -0,0 +1,335 @@
+// Package activitypub publishes accounts' public bookmarks to the
+// fediverse. Each account with a public profile is an ActivityPub actor
+// that Mastodon and other servers can follow; its bookmarks are Notes.
+// Requests between servers are authenticated with HTTP signatures.
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/safehttp"
+)
+
+// ContentType is the media type of ActivityPub documents
+const ContentType = "application/activity+json"
+
+// Public addresses an activity to everyone
+const Public = "https://www.w3.org/ns/activitystreams#Public"
+
+// maxDocument bounds the documents read from other servers
+const maxDocument = 1 << 20
+
+var contexts = []interface{}{
+	"https://www.w3.org/ns/activitystreams",
+	"https://w3id.org/security/v1",
+}
+
+// Config enables federation; it is disabled without a URL
+type Config struct {
+	// URL is the public https address of the instance, without a path;
+	// actor IDs are built from it and cannot change once followed
+	URL string
+}
+
+// Federation builds the documents of the instance's actors and talks to
+// other servers
+type Federation struct {
+	base   string
+	host   string
+	client *http.Client
+}
+
+// Open returns the configured federation, or nil when no URL is set
+func Open(cfg Config) (*Federation, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+		return nil, fmt.Errorf("federation URL %q must be an https URL without a path", cfg.URL)
+	}
+	return &Federation{
+		base:   u.Scheme + "://" + u.Host,
+		host:   u.Host,
+		client: safehttp.Client(15 * time.Second),
+	}, nil
+}
+
+// Host is the domain in the instance's fediverse handles
+func (f *Federation) Host() string {
+	return f.host
+}
+
+// ActorID returns the ID of a username's actor, which is also its
+// profile page
+func (f *Federation) ActorID(username string) string {
+	return f.base + "/u/" + username
+}
+
+// KeyID returns the ID of the key an actor signs with
+func (f *Federation) KeyID(username string) string {
+	return f.ActorID(username) + "#main-key"
+}
+
+// NoteID returns the ID of the Note a bookmark is published as
+func (f *Federation) NoteID(username, bookmarkID string) string {
+	return f.ActorID(username) + "/bookmarks/" + bookmarkID
+}
+
+// Resource parses a WebFinger resource, acct:username@host or an actor
+// ID, returning the username it names on this instance
+func (f *Federation) Resource(resource string) (string, bool) {
+	if rest, ok := strings.CutPrefix(resource, "acct:"); ok {
+		username, host, ok := strings.Cut(rest, "@")
+		return strings.ToLower(username), ok && strings.EqualFold(host, f.host)
+	}
+	username, ok := strings.CutPrefix(resource, f.base+"/u/")
+	return username, ok && username != "" && !strings.Contains(username, "/")
+}
+
+// WebFinger returns the WebFinger document pointing to a username's actor
+func (f *Federation) WebFinger(username string) map[string]interface{} {
+	return map[string]interface{}{
+		"subject": "acct:" + username + "@" + f.host,
+		"aliases": []string{f.ActorID(username)},
+		"links": []map[string]string{
+			{"rel": "self", "type": ContentType, "href": f.ActorID(username)},
+			{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", "href": f.ActorID(username)},
+		},
+	}
+}
+
+// Actor returns a username's actor document with its public key
+func (f *Federation) Actor(username, publicKeyPEM string, created time.Time) map[string]interface{} {
+	id := f.ActorID(username)
+	return map[string]interface{}{
+		"@context":                  contexts,
+		"id":                        id,
+		"type":                      "Person",
+		"preferredUsername":         username,
+		"name":                      username,
+		"summary":                   "<p>Public bookmarks of " + html.EscapeString(username) + "</p>",
+		"url":                       id,
+		"inbox":                     id + "/inbox",
+		"outbox":                    id + "/outbox",
+		"followers":                 id + "/followers",
+		"manuallyApprovesFollowers": false,
+		"discoverable":              true,
+		"published":                 created.UTC().Format(time.RFC3339),
+		"publicKey": map[string]string{
+			"id":           f.KeyID(username),
+			"owner":        id,
+			"publicKeyPem": publicKeyPEM,
+		},
+	}
+}
+
+// Note returns the Note a public bookmark is published as: its title
+// linked to its URL, its notes, and its tags as hashtags
+func (f *Federation) Note(username string, b model.Bookmark) map[string]interface{} {
+	id := f.NoteID(username, b.ID)
+	var content strings.Builder
+	fmt.Fprintf(&content, `<p><a href="%s" rel="nofollow noopener" target="_blank">%s</a></p>`,
+		html.EscapeString(b.URL), html.EscapeString(b.Title))
+	if notes := strings.TrimSpace(b.Notes); notes != "" {
+		fmt.Fprintf(&content, "<p>%s</p>", strings.ReplaceAll(html.EscapeString(notes), "\n", "<br>"))
+	}
+	tags := make([]map[string]string, 0, len(b.Tags))
+	if len(b.Tags) > 0 {
+		content.WriteString("<p>")
+		for i, tag := range b.Tags {
+			name := hashtag(tag)
+			if i > 0 {
+				content.WriteString(" ")
+			}
+			fmt.Fprintf(&content, `<a href="%s" class="mention hashtag" rel="tag">#<span>%s</span></a>`,
+				html.EscapeString(f.ActorID(username)+"?tag="+url.QueryEscape(tag)), html.EscapeString(name))
+			tags = append(tags, map[string]string{"type": "Hashtag", "name": "#" + name, "href": f.ActorID(username) + "?tag=" + url.QueryEscape(tag)})
+		}
+		content.WriteString("</p>")
+	}
+	return map[string]interface{}{
+		"id":           id,
+		"type":         "Note",
+		"attributedTo": f.ActorID(username),
+		"content":      content.String(),
+		"url":          b.URL,
+		"published":    b.CreatedAt.UTC().Format(time.RFC3339),
+		"to":           []string{Public},
+		"cc":           []string{f.ActorID(username) + "/followers"},
+		"tag":          tags,
+	}
+}
+
+// hashtag makes a tag usable as a hashtag, which ends at the first
+// character that is not a letter, digit or underscore
+func hashtag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, tag)
+}
+
+// Create wraps a Note in the activity that announces it to followers
+func (f *Federation) Create(username string, note map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"@context":  contexts[0],
+		"id":        note["id"].(string) + "#create",
+		"type":      "Create",
+		"actor":     f.ActorID(username),
+		"published": note["published"],
+		"to":        note["to"],
+		"cc":        note["cc"],
+		"object":    note,
+	}
+}
+
+// Accept answers a Follow activity, confirming the follow
+func (f *Federation) Accept(username string, follow json.RawMessage) map[string]interface{} {
+	return map[string]interface{}{
+		"@context": contexts[0],
+		"id":       f.ActorID(username) + "#accept-" + randomSuffix(),
+		"type":     "Accept",
+		"actor":    f.ActorID(username),
+		"object":   follow,
+	}
+}
+
+// OrderedCollection returns a collection of items, newest first
+func OrderedCollection(id string, total int, items []interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"@context":   contexts[0],
+		"id":         id,
+		"type":       "OrderedCollection",
+		"totalItems": total,
+	}
+	if items != nil {
+		c["orderedItems"] = items
+	}
+	return c
+}
+
+// Activity is an incoming activity. Object is kept raw, as it is either
+// an ID or an embedded object.
+type Activity struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// ObjectID returns the ID of the activity's object
+func (a Activity) ObjectID() string {
+	var id string
+	if json.Unmarshal(a.Object, &id) == nil {
+		return id
+	}
+	var obj struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(a.Object, &obj)
+	return obj.ID
+}
+
+// ObjectType returns the type of an embedded object, "" for an ID
+func (a Activity) ObjectType() string {
+	var obj struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(a.Object, &obj)
+	return obj.Type
+}
+
+// RemoteActor is the part of another server's actor needed to deliver to
+// it and check its signatures
+type RemoteActor struct {
+	ID        string `json:"id"`
+	Inbox     string `json:"inbox"`
+	Endpoints struct {
+		SharedInbox string `json:"sharedInbox"`
+	} `json:"endpoints"`
+	PublicKey struct {
+		ID           string `json:"id"`
+		Owner        string `json:"owner"`
+		PublicKeyPEM string `json:"publicKeyPem"`
+	} `json:"publicKey"`
+}
+
+// FetchActor fetches an actor, signing the request as signer for servers
+// that only answer signed requests. keyID may name the actor's key
+// rather than the actor itself.
+func (f *Federation) FetchActor(ctx context.Context, keyID string, signer Signer) (RemoteActor, error) {
+	id, _, _ := strings.Cut(keyID, "#")
+	if u, err := url.Parse(id); err != nil || u.Scheme != "https" {
+		return RemoteActor{}, fmt.Errorf("actor %q is not an https URL", id)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, id, nil)
+	if err != nil {
+		return RemoteActor{}, err
+	}
+	req.Header.Set("Accept", ContentType)
+	if err := signer.Sign(req, nil); err != nil {
+		return RemoteActor{}, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return RemoteActor{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return RemoteActor{}, fmt.Errorf("fetch actor %s: %s", id, resp.Status)
+	}
+	var actor RemoteActor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocument)).Decode(&actor); err != nil {
+		return RemoteActor{}, fmt.Errorf("fetch actor %s: %w", id, err)
+	}
+	if actor.ID != id || actor.Inbox == "" {
+		return RemoteActor{}, fmt.Errorf("fetch actor %s: not an actor", id)
+	}
+	return actor, nil
+}
+
+// ErrGone is returned when an inbox no longer exists
+var ErrGone = errors.New("inbox is gone")
+
+// Deliver posts an activity to an inbox, signed by signer
+func (f *Federation) Deliver(ctx context.Context, inbox string, activity interface{}, signer Signer) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if err := signer.Sign(req, body); err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("deliver to %s: %s", inbox, resp.Status)
+	}
+	return nil
+}
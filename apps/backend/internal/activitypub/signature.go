@@ -0,0 +1,210 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// keyBits is the size of the RSA keys actors sign with, which is what
+// Mastodon uses and expects
+const keyBits = 2048
+
+// maxClockSkew is how far the Date of a signed request may be from now
+const maxClockSkew = 5 * time.Minute
+
+// ErrSignature is returned for requests without a valid signature
+var ErrSignature = errors.New("invalid HTTP signature")
+
+// GenerateKey returns a new PEM-encoded private key for an actor
+func GenerateKey() (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// Signer signs requests as one actor
+type Signer struct {
+	KeyID string
+	key   *rsa.PrivateKey
+}
+
+// NewSigner parses a PEM-encoded private key from GenerateKey
+func NewSigner(keyID, privateKeyPEM string) (Signer, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return Signer{}, errors.New("invalid private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return Signer{}, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return Signer{}, errors.New("private key is not RSA")
+	}
+	return Signer{KeyID: keyID, key: key}, nil
+}
+
+// PublicKeyPEM returns the PEM-encoded public key published in the
+// actor document
+func (s Signer) PublicKeyPEM() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// Sign adds Date, Host, Digest (for a body) and Signature headers to req,
+// signing the request target with them as Mastodon expects
+func (s Signer) Sign(req *http.Request, body []byte) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Host", req.URL.Host)
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		sum := sha256.Sum256(body)
+		req.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sum[:]))
+		headers = append(headers, "digest")
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(req, headers)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		s.KeyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// SignatureKeyID returns the keyId a request claims to be signed with
+func SignatureKeyID(req *http.Request) (string, bool) {
+	params := signatureParams(req.Header.Get("Signature"))
+	return params["keyId"], params["keyId"] != ""
+}
+
+// Verify checks that req, with its body already read, was signed with
+// publicKeyPEM over at least its target, host, date and body digest, and
+// that the date is recent
+func Verify(req *http.Request, body []byte, publicKeyPEM string) error {
+	params := signatureParams(req.Header.Get("Signature"))
+	if params["signature"] == "" {
+		return ErrSignature
+	}
+	if alg := params["algorithm"]; alg != "" && alg != "rsa-sha256" && alg != "hs2019" {
+		return fmt.Errorf("%w: unsupported algorithm %s", ErrSignature, alg)
+	}
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	if len(headers) == 0 {
+		headers = []string{"date"}
+	}
+	required := []string{"(request-target)", "host", "date"}
+	if req.Method == http.MethodPost {
+		required = append(required, "digest")
+	}
+	for _, h := range required {
+		if !contains(headers, h) {
+			return fmt.Errorf("%w: %s is not signed", ErrSignature, h)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil || time.Since(date).Abs() > maxClockSkew {
+		return fmt.Errorf("%w: date is missing or too far off", ErrSignature)
+	}
+	if req.Method == http.MethodPost {
+		sum := sha256.Sum256(body)
+		digest := req.Header.Get("Digest")
+		if !strings.EqualFold(digest, "SHA-256="+base64.StdEncoding.EncodeToString(sum[:])) &&
+			!strings.EqualFold(digest, "sha-256="+hex.EncodeToString(sum[:])) {
+			return fmt.Errorf("%w: digest does not match the body", ErrSignature)
+		}
+	}
+
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return fmt.Errorf("%w: invalid public key", ErrSignature)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSignature, err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: public key is not RSA", ErrSignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return ErrSignature
+	}
+	hashed := sha256.Sum256([]byte(signingString(req, headers)))
+	if rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig) != nil {
+		return ErrSignature
+	}
+	return nil
+}
+
+// signingString is the text a signature covers: each header on a line
+func signingString(req *http.Request, headers []string) string {
+	lines := make([]string, len(headers))
+	for i, h := range headers {
+		switch h {
+		case "(request-target)":
+			lines[i] = h + ": " + strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			host := req.Header.Get("Host")
+			if host == "" {
+				host = req.Host
+			}
+			lines[i] = h + ": " + host
+		default:
+			lines[i] = h + ": " + strings.Join(req.Header.Values(h), ", ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// signatureParams parses a Signature header's key="value" pairs
+func signatureParams(header string) map[string]string {
+	params := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[key] = strings.Trim(value, `"`)
+		}
+	}
+	return params
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// randomSuffix makes activity IDs unique
+func randomSuffix() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
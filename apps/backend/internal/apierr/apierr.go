@@ -0,0 +1,96 @@
+// Package apierr defines the body of API error responses and the codes
+// they carry. Codes are stable: clients branch on them, while the
+// messages next to them are for people and may change.
+package apierr
+
+// Code identifies the kind of error a response reports
+type Code string
+
+// Request errors
+const (
+	InvalidRequestBody Code = "INVALID_REQUEST_BODY"
+	ValidationFailed   Code = "VALIDATION_FAILED"
+	PayloadTooLarge    Code = "PAYLOAD_TOO_LARGE"
+	Unauthorized       Code = "UNAUTHORIZED"
+	Forbidden          Code = "FORBIDDEN"
+	// SaveRefused is a link the domain rules or size limits keep from
+	// being saved
+	SaveRefused   Code = "SAVE_REFUSED"
+	DomainBlocked Code = "DOMAIN_BLOCKED"
+	Conflict      Code = "CONFLICT"
+	AliasTaken    Code = "ALIAS_TAKEN"
+	UsernameTaken Code = "USERNAME_TAKEN"
+	// IdempotencyKeyReused is an Idempotency-Key sent again with a
+	// different body
+	IdempotencyKeyReused Code = "IDEMPOTENCY_KEY_REUSED"
+	RateLimited          Code = "RATE_LIMITED"
+	// QuotaExceeded is a write that would take the account past one of
+	// its quotas
+	QuotaExceeded Code = "QUOTA_EXCEEDED"
+	// PreconditionFailed is a conditional write to something that changed
+	// since the client read it
+	PreconditionFailed Code = "PRECONDITION_FAILED"
+)
+
+// Missing resources
+const (
+	NotFound            Code = "NOT_FOUND"
+	AccountNotFound     Code = "ACCOUNT_NOT_FOUND"
+	AliasNotFound       Code = "ALIAS_NOT_FOUND"
+	BookmarkNotFound    Code = "BOOKMARK_NOT_FOUND"
+	ClipNotFound        Code = "CLIP_NOT_FOUND"
+	CollectionNotFound  Code = "COLLECTION_NOT_FOUND"
+	DeliveryNotFound    Code = "DELIVERY_NOT_FOUND"
+	DomainRuleNotFound  Code = "DOMAIN_RULE_NOT_FOUND"
+	ExportNotFound      Code = "EXPORT_NOT_FOUND"
+	HookNotFound        Code = "HOOK_NOT_FOUND"
+	IntegrationNotFound Code = "INTEGRATION_NOT_FOUND"
+	ProfileNotFound     Code = "PROFILE_NOT_FOUND"
+	ReminderNotFound    Code = "REMINDER_NOT_FOUND"
+	RevisionNotFound    Code = "REVISION_NOT_FOUND"
+	ShareNotFound       Code = "SHARE_NOT_FOUND"
+	TagNotFound         Code = "TAG_NOT_FOUND"
+)
+
+// Server errors
+const (
+	Internal Code = "INTERNAL_ERROR"
+	// NotSupported is a feature the storage driver or search backend in
+	// use does not provide
+	NotSupported Code = "NOT_SUPPORTED"
+	// FeatureDisabled is a feature this deployment has not turned on
+	FeatureDisabled Code = "FEATURE_DISABLED"
+	// UpstreamFailed is a third-party service or page that could not be
+	// reached or refused the request
+	UpstreamFailed Code = "UPSTREAM_FAILED"
+	// Timeout is a request that ran out of time before it was answered
+	Timeout     Code = "TIMEOUT"
+	Unavailable Code = "UNAVAILABLE"
+	Maintenance Code = "MAINTENANCE"
+	Overloaded  Code = "OVERLOADED"
+)
+
+// FieldError is a request field that failed validation, with the rule it
+// broke
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Response is the body of every error response. Success is always false
+// so clients can tell it from a data response by the same field.
+type Response struct {
+	Success bool   `json:"success"`
+	Code    Code   `json:"code"`
+	Error   string `json:"error"`
+	// Details holds more about the error, such as the state of the
+	// server or the underlying failure, when there is something to add
+	Details any          `json:"details,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// New returns the response for an error of kind code
+func New(code Code, message string) Response {
+	return Response{Code: code, Error: message}
+}
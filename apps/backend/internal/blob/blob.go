@@ -0,0 +1,127 @@
+// Package blob stores opaque files such as exports, page snapshots and
+// images on the local disk or in S3-compatible object storage.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by Get when no object exists under the key
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a flat namespace of objects addressed by slash-separated keys
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any existing
+	// object
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object under key; a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// Usage returns the total size in bytes of all objects under prefix
+	Usage(ctx context.Context, prefix string) (int64, error)
+	// List returns the objects under prefix, in key order
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Object describes a stored object
+type Object struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+}
+
+// Config selects and configures a blob store driver
+type Config struct {
+	Driver string // "local", "s3" or a registered third-party driver
+
+	// Local driver
+	Dir string
+
+	// S3 driver
+	S3Endpoint  string // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool // address the bucket in the path, as MinIO expects
+
+	// Options holds driver-specific settings from BLOB_OPTION_* variables,
+	// keyed by the lowercased suffix
+	Options map[string]string
+}
+
+// OpenFunc creates a store from its configuration
+type OpenFunc func(cfg Config) (Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]OpenFunc)
+)
+
+func init() {
+	Register("local", func(cfg Config) (Store, error) { return NewLocal(cfg.Dir) })
+	Register("s3", func(cfg Config) (Store, error) { return NewS3(cfg) })
+}
+
+// Register makes a blob driver available under name, selected with
+// BLOB_DRIVER. Drivers call it from an init function; it panics if name is
+// registered twice.
+func Register(name string, open OpenFunc) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if open == nil {
+		panic("blob: Register open func is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("blob: Register called twice for driver " + name)
+	}
+	drivers[name] = open
+}
+
+// Open returns the store selected by cfg.Driver, "local" by default
+func Open(cfg Config) (Store, error) {
+	name := cfg.Driver
+	if name == "" {
+		name = "local"
+	}
+	driversMu.RLock()
+	open, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown blob driver %q (available: %v)", name, Drivers())
+	}
+	return open(cfg)
+}
+
+// Drivers returns the names of the registered drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validKey rejects keys that could escape the store's namespace
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+// Package bookmarktree reads and writes the bookmark trees browsers keep:
+// Chrome's Bookmarks file, which Chromium-based browsers share, and
+// Firefox's JSON backups. Both are read into the same Tree, so a tree
+// read from one browser can be written for the other.
+package bookmarktree
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats
+const (
+	Chrome  = "chrome"
+	Firefox = "firefox"
+)
+
+// Root folders, named the same for every browser. Chrome has no menu.
+const (
+	RootToolbar = "Toolbar"
+	RootMenu    = "Bookmarks menu"
+	RootOther   = "Other bookmarks"
+	RootMobile  = "Mobile bookmarks"
+)
+
+// Node is a folder, or a bookmark when URL is set
+type Node struct {
+	Title    string
+	URL      string
+	Tags     []string
+	Added    time.Time
+	Modified time.Time
+	Children []*Node
+	// guid is kept so the browser recognizes nodes it wrote
+	guid string
+}
+
+// Folder reports whether n is a folder
+func (n *Node) Folder() bool {
+	return n.URL == ""
+}
+
+// Tree is a browser's bookmarks: one folder per root, titled with the
+// Root constants
+type Tree struct {
+	Format string
+	Roots  []*Node
+}
+
+// Root returns the root folder titled name, or nil
+func (t *Tree) Root(name string) *Node {
+	for _, r := range t.Roots {
+		if r.Title == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// chromeRoots and firefoxRoots map each format's root keys to the
+// shared root names, in the order the browser shows them
+var (
+	chromeRoots = [][2]string{
+		{"bookmark_bar", RootToolbar},
+		{"other", RootOther},
+		{"synced", RootMobile},
+	}
+	firefoxRoots = [][2]string{
+		{"bookmarksMenuFolder", RootMenu},
+		{"toolbarFolder", RootToolbar},
+		{"unfiledBookmarksFolder", RootOther},
+		{"mobileFolder", RootMobile},
+	}
+	// firefoxGUIDs are the fixed GUIDs of Firefox's roots
+	firefoxGUIDs = map[string]string{
+		"bookmarksMenuFolder":    "menu________",
+		"toolbarFolder":          "toolbar_____",
+		"unfiledBookmarksFolder": "unfiled_____",
+		"mobileFolder":           "mobile______",
+	}
+)
+
+// Empty returns a tree with a format's roots and nothing in them
+func Empty(format string) (*Tree, error) {
+	var roots [][2]string
+	switch format {
+	case Chrome:
+		roots = chromeRoots
+	case Firefox:
+		roots = firefoxRoots
+	default:
+		return nil, fmt.Errorf("unknown bookmark tree format %q", format)
+	}
+	t := &Tree{Format: format}
+	for _, r := range roots {
+		t.Roots = append(t.Roots, &Node{Title: r[1]})
+	}
+	return t, nil
+}
+
+// Parse reads a Chrome Bookmarks file or a Firefox JSON backup
+func Parse(data []byte) (*Tree, error) {
+	var probe struct {
+		Roots json.RawMessage `json:"roots"`
+		Type  string          `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	switch {
+	case probe.Roots != nil:
+		return parseChrome(probe.Roots)
+	case probe.Type == firefoxFolder:
+		return parseFirefox(data)
+	}
+	return nil, errors.New("not a Chrome Bookmarks file or Firefox bookmark backup")
+}
+
+// Marshal writes t in its format
+func (t *Tree) Marshal() ([]byte, error) {
+	switch t.Format {
+	case Chrome:
+		return t.marshalChrome()
+	case Firefox:
+		return t.marshalFirefox()
+	}
+	return nil, fmt.Errorf("unknown bookmark tree format %q", t.Format)
+}
+
+// chromeNode is a node of Chrome's Bookmarks file. Dates are microseconds
+// since 1601 as decimal strings.
+type chromeNode struct {
+	Children     []*chromeNode `json:"children,omitempty"`
+	DateAdded    string        `json:"date_added"`
+	DateModified string        `json:"date_modified,omitempty"`
+	GUID         string        `json:"guid,omitempty"`
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Type         string        `json:"type"`
+	URL          string        `json:"url,omitempty"`
+}
+
+// chromeEpochOffset is the number of microseconds from 1601, where
+// Chrome's timestamps count from, to 1970
+const chromeEpochOffset = 11644473600 * 1000000
+
+func chromeTime(s string) time.Time {
+	us, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || us <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(us - chromeEpochOffset).UTC()
+}
+
+func formatChromeTime(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixMicro()+chromeEpochOffset, 10)
+}
+
+func parseChrome(raw json.RawMessage) (*Tree, error) {
+	var roots map[string]*chromeNode
+	if err := json.Unmarshal(raw, &roots); err != nil {
+		return nil, err
+	}
+	t := &Tree{Format: Chrome}
+	for _, r := range chromeRoots {
+		n := &Node{Title: r[1]}
+		if c := roots[r[0]]; c != nil {
+			n = fromChrome(c)
+			n.Title = r[1]
+		}
+		t.Roots = append(t.Roots, n)
+	}
+	return t, nil
+}
+
+func fromChrome(c *chromeNode) *Node {
+	n := &Node{
+		Title:    c.Name,
+		Added:    chromeTime(c.DateAdded),
+		Modified: chromeTime(c.DateModified),
+		guid:     c.GUID,
+	}
+	if c.Type == "url" {
+		n.URL = c.URL
+		return n
+	}
+	for _, child := range c.Children {
+		if child.Type == "url" || child.Type == "folder" {
+			n.Children = append(n.Children, fromChrome(child))
+		}
+	}
+	return n
+}
+
+func (t *Tree) marshalChrome() ([]byte, error) {
+	var nextID int
+	var convert func(n *Node, name string) *chromeNode
+	convert = func(n *Node, name string) *chromeNode {
+		nextID++
+		c := &chromeNode{
+			DateAdded: formatChromeTime(n.Added),
+			GUID:      n.guid,
+			ID:        strconv.Itoa(nextID),
+			Name:      name,
+		}
+		if c.GUID == "" {
+			c.GUID = newUUID()
+		}
+		if !n.Folder() {
+			c.Type, c.URL = "url", n.URL
+			return c
+		}
+		c.Type = "folder"
+		c.DateModified = formatChromeTime(n.Modified)
+		c.Children = []*chromeNode{}
+		for _, child := range n.Children {
+			c.Children = append(c.Children, convert(child, child.Title))
+		}
+		return c
+	}
+
+	roots := make(map[string]*chromeNode)
+	names := map[string]string{"bookmark_bar": "Bookmarks bar", "other": "Other bookmarks", "synced": "Mobile bookmarks"}
+	for _, r := range chromeRoots {
+		n := t.Root(r[1])
+		if n == nil {
+			n = &Node{Title: r[1]}
+		}
+		roots[r[0]] = convert(n, names[r[0]])
+	}
+	// Chrome has no menu, so its contents go to a folder in Other
+	if menu := t.Root(RootMenu); menu != nil && len(menu.Children) > 0 {
+		other := roots["other"]
+		other.Children = append(other.Children, convert(&Node{Title: RootMenu, Children: menu.Children}, RootMenu))
+	}
+	return json.MarshalIndent(map[string]interface{}{"roots": roots, "version": 1}, "", "   ")
+}
+
+// Firefox backup node types
+const (
+	firefoxFolder    = "text/x-moz-place-container"
+	firefoxBookmark  = "text/x-moz-place"
+	firefoxTypeCode  = 1
+	firefoxFolderTC  = 2
+	firefoxRootsGUID = "root________"
+)
+
+// firefoxNode is a node of a Firefox JSON backup. Dates are microseconds
+// since 1970.
+type firefoxNode struct {
+	GUID         string         `json:"guid,omitempty"`
+	Title        string         `json:"title"`
+	Index        int            `json:"index"`
+	DateAdded    int64          `json:"dateAdded,omitempty"`
+	LastModified int64          `json:"lastModified,omitempty"`
+	TypeCode     int            `json:"typeCode"`
+	Type         string         `json:"type"`
+	Root         string         `json:"root,omitempty"`
+	URI          string         `json:"uri,omitempty"`
+	Tags         string         `json:"tags,omitempty"`
+	Children     []*firefoxNode `json:"children,omitempty"`
+}
+
+func firefoxTime(us int64) time.Time {
+	if us <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(us).UTC()
+}
+
+func formatFirefoxTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMicro()
+}
+
+func parseFirefox(data []byte) (*Tree, error) {
+	var root firefoxNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	byRoot := make(map[string]*firefoxNode)
+	for _, c := range root.Children {
+		if c.Root != "" {
+			byRoot[c.Root] = c
+		}
+	}
+	t := &Tree{Format: Firefox}
+	for _, r := range firefoxRoots {
+		n := &Node{Title: r[1]}
+		if f := byRoot[r[0]]; f != nil {
+			n = fromFirefox(f)
+			n.Title = r[1]
+		}
+		t.Roots = append(t.Roots, n)
+	}
+	return t, nil
+}
+
+func fromFirefox(f *firefoxNode) *Node {
+	n := &Node{
+		Title:    f.Title,
+		Added:    firefoxTime(f.DateAdded),
+		Modified: firefoxTime(f.LastModified),
+		guid:     f.GUID,
+	}
+	if f.Type == firefoxBookmark {
+		n.URL = f.URI
+		for _, tag := range strings.Split(f.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				n.Tags = append(n.Tags, tag)
+			}
+		}
+		return n
+	}
+	for _, child := range f.Children {
+		// Separators and queries have no place in a collection
+		if child.Type == firefoxFolder || (child.Type == firefoxBookmark && !strings.HasPrefix(child.URI, "place:")) {
+			n.Children = append(n.Children, fromFirefox(child))
+		}
+	}
+	return n
+}
+
+func (t *Tree) marshalFirefox() ([]byte, error) {
+	var convert func(n *Node, index int) *firefoxNode
+	convert = func(n *Node, index int) *firefoxNode {
+		f := &firefoxNode{
+			GUID:         n.guid,
+			Title:        n.Title,
+			Index:        index,
+			DateAdded:    formatFirefoxTime(n.Added),
+			LastModified: formatFirefoxTime(n.Modified),
+		}
+		if f.GUID == "" {
+			f.GUID = newFirefoxGUID()
+		}
+		if !n.Folder() {
+			f.TypeCode, f.Type, f.URI = firefoxTypeCode, firefoxBookmark, n.URL
+			f.Tags = strings.Join(n.Tags, ",")
+			return f
+		}
+		f.TypeCode, f.Type = firefoxFolderTC, firefoxFolder
+		f.Children = []*firefoxNode{}
+		for i, child := range n.Children {
+			f.Children = append(f.Children, convert(child, i))
+		}
+		return f
+	}
+
+	root := &firefoxNode{GUID: firefoxRootsGUID, TypeCode: firefoxFolderTC, Type: firefoxFolder, Root: "placesRoot"}
+	names := map[string]string{"bookmarksMenuFolder": "menu", "toolbarFolder": "toolbar", "unfiledBookmarksFolder": "unfiled", "mobileFolder": "mobile"}
+	for i, r := range firefoxRoots {
+		n := t.Root(r[1])
+		if n == nil {
+			n = &Node{Title: r[1]}
+		}
+		f := convert(n, i)
+		f.GUID, f.Root, f.Title = firefoxGUIDs[r[0]], r[0], names[r[0]]
+		root.Children = append(root.Children, f)
+	}
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// newUUID returns a random version 4 UUID, the form Chrome's GUIDs take
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// newFirefoxGUID returns a random 12-character GUID in Firefox's
+// URL-safe base64 alphabet
+func newFirefoxGUID() string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	b := make([]byte, 12)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[b[i]&63]
+	}
+	return string(b)
+}
@@ -0,0 +1,160 @@
+// Package client is a small Go client for the web-collector REST API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// Client talks to a web-collector server
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// New creates a client for the server at baseURL (e.g. http://localhost:8080)
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// APIError is returned for non-2xx responses
+type APIError struct {
+	Status int
+	// Code is the stable error code, such as BOOKMARK_NOT_FOUND
+	Code    string
+	Message string
+	Details interface{}
+}
+
+func (e *APIError) Error() string {
+	if e.Details != nil {
+		return fmt.Sprintf("%s (HTTP %d): %v", e.Message, e.Status, e.Details)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+type envelope struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Code    string          `json:"code"`
+	Error   string          `json:"error"`
+	Message string          `json:"message"`
+	Details interface{}     `json:"details"`
+}
+
+// do sends a request to path under /api/v1 and decodes the data field
+// of the response into out (if non-nil).
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+"/api/v1"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("decode response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode >= 300 || !env.Success {
+		msg := env.Error
+		if msg == "" {
+			msg = env.Message
+		}
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{Status: resp.StatusCode, Code: env.Code, Message: msg, Details: env.Details}
+	}
+	if out != nil && len(env.Data) > 0 {
+		return json.Unmarshal(env.Data, out)
+	}
+	return nil
+}
+
+// ListBookmarks returns bookmarks matching the optional query and tag
+func (c *Client) ListBookmarks(ctx context.Context, query, tag string) ([]model.Bookmark, error) {
+	params := url.Values{}
+	if query != "" {
+		params.Set("q", query)
+	}
+	if tag != "" {
+		params.Set("tag", tag)
+	}
+	path := "/bookmarks"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var bookmarks []model.Bookmark
+	err := c.do(ctx, http.MethodGet, path, nil, &bookmarks)
+	return bookmarks, err
+}
+
+// GetBookmark returns a bookmark by ID
+func (c *Client) GetBookmark(ctx context.Context, id string) (model.Bookmark, error) {
+	var b model.Bookmark
+	err := c.do(ctx, http.MethodGet, "/bookmarks/"+url.PathEscape(id), nil, &b)
+	return b, err
+}
+
+// CreateBookmark saves a new bookmark
+func (c *Client) CreateBookmark(ctx context.Context, req model.CreateBookmarkRequest) (model.Bookmark, error) {
+	var b model.Bookmark
+	err := c.do(ctx, http.MethodPost, "/bookmarks", req, &b)
+	return b, err
+}
+
+// UpdateBookmark changes an existing bookmark
+func (c *Client) UpdateBookmark(ctx context.Context, id string, req model.UpdateBookmarkRequest) (model.Bookmark, error) {
+	var b model.Bookmark
+	err := c.do(ctx, http.MethodPut, "/bookmarks/"+url.PathEscape(id), req, &b)
+	return b, err
+}
+
+// DeleteBookmark removes a bookmark
+func (c *Client) DeleteBookmark(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/bookmarks/"+url.PathEscape(id), nil, nil)
+}
+
+// LookupBookmark returns the bookmark saved under url, as its URL or an
+// alias
+func (c *Client) LookupBookmark(ctx context.Context, rawURL string) (model.Bookmark, error) {
+	var b model.Bookmark
+	err := c.do(ctx, http.MethodGet, "/bookmarks/lookup?"+url.Values{"url": {rawURL}}.Encode(), nil, &b)
+	return b, err
+}
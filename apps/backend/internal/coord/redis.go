@@ -0,0 +1,96 @@
+package coord
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "wc:coord:"
+
+// releaseScript deletes a lock only if it still holds our token, so an
+// instance whose lock expired can't release a lock taken over by another.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Redis coordinates instances through a shared Redis server using
+// SET NX locks and Pub/Sub.
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis connects to the Redis server at redisURL
+func NewRedis(redisURL string) (*Redis, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &Redis{client: client}, nil
+}
+
+// TryLock acquires a lock shared by all instances
+func (r *Redis) TryLock(ctx context.Context, name string, ttl time.Duration) (func(), bool, error) {
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	key := redisKeyPrefix + "lock:" + name
+
+	ok, err := r.client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	release := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := releaseScript.Run(ctx, r.client, []string{key}, token).Err(); err != nil {
+			log.Printf("coord: failed to release lock %s: %v", name, err)
+		}
+	}
+	return release, true, nil
+}
+
+// Publish broadcasts message to all instances
+func (r *Redis) Publish(ctx context.Context, channel, message string) error {
+	return r.client.Publish(ctx, redisKeyPrefix+channel, message).Err()
+}
+
+// Subscribe receives messages published by any instance until ctx is done
+func (r *Redis) Subscribe(ctx context.Context, channel string, handler func(string)) {
+	pubsub := r.client.Subscribe(ctx, redisKeyPrefix+channel)
+	go func() {
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				handler(msg.Payload)
+			}
+		}
+	}()
+}
+
+// Close closes the Redis connection pool
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
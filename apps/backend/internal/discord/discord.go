@@ -0,0 +1,298 @@
+// Package discord saves links posted in Discord channels. Watched
+// channels are read through the REST API, since a gateway connection
+// would need a websocket client, and the /save command arrives on the
+// application's interactions endpoint.
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiURL is the REST API endpoint; the resource path is appended
+const apiURL = "https://discord.com/api/v10"
+
+// Config enables the bot; it is disabled without a token
+type Config struct {
+	Token string
+	// PublicKey is the application's hex Ed25519 key, which signs
+	// interactions; without it the /save command is not answered
+	PublicKey string
+	// Channels maps channel IDs to the collections their links are saved
+	// into, as comma-separated channel=collection pairs
+	Channels string
+}
+
+// Bot reads watched channels and checks interactions
+type Bot struct {
+	token     string
+	publicKey ed25519.PublicKey
+	channels  map[string]string
+	client    *http.Client
+
+	mu sync.Mutex
+	// cursors holds the newest message read in each channel
+	cursors map[string]string
+}
+
+var snowflakePattern = regexp.MustCompile(`^[0-9]{1,20}$`)
+
+// Open returns the configured bot, or nil when no token is set
+func Open(cfg Config) (*Bot, error) {
+	if cfg.Token == "" {
+		return nil, nil
+	}
+	b := &Bot{
+		token:    cfg.Token,
+		channels: make(map[string]string),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		cursors:  make(map[string]string),
+	}
+	if cfg.PublicKey != "" {
+		key, err := hex.DecodeString(cfg.PublicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("Discord public key must be the application's 64-character hex key")
+		}
+		b.publicKey = key
+	}
+	for _, pair := range strings.Split(cfg.Channels, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		channel, collection, ok := strings.Cut(pair, "=")
+		channel, collection = strings.TrimSpace(channel), strings.TrimSpace(collection)
+		if !ok || collection == "" {
+			return nil, fmt.Errorf("Discord channel %q needs a collection, as channel=collection", pair)
+		}
+		if !snowflakePattern.MatchString(channel) {
+			return nil, fmt.Errorf("Discord channel ID %q is not a number", channel)
+		}
+		b.channels[channel] = collection
+	}
+	return b, nil
+}
+
+// Channels returns the IDs of the watched channels
+func (b *Bot) Channels() []string {
+	ids := make([]string, 0, len(b.channels))
+	for id := range b.channels {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Collection returns the collection a channel's links are saved into
+func (b *Bot) Collection(channelID string) (string, bool) {
+	col, ok := b.channels[channelID]
+	return col, ok
+}
+
+// Interactive reports whether the /save command can be verified
+func (b *Bot) Interactive() bool {
+	return b.publicKey != nil
+}
+
+// Verify reports whether body was signed by Discord with the
+// application's key
+func (b *Bot) Verify(header http.Header, body []byte) bool {
+	if b.publicKey == nil {
+		return false
+	}
+	sig, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	msg := append([]byte(header.Get("X-Signature-Timestamp")), body...)
+	return ed25519.Verify(b.publicKey, msg, sig)
+}
+
+// Message is a channel message
+type Message struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Author  struct {
+		ID  string `json:"id"`
+		Bot bool   `json:"bot"`
+	} `json:"author"`
+}
+
+// NewMessages returns the messages posted in a channel since the last
+// call, oldest first. The first call only notes where the channel is,
+// so history from before the bot started watching is not saved.
+func (b *Bot) NewMessages(ctx context.Context, channelID string) ([]Message, error) {
+	b.mu.Lock()
+	after, seen := b.cursors[channelID]
+	b.mu.Unlock()
+
+	query := url.Values{"limit": {"100"}}
+	if seen {
+		query.Set("after", after)
+	} else {
+		query.Set("limit", "1")
+	}
+	var messages []Message
+	if err := b.do(ctx, http.MethodGet, "/channels/"+channelID+"/messages?"+query.Encode(), &messages); err != nil {
+		return nil, err
+	}
+	// Discord lists newest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(messages) > 0 {
+		b.cursors[channelID] = messages[len(messages)-1].ID
+	} else if !seen {
+		b.cursors[channelID] = "0"
+	}
+	if !seen {
+		return nil, nil
+	}
+	return messages, nil
+}
+
+// React adds an emoji reaction to a message, to acknowledge it
+func (b *Bot) React(ctx context.Context, channelID, messageID, emoji string) error {
+	path := "/channels/" + channelID + "/messages/" + messageID + "/reactions/" + url.PathEscape(emoji) + "/@me"
+	return b.do(ctx, http.MethodPut, path, nil)
+}
+
+func (b *Bot) do(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+b.token)
+	req.Header.Set("User-Agent", "DiscordBot (web-collector, 1.0)")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("%s %s: %s", method, strings.SplitN(path, "?", 2)[0], apiErr.Message)
+		}
+		return fmt.Errorf("%s %s: %s", method, strings.SplitN(path, "?", 2)[0], resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Link is what a message asks to save: its first http(s) URL, its
+// #hashtags as tags, and the rest of its words as the title
+type Link struct {
+	URL   string
+	Title string
+	Tags  []string
+}
+
+// ParseLink reads the link out of message content. A link in angle
+// brackets is one posted without an embed; mentions such as <#123> are
+// left in the title.
+func ParseLink(content string) (Link, bool) {
+	var (
+		link  Link
+		title []string
+	)
+	for _, word := range strings.Fields(content) {
+		bare := strings.TrimSuffix(strings.TrimPrefix(word, "<"), ">")
+		switch {
+		case strings.HasPrefix(word, "#") && len(word) > 1:
+			link.Tags = append(link.Tags, strings.TrimPrefix(word, "#"))
+		case link.URL == "" && isWebURL(bare):
+			link.URL = bare
+		default:
+			title = append(title, word)
+		}
+	}
+	link.Title = strings.Join(title, " ")
+	return link, link.URL != ""
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Interaction types and response types used by the /save command
+const (
+	InteractionPing    = 1
+	InteractionCommand = 2
+
+	ResponsePong    = 1
+	ResponseMessage = 4
+
+	// flagEphemeral shows a response only to the user who ran the command
+	flagEphemeral = 1 << 6
+)
+
+// Interaction is a request sent to the interactions endpoint
+type Interaction struct {
+	Type      int    `json:"type"`
+	ChannelID string `json:"channel_id"`
+	Data      struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+// Option returns a string option of a command, or "" when it is unset
+func (i Interaction) Option(name string) string {
+	for _, o := range i.Data.Options {
+		if o.Name == name {
+			var s string
+			if json.Unmarshal(o.Value, &s) == nil {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// Response answers an interaction
+type Response struct {
+	Type int           `json:"type"`
+	Data *ResponseData `json:"data,omitempty"`
+}
+
+// ResponseData is the message a response posts
+type ResponseData struct {
+	Content string `json:"content"`
+	Flags   int    `json:"flags,omitempty"`
+}
+
+// Pong answers the ping Discord sends to check the endpoint
+func Pong() Response {
+	return Response{Type: ResponsePong}
+}
+
+// Reply posts content in the channel the command was run in
+func Reply(content string) Response {
+	return Response{Type: ResponseMessage, Data: &ResponseData{Content: content}}
+}
+
+// Ephemeral answers only the user who ran the command
+func Ephemeral(content string) Response {
+	return Response{Type: ResponseMessage, Data: &ResponseData{Content: content, Flags: flagEphemeral}}
+}
@@ -0,0 +1,208 @@
+// Package encrypt seals files at rest with AES-256-GCM.
+//
+// Data is split into 64 KiB chunks that are sealed independently (the
+// STREAM construction), so arbitrarily large files can be encrypted and
+// decrypted without holding them in memory, and truncation or reordering
+// of chunks is detected. The file format is:
+//
+//	magic "WCENC1" | 7-byte random nonce prefix | sealed chunks...
+//
+// Each chunk's nonce is the prefix, a 4-byte big-endian counter and a
+// final-chunk flag.
+package encrypt
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	magic       = "WCENC1"
+	prefixSize  = 7
+	chunkSize   = 64 * 1024
+	overhead    = 16 // GCM tag
+	sealedChunk = chunkSize + overhead
+)
+
+// KeySize is the length of an encryption key in bytes
+const KeySize = 32
+
+// ErrCorrupt is returned when a file is not valid or has been tampered with
+var ErrCorrupt = errors.New("encrypted data is corrupt or was sealed with a different key")
+
+// ParseKey decodes a base64-encoded 256-bit key
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(prefix []byte, counter uint32, final bool) []byte {
+	n := make([]byte, 12)
+	copy(n, prefix)
+	binary.BigEndian.PutUint32(n[prefixSize:], counter)
+	if final {
+		n[11] = 1
+	}
+	return n
+}
+
+// Writer encrypts everything written to it. Close must be called to seal
+// the final chunk; it does not close the underlying writer.
+type Writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+// NewWriter writes the header to w and returns a Writer sealing with key
+func NewWriter(w io.Writer, key []byte) (*Writer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, magic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("encrypt: write after close")
+	}
+	written := 0
+	for len(p) > 0 {
+		// Only flush when more data follows, so the last chunk is always
+		// sealed by Close with the final flag set.
+		if len(w.buf) == chunkSize {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *Writer) flush(final bool) error {
+	if w.counter == math.MaxUint32 {
+		return errors.New("encrypt: file too large")
+	}
+	sealed := w.aead.Seal(nil, nonce(w.prefix, w.counter, final), w.buf, nil)
+	w.counter++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+// Close seals the final chunk
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+// Reader decrypts a stream produced by Writer
+type Reader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	chunk   []byte
+	sealed  []byte
+	done    bool
+}
+
+// NewReader reads the header from r and returns a Reader opening with key
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(magic)+prefixSize)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(magic)]) != magic {
+		return nil, ErrCorrupt
+	}
+	return &Reader{
+		r:      bufio.NewReaderSize(r, sealedChunk+1),
+		aead:   aead,
+		prefix: header[len(magic):],
+		sealed: make([]byte, sealedChunk),
+	}, nil
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+func (r *Reader) next() error {
+	n, err := io.ReadFull(r.r, r.sealed)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			// The stream ended without a final chunk: truncated.
+			return ErrCorrupt
+		}
+		return err
+	}
+	// A chunk is final if nothing follows it.
+	_, peekErr := r.r.Peek(1)
+	final := peekErr == io.EOF
+	if n < overhead {
+		return ErrCorrupt
+	}
+	plain, err := r.aead.Open(r.sealed[:0:0], nonce(r.prefix, r.counter, final), r.sealed[:n], nil)
+	if err != nil {
+		return ErrCorrupt
+	}
+	r.counter++
+	r.chunk = plain
+	r.done = final
+	return nil
+}
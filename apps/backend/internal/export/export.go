@@ -0,0 +1,144 @@
+// Package export renders bookmark collections into downloadable formats.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// Format describes one export format. Formats that include highlights
+// set WriteWithClips instead of Write.
+type Format struct {
+	Name           string
+	ContentType    string
+	Extension      string
+	Write          func(w io.Writer, bookmarks []model.Bookmark) error
+	WriteWithClips func(w io.Writer, bookmarks []model.Bookmark, clips Clips) error
+}
+
+// Clips returns the clips of a bookmark, oldest first
+type Clips func(bookmarkID string) []model.Clip
+
+var formats = map[string]Format{
+	"json": {
+		Name:        "json",
+		ContentType: "application/json",
+		Extension:   ".json",
+		Write:       writeJSON,
+	},
+	"csv": {
+		Name:        "csv",
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   ".csv",
+		Write:       writeCSV,
+	},
+	"html": {
+		Name:        "html",
+		ContentType: "text/html; charset=utf-8",
+		Extension:   ".html",
+		Write:       writeNetscape,
+	},
+	"shaarli": {
+		Name:        "shaarli",
+		ContentType: "text/html; charset=utf-8",
+		Extension:   ".html",
+		Write:       writeShaarli,
+	},
+	"xbel": {
+		Name:        "xbel",
+		ContentType: "application/xml; charset=utf-8",
+		Extension:   ".xbel",
+		Write:       writeXBEL,
+	},
+	"markdown": {
+		Name:           "markdown",
+		ContentType:    "application/zip",
+		Extension:      ".zip",
+		WriteWithClips: writeMarkdown,
+	},
+	"site": {
+		Name:        "site",
+		ContentType: "application/zip",
+		Extension:   ".zip",
+		Write:       writeSite,
+	},
+}
+
+// Lookup returns the format registered under name
+func Lookup(name string) (Format, bool) {
+	f, ok := formats[name]
+	return f, ok
+}
+
+// Names returns the names of all supported formats
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeJSON(w io.Writer, bookmarks []model.Bookmark) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bookmarks)
+}
+
+func writeCSV(w io.Writer, bookmarks []model.Bookmark) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "title", "url", "tags", "created_at"}); err != nil {
+		return err
+	}
+	for _, b := range bookmarks {
+		if err := cw.Write([]string{b.ID, csvCell(b.Title), csvCell(b.URL), csvCell(strings.Join(b.Tags, ",")), b.CreatedAt.Format(time.RFC3339)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvFormulaPrefixes start a cell that spreadsheets evaluate as a formula
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvCell quotes a cell that a spreadsheet would evaluate as a formula by
+// prefixing it with an apostrophe, so opening an export cannot run
+// anything a bookmark's author put in its title or tags.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// writeNetscape writes the Netscape bookmark file format understood by
+// every major browser's "import bookmarks" dialog.
+func writeNetscape(w io.Writer, bookmarks []model.Bookmark) error {
+	if _, err := io.WriteString(w, `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`); err != nil {
+		return err
+	}
+	for _, b := range bookmarks {
+		if _, err := fmt.Fprintf(w, "    <DT><A HREF=\"%s\" ADD_DATE=\"%d\" TAGS=\"%s\">%s</A>\n",
+			html.EscapeString(b.URL), b.CreatedAt.Unix(), html.EscapeString(strings.Join(b.Tags, ",")),
+			html.EscapeString(b.Title)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</DL><p>\n")
+	return err
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// maxNoteName is the longest file name, in runes, a note is given before
+// its extension
+const maxNoteName = 100
+
+// writeMarkdown writes one Markdown note per bookmark into a zip, laid out
+// as an Obsidian vault folder. Each note has YAML front matter with the
+// URL, tags and dates, and a body with the bookmark's notes and
+// highlights.
+func writeMarkdown(w io.Writer, bookmarks []model.Bookmark, clips Clips) error {
+	zw := zip.NewWriter(w)
+	taken := make(map[string]bool)
+	for _, b := range bookmarks {
+		name := noteName(b, taken)
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     path.Join("Bookmarks", name+".md"),
+			Method:   zip.Deflate,
+			Modified: b.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, markdownNote(b, clips(b.ID))); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// markdownNote renders a bookmark as a Markdown note
+func markdownNote(b model.Bookmark, highlights []model.Clip) string {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	fmt.Fprintf(&sb, "title: %s\n", yamlString(b.Title))
+	fmt.Fprintf(&sb, "url: %s\n", yamlString(b.URL))
+	if len(b.Tags) > 0 {
+		sb.WriteString("tags:\n")
+		for _, tag := range b.Tags {
+			fmt.Fprintf(&sb, "  - %s\n", yamlString(noteTag(tag)))
+		}
+	}
+	fmt.Fprintf(&sb, "created: %s\n", b.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "updated: %s\n", b.UpdatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "read: %t\n", b.IsRead)
+	fmt.Fprintf(&sb, "archived: %t\n", b.Archived)
+	sb.WriteString("---\n\n")
+
+	fmt.Fprintf(&sb, "# [%s](<%s>)\n", markdownEscaper.Replace(b.Title), b.URL)
+	if notes := strings.TrimSpace(b.Notes); notes != "" {
+		sb.WriteString("\n" + notes + "\n")
+	}
+	if len(highlights) > 0 {
+		sb.WriteString("\n## Highlights\n")
+		for _, h := range highlights {
+			text := strings.TrimSpace(h.Text)
+			if text == "" {
+				continue
+			}
+			sb.WriteString("\n> " + strings.ReplaceAll(text, "\n", "\n> ") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// yamlString quotes s as a YAML double-quoted scalar, whose escapes are a
+// superset of JSON's
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// noteTag makes a tag valid in Obsidian, which ends tags at whitespace
+func noteTag(tag string) string {
+	return strings.Join(strings.Fields(tag), "-")
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, "\n", " ")
+
+// noteName derives a file name from a bookmark's title that is valid on
+// every platform and in Obsidian links, numbering names already taken
+func noteName(b model.Bookmark, taken map[string]bool) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(`/\:*?"<>|#^[]`, r), unicode.IsControl(r):
+			return ' '
+		}
+		return r
+	}, b.Title)
+	name = strings.Trim(strings.Join(strings.Fields(name), " "), ". ")
+	if runes := []rune(name); len(runes) > maxNoteName {
+		name = strings.TrimSpace(string(runes[:maxNoteName]))
+	}
+	if name == "" {
+		name = "Bookmark " + b.ID
+	}
+
+	unique := name
+	for i := 2; taken[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s %d", name, i)
+	}
+	taken[strings.ToLower(unique)] = true
+	return unique
+}
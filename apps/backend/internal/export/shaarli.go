@@ -0,0 +1,51 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// writeShaarli writes the Netscape bookmark file the way Shaarli exports
+// it, so Shaarli and tools reading its exports import it as their own:
+// every link is public, tags are separated by spaces, and notes follow
+// their link as a <DD> description.
+func writeShaarli(w io.Writer, bookmarks []model.Bookmark) error {
+	if _, err := io.WriteString(w, `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     Do Not Edit! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Shaarli export</TITLE>
+<H1>Shaarli export</H1>
+<DL><p>
+`); err != nil {
+		return err
+	}
+	for _, b := range bookmarks {
+		if _, err := fmt.Fprintf(w, "<DT><A HREF=\"%s\" ADD_DATE=\"%d\" LAST_MODIFIED=\"%d\" PRIVATE=\"0\" TAGS=\"%s\">%s</A>\n",
+			html.EscapeString(b.URL), b.CreatedAt.Unix(), b.UpdatedAt.Unix(),
+			html.EscapeString(strings.Join(shaarliTags(b.Tags), " ")), html.EscapeString(b.Title)); err != nil {
+			return err
+		}
+		if b.Notes != "" {
+			if _, err := fmt.Fprintf(w, "<DD>%s\n", strings.ReplaceAll(html.EscapeString(b.Notes), "\n", "<br>")); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, "</DL><p>\n")
+	return err
+}
+
+// shaarliTags replaces the spaces Shaarli does not allow in tags
+func shaarliTags(tags []string) []string {
+	result := make([]string, len(tags))
+	for i, tag := range tags {
+		result[i] = strings.Join(strings.Fields(tag), "-")
+	}
+	return result
+}
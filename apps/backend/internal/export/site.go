@@ -0,0 +1,184 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// writeSite renders the library as a self-contained static website packed
+// into a zip: an index page with client-side search, one page per tag and
+// a JSON search index. Unzipped, it can be published as-is on GitHub Pages
+// or any static host.
+func writeSite(w io.Writer, bookmarks []model.Bookmark) error {
+	sorted := make([]model.Bookmark, len(bookmarks))
+	copy(sorted, bookmarks)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.After(sorted[j].CreatedAt) })
+
+	byTag := make(map[string][]model.Bookmark)
+	for _, b := range sorted {
+		for _, tag := range b.Tags {
+			byTag[tag] = append(byTag[tag], b)
+		}
+	}
+	tags := make([]siteTag, 0, len(byTag))
+	for name, items := range byTag {
+		tags = append(tags, siteTag{Name: name, Count: len(items)})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	zw := zip.NewWriter(w)
+	page := func(name string, data sitePage) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		return siteTemplate.Execute(f, data)
+	}
+
+	if err := page("index.html", sitePage{Title: "Bookmarks", Root: "", Bookmarks: sorted, Tags: tags, Search: true}); err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if err := page("tags/"+tagFile(t.Name), sitePage{Title: "#" + t.Name, Root: "../", Bookmarks: byTag[t.Name], Tags: tags}); err != nil {
+			return err
+		}
+	}
+
+	index := make([]siteIndexEntry, 0, len(sorted))
+	for _, b := range sorted {
+		index = append(index, siteIndexEntry{Title: b.Title, URL: b.URL, Tags: b.Tags, Date: b.CreatedAt.Format("2006-01-02")})
+	}
+	f, err := zw.Create("search-index.json")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(index); err != nil {
+		return err
+	}
+
+	for _, asset := range [][2]string{{"style.css", siteCSS}, {"search.js", siteJS}} {
+		f, err := zw.Create(asset[0])
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, asset[1]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+type siteTag struct {
+	Name  string
+	Count int
+}
+
+type sitePage struct {
+	Title     string
+	Root      string
+	Bookmarks []model.Bookmark
+	Tags      []siteTag
+	Search    bool
+}
+
+type siteIndexEntry struct {
+	Title string   `json:"title"`
+	URL   string   `json:"url"`
+	Tags  []string `json:"tags"`
+	Date  string   `json:"date"`
+}
+
+// tagFile maps a tag to a file name that is safe on every static host and
+// needs no URL escaping: ASCII letters, digits and dashes are kept, anything
+// else is hex-encoded.
+func tagFile(tag string) string {
+	var b strings.Builder
+	for _, r := range tag {
+		if r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "_%x", r)
+		}
+	}
+	return b.String() + ".html"
+}
+
+var siteTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"tagFile": tagFile,
+	"host": func(raw string) string {
+		if u, err := url.Parse(raw); err == nil {
+			return u.Host
+		}
+		return ""
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}style.css">
+</head>
+<body>
+<header>
+  <h1><a href="{{.Root}}index.html">Bookmarks</a>{{if not .Search}} / {{.Title}}{{end}}</h1>
+  {{if .Search}}<input id="search" type="search" placeholder="Search {{len .Bookmarks}} bookmarks" autofocus>{{end}}
+</header>
+<nav>{{range .Tags}}<a href="{{$.Root}}tags/{{tagFile .Name}}">#{{.Name}} <small>{{.Count}}</small></a> {{end}}</nav>
+<ul id="bookmarks">
+{{range .Bookmarks}}  <li>
+    <a href="{{.URL}}" rel="noopener">{{.Title}}</a>
+    <span class="meta">{{host .URL}} &middot; {{.CreatedAt.Format "2006-01-02"}}{{range .Tags}} &middot; <a href="{{$.Root}}tags/{{tagFile .}}">#{{.}}</a>{{end}}</span>
+  </li>
+{{end}}</ul>
+{{if .Search}}<script src="search.js"></script>{{end}}
+</body>
+</html>
+`))
+
+const siteCSS = `body { font: 16px/1.5 system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h1 a { color: inherit; text-decoration: none; }
+#search { width: 100%; padding: .5rem; font-size: 1rem; box-sizing: border-box; }
+nav { margin: 1rem 0; line-height: 2; }
+nav a { margin-right: .5rem; }
+ul { list-style: none; padding: 0; }
+li { margin: .75rem 0; }
+.meta { display: block; color: #777; font-size: .85rem; }
+a { color: #0b57d0; }
+`
+
+const siteJS = `(function () {
+  var input = document.getElementById('search');
+  var list = document.getElementById('bookmarks');
+  var original = list.innerHTML;
+  var index = [];
+  fetch('search-index.json').then(function (r) { return r.json(); }).then(function (data) { index = data; });
+
+  function escape(s) {
+    return s.replace(/[&<>"']/g, function (c) {
+      return { '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' }[c];
+    });
+  }
+
+  input.addEventListener('input', function () {
+    var terms = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+    if (!terms.length) { list.innerHTML = original; return; }
+    list.innerHTML = index.filter(function (b) {
+      var text = (b.title + ' ' + b.url + ' ' + b.tags.join(' ')).toLowerCase();
+      return terms.every(function (t) { return text.indexOf(t) !== -1; });
+    }).map(function (b) {
+      var href = /^https?:/i.test(b.url) ? b.url : '#';
+      return '<li><a href="' + escape(href) + '" rel="noopener">' + escape(b.title) + '</a>' +
+        '<span class="meta">' + escape(b.date) + (b.tags.length ? ' &middot; #' + b.tags.map(escape).join(' #') : '') + '</span></li>';
+    }).join('');
+  });
+})();
+`
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// xbelOwner identifies our metadata in XBEL files. XBEL has no tags, so
+// they are kept there, where the importer finds them again.
+const xbelOwner = "https://github.com/hereisth/web-collector"
+
+type xbelDocument struct {
+	XMLName   xml.Name       `xml:"xbel"`
+	Version   string         `xml:"version,attr"`
+	Title     string         `xml:"title"`
+	Bookmarks []xbelBookmark `xml:"bookmark"`
+}
+
+type xbelBookmark struct {
+	Href     string        `xml:"href,attr"`
+	Added    string        `xml:"added,attr,omitempty"`
+	Modified string        `xml:"modified,attr,omitempty"`
+	Title    string        `xml:"title"`
+	Metadata *xbelMetadata `xml:"info>metadata,omitempty"`
+}
+
+type xbelMetadata struct {
+	Owner string   `xml:"owner,attr"`
+	Tags  []string `xml:"tag"`
+}
+
+// writeXBEL writes XBEL 1.0, the XML bookmark format read by KDE,
+// qutebrowser and other XBEL tools
+func writeXBEL(w io.Writer, bookmarks []model.Bookmark) error {
+	doc := xbelDocument{Version: "1.0", Title: "Bookmarks"}
+	for _, b := range bookmarks {
+		xb := xbelBookmark{Href: b.URL, Title: b.Title, Added: xbelTime(b.CreatedAt), Modified: xbelTime(b.UpdatedAt)}
+		if len(b.Tags) > 0 {
+			xb.Metadata = &xbelMetadata{Owner: xbelOwner, Tags: b.Tags}
+		}
+		doc.Bookmarks = append(doc.Bookmarks, xb)
+	}
+	if _, err := io.WriteString(w, xml.Header+`<!DOCTYPE xbel PUBLIC "+//IDN python.org//DTD XML Bookmark Exchange Language 1.0//EN//XML" "http://pyxml.sourceforge.net/topics/dtds/xbel.dtd">`+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func xbelTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,95 @@
+// Package extract pulls readable text out of web pages.
+package extract
+
+import (
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// skipped are elements whose text is not part of what a reader reads:
+// code, markup and page chrome around the article
+var skipped = map[string]bool{
+	"head":     true,
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+	"svg":      true,
+	"nav":      true,
+	"header":   true,
+	"footer":   true,
+	"aside":    true,
+	"form":     true,
+}
+
+// Words counts the words of readable text in an HTML document
+func Words(r io.Reader) int {
+	words := 0
+	walk(r, func(text string) bool {
+		words += countWords(text)
+		return true
+	})
+	return words
+}
+
+// Text returns the readable text of an HTML document with whitespace
+// collapsed, cut at about limit bytes
+func Text(r io.Reader, limit int) string {
+	var b strings.Builder
+	walk(r, func(text string) bool {
+		for _, field := range strings.Fields(text) {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(field)
+		}
+		return b.Len() < limit
+	})
+	return b.String()
+}
+
+// walk calls fn with the readable text of an HTML document, piece by
+// piece, until fn returns false
+func walk(r io.Reader, fn func(text string) bool) {
+	var (
+		z     = html.NewTokenizer(r)
+		depth int // nesting depth inside skipped elements
+	)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if skipped[string(name)] {
+				depth++
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if skipped[string(name)] && depth > 0 {
+				depth--
+			}
+		case html.TextToken:
+			if depth == 0 && !fn(string(z.Text())) {
+				return
+			}
+		}
+	}
+}
+
+// countWords counts whitespace-separated tokens that contain at least one
+// letter or digit, so stray punctuation is not counted
+func countWords(text string) int {
+	n := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r)
+		}) >= 0 {
+			n++
+		}
+	}
+	return n
+}
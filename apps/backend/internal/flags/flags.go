@@ -0,0 +1,114 @@
+// Package flags gates experimental features per deployment or per user.
+package flags
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Known feature flags
+const (
+	SemanticSearch = "semantic_search"
+	Archiving      = "archiving"
+	Thumbnails     = "thumbnails"
+	ReadingTime    = "reading_time"
+)
+
+// Flag describes a feature flag and its built-in default
+type Flag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+var definitions = map[string]Flag{
+	SemanticSearch: {Name: SemanticSearch, Description: "Embedding-based semantic search", Default: false},
+	Archiving:      {Name: Archiving, Description: "Store page snapshots alongside bookmarks", Default: false},
+	Thumbnails:     {Name: Thumbnails, Description: "Generate preview thumbnails from og:image", Default: false},
+	ReadingTime:    {Name: ReadingTime, Description: "Estimate reading time from the word count of bookmarked pages", Default: false},
+}
+
+// Set holds deployment-wide flag values plus per-user overrides
+type Set struct {
+	mu        sync.RWMutex
+	values    map[string]bool
+	overrides map[string]map[string]bool // flag -> user -> value
+}
+
+// Parse builds a Set from a spec such as
+// "archiving=true,semantic_search=false,semantic_search@alice=true".
+// Bare names enable a flag; "name@user" entries override it for one user.
+func Parse(spec string) (*Set, error) {
+	s := &Set{
+		values:    make(map[string]bool),
+		overrides: make(map[string]map[string]bool),
+	}
+	for name, def := range definitions {
+		s.values[name] = def.Default
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, raw, hasValue := strings.Cut(entry, "=")
+		value := true
+		if hasValue {
+			v, err := strconv.ParseBool(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for feature flag %q: %q", key, raw)
+			}
+			value = v
+		}
+
+		name, user, perUser := strings.Cut(strings.TrimSpace(key), "@")
+		if _, ok := definitions[name]; !ok {
+			return nil, fmt.Errorf("unknown feature flag %q", name)
+		}
+		if !perUser {
+			s.values[name] = value
+			continue
+		}
+		if s.overrides[name] == nil {
+			s.overrides[name] = make(map[string]bool)
+		}
+		s.overrides[name][user] = value
+	}
+	return s, nil
+}
+
+// Enabled reports whether a flag is on for user ("" for anonymous callers)
+func (s *Set) Enabled(name, user string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if user != "" {
+		if v, ok := s.overrides[name][user]; ok {
+			return v
+		}
+	}
+	return s.values[name]
+}
+
+// Evaluate returns the value of every known flag for user
+func (s *Set) Evaluate(user string) map[string]bool {
+	result := make(map[string]bool, len(definitions))
+	for name := range definitions {
+		result[name] = s.Enabled(name, user)
+	}
+	return result
+}
+
+// Definitions lists all known flags sorted by name
+func Definitions() []Flag {
+	result := make([]Flag, 0, len(definitions))
+	for _, f := range definitions {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
@@ -0,0 +1,129 @@
+// Package ical writes iCalendar (RFC 5545) feeds that calendar apps can
+// subscribe to.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLine is the longest content line, in octets, before it is folded
+const maxLine = 75
+
+// Calendar is a published calendar. Refresh is how often subscribers are
+// asked to fetch it again.
+type Calendar struct {
+	Name    string
+	ProdID  string
+	Refresh time.Duration
+	Events  []Event
+}
+
+// Event is a calendar entry. All-day events take the date of Start and
+// ignore Duration. An event with Alarm set notifies at its start.
+type Event struct {
+	UID         string
+	Start       time.Time
+	Duration    time.Duration
+	AllDay      bool
+	Summary     string
+	Description string
+	URL         string
+	Alarm       bool
+}
+
+// Write writes c as an iCalendar stream stamped with now
+func (c *Calendar) Write(w io.Writer, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", c.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+	if c.Refresh > 0 {
+		line("REFRESH-INTERVAL;VALUE=DURATION", duration(c.Refresh))
+		line("X-PUBLISHED-TTL", duration(c.Refresh))
+	}
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(e.UID))
+		line("DTSTAMP", dateTime(now))
+		if e.AllDay {
+			line("DTSTART;VALUE=DATE", e.Start.Format("20060102"))
+		} else {
+			line("DTSTART", dateTime(e.Start))
+			if e.Duration > 0 {
+				line("DURATION", duration(e.Duration))
+			}
+		}
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.URL != "" {
+			line("URL;VALUE=URI", e.URL)
+		}
+		if e.Alarm {
+			line("BEGIN", "VALARM")
+			line("ACTION", "DISPLAY")
+			line("DESCRIPTION", escape(e.Summary))
+			line("TRIGGER", "PT0S")
+			line("END", "VALARM")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeLine ends a content line with CRLF, folding it so no line exceeds
+// maxLine octets without splitting a UTF-8 sequence
+func writeLine(w *bufio.Writer, s string) {
+	limit := maxLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts
+		limit = maxLine - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape makes s a TEXT value
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func dateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// duration formats d as a whole number of seconds, minutes or hours
+func duration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return "PT" + strconv.Itoa(int(d/time.Hour)) + "H"
+	case d%time.Minute == 0:
+		return "PT" + strconv.Itoa(int(d/time.Minute)) + "M"
+	}
+	return "PT" + strconv.Itoa(int(d/time.Second)) + "S"
+}
@@ -0,0 +1,312 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+)
+
+// HistoryFilter decides which pages of a browsing history become
+// bookmarks. Domain and date filters apply to every page; MinVisits only
+// to pages that were not bookmarked in the browser.
+type HistoryFilter struct {
+	// MinVisits skips pages visited fewer times
+	MinVisits int
+	// Domains, when set, keeps only pages on these domains or their
+	// subdomains
+	Domains []string
+	// ExcludeDomains skips pages on these domains or their subdomains
+	ExcludeDomains []string
+	// Since and Until bound the last visit; zero leaves a side open
+	Since, Until time.Time
+}
+
+// DefaultHistoryFilter keeps pages visited at least three times, plus
+// starred ones
+var DefaultHistoryFilter = HistoryFilter{MinVisits: 3}
+
+// historyPage is a page of a browsing history with its visits summed up
+type historyPage struct {
+	url       string
+	title     string
+	visits    int
+	lastVisit time.Time
+	starred   bool
+	tags      []string
+}
+
+// ParseHistory reads a browser's history and returns the pages that pass
+// filter, most visited first. It accepts Chrome's History and Firefox's
+// places.sqlite database files, and the BrowserHistory.json that Google
+// Takeout exports from Chrome. Visited pages are imported as read.
+func ParseHistory(r io.Reader, filter HistoryFilter) ([]seed.Fixture, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var pages []historyPage
+	if isSQLite(data) {
+		pages, err = readHistoryDB(data)
+	} else {
+		pages, err = readTakeoutHistory(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(pages, func(i, j int) bool {
+		if pages[i].visits != pages[j].visits {
+			return pages[i].visits > pages[j].visits
+		}
+		return pages[i].lastVisit.After(pages[j].lastVisit)
+	})
+	var result []seed.Fixture
+	for _, p := range pages {
+		if !isWebURL(p.url) || !filter.keep(p) {
+			continue
+		}
+		f := fixture(strings.TrimSpace(p.title), p.url, strings.Join(p.tags, ","))
+		f.Read = p.visits > 0
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// parseHistory is ParseHistory with the default filter, for callers that
+// pick a parser by format name
+func parseHistory(r io.Reader) ([]seed.Fixture, error) {
+	return ParseHistory(r, DefaultHistoryFilter)
+}
+
+func (f HistoryFilter) keep(p historyPage) bool {
+	if !p.starred && p.visits < f.MinVisits {
+		return false
+	}
+	if !f.Since.IsZero() && p.lastVisit.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !p.lastVisit.Before(f.Until) {
+		return false
+	}
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if len(f.Domains) > 0 && !onDomain(host, f.Domains) {
+		return false
+	}
+	return !onDomain(host, f.ExcludeDomains)
+}
+
+// onDomain reports whether host is one of domains or a subdomain of one
+func onDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readHistoryDB reads the history database of Chrome (and browsers built
+// on Chromium) or Firefox, telling them apart by their tables
+func readHistoryDB(data []byte) ([]historyPage, error) {
+	db, err := openSQLite(data)
+	if err != nil {
+		return nil, err
+	}
+	if places, found, err := db.table("moz_places"); err != nil {
+		return nil, err
+	} else if found {
+		return readFirefoxHistory(db, places)
+	}
+	if urls, found, err := db.table("urls"); err != nil {
+		return nil, err
+	} else if found {
+		return readChromeHistory(db, urls)
+	}
+	return nil, errors.New("not a Chrome or Firefox history database")
+}
+
+// chromeEpochOffset is how many microseconds Chrome's timestamps, which
+// count from 1601, are ahead of Unix ones
+const chromeEpochOffset = 11644473600 * 1000000
+
+// readChromeHistory reads the urls table of Chrome's History file. Chrome
+// keeps its bookmarks in a separate file, so no page here is starred.
+func readChromeHistory(db *sqliteDB, urls sqliteTable) ([]historyPage, error) {
+	var pages []historyPage
+	err := db.rows(urls, func(row map[string]any) error {
+		if integer(row["hidden"]) != 0 {
+			return nil
+		}
+		p := historyPage{
+			url:    text(row["url"]),
+			title:  text(row["title"]),
+			visits: int(integer(row["visit_count"])),
+		}
+		if usec := integer(row["last_visit_time"]); usec > 0 {
+			p.lastVisit = time.UnixMicro(usec - chromeEpochOffset).UTC()
+		}
+		pages = append(pages, p)
+		return nil
+	})
+	return pages, err
+}
+
+// firefoxTagsRoot is the GUID of the folder holding Firefox's tags; each
+// tag is a folder in it, holding a bookmark of every tagged page
+const firefoxTagsRoot = "tags________"
+
+// readFirefoxHistory reads moz_places from Firefox's places.sqlite.
+// Pages bookmarked in Firefox are starred and keep their bookmark title
+// and tags.
+func readFirefoxHistory(db *sqliteDB, places sqliteTable) ([]historyPage, error) {
+	type mark struct {
+		kind, parent, place int64
+		title, guid         string
+	}
+	var marks map[int64]mark
+	if bookmarks, found, err := db.table("moz_bookmarks"); err != nil {
+		return nil, err
+	} else if found {
+		marks = make(map[int64]mark)
+		err := db.rows(bookmarks, func(row map[string]any) error {
+			marks[integer(row["id"])] = mark{
+				kind:   integer(row["type"]),
+				parent: integer(row["parent"]),
+				place:  integer(row["fk"]),
+				title:  text(row["title"]),
+				guid:   text(row["guid"]),
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var tagsRoot int64 = -1
+	for id, m := range marks {
+		if m.guid == firefoxTagsRoot {
+			tagsRoot = id
+		}
+	}
+	type placeMarks struct {
+		starred bool
+		title   string
+		tags    []string
+	}
+	byPlace := make(map[int64]*placeMarks)
+	for _, m := range marks {
+		if m.kind != 1 || m.place == 0 {
+			continue // folders and separators
+		}
+		pm := byPlace[m.place]
+		if pm == nil {
+			pm = &placeMarks{}
+			byPlace[m.place] = pm
+		}
+		if folder, ok := marks[m.parent]; ok && folder.parent == tagsRoot {
+			pm.tags = append(pm.tags, folder.title)
+			continue
+		}
+		pm.starred = true
+		if pm.title == "" {
+			pm.title = m.title
+		}
+	}
+
+	var pages []historyPage
+	err := db.rows(places, func(row map[string]any) error {
+		p := historyPage{
+			url:    text(row["url"]),
+			title:  text(row["title"]),
+			visits: int(integer(row["visit_count"])),
+		}
+		if usec := integer(row["last_visit_date"]); usec > 0 {
+			p.lastVisit = time.UnixMicro(usec).UTC()
+		}
+		if pm := byPlace[integer(row["id"])]; pm != nil {
+			p.starred, p.tags = pm.starred, pm.tags
+			sort.Strings(p.tags)
+			if pm.title != "" {
+				p.title = pm.title
+			}
+		}
+		if integer(row["hidden"]) != 0 && !p.starred {
+			return nil
+		}
+		pages = append(pages, p)
+		return nil
+	})
+	return pages, err
+}
+
+// takeoutVisit is a visit in the BrowserHistory.json of a Google Takeout
+// export, which lists every visit rather than every page
+type takeoutVisit struct {
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+	TimeUsec int64  `json:"time_usec"`
+}
+
+// readTakeoutHistory sums up the visits of a Takeout export by page
+func readTakeoutHistory(data []byte) ([]historyPage, error) {
+	var export struct {
+		History []takeoutVisit `json:"Browser History"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("not a history database or Takeout BrowserHistory.json: %w", err)
+	}
+	index := make(map[string]int)
+	var pages []historyPage
+	for _, v := range export.History {
+		at := time.UnixMicro(v.TimeUsec).UTC()
+		i, seen := index[v.URL]
+		if !seen {
+			i = len(pages)
+			index[v.URL] = i
+			pages = append(pages, historyPage{url: v.URL})
+		}
+		p := &pages[i]
+		p.visits++
+		if !at.Before(p.lastVisit) {
+			p.lastVisit = at
+			if v.Title != "" {
+				p.title = v.Title
+			}
+		}
+	}
+	return pages, nil
+}
+
+// integer returns a SQLite value as an integer, or 0
+func integer(v any) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// text returns a SQLite value as text, or ""
+func text(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+// Package importer parses bookmark files exported by browsers and other
+// read-later services.
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+)
+
+// Parser reads bookmarks from an exported file
+type Parser func(r io.Reader) ([]seed.Fixture, error)
+
+var parsers = map[string]Parser{
+	"netscape": parseAnchors,
+	"pocket":   parseAnchors,
+	"csv":      parseCSV,
+	"xbel":     parseXBEL,
+	"wallabag": parseWallabag,
+	"shaarli":  parseShaarli,
+	"history":  parseHistory,
+}
+
+// Lookup returns the parser registered under format
+func Lookup(format string) (Parser, bool) {
+	p, ok := parsers[format]
+	return p, ok
+}
+
+// Names returns the names of all supported formats
+func Names() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	anchorPattern    = regexp.MustCompile(`(?is)<a\s([^>]*)>(.*?)</a>`)
+	attributePattern = regexp.MustCompile(`(?is)([a-z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	tagPattern       = regexp.MustCompile(`<[^>]*>`)
+)
+
+// parseAnchors extracts every link from a Netscape bookmark file (what
+// browsers export) or a Pocket HTML export. Both use <A HREF=... TAGS=...>;
+// folders and other markup are ignored.
+func parseAnchors(r io.Reader) ([]seed.Fixture, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []seed.Fixture
+	for _, m := range anchorPattern.FindAllStringSubmatch(string(data), -1) {
+		attrs := make(map[string]string)
+		for _, a := range attributePattern.FindAllStringSubmatch(m[1], -1) {
+			attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2] + a[3])
+		}
+		url := strings.TrimSpace(attrs["href"])
+		if !isWebURL(url) {
+			continue
+		}
+		title := strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(m[2], "")))
+		result = append(result, fixture(title, url, attrs["tags"]))
+	}
+	return result, nil
+}
+
+// parseCSV reads a CSV file with a header row. A "url" column is required;
+// "title" and "tags" are used when present, which covers both our own CSV
+// export and Pocket's. The apostrophe our export puts before cells that
+// look like formulas is removed.
+func parseCSV(r io.Reader) ([]seed.Fixture, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, fmt.Errorf(`missing "url" column`)
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			v := record[i]
+			if len(v) > 1 && v[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(v[1])) {
+				v = v[1:]
+			}
+			return strings.TrimSpace(v)
+		}
+		return ""
+	}
+
+	var result []seed.Fixture
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		url := field(record, "url")
+		if !isWebURL(url) {
+			continue
+		}
+		result = append(result, fixture(field(record, "title"), url, field(record, "tags")))
+	}
+	return result, nil
+}
+
+// fixture builds a bookmark, falling back to the URL when the title is
+// empty. Tags may be separated by commas or pipes.
+func fixture(title, url, tags string) seed.Fixture {
+	if title == "" {
+		title = url
+	}
+	if len(title) > 500 {
+		title = strings.ToValidUTF8(title[:500], "")
+	}
+	f := seed.Fixture{Title: title, URL: url}
+	for _, tag := range strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == '|' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			f.Tags = append(f.Tags, tag)
+		}
+	}
+	return f
+}
+
+func isWebURL(url string) bool {
+	lower := strings.ToLower(url)
+	return (strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")) && len(url) <= 2048
+}
@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+)
+
+// shaarliEntryPattern matches a link in a Shaarli HTML export together
+// with the <DD> description that may follow it
+var shaarliEntryPattern = regexp.MustCompile(`(?is)<a\s([^>]*)>(.*?)</a>(?:\s*<dd>(.*?)(?:<dt|</dl|$))?`)
+
+var lineBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+// shaarliLink is a link as Shaarli's REST API and JSON exports return it
+type shaarliLink struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// parseShaarli reads a Shaarli export, either the HTML bookmark file from
+// its export page or the JSON list of links its API returns. Descriptions
+// become notes. Shaarli separates tags with spaces in HTML exports.
+func parseShaarli(r io.Reader) ([]seed.Fixture, error) {
+	br := bufio.NewReader(r)
+	for {
+		c, _, err := br.ReadRune()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == '\uFEFF' || strings.ContainsRune(" \t\r\n", c) {
+			continue
+		}
+		br.UnreadRune()
+		if c == '[' {
+			return parseShaarliJSON(br)
+		}
+		return parseShaarliHTML(br)
+	}
+}
+
+func parseShaarliJSON(r io.Reader) ([]seed.Fixture, error) {
+	var links []shaarliLink
+	if err := json.NewDecoder(r).Decode(&links); err != nil {
+		return nil, fmt.Errorf("not a Shaarli JSON export: %w", err)
+	}
+	var result []seed.Fixture
+	for _, l := range links {
+		url := strings.TrimSpace(l.URL)
+		if !isWebURL(url) {
+			continue
+		}
+		f := fixture(strings.TrimSpace(l.Title), url, strings.Join(l.Tags, ","))
+		f.Notes = notes(l.Description)
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+func parseShaarliHTML(r io.Reader) ([]seed.Fixture, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var result []seed.Fixture
+	for _, m := range shaarliEntryPattern.FindAllStringSubmatch(string(data), -1) {
+		attrs := make(map[string]string)
+		for _, a := range attributePattern.FindAllStringSubmatch(m[1], -1) {
+			attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2] + a[3])
+		}
+		url := strings.TrimSpace(attrs["href"])
+		if !isWebURL(url) {
+			continue
+		}
+		title := strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(m[2], "")))
+		tags := strings.Join(strings.Fields(attrs["tags"]), ",")
+		f := fixture(title, url, tags)
+		description := lineBreakPattern.ReplaceAllString(m[3], "\n")
+		f.Notes = notes(html.UnescapeString(tagPattern.ReplaceAllString(description, "")))
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// notes trims text to what a bookmark's notes may hold
+func notes(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) > maxNotes {
+		text = strings.ToValidUTF8(text[:maxNotes], "")
+	}
+	return text
+}
@@ -0,0 +1,314 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+)
+
+// sqliteMagic starts every SQLite database file
+const sqliteMagic = "SQLite format 3\x00"
+
+// maxTreeDepth bounds b-tree descent, so a corrupt file with a page
+// cycle fails instead of recursing forever
+const maxTreeDepth = 64
+
+var errMalformed = errors.New("malformed SQLite database")
+
+// sqliteDB reads tables out of a SQLite database file held in memory.
+// It understands just enough of the file format to scan the tables of a
+// browser's history database: no indexes, no WAL, no writes. A database
+// in WAL mode only shows what was checkpointed, so the browser should be
+// closed before its history is copied.
+type sqliteDB struct {
+	data     []byte
+	pageSize int
+	usable   int // page size minus the bytes reserved at the end of each page
+}
+
+// sqliteTable is a table's root page and column names, in order
+type sqliteTable struct {
+	root    int
+	columns []string
+	rowid   int // index of the INTEGER PRIMARY KEY column, which aliases the rowid, or -1
+}
+
+func isSQLite(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(sqliteMagic))
+}
+
+func openSQLite(data []byte) (*sqliteDB, error) {
+	if len(data) < 100 || !isSQLite(data) {
+		return nil, errors.New("not a SQLite database")
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, errMalformed
+	}
+	if enc := binary.BigEndian.Uint32(data[56:60]); enc > 1 {
+		return nil, errors.New("only UTF-8 SQLite databases are supported")
+	}
+	return &sqliteDB{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+	}, nil
+}
+
+// table looks a table up in the schema. It returns false when there is
+// no such table.
+func (db *sqliteDB) table(name string) (sqliteTable, bool, error) {
+	var (
+		t     sqliteTable
+		found bool
+	)
+	// sqlite_schema: type, name, tbl_name, rootpage, sql
+	err := db.scan(1, func(_ int64, values []any) error {
+		if len(values) < 5 || values[0] != "table" || !strings.EqualFold(fmt.Sprint(values[1]), name) {
+			return nil
+		}
+		root, ok := values[3].(int64)
+		sql, _ := values[4].(string)
+		if !ok || root < 1 {
+			return errMalformed
+		}
+		t = sqliteTable{root: int(root)}
+		t.columns, t.rowid = parseColumns(sql)
+		found = true
+		return nil
+	})
+	return t, found, err
+}
+
+// rows calls fn with every row of a table, as a map from column name to
+// value: nil, int64, float64, string or []byte
+func (db *sqliteDB) rows(t sqliteTable, fn func(row map[string]any) error) error {
+	return db.scan(t.root, func(rowid int64, values []any) error {
+		row := make(map[string]any, len(t.columns))
+		for i, name := range t.columns {
+			switch {
+			case i == t.rowid:
+				row[name] = rowid
+			case i < len(values):
+				row[name] = values[i]
+			default:
+				// Columns added after the row was written read as NULL
+				row[name] = nil
+			}
+		}
+		return fn(row)
+	})
+}
+
+// scan walks the table b-tree rooted at page root in rowid order. A
+// corrupt file fails the scan rather than crashing the import.
+func (db *sqliteDB) scan(root int, fn func(rowid int64, values []any) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); !ok {
+				panic(r)
+			}
+			err = errMalformed
+		}
+	}()
+	return db.walk(root, 0, fn)
+}
+
+func (db *sqliteDB) walk(n, depth int, fn func(rowid int64, values []any) error) error {
+	if depth > maxTreeDepth || n < 1 || n*db.pageSize > len(db.data) {
+		return errMalformed
+	}
+	page := db.data[(n-1)*db.pageSize : n*db.pageSize]
+	header := 0
+	if n == 1 {
+		header = 100 // the database header shares the first page
+	}
+	kind := page[header]
+	cells := int(binary.BigEndian.Uint16(page[header+3:]))
+	pointers := header + 8
+	if kind == 0x05 {
+		pointers = header + 12
+	}
+
+	for i := 0; i < cells; i++ {
+		cell := page[binary.BigEndian.Uint16(page[pointers+2*i:]):]
+		switch kind {
+		case 0x05: // interior table page: left child, key
+			if err := db.walk(int(binary.BigEndian.Uint32(cell)), depth+1, fn); err != nil {
+				return err
+			}
+		case 0x0d: // leaf table page: payload size, rowid, payload
+			size, k := varint(cell)
+			rowid, m := varint(cell[k:])
+			payload, err := db.payload(cell[k+m:], int(size))
+			if err != nil {
+				return err
+			}
+			values, err := record(payload)
+			if err != nil {
+				return err
+			}
+			if err := fn(int64(rowid), values); err != nil {
+				return err
+			}
+		default:
+			return errMalformed
+		}
+	}
+	if kind == 0x05 {
+		return db.walk(int(binary.BigEndian.Uint32(page[header+8:])), depth+1, fn)
+	}
+	return nil
+}
+
+// payload returns a cell's payload of size bytes, following its chain of
+// overflow pages when it does not fit in the cell
+func (db *sqliteDB) payload(cell []byte, size int) ([]byte, error) {
+	maxLocal := db.usable - 35
+	if size <= maxLocal {
+		return cell[:size], nil
+	}
+	minLocal := (db.usable-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(db.usable-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	out := make([]byte, 0, size)
+	out = append(out, cell[:local]...)
+	next := int(binary.BigEndian.Uint32(cell[local:]))
+	for pages := 0; len(out) < size; pages++ {
+		if next < 1 || next*db.pageSize > len(db.data) || pages > len(db.data)/db.pageSize {
+			return nil, errMalformed
+		}
+		page := db.data[(next-1)*db.pageSize:]
+		chunk := min(size-len(out), db.usable-4)
+		out = append(out, page[4:4+chunk]...)
+		next = int(binary.BigEndian.Uint32(page))
+	}
+	return out, nil
+}
+
+// record decodes a row's values
+func record(payload []byte) ([]any, error) {
+	headerSize, n := varint(payload)
+	if int(headerSize) > len(payload) {
+		return nil, errMalformed
+	}
+	header, body := payload[n:headerSize], payload[headerSize:]
+	var values []any
+	for len(header) > 0 {
+		serial, k := varint(header)
+		header = header[k:]
+
+		var value any
+		size := 0
+		switch {
+		case serial == 0:
+		case serial <= 6:
+			size = []int{0, 1, 2, 3, 4, 6, 8}[serial]
+			var v int64
+			for _, b := range body[:size] {
+				v = v<<8 | int64(b)
+			}
+			// Sign-extend from the stored width
+			shift := 64 - 8*size
+			value = v << shift >> shift
+		case serial == 7:
+			size = 8
+			value = math.Float64frombits(binary.BigEndian.Uint64(body))
+		case serial == 8, serial == 9:
+			value = int64(serial - 8)
+		case serial >= 12 && serial%2 == 0:
+			size = int(serial-12) / 2
+			value = body[:size]
+		case serial >= 13:
+			size = int(serial-13) / 2
+			value = string(body[:size])
+		default:
+			return nil, errMalformed
+		}
+		body = body[size:]
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// varint decodes a SQLite variable-length integer, returning it and its
+// length in bytes
+func varint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return v<<8 | uint64(b[8]), 9
+}
+
+// parseColumns reads the column names out of a CREATE TABLE statement,
+// along with the index of the INTEGER PRIMARY KEY column, if any
+func parseColumns(sql string) ([]string, int) {
+	open, end := strings.IndexByte(sql, '('), strings.LastIndexByte(sql, ')')
+	if open < 0 || end < open {
+		return nil, -1
+	}
+	var (
+		columns []string
+		rowid   = -1
+	)
+	for _, def := range splitDefinitions(sql[open+1 : end]) {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			continue
+		}
+		upper := strings.ToUpper(strings.Join(fields[1:], " "))
+		if strings.HasPrefix(upper, "INTEGER PRIMARY KEY") {
+			rowid = len(columns)
+		}
+		columns = append(columns, strings.Trim(fields[0], "\"`[]"))
+	}
+	return columns, rowid
+}
+
+// splitDefinitions splits a table's column list on the commas that are
+// not nested in parentheses or quotes
+func splitDefinitions(s string) []string {
+	var (
+		parts []string
+		depth int
+		quote rune
+		start int
+	)
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '[':
+			quote = ']'
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+)
+
+// maxNotes is the longest notes a bookmark may have
+const maxNotes = 10000
+
+// wallabagEntry is an article in a Wallabag JSON export. Archived
+// articles are the ones the user has read.
+type wallabagEntry struct {
+	Title       string               `json:"title"`
+	URL         string               `json:"url"`
+	Tags        []string             `json:"tags"`
+	IsArchived  wallabagFlag         `json:"is_archived"`
+	Annotations []wallabagAnnotation `json:"annotations"`
+}
+
+// wallabagAnnotation is a highlighted passage and the note on it
+type wallabagAnnotation struct {
+	Quote string `json:"quote"`
+	Text  string `json:"text"`
+}
+
+// wallabagFlag accepts the 0/1 and true/false that different Wallabag
+// versions write
+type wallabagFlag bool
+
+func (f *wallabagFlag) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "1", "true":
+		*f = true
+	case "0", "false", "null", "":
+		*f = false
+	default:
+		return fmt.Errorf("invalid flag %s", data)
+	}
+	return nil
+}
+
+// parseWallabag reads a Wallabag JSON export. Annotations become
+// highlights of their quotes, and their notes are joined into the
+// bookmark's notes; archived articles are marked read.
+func parseWallabag(r io.Reader) ([]seed.Fixture, error) {
+	var entries []wallabagEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("not a Wallabag JSON export: %w", err)
+	}
+
+	var result []seed.Fixture
+	for _, e := range entries {
+		url := strings.TrimSpace(e.URL)
+		if !isWebURL(url) {
+			continue
+		}
+		f := fixture(strings.TrimSpace(e.Title), url, strings.Join(e.Tags, ","))
+		f.Read = bool(e.IsArchived)
+		var quoted []string
+		for _, a := range e.Annotations {
+			quote, text := strings.TrimSpace(a.Quote), strings.TrimSpace(a.Text)
+			if quote != "" {
+				f.Highlights = append(f.Highlights, quote)
+			}
+			switch {
+			case text != "" && quote != "":
+				quoted = append(quoted, "> "+strings.ReplaceAll(quote, "\n", "\n> ")+"\n\n"+text)
+			case text != "":
+				quoted = append(quoted, text)
+			}
+		}
+		f.Notes = notes(strings.Join(quoted, "\n\n"))
+		result = append(result, f)
+	}
+	return result, nil
+}
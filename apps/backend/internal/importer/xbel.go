@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+)
+
+// parseXBEL reads an XBEL (XML Bookmark Exchange Language) file, as
+// written by KDE, qutebrowser and our own XBEL export. Bookmarks are tagged
+// with the titles of the folders they are in, plus any tags our export
+// kept in their metadata. Aliases and separators are skipped.
+func parseXBEL(r io.Reader) ([]seed.Fixture, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+
+	var (
+		result  []seed.Fixture
+		folders []string // titles of the enclosing folders; "" until read
+		sawRoot bool
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			if end, ok := tok.(xml.EndElement); ok && end.Name.Local == "folder" && len(folders) > 0 {
+				folders = folders[:len(folders)-1]
+			}
+			continue
+		}
+		switch start.Name.Local {
+		case "xbel":
+			sawRoot = true
+		case "folder":
+			folders = append(folders, "")
+		case "title":
+			// A folder's title comes before its children
+			if len(folders) > 0 && folders[len(folders)-1] == "" {
+				var title string
+				if err := dec.DecodeElement(&title, &start); err != nil {
+					return nil, err
+				}
+				folders[len(folders)-1] = strings.TrimSpace(title)
+			}
+		case "bookmark":
+			var b xbelBookmark
+			if err := dec.DecodeElement(&b, &start); err != nil {
+				return nil, err
+			}
+			url := strings.TrimSpace(b.Href)
+			if !isWebURL(url) {
+				continue
+			}
+			tags := append(nonEmpty(folders), b.tags()...)
+			result = append(result, fixture(strings.TrimSpace(b.Title), url, strings.Join(tags, ",")))
+		}
+	}
+	if !sawRoot {
+		return nil, errors.New("not an XBEL file: no <xbel> element")
+	}
+	return result, nil
+}
+
+// xbelBookmark is a <bookmark> element. Tags are kept in web-collector
+// metadata, as XBEL has no tags of its own.
+type xbelBookmark struct {
+	Href     string `xml:"href,attr"`
+	Title    string `xml:"title"`
+	Metadata []struct {
+		Owner string   `xml:"owner,attr"`
+		Tags  []string `xml:"tag"`
+	} `xml:"info>metadata"`
+}
+
+// xbelOwner identifies the metadata our XBEL export writes
+const xbelOwner = "https://github.com/hereisth/web-collector"
+
+func (b *xbelBookmark) tags() []string {
+	var tags []string
+	for _, m := range b.Metadata {
+		if m.Owner == xbelOwner {
+			tags = append(tags, m.Tags...)
+		}
+	}
+	return tags
+}
+
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,261 @@
+// Package mailin reads mail forwarded to the instance's inbound address,
+// so a forwarded link or newsletter can be saved as a bookmark. Messages
+// arrive from a mail provider's inbound webhook as raw RFC 5322 text.
+package mailin
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/sanitize"
+	"golang.org/x/net/html/charset"
+)
+
+// maxNotes bounds the notes taken from a message body, matching the API's
+// limit on notes
+const maxNotes = 10000
+
+// maxDepth bounds how deeply nested multipart bodies are read
+const maxDepth = 5
+
+// Config enables the inbound handler; it is disabled without a secret
+type Config struct {
+	// Secret authenticates the provider's webhook, which posts to
+	// /api/v1/integrations/mail/<secret>
+	Secret string
+	// Senders are the comma-separated addresses, besides those of
+	// accounts, whose mail is saved
+	Senders string
+	// CollectionID, when set, is the collection mail is saved into
+	CollectionID string
+}
+
+// Inbox checks and reads inbound mail
+type Inbox struct {
+	secret  string
+	senders map[string]bool
+}
+
+// Open returns the configured inbox, or nil when no secret is set
+func Open(cfg Config) (*Inbox, error) {
+	if cfg.Secret == "" {
+		return nil, nil
+	}
+	if len(cfg.Secret) < 16 {
+		return nil, errors.New("the inbound mail secret must be at least 16 characters, since it is the only thing guarding the address")
+	}
+	in := &Inbox{secret: cfg.Secret, senders: make(map[string]bool)}
+	for _, field := range strings.Split(cfg.Senders, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		addr, err := mail.ParseAddress(field)
+		if err != nil {
+			return nil, fmt.Errorf("inbound mail sender %q is not an address", field)
+		}
+		in.senders[strings.ToLower(addr.Address)] = true
+	}
+	return in, nil
+}
+
+// Authentic reports whether secret is the webhook secret
+func (in *Inbox) Authentic(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(in.secret)) == 1
+}
+
+// Sender reports whether mail from address is saved because it is listed
+// in the configuration
+func (in *Inbox) Sender(address string) bool {
+	return in.senders[strings.ToLower(address)]
+}
+
+// Message is the part of a mail message a bookmark is made from
+type Message struct {
+	From    string
+	Subject string
+	// Text is the plain text body, or the text of the HTML body when the
+	// message has no plain text
+	Text string
+	// Links are the http(s) URLs in the body, in order
+	Links []string
+}
+
+// Parse reads a raw message
+func Parse(r io.Reader) (Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return Message{}, err
+	}
+	var m Message
+	if from, err := msg.Header.AddressList("From"); err == nil && len(from) > 0 {
+		m.From = from[0].Address
+	}
+	dec := mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+	if subject, err := dec.DecodeHeader(msg.Header.Get("Subject")); err == nil {
+		m.Subject = strings.TrimSpace(subject)
+	}
+
+	var plain, markup string
+	if err := readPart(msg.Header, msg.Body, &plain, &markup, 0); err != nil {
+		return Message{}, err
+	}
+	if plain != "" {
+		m.Text = plain
+		m.Links = textLinks(plain)
+	} else {
+		m.Text = sanitize.Text(markup)
+		m.Links = hrefLinks(markup)
+	}
+	m.Text = strings.TrimSpace(m.Text)
+	return m, nil
+}
+
+// header is what readPart needs of a message or part header
+type header interface {
+	Get(key string) string
+}
+
+// readPart keeps the first text/plain and text/html bodies found in a
+// part and the parts nested below it. Attachments are skipped.
+func readPart(h header, body io.Reader, plain, markup *string, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxDepth || params["boundary"] == "" {
+			return nil
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if disp, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disp == "attachment" {
+				continue
+			}
+			if err := readPart(part.Header, part, plain, markup, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return nil
+	}
+
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &lineJoiner{r: body})
+	}
+	if cs := params["charset"]; cs != "" {
+		if body, err = charset.NewReaderLabel(cs, body); err != nil {
+			return nil
+		}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if mediaType == "text/plain" && *plain == "" {
+		*plain = string(data)
+	} else if mediaType == "text/html" && *markup == "" {
+		*markup = string(data)
+	}
+	return nil
+}
+
+// lineJoiner drops the line breaks base64 bodies are wrapped with
+type lineJoiner struct {
+	r io.Reader
+}
+
+func (l *lineJoiner) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	kept := bytes.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, p[:n])
+	return copy(p, kept), err
+}
+
+var (
+	urlPattern  = regexp.MustCompile(`https?://[^\s<>"'\x60]+`)
+	hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*["'](https?://[^"']+)["']`)
+)
+
+func textLinks(text string) []string {
+	var links []string
+	for _, l := range urlPattern.FindAllString(text, -1) {
+		links = append(links, strings.TrimRight(l, ".,;:!?)]}"))
+	}
+	return links
+}
+
+func hrefLinks(markup string) []string {
+	var links []string
+	for _, m := range hrefPattern.FindAllStringSubmatch(markup, -1) {
+		links = append(links, strings.ReplaceAll(m[1], "&amp;", "&"))
+	}
+	return links
+}
+
+// Link is a bookmark a message asks to save: the link in its subject or
+// the first in its body, titled with the rest of its subject, with the subject's #hashtags as tags and the
+// body as notes
+type Link struct {
+	URL   string
+	Title string
+	Tags  []string
+	Notes string
+}
+
+// forwardPrefix matches the markers mail clients put before a forwarded
+// subject
+var forwardPrefix = regexp.MustCompile(`(?i)^((fwd?|fw|wg|tr|rv)\s*:\s*)+`)
+
+// ParseLink reads the link out of a message, reporting false when it has
+// none. A link in the subject is taken over those in the body.
+func ParseLink(m Message) (Link, bool) {
+	var (
+		link  Link
+		title []string
+	)
+	for _, word := range strings.Fields(forwardPrefix.ReplaceAllString(m.Subject, "")) {
+		switch {
+		case strings.HasPrefix(word, "#") && len(word) > 1:
+			link.Tags = append(link.Tags, strings.TrimPrefix(word, "#"))
+		case link.URL == "" && len(urlPattern.FindString(word)) == len(word):
+			link.URL = word
+		default:
+			title = append(title, word)
+		}
+	}
+	if link.URL == "" && len(m.Links) > 0 {
+		link.URL = m.Links[0]
+	}
+	link.Title = strings.Join(title, " ")
+	if r := []rune(m.Text); len(r) > maxNotes {
+		link.Notes = string(r[:maxNotes])
+	} else {
+		link.Notes = m.Text
+	}
+	return link, link.URL != ""
+}
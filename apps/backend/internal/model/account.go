@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// Account roles
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Account is a login for the web-collector instance. An account with a
+// Username and PublicProfile set has a public profile page. With
+// ArchiveAfterDays set, the bookmarks in the account's collections are
+// archived once they go that many days untouched.
+type Account struct {
+	ID               string    `json:"id"`
+	Email            string    `json:"email"`
+	Role             string    `json:"role"`
+	PasswordHash     string    `json:"-"`
+	Username         string    `json:"username,omitempty"`
+	PublicProfile    bool      `json:"public_profile"`
+	ArchiveAfterDays int       `json:"archive_after_days"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UpdateProfileRequest represents the request body for changing the
+// signed-in account's public profile
+type UpdateProfileRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=32"`
+	Public   bool   `json:"public"`
+}
+
+// ArchivePolicyRequest represents the request body for the signed-in
+// account's auto-archive policy
+type ArchivePolicyRequest struct {
+	AfterDays *int `json:"after_days" binding:"required,min=0,max=3650"`
+}
+
+// Follower is a fediverse actor following an account's public bookmarks.
+// Inbox is where new bookmarks are delivered: the shared inbox of the
+// actor's server when it has one.
+type Follower struct {
+	AccountID string    `json:"-"`
+	ActorID   string    `json:"actor_id"`
+	Inbox     string    `json:"inbox"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Activity kinds
+const (
+	ActivitySaved    = "saved"
+	ActivityEdited   = "edited"
+	ActivityTagged   = "tagged"
+	ActivityShared   = "shared"
+	ActivityUnshared = "unshared"
+)
+
+// Activity is one entry in the activity feed. AccountID is who acted and
+// is empty for requests made without signing in; BookmarkID and
+// CollectionID name what was acted on, and Detail describes the change.
+type Activity struct {
+	ID           string    `json:"id"`
+	Kind         string    `json:"kind"`
+	AccountID    string    `json:"account_id,omitempty"`
+	BookmarkID   string    `json:"bookmark_id,omitempty"`
+	CollectionID string    `json:"collection_id,omitempty"`
+	Detail       string    `json:"detail,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -1,23 +1,174 @@
 package model
 
-import "time"
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
 
-// Bookmark represents a saved bookmark
+// Bookmark represents a saved bookmark. Aliases are alternate URLs of the
+// same page, such as AMP versions, mirrors or shortlinks. Notes are the
+// user's own text about the page, such as a passage selected when saving. Archived
+// bookmarks are left out of the default list. UpdatedAt is when the
+// bookmark was last edited, read or archived, which is what auto-archiving
+// measures staleness by.
 type Bookmark struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	URL       string    `json:"url"`
-	CreatedAt time.Time `json:"created_at"`
+	ID string `json:"id" xml:"id,attr"`
+	// OwnerID is the account that saved the bookmark; bookmarks saved
+	// without signing in, imported or seeded have no owner
+	OwnerID        string           `json:"owner_id,omitempty" xml:"owner_id,omitempty"`
+	Title          string           `json:"title" xml:"title"`
+	URL            string           `json:"url" xml:"url"`
+	Aliases        []string         `json:"aliases,omitempty" xml:"alias,omitempty"`
+	Tags           []string         `json:"tags" xml:"tag"`
+	Notes          string           `json:"notes,omitempty" xml:"notes,omitempty"`
+	IsRead         bool             `json:"is_read" xml:"is_read"`
+	Archived       bool             `json:"archived" xml:"archived"`
+	Progress       *ReadingProgress `json:"progress,omitempty" xml:"progress,omitempty"`
+	WordCount      int              `json:"word_count,omitempty" xml:"word_count,omitempty"`
+	ReadingMinutes int              `json:"reading_minutes,omitempty" xml:"reading_minutes,omitempty"`
+	CreatedAt      time.Time        `json:"created_at" xml:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at" xml:"updated_at"`
+}
+
+// DeletedBookmark is a bookmark in the trash, with the collections it was
+// in. Deleted bookmarks can be restored until PurgeAt, after which the
+// purge job removes them for good.
+type DeletedBookmark struct {
+	Bookmark
+	CollectionIDs []string  `json:"collection_ids,omitempty"`
+	DeletedAt     time.Time `json:"deleted_at"`
+	PurgeAt       time.Time `json:"purge_at"`
+}
+
+// ReadingProgress is a position within a bookmarked page, so reading can
+// resume on another device
+type ReadingProgress struct {
+	// Percent is the share of the page scrolled past, from 0 to 100
+	Percent float64 `json:"percent" xml:"percent"`
+	// Anchor optionally pins the position more precisely, e.g. an element
+	// ID or text fragment chosen by the client
+	Anchor    string    `json:"anchor,omitempty" xml:"anchor,omitempty"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
 }
 
 // CreateBookmarkRequest represents the request body for creating a bookmark
 type CreateBookmarkRequest struct {
-	Title string `json:"title" binding:"required"`
-	URL   string `json:"url" binding:"required"`
+	Title string   `json:"title" binding:"required,max=500"`
+	URL   string   `json:"url" binding:"required,max=2048,noscripturl,weburl"`
+	Tags  []string `json:"tags" binding:"max=50,dive,max=100"`
+	// Selection is text selected on the page when saving it, kept as the
+	// bookmark's first note
+	Selection string `json:"selection" binding:"max=10000"`
 }
 
-// UpdateBookmarkRequest represents the request body for updating a bookmark
+// NotesRequest represents the request body for replacing a bookmark's notes
+type NotesRequest struct {
+	Notes string `json:"notes" binding:"max=10000"`
+}
+
+// UpdateBookmarkRequest represents the request body for updating a
+// bookmark. Fields left out, or null, keep their value; fields present
+// replace it, so empty tags or notes clear them. The title and URL cannot
+// be empty.
 type UpdateBookmarkRequest struct {
-	Title string `json:"title"`
-	URL   string `json:"url"`
+	Title *string   `json:"title,omitempty" binding:"omitempty,min=1,max=500"`
+	URL   *string   `json:"url,omitempty" binding:"omitempty,max=2048,noscripturl,weburl"`
+	Tags  *[]string `json:"tags,omitempty" binding:"omitempty,max=50,dive,max=100"`
+	Notes *string   `json:"notes,omitempty" binding:"omitempty,max=10000"`
+}
+
+// TagSeparator splits hierarchical tags: "dev/go/concurrency" sits below
+// "dev/go", which sits below "dev"
+const TagSeparator = "/"
+
+// NormalizeTag lowercases a tag and trims the space around it and around
+// each of its path segments, dropping empty segments, so "Dev / Go/" and
+// "dev/go" are the same tag
+func NormalizeTag(tag string) string {
+	segments := strings.Split(strings.ToLower(tag), TagSeparator)
+	kept := segments[:0]
+	for _, s := range segments {
+		if s = strings.TrimSpace(s); s != "" {
+			kept = append(kept, s)
+		}
+	}
+	return strings.Join(kept, TagSeparator)
+}
+
+// AliasRequest represents the request body for adding an alias to a
+// bookmark
+type AliasRequest struct {
+	URL string `json:"url" binding:"required,max=2048,noscripturl,weburl"`
+}
+
+// MarkReadRequest represents the request body for changing a bookmark's
+// read state; without is_read the state is toggled
+type MarkReadRequest struct {
+	IsRead *bool `json:"is_read"`
+}
+
+// ArchiveRequest represents the request body for archiving a bookmark or
+// bringing it back; without archived the state is toggled
+type ArchiveRequest struct {
+	Archived *bool `json:"archived"`
+}
+
+// UpdateProgressRequest represents the request body for recording reading
+// progress
+type UpdateProgressRequest struct {
+	Percent *float64 `json:"percent" binding:"required,min=0,max=100"`
+	Anchor  string   `json:"anchor" binding:"max=2000"`
+}
+
+// NormalizeTags normalizes, de-duplicates and sorts tags, dropping empty
+// ones. It always returns a non-nil slice.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = NormalizeTag(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// TagWithin reports whether tag is ancestor or one of its descendants
+func TagWithin(tag, ancestor string) bool {
+	return tag == ancestor || strings.HasPrefix(tag, ancestor+TagSeparator)
+}
+
+// Domain returns the host of the bookmark's URL without a leading "www.",
+// or "" when the URL does not parse
+func (b Bookmark) Domain() string {
+	return URLDomain(b.URL)
+}
+
+// URLDomain returns the host of a URL without a leading "www.", or ""
+// when the URL does not parse
+func URLDomain(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// WordsPerMinute is the reading speed behind reading time estimates
+const WordsPerMinute = 230
+
+// ReadingMinutes estimates how long reading words takes, rounded up to
+// whole minutes. Bookmarks whose page has not been counted yet have 0
+// words and no estimate.
+func ReadingMinutes(words int) int {
+	if words <= 0 {
+		return 0
+	}
+	return (words + WordsPerMinute - 1) / WordsPerMinute
 }
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Clip is a fragment of a bookmarked page captured by the user, such as a
+// selected passage with its formatting. HTML is sanitized before it is
+// stored and is safe to render; Text is the same fragment without markup.
+type Clip struct {
+	ID         string    `json:"id"`
+	BookmarkID string    `json:"bookmark_id"`
+	HTML       string    `json:"html"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ClipRequest represents the request body for capturing a clip
+type ClipRequest struct {
+	HTML string `json:"html" binding:"required,max=100000"`
+}
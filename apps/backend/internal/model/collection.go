@@ -0,0 +1,129 @@
+package model
+
+import "time"
+
+// Collection groups bookmarks. Collections nest: a collection without a
+// parent sits at the top level.
+type Collection struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id,omitempty"`
+	// OwnerID is the account that created the collection; collections
+	// created without signing in have no owner and are open to everyone
+	OwnerID   string    `json:"owner_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Collection access levels, from least to most privileged
+const (
+	AccessView = "view"
+	AccessEdit = "edit"
+	// AccessOwner also allows deleting, moving and sharing; it cannot be
+	// granted through a share
+	AccessOwner = "owner"
+)
+
+// Special share grantees
+const (
+	// GranteeWorkspace shares a collection with every account on the
+	// instance
+	GranteeWorkspace = "workspace"
+	// GranteePublic makes a collection viewable by anyone, signed in or
+	// not, and lists it on its owner's public profile
+	GranteePublic = "public"
+)
+
+// CollectionShare grants an account, or the whole workspace, access to a
+// collection and everything below it
+type CollectionShare struct {
+	CollectionID string `json:"collection_id"`
+	// Grantee is an account ID, GranteeWorkspace or GranteePublic
+	Grantee string `json:"grantee"`
+	Access  string `json:"access"`
+}
+
+// CreateCollectionRequest represents the request body for creating a
+// collection
+type CreateCollectionRequest struct {
+	Name     string `json:"name" binding:"required,max=200"`
+	ParentID string `json:"parent_id"`
+}
+
+// UpdateCollectionRequest represents the request body for renaming a
+// collection
+type UpdateCollectionRequest struct {
+	Name string `json:"name" binding:"required,max=200"`
+}
+
+// MoveCollectionRequest moves a collection, with everything below it,
+// under a new parent; an empty parent moves it to the top level
+type MoveCollectionRequest struct {
+	ParentID string `json:"parent_id"`
+}
+
+// MergeCollectionsRequest folds the source collection into the target
+type MergeCollectionsRequest struct {
+	Source string `json:"source" binding:"required"`
+	Target string `json:"target" binding:"required"`
+}
+
+// CollectionBookmarksRequest adds existing bookmarks to a collection, or
+// creates a new bookmark in it
+type CollectionBookmarksRequest struct {
+	BookmarkIDs []string               `json:"bookmark_ids" binding:"max=1000"`
+	Bookmark    *CreateBookmarkRequest `json:"bookmark"`
+}
+
+// ShareCollectionRequest grants access to a collection: to the account
+// with Email, to every account with Workspace set or, with Public set, to
+// anyone. Exactly one of them must be given; public shares are view-only.
+type ShareCollectionRequest struct {
+	Email     string `json:"email" binding:"omitempty,email,max=254"`
+	Workspace bool   `json:"workspace"`
+	Public    bool   `json:"public"`
+	Access    string `json:"access" binding:"required,oneof=view edit"`
+}
+
+// Collection event types
+const (
+	EventBookmarkAdded     = "bookmark.added"
+	EventBookmarkUpdated   = "bookmark.updated"
+	EventBookmarkRemoved   = "bookmark.removed"
+	EventBookmarkReactions = "bookmark.reactions"
+	EventCollectionUpdated = "collection.updated"
+	EventCollectionDeleted = "collection.deleted"
+)
+
+// CollectionEvent tells the clients watching a collection that it changed.
+// Bookmark events carry the bookmark as written; since concurrent edits
+// resolve to the last write, clients reconcile by applying events in the
+// order they arrive.
+type CollectionEvent struct {
+	Type         string      `json:"type"`
+	CollectionID string      `json:"collection_id"`
+	BookmarkID   string      `json:"bookmark_id,omitempty"`
+	Bookmark     *Bookmark   `json:"bookmark,omitempty"`
+	Collection   *Collection `json:"collection,omitempty"`
+	// Reactions is the new reaction tally of a bookmark.reactions event;
+	// Reacted is always false since the event goes to every watcher
+	Reactions []ReactionCount `json:"reactions,omitempty"`
+	// ActorID is the account that made the change, empty when anonymous
+	ActorID string    `json:"actor_id,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Reaction is an account's emoji reaction to a bookmark in a collection
+type Reaction struct {
+	CollectionID string `json:"collection_id"`
+	BookmarkID   string `json:"bookmark_id"`
+	AccountID    string `json:"account_id"`
+	Emoji        string `json:"emoji"`
+}
+
+// ReactionCount aggregates the reactions to a bookmark with one emoji;
+// Reacted tells whether the caller is among them
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
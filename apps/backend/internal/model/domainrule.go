@@ -0,0 +1,65 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Domain rule modes
+const (
+	// DomainBlock rejects bookmarks from the domain
+	DomainBlock = "block"
+	// DomainAllow rejects bookmarks from any domain that no allow rule
+	// covers
+	DomainAllow = "allow"
+)
+
+// DomainRule restricts the sites bookmarks can be saved from. A rule
+// covers its domain and the subdomains below it. Rules without an
+// AccountID are set by admins and apply to everyone; the others apply to
+// bookmarks saved by their account.
+type DomainRule struct {
+	Domain    string    `json:"domain"`
+	Mode      string    `json:"mode"`
+	AccountID string    `json:"account_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DomainRuleRequest represents the request body for setting a domain rule
+type DomainRuleRequest struct {
+	Domain string `json:"domain" binding:"required,max=253"`
+	Mode   string `json:"mode" binding:"required,oneof=block allow"`
+}
+
+// NormalizeDomain lowercases a domain and trims the space, dots and
+// leading "www." around it
+func NormalizeDomain(domain string) string {
+	domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// Covers reports whether the rule applies to domain
+func (r DomainRule) Covers(domain string) bool {
+	return domain == r.Domain || strings.HasSuffix(domain, "."+r.Domain)
+}
+
+// DomainAllowed reports whether rules let bookmarks be saved from domain:
+// no block rule covers it and, when there are allow rules, one of them
+// does
+func DomainAllowed(rules []DomainRule, domain string) bool {
+	allowed, restricted := false, false
+	for _, r := range rules {
+		switch r.Mode {
+		case DomainBlock:
+			if r.Covers(domain) {
+				return false
+			}
+		case DomainAllow:
+			restricted = true
+			if r.Covers(domain) {
+				allowed = true
+			}
+		}
+	}
+	return allowed || !restricted
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Events a hook can subscribe to
+const (
+	HookBookmarkCreated = "bookmark.created"
+	HookBookmarkUpdated = "bookmark.updated"
+	HookBookmarkDeleted = "bookmark.deleted"
+)
+
+// Hook is a REST hook subscription made by an automation platform such
+// as Zapier or IFTTT: every time Event happens, a FlatBookmark of the
+// bookmark is posted to TargetURL.
+type Hook struct {
+	ID        string    `json:"id"`
+	AccountID string    `json:"account_id"`
+	Event     string    `json:"event"`
+	TargetURL string    `json:"target_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// States of a hook delivery
+const (
+	// HookDeliveryPending is waiting for its next attempt
+	HookDeliveryPending = "pending"
+	// HookDeliveryDelivered was accepted by the target
+	HookDeliveryDelivered = "delivered"
+	// HookDeliveryDead ran out of attempts; it stays listed as a dead
+	// letter until it is redelivered or ages out of the log
+	HookDeliveryDead = "dead"
+)
+
+// HookDelivery is the log of posting one event's payload to one hook.
+// Failed attempts are retried with exponential backoff until the target
+// accepts the payload or the attempts run out. LastStatus is the HTTP
+// status of the last attempt, 0 when no response came, and LastError
+// what went wrong with it.
+type HookDelivery struct {
+	ID            string          `json:"id"`
+	HookID        string          `json:"hook_id"`
+	AccountID     string          `json:"-"`
+	TargetURL     string          `json:"target_url"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastStatus    int             `json:"last_status,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// HookRequest represents the request body for subscribing a hook
+type HookRequest struct {
+	Event     string `json:"event" binding:"required,oneof=bookmark.created bookmark.updated bookmark.deleted"`
+	TargetURL string `json:"target_url" binding:"required,url,max=2048"`
+}
+
+// FlatBookmark is a bookmark as automation platforms take it best, with
+// no nested values: tags are joined with commas. Event is set in hook
+// payloads; deleted bookmarks carry only their ID.
+type FlatBookmark struct {
+	Event     string    `json:"event,omitempty"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Tags      string    `json:"tags"`
+	Notes     string    `json:"notes"`
+	IsRead    bool      `json:"is_read"`
+	Archived  bool      `json:"archived"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Flatten returns b as a FlatBookmark
+func Flatten(b Bookmark) FlatBookmark {
+	return FlatBookmark{
+		ID:        b.ID,
+		Title:     b.Title,
+		URL:       b.URL,
+		Tags:      strings.Join(b.Tags, ","),
+		Notes:     b.Notes,
+		IsRead:    b.IsRead,
+		Archived:  b.Archived,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+}
+
+// SaveActionRequest represents the flat request body of the save action.
+// Tags are comma-separated, the way form fields in automation platforms
+// send lists.
+type SaveActionRequest struct {
+	URL   string `json:"url" form:"url" binding:"required,max=2048,noscripturl,weburl"`
+	Title string `json:"title" form:"title" binding:"max=500"`
+	Tags  string `json:"tags" form:"tags" binding:"max=5000"`
+	Notes string `json:"notes" form:"notes" binding:"max=10000"`
+}
+
+// SplitTags splits a comma-separated tag list, dropping empty entries
+func SplitTags(tags string) []string {
+	var result []string
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			result = append(result, t)
+		}
+	}
+	return result
+}
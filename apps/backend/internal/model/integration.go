@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// Services an account can push its bookmarks to
+const (
+	IntegrationNotion   = "notion"
+	IntegrationReadwise = "readwise"
+)
+
+// Services an account can pull saved items from
+const (
+	IntegrationGitHub     = "github"
+	IntegrationHackerNews = "hackernews"
+	IntegrationReddit     = "reddit"
+)
+
+// Integration connects an account to a service it pushes bookmarks to or
+// pulls them from. Token is the account's API token for the service and
+// is never returned. Target is where in the service bookmarks go, such as
+// a Notion database ID, or for pulls the account's username there.
+// CollectionIDs are the collections pushed, or the one collection pulled
+// items go into. LastSyncAt is when the last sync succeeded and LastError
+// why the last one failed, if it did. Cursor is the newest item pulled,
+// where the next pull stops.
+type Integration struct {
+	AccountID     string     `json:"-"`
+	Service       string     `json:"service"`
+	Token         string     `json:"-"`
+	Target        string     `json:"target,omitempty"`
+	CollectionIDs []string   `json:"collection_ids"`
+	LastSyncAt    *time.Time `json:"last_sync_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Cursor        string     `json:"-"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// NotionRequest represents the request body for connecting the signed-in
+// account to a Notion database
+type NotionRequest struct {
+	Token         string   `json:"token" binding:"required,max=200"`
+	DatabaseID    string   `json:"database_id" binding:"required,max=100"`
+	CollectionIDs []string `json:"collection_ids" binding:"required,min=1,max=100"`
+}
+
+// ReadwiseRequest represents the request body for connecting the
+// signed-in account to Readwise. Without collections, the highlights of
+// every bookmark the account can see are sent.
+type ReadwiseRequest struct {
+	Token         string   `json:"token" binding:"required,max=200"`
+	CollectionIDs []string `json:"collection_ids" binding:"max=100"`
+}
+
+// PullRequest represents the request body for connecting the signed-in
+// account to a service it pulls saved items from. Which of the token and
+// username are required depends on the service.
+type PullRequest struct {
+	Token        string `json:"token" binding:"max=200"`
+	Username     string `json:"username" binding:"max=100"`
+	CollectionID string `json:"collection_id" binding:"required,max=100"`
+}
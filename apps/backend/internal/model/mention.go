@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Mention is a verified Webmention of a public bookmark's page: Source
+// links to it
+type Mention struct {
+	BookmarkID string    `json:"bookmark_id"`
+	Source     string    `json:"source"`
+	Title      string    `json:"title,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
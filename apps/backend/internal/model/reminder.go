@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Reminder asks for a bookmark to be brought back at RemindAt. A bookmark
+// has at most one reminder; setting another replaces it.
+type Reminder struct {
+	BookmarkID string `json:"bookmark_id"`
+	// AccountID is the account that set the reminder, which is emailed
+	// when it is due; reminders set without signing in have none
+	AccountID string    `json:"account_id,omitempty"`
+	RemindAt  time.Time `json:"remind_at"`
+	// DeliveredAt is when the reminder was sent; nil while it is pending
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// SetReminderRequest represents the request body for setting a reminder
+type SetReminderRequest struct {
+	RemindAt time.Time `json:"remind_at" binding:"required"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Revision is a bookmark's title, URL and tags as they were after one
+// change. Version 1 is the bookmark as it was saved.
+type Revision struct {
+	BookmarkID string    `json:"bookmark_id"`
+	Version    int       `json:"version"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	Tags       []string  `json:"tags"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"sort"
+	"strings"
+)
+
+// RenameTagRequest represents the request body for renaming a tag
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// MergeTagsRequest folds the source tags into the target tag
+type MergeTagsRequest struct {
+	Sources []string `json:"sources" binding:"required,min=1,max=100,dive,max=100"`
+	Target  string   `json:"target" binding:"required,max=100"`
+}
+
+// MoveTagRequest moves a tag and its descendants to a new path
+type MoveTagRequest struct {
+	From string `json:"from" binding:"required,max=100"`
+	To   string `json:"to" binding:"required,max=100"`
+}
+
+// BulkTagRequest adds and removes tags on several bookmarks at once
+type BulkTagRequest struct {
+	BookmarkIDs []string `json:"bookmark_ids" binding:"required,min=1,max=1000"`
+	Add         []string `json:"add" binding:"max=50,dive,max=100"`
+	Remove      []string `json:"remove" binding:"max=50,dive,max=100"`
+}
+
+// TagChange reports the result of renaming or merging tags
+type TagChange struct {
+	Tag     string `json:"tag"`
+	Updated int    `json:"updated"`
+}
+
+// TagNode is a tag in the tag tree. Count is the number of bookmarks
+// carrying the tag itself, Total also counts those carrying a descendant;
+// a bookmark tagged at several levels is counted once per tag.
+type TagNode struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Count    int       `json:"count"`
+	Total    int       `json:"total"`
+	Children []TagNode `json:"children"`
+}
+
+// BuildTagTree arranges tag usage counts into a tree. Intermediate levels
+// that no bookmark uses directly appear with a zero Count. With a root,
+// only the tags below it are returned.
+func BuildTagTree(counts map[string]int, root string) []TagNode {
+	type node struct {
+		TagNode
+		children map[string]*node
+	}
+	top := &node{children: make(map[string]*node)}
+	for tag, n := range counts {
+		if root != "" {
+			if !TagWithin(tag, root) || tag == root {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, root+TagSeparator)
+		}
+		cur := top
+		path := root
+		for _, name := range strings.Split(tag, TagSeparator) {
+			if path == "" {
+				path = name
+			} else {
+				path += TagSeparator + name
+			}
+			next, ok := cur.children[name]
+			if !ok {
+				next = &node{TagNode: TagNode{Name: name, Path: path}, children: make(map[string]*node)}
+				cur.children[name] = next
+			}
+			next.Total += n
+			cur = next
+		}
+		cur.Count += n
+	}
+
+	var flatten func(n *node) []TagNode
+	flatten = func(n *node) []TagNode {
+		result := make([]TagNode, 0, len(n.children))
+		for _, child := range n.children {
+			child.Children = flatten(child)
+			result = append(result, child.TagNode)
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+		return result
+	}
+	return flatten(top)
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// URLRules normalize URLs before bookmarks are stored or looked up, so
+// the same page saved with different tracking parameters is recognized
+// as one. Site rules add to the global ones for a domain and the
+// subdomains below it.
+type URLRules struct {
+	// StripParams are query parameters to drop, as patterns such as
+	// "utm_*" matched against the lowercased name
+	StripParams    []string      `json:"strip_params" binding:"max=100,dive,max=100"`
+	LowercaseHost  bool          `json:"lowercase_host"`
+	RemoveFragment bool          `json:"remove_fragment"`
+	Sites          []SiteURLRule `json:"sites" binding:"max=200,dive"`
+}
+
+// SiteURLRule is a normalization rule for one site. KeepParams, when set,
+// drops every query parameter it does not match.
+type SiteURLRule struct {
+	Domain         string   `json:"domain" binding:"required,max=253"`
+	StripParams    []string `json:"strip_params,omitempty" binding:"max=100,dive,max=100"`
+	KeepParams     []string `json:"keep_params,omitempty" binding:"max=100,dive,max=100"`
+	RemoveFragment bool     `json:"remove_fragment,omitempty"`
+}
+
+// DefaultURLRules are the rules in effect until an admin changes them:
+// common tracking parameters are stripped and hosts lowercased.
+// Fragments are kept since some sites route on them.
+func DefaultURLRules() URLRules {
+	return URLRules{
+		StripParams:   []string{"utm_*", "fbclid", "gclid", "mc_cid", "mc_eid"},
+		LowercaseHost: true,
+		Sites:         []SiteURLRule{},
+	}
+}
+
+// Validate reports the first parameter pattern that does not parse
+func (r URLRules) Validate() error {
+	patterns := append([]string{}, r.StripParams...)
+	for _, site := range r.Sites {
+		patterns = append(patterns, site.StripParams...)
+		patterns = append(patterns, site.KeepParams...)
+	}
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Normalize applies the rules to raw. URLs without a host are returned
+// unchanged; query parameters that are kept keep their order and encoding.
+func (r URLRules) Normalize(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	if r.LowercaseHost {
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = strings.ToLower(u.Host)
+	}
+
+	strip, fragment := r.StripParams, r.RemoveFragment
+	var keep []string
+	domain := NormalizeDomain(u.Hostname())
+	for _, site := range r.Sites {
+		if domain != site.Domain && !strings.HasSuffix(domain, "."+site.Domain) {
+			continue
+		}
+		strip = append(strip[:len(strip):len(strip)], site.StripParams...)
+		if len(site.KeepParams) > 0 {
+			keep = site.KeepParams
+		}
+		fragment = fragment || site.RemoveFragment
+	}
+
+	if u.RawQuery != "" {
+		var kept []string
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			name, _, _ := strings.Cut(param, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			name = strings.ToLower(name)
+			if matchParam(strip, name) || (keep != nil && !matchParam(keep, name)) {
+				continue
+			}
+			kept = append(kept, param)
+		}
+		u.RawQuery = strings.Join(kept, "&")
+		u.ForceQuery = false
+	}
+	if fragment {
+		u.Fragment, u.RawFragment = "", ""
+	}
+	return u.String()
+}
+
+func matchParam(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
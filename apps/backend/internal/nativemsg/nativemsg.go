@@ -0,0 +1,73 @@
+// Package nativemsg implements the browser native messaging protocol,
+// which lets an extension exchange JSON messages with a local program
+// over its stdin and stdout. Each message is UTF-8 JSON preceded by its
+// length as a 32-bit unsigned integer in native byte order.
+package nativemsg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// MaxIncoming bounds a message from the browser; browsers send at
+	// most 64 MiB
+	MaxIncoming = 64 << 20
+	// MaxOutgoing bounds a message to the browser, which drops the
+	// connection on anything larger
+	MaxOutgoing = 1 << 20
+)
+
+// ErrTooLarge is returned for messages over the protocol's limits
+var ErrTooLarge = errors.New("native message too large")
+
+// Read reads one message into v. It returns io.EOF when the browser has
+// closed the connection between messages.
+func Read(r io.Reader, v interface{}) error {
+	var length uint32
+	if err := binary.Read(r, binary.NativeEndian, &length); err != nil {
+		return err
+	}
+	if length > MaxIncoming {
+		return ErrTooLarge
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return fmt.Errorf("read message: %w", io.ErrUnexpectedEOF)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Write sends v as one message
+func Write(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxOutgoing {
+		return ErrTooLarge
+	}
+	if err := binary.Write(w, binary.NativeEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Manifest describes a host to the browser. It is installed as
+// <Name>.json in the browser's NativeMessagingHosts directory, or
+// registered in the Windows registry.
+type Manifest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+	// AllowedOrigins lists the Chrome extensions that may connect, as
+	// chrome-extension://<id>/
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	// AllowedExtensions lists the Firefox extension IDs that may connect
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"`
+}
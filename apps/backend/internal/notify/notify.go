@@ -0,0 +1,168 @@
+// Package notify delivers notifications such as read-later reminders by
+// email (SMTP) and webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is a notification. Email receives Subject and Text; webhooks
+// receive all of it as JSON.
+type Message struct {
+	// To is the email recipient; empty falls back to Config.EmailTo
+	To      string      `json:"-"`
+	Subject string      `json:"subject"`
+	Text    string      `json:"text"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Notifier delivers messages
+type Notifier interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Config selects the delivery channels; a channel without its address is
+// disabled
+type Config struct {
+	// SMTP server as host:port
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	// EmailTo receives messages that name no recipient
+	EmailTo string
+
+	// WebhookURL receives every message as a JSON POST
+	WebhookURL string
+}
+
+// Open returns a Notifier delivering over every configured channel, or
+// nil when none is
+func Open(cfg Config) (Notifier, error) {
+	var m multi
+	if cfg.SMTPAddr != "" {
+		if _, _, err := net.SplitHostPort(cfg.SMTPAddr); err != nil {
+			return nil, fmt.Errorf("SMTP address %q: %w", cfg.SMTPAddr, err)
+		}
+		if cfg.SMTPFrom == "" {
+			return nil, errors.New("SMTP needs a sender address")
+		}
+		m = append(m, &mailer{cfg: cfg})
+	}
+	if cfg.WebhookURL != "" {
+		if !strings.HasPrefix(cfg.WebhookURL, "http://") && !strings.HasPrefix(cfg.WebhookURL, "https://") {
+			return nil, fmt.Errorf("webhook URL %q must be http(s)", cfg.WebhookURL)
+		}
+		m = append(m, &webhook{url: cfg.WebhookURL, client: &http.Client{Timeout: 10 * time.Second}})
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// ErrNoRecipient is returned when no channel could address a message:
+// email is the only channel and neither the message nor the config names
+// a recipient
+var ErrNoRecipient = errors.New("no recipient for the message")
+
+// multi sends over several channels. A message counts as delivered when
+// at least one channel takes it; the failures of the others are logged.
+type multi []Notifier
+
+func (m multi) Send(ctx context.Context, msg Message) error {
+	var (
+		errs      []error
+		delivered bool
+	)
+	for _, n := range m {
+		err := n.Send(ctx, msg)
+		switch {
+		case err == nil:
+			delivered = true
+		case !errors.Is(err, ErrNoRecipient):
+			errs = append(errs, err)
+		}
+	}
+	switch {
+	case delivered:
+		for _, err := range errs {
+			log.Printf("notify: %v", err)
+		}
+		return nil
+	case len(errs) > 0:
+		return errors.Join(errs...)
+	default:
+		return ErrNoRecipient
+	}
+}
+
+type mailer struct {
+	cfg Config
+}
+
+func (m *mailer) Send(_ context.Context, msg Message) error {
+	to := msg.To
+	if to == "" {
+		to = m.cfg.EmailTo
+	}
+	if to == "" {
+		return ErrNoRecipient
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", m.cfg.SMTPFrom)
+	fmt.Fprintf(&body, "To: %s\r\n", to)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if m.cfg.SMTPUsername != "" {
+		host, _, _ := net.SplitHostPort(m.cfg.SMTPAddr)
+		auth = smtp.PlainAuth("", m.cfg.SMTPUsername, m.cfg.SMTPPassword, host)
+	}
+	if err := smtp.SendMail(m.cfg.SMTPAddr, auth, m.cfg.SMTPFrom, []string{to}, body.Bytes()); err != nil {
+		return fmt.Errorf("email to %s: %w", to, err)
+	}
+	return nil
+}
+
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhook) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "web-collector-notify/1.0")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
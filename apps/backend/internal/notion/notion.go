@@ -0,0 +1,256 @@
+// Package notion pushes bookmarks into a Notion database, one page per
+// bookmark, through the Notion API. Pages are matched by URL, so pushing
+// a bookmark again updates its page.
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// apiURL is the Notion API endpoint; the method path is appended
+	apiURL = "https://api.notion.com/v1/"
+	// apiVersion is the Notion API version the requests are written for
+	apiVersion = "2022-06-28"
+	// maxText is the longest text Notion accepts in one rich text object
+	maxText = 2000
+	// maxTextObjects is how many rich text objects a property may hold
+	maxTextObjects = 100
+	// maxAttempts is how many times a rate-limited request is tried
+	maxAttempts = 3
+)
+
+// ErrNoURLProperty is returned for databases without a URL property,
+// which pages are matched by
+var ErrNoURLProperty = errors.New("the Notion database needs a property of type URL")
+
+var databaseIDPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}`)
+
+// ParseDatabaseID returns the database ID in s, which may be the ID or
+// the database's URL, reporting false when there is none
+func ParseDatabaseID(s string) (string, bool) {
+	// The query of a database URL names the view
+	if i := strings.IndexAny(s, "?#"); i >= 0 {
+		s = s[:i]
+	}
+	matches := databaseIDPattern.FindAllString(s, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	// A database URL ends with its ID, after the workspace and title
+	id := strings.ToLower(strings.ReplaceAll(matches[len(matches)-1], "-", ""))
+	return id[:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:], true
+}
+
+// Page is a bookmark as pushed to Notion
+type Page struct {
+	Title string
+	URL   string
+	Tags  []string
+	Notes string
+}
+
+// Database is a Notion database bookmarks are pushed to, with the names of
+// the properties each bookmark field goes in. Tags and notes are left out
+// when the database has no property for them.
+type Database struct {
+	id     string
+	token  string
+	client *http.Client
+
+	title, url, tags, notes string
+}
+
+// Open checks that token can reach the database and finds its
+// properties: the title, the URL property, and the multi-select and text
+// properties named Tags and Notes, or else the first of each type
+func Open(ctx context.Context, token, databaseID string) (*Database, error) {
+	d := &Database{
+		id:     databaseID,
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	var schema struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+	}
+	if err := d.do(ctx, http.MethodGet, "databases/"+databaseID, nil, &schema); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pick := func(kind, preferred string) string {
+		found := ""
+		for _, name := range names {
+			if schema.Properties[name].Type != kind {
+				continue
+			}
+			if strings.EqualFold(name, preferred) {
+				return name
+			}
+			if found == "" {
+				found = name
+			}
+		}
+		return found
+	}
+	d.title = pick("title", "Name")
+	d.url = pick("url", "URL")
+	d.tags = pick("multi_select", "Tags")
+	d.notes = pick("rich_text", "Notes")
+	if d.url == "" {
+		return nil, ErrNoURLProperty
+	}
+	return d, nil
+}
+
+// Push creates a page for p, or updates the page that has its URL
+func (d *Database) Push(ctx context.Context, p Page) error {
+	var existing struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+	}
+	query := map[string]interface{}{
+		"filter":    map[string]interface{}{"property": d.url, "url": map[string]string{"equals": p.URL}},
+		"page_size": 1,
+	}
+	if err := d.do(ctx, http.MethodPost, "databases/"+d.id+"/query", query, &existing); err != nil {
+		return err
+	}
+
+	properties := d.properties(p)
+	if len(existing.Results) > 0 {
+		return d.do(ctx, http.MethodPatch, "pages/"+existing.Results[0].ID, map[string]interface{}{
+			"properties": properties,
+		}, nil)
+	}
+	return d.do(ctx, http.MethodPost, "pages", map[string]interface{}{
+		"parent":     map[string]string{"database_id": d.id},
+		"properties": properties,
+	}, nil)
+}
+
+func (d *Database) properties(p Page) map[string]interface{} {
+	properties := map[string]interface{}{
+		d.title: map[string]interface{}{"title": richText(p.Title)},
+		d.url:   map[string]interface{}{"url": p.URL},
+	}
+	if d.tags != "" {
+		options := []map[string]string{}
+		for _, tag := range p.Tags {
+			// Option names cannot contain commas
+			if tag = strings.TrimSpace(strings.ReplaceAll(tag, ",", " ")); tag != "" {
+				options = append(options, map[string]string{"name": truncate(tag, 100)})
+			}
+		}
+		properties[d.tags] = map[string]interface{}{"multi_select": options}
+	}
+	if d.notes != "" {
+		properties[d.notes] = map[string]interface{}{"rich_text": richText(p.Notes)}
+	}
+	return properties
+}
+
+// richText splits s into the rich text objects Notion stores it in
+func richText(s string) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for s != "" && len(result) < maxTextObjects {
+		chunk := truncate(s, maxText)
+		s = s[len(chunk):]
+		result = append(result, map[string]interface{}{
+			"type": "text",
+			"text": map[string]string{"content": chunk},
+		})
+	}
+	return result
+}
+
+// truncate returns the longest prefix of s of at most n UTF-16 code
+// units, which is how Notion measures text
+func truncate(s string, n int) string {
+	units := 0
+	for i, r := range s {
+		size := 1
+		if r > 0xFFFF {
+			size = 2
+		}
+		if units+size > n {
+			return s[:i]
+		}
+		units += size
+	}
+	return s
+}
+
+// do calls the API, decoding the response into out when it is not nil.
+// Rate-limited requests are retried after the wait Notion asks for.
+func (d *Database) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, apiURL+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+d.token)
+		req.Header.Set("Notion-Version", apiVersion)
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("notion: %w", err)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("notion: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxAttempts {
+			wait, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(max(wait, 1)) * time.Second):
+			}
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			var apiErr struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+				return fmt.Errorf("notion: %s", apiErr.Message)
+			}
+			return fmt.Errorf("notion: %s", resp.Status)
+		}
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(data, out)
+	}
+}
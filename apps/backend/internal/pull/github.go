@@ -0,0 +1,72 @@
+package pull
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// githubAPI is the GitHub REST API endpoint
+var githubAPI = "https://api.github.com"
+
+// maxTopics bounds the repository topics kept as tags
+const maxTopics = 10
+
+type githubStars struct {
+	header http.Header
+}
+
+// GitHub lists the repositories the owner of token starred. A
+// fine-grained token needs no permissions beyond reading public data.
+func GitHub(token string) Source {
+	return &githubStars{header: http.Header{
+		"Authorization":        {"Bearer " + token},
+		"Accept":               {"application/vnd.github+json"},
+		"X-Github-Api-Version": {"2022-11-28"},
+	}}
+}
+
+func (g *githubStars) Check(ctx context.Context) error {
+	_, err := get(ctx, "github", githubAPI+"/user", g.header)
+	return err
+}
+
+// Page returns a page of stars. Repositories become bookmarks of their
+// GitHub page, with the description as notes and topics as tags.
+func (g *githubStars) Page(ctx context.Context, cursor string) ([]Item, string, error) {
+	page := 1
+	if cursor != "" {
+		page, _ = strconv.Atoi(cursor)
+	}
+	data, err := get(ctx, "github", githubAPI+"/user/starred?sort=created&direction=desc&per_page=100&page="+strconv.Itoa(page), g.header)
+	if err != nil {
+		return nil, "", err
+	}
+	var repos []struct {
+		ID          int64    `json:"id"`
+		FullName    string   `json:"full_name"`
+		HTMLURL     string   `json:"html_url"`
+		Description string   `json:"description"`
+		Topics      []string `json:"topics"`
+	}
+	if err := json.Unmarshal(data, &repos); err != nil {
+		return nil, "", err
+	}
+
+	items := make([]Item, 0, len(repos))
+	for _, r := range repos {
+		items = append(items, Item{
+			Key:   strconv.FormatInt(r.ID, 10),
+			URL:   r.HTMLURL,
+			Title: r.FullName,
+			Tags:  r.Topics[:min(len(r.Topics), maxTopics)],
+			Notes: r.Description,
+		})
+	}
+	next := ""
+	if len(repos) == 100 {
+		next = strconv.Itoa(page + 1)
+	}
+	return items, next, nil
+}
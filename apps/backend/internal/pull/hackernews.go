@@ -0,0 +1,125 @@
+package pull
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// hackerNewsSite serves the favorites pages, which have no API
+	hackerNewsSite = "https://news.ycombinator.com/"
+	// hackerNewsAPI is the official read-only API, used to check users
+	hackerNewsAPI = "https://hacker-news.firebaseio.com/v0/"
+)
+
+type hackerNewsFavorites struct {
+	username string
+}
+
+// HackerNews lists the stories username marked as favorites. Favorites
+// are public, so no token is needed.
+func HackerNews(username string) Source {
+	return &hackerNewsFavorites{username: username}
+}
+
+func (h *hackerNewsFavorites) Check(ctx context.Context) error {
+	data, err := get(ctx, "hackernews", hackerNewsAPI+"user/"+url.PathEscape(h.username)+".json", nil)
+	if err != nil {
+		return err
+	}
+	if string(bytes.TrimSpace(data)) == "null" {
+		return fmt.Errorf("hackernews: %w", ErrRefused)
+	}
+	return nil
+}
+
+// Page reads a page of favorites. Stories without a link of their own,
+// such as Ask HN, become bookmarks of their discussion.
+func (h *hackerNewsFavorites) Page(ctx context.Context, cursor string) ([]Item, string, error) {
+	page := 1
+	if cursor != "" {
+		page, _ = strconv.Atoi(cursor)
+	}
+	query := url.Values{"id": {h.username}, "p": {strconv.Itoa(page)}}
+	data, err := get(ctx, "hackernews", hackerNewsSite+"favorites?"+query.Encode(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	items, more := parseFavorites(data)
+	next := ""
+	if more {
+		next = strconv.Itoa(page + 1)
+	}
+	return items, next, nil
+}
+
+// parseFavorites reads the stories of a favorites page, each a table row
+// of class "athing" whose title link sits in a "titleline" span, and
+// reports whether a "More" link follows them
+func parseFavorites(page []byte) ([]Item, bool) {
+	base, _ := url.Parse(hackerNewsSite)
+	var (
+		items           []Item
+		more            bool
+		story           string // ID of the row being read
+		inTitle, inLink bool
+		item            Item
+	)
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return items, more
+		case html.TextToken:
+			if inLink {
+				item.Title += string(z.Text())
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); inLink && string(name) == "a" {
+				item.Title = strings.TrimSpace(item.Title)
+				if item.URL != "" {
+					items = append(items, item)
+				}
+				story, inTitle, inLink = "", false, false
+			}
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			attrs := make(map[string]string)
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				attrs[string(k)] = string(v)
+			}
+			classes := strings.Fields(attrs["class"])
+			switch {
+			case string(name) == "tr" && hasClass(classes, "athing"):
+				story, inTitle = attrs["id"], false
+			case string(name) == "span" && story != "" && hasClass(classes, "titleline"):
+				inTitle = true
+			case string(name) == "a" && hasClass(classes, "morelink"):
+				more = true
+			case string(name) == "a" && inTitle && !inLink:
+				inLink = true
+				item = Item{Key: story}
+				if link, err := base.Parse(attrs["href"]); err == nil && (link.Scheme == "http" || link.Scheme == "https") {
+					item.URL = link.String()
+				}
+			}
+		}
+	}
+}
+
+func hasClass(classes []string, class string) bool {
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// Package pull lists what an account saved in another service, such as
+// its GitHub stars, Hacker News favorites or Reddit saves, so the items
+// can be pulled in as bookmarks.
+package pull
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// userAgent identifies pulls; Reddit refuses generic agents
+const userAgent = "web-collector-pull/1.0"
+
+// maxResponseBytes bounds a page read from a service
+const maxResponseBytes = 8 << 20
+
+// ErrRefused is returned when a service does not accept the credentials
+var ErrRefused = errors.New("the service refused the token or username")
+
+// Item is something an account saved in a service. Key identifies it in
+// the service and stays the same when it is listed again.
+type Item struct {
+	Key   string
+	URL   string
+	Title string
+	Tags  []string
+	Notes string
+}
+
+// Source lists an account's saved items one page at a time, most
+// recently saved first
+type Source interface {
+	// Check reports an error when the service does not accept the
+	// account's credentials
+	Check(ctx context.Context) error
+	// Page returns the page of items at cursor, "" being the first, and
+	// the cursor of the next page, "" after the last
+	Page(ctx context.Context, cursor string) ([]Item, string, error)
+}
+
+// Since returns the items saved after the one with key last, newest
+// first, reading at most maxPages pages. With last empty, or when that
+// item is no longer listed, it returns everything in those pages.
+func Since(ctx context.Context, src Source, last string, maxPages int) ([]Item, error) {
+	var (
+		result []Item
+		cursor string
+	)
+	for page := 0; page < maxPages; page++ {
+		items, next, err := src.Page(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if last != "" && item.Key == last {
+				return result, nil
+			}
+			result = append(result, item)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	return result, nil
+}
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// get fetches url, returning the body of a successful response. header
+// adds request headers such as credentials.
+func get(ctx context.Context, service, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden, resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", service, ErrRefused)
+	case resp.StatusCode >= 300:
+		if len(data) > 0 && len(data) < 500 {
+			return nil, fmt.Errorf("%s: %s: %s", service, resp.Status, bytes.TrimSpace(data))
+		}
+		return nil, fmt.Errorf("%s: %s", service, resp.Status)
+	}
+	return data, nil
+}
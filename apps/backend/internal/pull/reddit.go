@@ -0,0 +1,90 @@
+package pull
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// redditSite serves the private feeds of saved items
+var redditSite = "https://www.reddit.com"
+
+type redditSaves struct {
+	username string
+	feed     string
+}
+
+// Reddit lists the posts and comments username saved. Reddit's OAuth
+// tokens expire after an hour, so this reads the private JSON feed
+// instead; feedToken is the feed= value of the links on Reddit's RSS
+// feeds preferences page.
+func Reddit(username, feedToken string) Source {
+	return &redditSaves{username: username, feed: feedToken}
+}
+
+func (r *redditSaves) Check(ctx context.Context) error {
+	_, err := get(ctx, "reddit", r.url("", 1), nil)
+	return err
+}
+
+func (r *redditSaves) url(after string, limit int) string {
+	query := url.Values{"feed": {r.feed}, "user": {r.username}, "limit": {strconv.Itoa(limit)}, "raw_json": {"1"}}
+	if after != "" {
+		query.Set("after", after)
+	}
+	return redditSite + "/user/" + url.PathEscape(r.username) + "/saved.json?" + query.Encode()
+}
+
+// Page returns a page of saves. A saved post becomes a bookmark of the
+// page it links to, a saved comment one of the comment itself with its
+// text as notes; both are tagged with their subreddit.
+func (r *redditSaves) Page(ctx context.Context, cursor string) ([]Item, string, error) {
+	data, err := get(ctx, "reddit", r.url(cursor, 100), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	var listing struct {
+		Data struct {
+			After    string `json:"after"`
+			Children []struct {
+				Kind string `json:"kind"`
+				Data struct {
+					Name      string `json:"name"`
+					Title     string `json:"title"`
+					URL       string `json:"url"`
+					Permalink string `json:"permalink"`
+					Subreddit string `json:"subreddit"`
+					LinkTitle string `json:"link_title"`
+					Body      string `json:"body"`
+				} `json:"data"`
+			} `json:"children"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return nil, "", err
+	}
+
+	var items []Item
+	for _, child := range listing.Data.Children {
+		d := child.Data
+		item := Item{Key: d.Name}
+		if d.Subreddit != "" {
+			item.Tags = []string{strings.ToLower(d.Subreddit)}
+		}
+		switch child.Kind {
+		case "t3": // post
+			item.URL, item.Title = d.URL, d.Title
+			if strings.HasPrefix(item.URL, "/") {
+				item.URL = redditSite + item.URL
+			}
+		case "t1": // comment
+			item.URL, item.Title, item.Notes = redditSite+d.Permalink, d.LinkTitle, d.Body
+		default:
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, listing.Data.After, nil
+}
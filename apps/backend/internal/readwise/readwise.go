@@ -0,0 +1,145 @@
+// Package readwise sends highlights to Readwise through its API, where
+// they join the highlights from an account's other reading.
+package readwise
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// apiURL is the Readwise API endpoint; the method path is appended
+	apiURL = "https://readwise.io/api/v2/"
+	// batchSize is how many highlights are sent in one request
+	batchSize = 100
+	// maxAttempts is how many times a rate-limited request is tried
+	maxAttempts = 3
+	// sourceType names where the highlights came from in Readwise
+	sourceType = "web_collector"
+)
+
+// Limits Readwise puts on highlight fields, in characters
+const (
+	maxText  = 8191
+	maxTitle = 511
+	maxURL   = 2047
+)
+
+// Highlight is a passage highlighted on a bookmarked page
+type Highlight struct {
+	Text          string
+	Title         string
+	SourceURL     string
+	Note          string
+	HighlightedAt time.Time
+}
+
+// Client calls the Readwise API with an account's access token
+type Client struct {
+	token  string
+	client *http.Client
+}
+
+// New returns a client using token
+func New(token string) *Client {
+	return &Client{token: token, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Check reports an error when Readwise does not accept the token
+func (c *Client) Check(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "auth/", nil)
+}
+
+// Send adds highlights to Readwise, which files them under their page's
+// title and skips ones it already has
+func (c *Client) Send(ctx context.Context, highlights []Highlight) error {
+	for len(highlights) > 0 {
+		n := min(len(highlights), batchSize)
+		batch := make([]map[string]interface{}, 0, n)
+		for _, h := range highlights[:n] {
+			item := map[string]interface{}{
+				"text":        truncate(h.Text, maxText),
+				"title":       truncate(h.Title, maxTitle),
+				"source_type": sourceType,
+				"category":    "articles",
+			}
+			if len(h.SourceURL) <= maxURL {
+				item["source_url"] = h.SourceURL
+			}
+			if h.Note != "" {
+				item["note"] = truncate(h.Note, maxText)
+			}
+			if !h.HighlightedAt.IsZero() {
+				item["highlighted_at"] = h.HighlightedAt.UTC().Format(time.RFC3339)
+			}
+			batch = append(batch, item)
+		}
+		if err := c.do(ctx, http.MethodPost, "highlights/", map[string]interface{}{"highlights": batch}); err != nil {
+			return err
+		}
+		highlights = highlights[n:]
+	}
+	return nil
+}
+
+// truncate cuts s to at most n runes
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// do calls the API. Rate-limited requests are retried after the wait
+// Readwise asks for.
+func (c *Client) do(ctx context.Context, method, path string, in interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, apiURL+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Token "+c.token)
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("readwise: %w", err)
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < maxAttempts:
+			wait, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(max(wait, 1)) * time.Second):
+			}
+			continue
+		case resp.StatusCode == http.StatusUnauthorized:
+			return fmt.Errorf("readwise: the access token was refused")
+		case resp.StatusCode >= 300:
+			if len(data) > 0 && len(data) < 500 {
+				return fmt.Errorf("readwise: %s: %s", resp.Status, bytes.TrimSpace(data))
+			}
+			return fmt.Errorf("readwise: %s", resp.Status)
+		}
+		return nil
+	}
+}
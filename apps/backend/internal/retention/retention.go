@@ -0,0 +1,196 @@
+// Package retention prunes archived page versions from blob storage.
+//
+// Archives are stored as prefix/<bookmark id>/<version>[/<file>...]; all
+// objects sharing a version directory form one version, and a version is
+// as old as its most recently written object. The newest version of each
+// bookmark is never deleted, so limits never leave a bookmark without an
+// archive.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/blob"
+)
+
+// Policy bounds how much archived content is kept. Zero values disable
+// the corresponding limit.
+type Policy struct {
+	// MaxAge deletes versions last written longer ago than this
+	MaxAge time.Duration
+	// MaxBytes caps the total size of all archives; the oldest versions
+	// across all bookmarks are deleted first
+	MaxBytes int64
+	// KeepVersions keeps only the newest N versions of each bookmark
+	KeepVersions int
+}
+
+// Enabled reports whether any limit is set
+func (p Policy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxBytes > 0 || p.KeepVersions > 0
+}
+
+// Report summarizes a garbage collection run
+type Report struct {
+	Versions       int   // versions found
+	Deleted        int   // versions deleted
+	ReclaimedBytes int64 // size of the deleted versions
+	RemainingBytes int64 // size of what is left
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("scanned %d versions, deleted %d, reclaimed %d bytes, %d bytes remaining",
+		r.Versions, r.Deleted, r.ReclaimedBytes, r.RemainingBytes)
+}
+
+// version is one archived copy of a bookmark
+type version struct {
+	bookmark string
+	objects  []blob.Object
+	size     int64
+	modified time.Time
+	newest   bool
+}
+
+// versionOf splits an object key into its bookmark and version directory
+func versionOf(key, prefix string) (bookmark, dir string, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 3)
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	return parts[0], parts[0] + "/" + parts[1], true
+}
+
+// group collects the objects under prefix into versions. Objects that do
+// not follow the layout are left alone.
+func group(objects []blob.Object, prefix string) []*version {
+	byKey := make(map[string]*version)
+	var versions []*version
+	for _, obj := range objects {
+		bookmark, dir, ok := versionOf(obj.Key, prefix)
+		if !ok {
+			continue
+		}
+		v, ok := byKey[dir]
+		if !ok {
+			v = &version{bookmark: bookmark}
+			byKey[dir] = v
+			versions = append(versions, v)
+		}
+		v.objects = append(v.objects, obj)
+		v.size += obj.Size
+		if obj.Modified.After(v.modified) {
+			v.modified = obj.Modified
+		}
+	}
+	return versions
+}
+
+// Latest returns the objects of each bookmark's newest version under
+// prefix, keyed by bookmark ID
+func Latest(objects []blob.Object, prefix string) map[string][]blob.Object {
+	latest := make(map[string]*version)
+	for _, v := range group(objects, prefix) {
+		if cur, ok := latest[v.bookmark]; !ok || v.modified.After(cur.modified) {
+			latest[v.bookmark] = v
+		}
+	}
+	result := make(map[string][]blob.Object, len(latest))
+	for bookmark, v := range latest {
+		result[bookmark] = v.objects
+	}
+	return result
+}
+
+// Sizes returns the total size of each bookmark's versions under prefix,
+// keyed by bookmark ID
+func Sizes(objects []blob.Object, prefix string) map[string]int64 {
+	result := make(map[string]int64)
+	for _, v := range group(objects, prefix) {
+		result[v.bookmark] += v.size
+	}
+	return result
+}
+
+// Select returns the versions p deletes at now, as the objects to remove,
+// along with the report the deletion would produce.
+func Select(objects []blob.Object, prefix string, p Policy, now time.Time) ([]blob.Object, Report) {
+	versions := group(objects, prefix)
+	// Newest first, so each bookmark's versions are ranked by age.
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].modified.After(versions[j].modified)
+	})
+
+	var report Report
+	report.Versions = len(versions)
+	doomed := make(map[*version]bool)
+	rank := make(map[string]int)
+	var total int64
+	for _, v := range versions {
+		n := rank[v.bookmark]
+		rank[v.bookmark]++
+		v.newest = n == 0
+		switch {
+		case v.newest:
+		case p.KeepVersions > 0 && n >= p.KeepVersions:
+			doomed[v] = true
+		case p.MaxAge > 0 && now.Sub(v.modified) > p.MaxAge:
+			doomed[v] = true
+		}
+		if !doomed[v] {
+			total += v.size
+		}
+	}
+
+	// Over budget: drop the oldest remaining versions until it fits.
+	for i := len(versions) - 1; i >= 0 && p.MaxBytes > 0 && total > p.MaxBytes; i-- {
+		v := versions[i]
+		if v.newest || doomed[v] {
+			continue
+		}
+		doomed[v] = true
+		total -= v.size
+	}
+
+	var remove []blob.Object
+	for _, v := range versions {
+		if doomed[v] {
+			remove = append(remove, v.objects...)
+			report.Deleted++
+			report.ReclaimedBytes += v.size
+		}
+	}
+	report.RemainingBytes = total
+	return remove, report
+}
+
+// Sweep applies p to the archives under prefix in s. On error, the report
+// covers what was deleted before it.
+func Sweep(ctx context.Context, s blob.Store, prefix string, p Policy) (Report, error) {
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return Report{}, fmt.Errorf("list archives: %w", err)
+	}
+	remove, planned := Select(objects, prefix, p, time.Now())
+
+	report := Report{Versions: planned.Versions, RemainingBytes: planned.RemainingBytes + planned.ReclaimedBytes}
+	deleted := make(map[string]bool)
+	for _, obj := range remove {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := s.Delete(ctx, obj.Key); err != nil {
+			return report, fmt.Errorf("delete %s: %w", obj.Key, err)
+		}
+		report.ReclaimedBytes += obj.Size
+		report.RemainingBytes -= obj.Size
+		_, dir, _ := versionOf(obj.Key, prefix)
+		deleted[dir] = true
+		report.Deleted = len(deleted)
+	}
+	return report, nil
+}
@@ -0,0 +1,221 @@
+// Package sanitize cleans untrusted HTML so it can be stored and shown
+// back to users.
+package sanitize
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// allowed are the elements kept, with the attributes kept on each.
+// Elements not listed are dropped but their text is kept.
+var allowed = map[string][]string{
+	"a": {"href", "title"}, "abbr": {"title"}, "b": nil, "blockquote": nil,
+	"br": nil, "caption": nil, "cite": nil, "code": nil, "del": nil,
+	"div": nil, "em": nil, "figcaption": nil, "figure": nil, "h1": nil,
+	"h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil, "hr": nil,
+	"i": nil, "img": {"src", "alt", "title", "width", "height"}, "ins": nil,
+	"kbd": nil, "li": nil, "mark": nil, "ol": nil, "p": nil, "pre": nil,
+	"q": nil, "s": nil, "small": nil, "span": nil, "strong": nil,
+	"sub": nil, "sup": nil, "table": nil, "tbody": nil, "td": nil,
+	"tfoot": nil, "th": nil, "thead": nil, "tr": nil, "u": nil, "ul": nil,
+}
+
+// void elements have no end tag
+var void = map[string]bool{"br": true, "hr": true, "img": true}
+
+// dropped are elements removed together with everything inside them
+var dropped = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"embed": true, "template": true, "noscript": true, "svg": true,
+	"math": true, "head": true, "title": true, "textarea": true,
+	"select": true, "button": true,
+}
+
+// markup are elements Plain strips besides those HTML keeps or drops.
+// Tags with names that are not elements are taken as text.
+var markup = map[string]bool{
+	"applet": true, "article": true, "aside": true, "audio": true,
+	"base": true, "body": true, "canvas": true, "center": true,
+	"details": true, "dialog": true, "font": true, "footer": true,
+	"form": true, "frame": true, "frameset": true, "header": true,
+	"html": true, "input": true, "label": true, "link": true, "main": true,
+	"marquee": true, "meta": true, "nav": true, "option": true,
+	"picture": true, "section": true, "source": true, "summary": true,
+	"track": true, "video": true,
+}
+
+// HTML returns fragment with only allowed elements and attributes, links
+// and images restricted to http, https and mailto URLs, and every element
+// closed
+func HTML(fragment string) string {
+	var (
+		b     strings.Builder
+		z     = html.NewTokenizer(strings.NewReader(fragment))
+		open  []string // allowed elements not closed yet
+		depth int      // nesting depth inside dropped elements
+	)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			for i := len(open) - 1; i >= 0; i-- {
+				b.WriteString("</" + open[i] + ">")
+			}
+			return b.String()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if dropped[t.Data] {
+				if tt == html.StartTagToken {
+					depth++
+				}
+				continue
+			}
+			attrs, ok := allowed[t.Data]
+			if depth > 0 || !ok {
+				continue
+			}
+			b.WriteString("<" + t.Data)
+			for _, a := range t.Attr {
+				if a.Namespace != "" || !contains(attrs, a.Key) {
+					continue
+				}
+				if (a.Key == "href" || a.Key == "src") && !safeURL(a.Val) {
+					continue
+				}
+				b.WriteString(" " + a.Key + `="` + html.EscapeString(a.Val) + `"`)
+			}
+			b.WriteString(">")
+			if !void[t.Data] && tt == html.StartTagToken {
+				open = append(open, t.Data)
+			}
+		case html.EndTagToken:
+			t := z.Token()
+			if dropped[t.Data] {
+				if depth > 0 {
+					depth--
+				}
+				continue
+			}
+			if depth > 0 {
+				continue
+			}
+			// Close the element and any left open inside it
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == t.Data {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		case html.TextToken:
+			if depth == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+		}
+	}
+}
+
+// Text returns the text of fragment without any markup
+func Text(fragment string) string {
+	var (
+		b     strings.Builder
+		z     = html.NewTokenizer(strings.NewReader(fragment))
+		depth int
+	)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.StartTagToken:
+			if name, _ := z.TagName(); dropped[string(name)] {
+				depth++
+			}
+			b.WriteString(" ")
+		case html.EndTagToken:
+			if name, _ := z.TagName(); dropped[string(name)] && depth > 0 {
+				depth--
+			}
+			b.WriteString(" ")
+		case html.TextToken:
+			if depth == 0 {
+				b.Write(z.Text())
+			}
+		}
+	}
+}
+
+// Plain returns s as plain text, for fields such as titles and notes
+// that are shown as text. HTML elements and comments are removed, along
+// with the content of scripts, styles and the like, and control
+// characters other than newlines and tabs are dropped. Unlike Text, it
+// keeps spacing, line breaks and character references as they are, so
+// cleaning text twice changes nothing, and text that only looks like a
+// tag, such as "<vector>", is kept.
+func Plain(s string) string {
+	var (
+		b     strings.Builder
+		z     = html.NewTokenizer(strings.NewReader(s))
+		depth int
+	)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.Map(func(r rune) rune {
+				if unicode.IsControl(r) && r != '\n' && r != '\t' {
+					return -1
+				}
+				return r
+			}, b.String())
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			raw := string(z.Raw())
+			name, _ := z.TagName()
+			_, kept := allowed[string(name)]
+			switch {
+			case dropped[string(name)]:
+				if tt == html.StartTagToken {
+					depth++
+				} else if tt == html.EndTagToken && depth > 0 {
+					depth--
+				}
+			case depth == 0 && !kept && !markup[string(name)]:
+				b.WriteString(raw)
+			}
+		case html.TextToken:
+			if depth == 0 {
+				b.Write(z.Raw())
+			}
+		}
+	}
+}
+
+// safeURL reports whether a link or image URL is absolute and uses a
+// scheme that cannot run script
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return true
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
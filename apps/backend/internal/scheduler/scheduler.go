@@ -0,0 +1,211 @@
+// Package scheduler runs periodic maintenance jobs on cron-style schedules
+// and keeps track of their most recent outcome.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobFunc performs one run of a job and returns a short human-readable
+// summary of what it did.
+type JobFunc func(ctx context.Context) (string, error)
+
+// Status describes a registered job and its last run.
+type Status struct {
+	Name         string    `json:"name"`
+	Schedule     string    `json:"schedule"`
+	Running      bool      `json:"running"`
+	NextRun      time.Time `json:"next_run"`
+	LastRun      time.Time `json:"last_run,omitempty"`
+	LastDuration string    `json:"last_duration,omitempty"`
+	LastResult   string    `json:"last_result,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	Runs         int       `json:"runs"`
+	Failures     int       `json:"failures"`
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       JobFunc
+	status   Status
+}
+
+// Scheduler triggers registered jobs when their schedule fires. A job never
+// overlaps with itself: if a run is still in progress the tick is skipped.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*job
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	onError func(job string, err error)
+}
+
+// New creates an empty scheduler.
+func New() *Scheduler {
+	return &Scheduler{jobs: make(map[string]*job)}
+}
+
+// OnError sets a callback invoked whenever a job run fails or panics
+func (s *Scheduler) OnError(fn func(job string, err error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onError = fn
+}
+
+// Add registers a job. An empty spec disables the job and is not an error.
+func (s *Scheduler) Add(name, spec string, fn JobFunc) error {
+	if spec == "" {
+		return nil
+	}
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("job %s already registered", name)
+	}
+	s.jobs[name] = &job{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		fn:       fn,
+		status:   Status{Name: name, Schedule: spec},
+	}
+	return nil
+}
+
+// Start launches the scheduling loop. It returns immediately; call Stop to
+// shut it down and wait for running jobs to finish.
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.cancel = cancel
+	now := time.Now()
+	for _, j := range s.jobs {
+		j.status.NextRun = j.schedule.Next(now)
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.loop(ctx)
+}
+
+// Stop cancels the scheduling loop and any running jobs, then waits for
+// them to return.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for _, j := range s.jobs {
+				if j.status.Running || j.status.NextRun.IsZero() || now.Before(j.status.NextRun) {
+					continue
+				}
+				j.status.Running = true
+				j.status.NextRun = j.schedule.Next(now)
+				s.wg.Add(1)
+				go s.run(ctx, j)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// RunNow triggers a job immediately, outside its schedule.
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("job %s not found", name)
+	}
+	if j.status.Running {
+		s.mu.Unlock()
+		return fmt.Errorf("job %s is already running", name)
+	}
+	j.status.Running = true
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go s.run(ctx, j)
+	return nil
+}
+
+func (s *Scheduler) run(ctx context.Context, j *job) {
+	defer s.wg.Done()
+
+	start := time.Now()
+	result, err := safeRun(ctx, j.fn)
+	duration := time.Since(start)
+
+	s.mu.Lock()
+	onError := s.onError
+	defer func() {
+		s.mu.Unlock()
+		if err != nil && onError != nil {
+			onError(j.name, err)
+		}
+	}()
+	j.status.Running = false
+	j.status.LastRun = start
+	j.status.LastDuration = duration.Round(time.Millisecond).String()
+	j.status.LastResult = result
+	j.status.LastError = ""
+	j.status.Runs++
+	if err != nil {
+		j.status.LastError = err.Error()
+		j.status.Failures++
+		log.Printf("Job %s failed after %v: %v", j.name, duration, err)
+		return
+	}
+	log.Printf("Job %s finished in %v: %s", j.name, duration, result)
+}
+
+func safeRun(ctx context.Context, fn JobFunc) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// Statuses returns a snapshot of all registered jobs, sorted by name.
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		result = append(result, j.status)
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].Name < result[b].Name })
+	return result
+}
@@ -0,0 +1,266 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxResults caps the hits requested from external engines when the
+// caller asks for all of them; it is Elasticsearch's default window
+const maxResults = 10000
+
+// elasticsearchMapping indexes tags and the domain as exact keywords for
+// filtering, and the text fields for full-text matching. Text arrives
+// normalized; the default analyzer splits CJK text into pairs of
+// characters like the embedded index does. It only applies to indexes
+// created with it, so older ones must be deleted and rebuilt.
+const elasticsearchMapping = `{
+  "settings": {
+    "analysis": {
+      "analyzer": {
+        "default": {"tokenizer": "standard", "filter": ["cjk_width", "lowercase", "cjk_bigram"]}
+      }
+    }
+  },
+  "mappings": {
+    "properties": {
+      "title":   {"type": "text"},
+      "url":     {"type": "keyword", "fields": {"text": {"type": "text"}}},
+      "domain":  {"type": "keyword", "fields": {"text": {"type": "text"}}},
+      "tags":    {"type": "keyword", "fields": {"text": {"type": "text"}}},
+      "notes":   {"type": "text"},
+      "content": {"type": "text"}
+    }
+  }
+}`
+
+// Elasticsearch indexes bookmarks in an Elasticsearch or OpenSearch
+// cluster over its REST API
+type Elasticsearch struct {
+	base     string
+	index    string
+	username string
+	password string
+	apiKey   string
+	client   *http.Client
+}
+
+type elasticsearchDoc struct {
+	Title   string   `json:"title"`
+	URL     string   `json:"url"`
+	Domain  string   `json:"domain"`
+	Tags    []string `json:"tags"`
+	Notes   string   `json:"notes,omitempty"`
+	Content string   `json:"content,omitempty"`
+}
+
+// NewElasticsearch connects to the cluster and creates the index with its
+// mapping if it does not exist yet
+func NewElasticsearch(ctx context.Context, cfg Config) (*Elasticsearch, error) {
+	base := strings.TrimSuffix(cfg.URL, "/")
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("elasticsearch: invalid URL %q", cfg.URL)
+	}
+	index := cfg.Index
+	if index == "" {
+		index = "bookmarks"
+	}
+	es := &Elasticsearch{
+		base:     base,
+		index:    index,
+		username: cfg.Username,
+		password: cfg.Password,
+		apiKey:   cfg.APIKey,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+
+	resp, err := es.do(ctx, http.MethodHead, "/"+index, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return es, nil
+	case http.StatusNotFound:
+		if err := es.expect(es.do(ctx, http.MethodPut, "/"+index, "application/json", strings.NewReader(elasticsearchMapping))); err != nil {
+			return nil, fmt.Errorf("elasticsearch: create index: %w", err)
+		}
+		return es, nil
+	default:
+		return nil, fmt.Errorf("elasticsearch: check index: unexpected status %s", resp.Status)
+	}
+}
+
+func (es *Elasticsearch) do(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, es.base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	switch {
+	case es.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+es.apiKey)
+	case es.username != "":
+		req.SetBasicAuth(es.username, es.password)
+	}
+	return es.client.Do(req)
+}
+
+// expect drains the response and turns error statuses into errors
+func (es *Elasticsearch) expect(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// bulk sends NDJSON actions to the _bulk API and reports item failures.
+// Writes wait for a refresh, so changes are searchable when it returns.
+func (es *Elasticsearch) bulk(ctx context.Context, body *bytes.Buffer) error {
+	resp, err := es.do(ctx, http.MethodPost, "/"+es.index+"/_bulk?refresh=wait_for", "application/x-ndjson", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return es.expect(resp, nil)
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string          `json:"_id"`
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("elasticsearch: decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+	for _, item := range result.Items {
+		for action, r := range item {
+			// Deleting a document that is not indexed is fine.
+			if r.Error != nil && !(action == "delete" && r.Status == http.StatusNotFound) {
+				return fmt.Errorf("elasticsearch: %s %s: %s", action, r.ID, r.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// Index adds or replaces documents in one bulk request
+func (es *Elasticsearch) Index(ctx context.Context, docs ...Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, d := range docs {
+		enc.Encode(map[string]interface{}{"index": map[string]string{"_id": d.ID}})
+		enc.Encode(elasticsearchDoc{
+			Title:   Normalize(d.Title),
+			URL:     d.URL,
+			Domain:  domain(d.URL),
+			Tags:    d.Tags,
+			Notes:   Normalize(d.Notes),
+			Content: Normalize(d.Content),
+		})
+	}
+	return es.bulk(ctx, &body)
+}
+
+// Delete removes documents in one bulk request
+func (es *Elasticsearch) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, id := range ids {
+		enc.Encode(map[string]interface{}{"delete": map[string]string{"_id": id}})
+	}
+	return es.bulk(ctx, &body)
+}
+
+// elasticsearchFields weights fields like the embedded index does
+var elasticsearchFields = []string{"title^3", "tags.text^2.5", "domain.text^2", "url.text^1.5", "notes^1.5", "content"}
+
+// Search runs multi_match queries requiring every word, tolerating typos
+// with AUTO fuzziness
+func (es *Elasticsearch) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	if limit <= 0 || limit > maxResults {
+		limit = maxResults
+	}
+	query = Normalize(query)
+	body, _ := json.Marshal(map[string]interface{}{
+		"size":    limit,
+		"_source": false,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				// Exact matches across fields, boosted over typo-tolerant
+				// matches within a field; cross_fields cannot be fuzzy.
+				"should": []interface{}{
+					map[string]interface{}{"multi_match": map[string]interface{}{
+						"query":    query,
+						"type":     "cross_fields",
+						"operator": "and",
+						"fields":   elasticsearchFields,
+						"boost":    2,
+					}},
+					map[string]interface{}{"multi_match": map[string]interface{}{
+						"query":         query,
+						"type":          "best_fields",
+						"operator":      "and",
+						"fields":        elasticsearchFields,
+						"fuzziness":     "AUTO",
+						"prefix_length": 1,
+					}},
+				},
+				"minimum_should_match": 1,
+			},
+		},
+	})
+	resp, err := es.do(ctx, http.MethodPost, "/"+es.index+"/_search", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("elasticsearch: search: %w", es.expect(resp, nil))
+	}
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID    string  `json:"_id"`
+				Score float64 `json:"_score"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("elasticsearch: decode search response: %w", err)
+	}
+	hits := make([]Hit, len(result.Hits.Hits))
+	for i, h := range result.Hits.Hits {
+		hits[i] = Hit{ID: h.ID, Score: h.Score}
+	}
+	return hits, nil
+}
@@ -0,0 +1,111 @@
+package search
+
+import "unicode/utf8"
+
+// Score factors for inexact matches, relative to an exact match
+const (
+	oneEditFactor  = 0.6
+	twoEditsFactor = 0.3
+)
+
+// maxEdits is the typo budget for a query word: none for short words,
+// where a single edit changes the meaning, then one, then two. These are
+// the thresholds Elasticsearch's AUTO fuzziness and Meilisearch use.
+func maxEdits(word string) int {
+	switch n := utf8.RuneCountInString(word); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// matchFactor reports how well term matches a query word: 1 for the same
+// word, prefixPenalty for a completion when prefix is set, less for terms
+// within the word's typo budget, and 0 for no match.
+func matchFactor(term, word string, prefix bool) float64 {
+	switch {
+	case term == word:
+		return 1
+	case prefix && len(term) > len(word) && term[:len(word)] == word:
+		return prefixPenalty
+	}
+	budget := maxEdits(word)
+	if budget == 0 {
+		return 0
+	}
+	switch editDistance(term, word, budget) {
+	case 1:
+		return oneEditFactor
+	case 2:
+		if budget >= 2 {
+			return twoEditsFactor
+		}
+	}
+	return 0
+}
+
+// Matches reports whether every word of query matches a word of text,
+// allowing typos, and treating the last query word as a prefix
+func Matches(text, query string) bool {
+	words := tokenize(query)
+	if len(words) == 0 {
+		return true
+	}
+	tokens := tokenize(text)
+	for i, word := range words {
+		found := false
+		for _, t := range tokens {
+			if matchFactor(t, word, i == len(words)-1) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance returns the optimal string alignment distance between a
+// and b, counting an adjacent transposition as one edit, or max+1 once it
+// is known to exceed max
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	if prev[len(rb)] > max {
+		return max + 1
+	}
+	return prev[len(rb)]
+}
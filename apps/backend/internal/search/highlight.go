@@ -0,0 +1,145 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Match markers wrapped around highlighted words. Highlighted text is
+// HTML-escaped, so clients can render it as HTML.
+const (
+	MarkStart = "<mark>"
+	MarkEnd   = "</mark>"
+)
+
+const (
+	// snippetRunes is the length of fragments cut from long fields
+	snippetRunes = 160
+	// snippetLead is how much context is kept before the first match
+	snippetLead = 40
+)
+
+// Highlights returns fragments of d's title, URL, notes and content with
+// the words matching query marked. Fields without a match are omitted.
+func Highlights(d Document, query string) map[string]string {
+	words := tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+	result := make(map[string]string)
+	for _, f := range []struct{ name, text string }{
+		{"title", d.Title},
+		{"url", d.URL},
+		{"notes", d.Notes},
+		{"content", d.Content},
+	} {
+		if fragment, ok := highlight(f.text, words); ok {
+			result[f.name] = fragment
+		}
+	}
+	return result
+}
+
+// span is a word's byte range in a text
+type span struct{ start, end int }
+
+// highlight marks the words of text that match words, trimming long text
+// to a snippet around the first match
+func highlight(text string, words []string) (string, bool) {
+	var matches []span
+	for _, t := range terms(text) {
+		if !matchesAny(t.text, words) {
+			continue
+		}
+		// The pairs of characters CJK text is split into overlap, so
+		// neighbouring matches are marked together
+		if n := len(matches); n > 0 && t.start <= matches[n-1].end {
+			matches[n-1].end = max(matches[n-1].end, t.end)
+			continue
+		}
+		matches = append(matches, span{t.start, t.end})
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	from, to := snippet(text, matches[0].start)
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range matches {
+		if m.start < from || m.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString(MarkStart)
+		b.WriteString(html.EscapeString(text[m.start:m.end]))
+		b.WriteString(MarkEnd)
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// matchesAny reports whether token matches one of words, the way the
+// embedded index matches them
+func matchesAny(token string, words []string) bool {
+	for i, w := range words {
+		if matchFactor(token, w, i == len(words)-1) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// wordSpans finds the runs of letters, digits and marks in text, which
+// terms splits into words
+func wordSpans(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		isWord := isWordRune(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+// snippet returns the byte range of a fragment of at most snippetRunes
+// runes that shows the match at offset with some context before it
+func snippet(text string, offset int) (int, int) {
+	if utf8.RuneCountInString(text) <= snippetRunes {
+		return 0, len(text)
+	}
+	from := offset
+	for n := 0; n < snippetLead && from > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	// Start at a word boundary rather than mid-word.
+	if from > 0 {
+		if i := strings.IndexByte(text[from:offset], ' '); i >= 0 {
+			from += i + 1
+		}
+	}
+	to := from
+	for n := 0; n < snippetRunes && to < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+	return from, to
+}
@@ -0,0 +1,179 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// meilisearchSettings ranks title matches above tags, the domain and the
+// rest, and allows typos from the same word lengths, mirroring the other
+// backends
+var meilisearchSettings = map[string]interface{}{
+	"searchableAttributes": []string{"title", "tags", "domain", "url", "notes", "content"},
+	"filterableAttributes": []string{"tags", "domain"},
+	"typoTolerance": map[string]interface{}{
+		"enabled":             true,
+		"minWordSizeForTypos": map[string]int{"oneTypo": 4, "twoTypos": 8},
+	},
+}
+
+// Meilisearch indexes bookmarks in a Meilisearch instance, which brings
+// typo tolerance and prefix search out of the box. Meilisearch applies
+// writes asynchronously, so a change becomes searchable shortly after the
+// call returns rather than immediately.
+type Meilisearch struct {
+	base   string
+	index  string
+	apiKey string
+	client *http.Client
+}
+
+type meilisearchDoc struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	URL     string   `json:"url"`
+	Domain  string   `json:"domain"`
+	Tags    []string `json:"tags"`
+	Notes   string   `json:"notes,omitempty"`
+	Content string   `json:"content,omitempty"`
+}
+
+// NewMeilisearch connects to the instance and creates and configures the
+// index if needed
+func NewMeilisearch(ctx context.Context, cfg Config) (*Meilisearch, error) {
+	base := strings.TrimSuffix(cfg.MeilisearchURL, "/")
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("meilisearch: invalid URL %q", cfg.MeilisearchURL)
+	}
+	index := cfg.MeilisearchIndex
+	if index == "" {
+		index = "bookmarks"
+	}
+	m := &Meilisearch{
+		base:   base,
+		index:  index,
+		apiKey: cfg.MeilisearchAPIKey,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if err := m.call(ctx, http.MethodGet, "/health", nil, nil); err != nil {
+		return nil, err
+	}
+	// Both are enqueued as tasks; creating an index that already exists
+	// fails its task without affecting the settings update.
+	if err := m.call(ctx, http.MethodPost, "/indexes", map[string]string{"uid": index, "primaryKey": "id"}, nil); err != nil {
+		return nil, fmt.Errorf("meilisearch: create index: %w", err)
+	}
+	if err := m.call(ctx, http.MethodPatch, "/indexes/"+url.PathEscape(index)+"/settings", meilisearchSettings, nil); err != nil {
+		return nil, fmt.Errorf("meilisearch: update settings: %w", err)
+	}
+	return m, nil
+}
+
+// call sends a JSON request and decodes the JSON response into out when
+// it is not nil
+func (m *Meilisearch) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.base+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &e) == nil && e.Message != "" {
+			return fmt.Errorf("meilisearch: %s: %s", e.Code, e.Message)
+		}
+		return fmt.Errorf("meilisearch: unexpected status %s", resp.Status)
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Index adds or replaces documents
+func (m *Meilisearch) Index(ctx context.Context, docs ...Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	batch := make([]meilisearchDoc, len(docs))
+	for i, d := range docs {
+		batch[i] = meilisearchDoc{
+			ID:      d.ID,
+			Title:   Normalize(d.Title),
+			URL:     d.URL,
+			Domain:  domain(d.URL),
+			Tags:    d.Tags,
+			Notes:   Normalize(d.Notes),
+			Content: Normalize(d.Content),
+		}
+	}
+	return m.call(ctx, http.MethodPost, "/indexes/"+url.PathEscape(m.index)+"/documents", batch, nil)
+}
+
+// Delete removes documents by ID
+func (m *Meilisearch) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return m.call(ctx, http.MethodPost, "/indexes/"+url.PathEscape(m.index)+"/documents/delete-batch", ids, nil)
+}
+
+// Search queries the index; Meilisearch matches all words, tolerating
+// typos and treating the last word as a prefix
+func (m *Meilisearch) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	if limit <= 0 || limit > maxResults {
+		limit = maxResults
+	}
+	var result struct {
+		Hits []struct {
+			ID    string  `json:"id"`
+			Score float64 `json:"_rankingScore"`
+		} `json:"hits"`
+	}
+	err := m.call(ctx, http.MethodPost, "/indexes/"+url.PathEscape(m.index)+"/search", map[string]interface{}{
+		"q":                    Normalize(query),
+		"limit":                limit,
+		"attributesToRetrieve": []string{"id"},
+		"showRankingScore":     true,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]Hit, len(result.Hits))
+	for i, h := range result.Hits {
+		hits[i] = Hit{ID: h.ID, Score: h.Score}
+	}
+	return hits, nil
+}
@@ -0,0 +1,189 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field weights: a word in the title says more about a bookmark than the
+// same word somewhere in the page text.
+const (
+	titleWeight   = 3.0
+	tagWeight     = 2.5
+	urlWeight     = 1.5
+	notesWeight   = 1.5
+	contentWeight = 1.0
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// prefixPenalty scales the score of terms matched as a prefix of the last
+// query word, so exact matches rank first while the user is still typing
+const prefixPenalty = 0.5
+
+// Memory is an embedded inverted index ranked with BM25. It lives in
+// process memory and is rebuilt from the store at startup, which keeps
+// single-node deployments free of an external search service.
+type Memory struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]float64 // term -> doc ID -> weighted frequency
+	docs     map[string]*memoryDoc
+	totalLen float64
+}
+
+type memoryDoc struct {
+	terms  map[string]float64
+	length float64
+}
+
+// NewMemory returns an empty index
+func NewMemory() *Memory {
+	return &Memory{
+		postings: make(map[string]map[string]float64),
+		docs:     make(map[string]*memoryDoc),
+	}
+}
+
+// analyze returns the weighted term frequencies of d
+func analyze(d Document) *memoryDoc {
+	doc := &memoryDoc{terms: make(map[string]float64)}
+	add := func(tokens []string, weight float64) {
+		for _, t := range tokens {
+			doc.terms[t] += weight
+			doc.length += weight
+		}
+	}
+	add(tokenize(d.Title), titleWeight)
+	add(tokenize(strings.Join(d.Tags, " ")), tagWeight)
+	add(urlTokens(d.URL), urlWeight)
+	add(tokenize(d.Notes), notesWeight)
+	add(tokenize(d.Content), contentWeight)
+	return doc
+}
+
+// Index adds or replaces documents
+func (m *Memory) Index(_ context.Context, docs ...Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range docs {
+		m.remove(d.ID)
+		doc := analyze(d)
+		for term, freq := range doc.terms {
+			p := m.postings[term]
+			if p == nil {
+				p = make(map[string]float64)
+				m.postings[term] = p
+			}
+			p[d.ID] = freq
+		}
+		m.docs[d.ID] = doc
+		m.totalLen += doc.length
+	}
+	return nil
+}
+
+// Delete removes documents by ID
+func (m *Memory) Delete(_ context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		m.remove(id)
+	}
+	return nil
+}
+
+func (m *Memory) remove(id string) {
+	doc, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(m.postings[term], id)
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+		}
+	}
+	m.totalLen -= doc.length
+	delete(m.docs, id)
+}
+
+// Search ranks the documents containing every query word. Words also
+// match terms within a small edit distance, so typos still find results,
+// and the last word matches as a prefix, for search-as-you-type.
+func (m *Memory) Search(_ context.Context, query string, limit int) ([]Hit, error) {
+	words := tokenize(query)
+	if len(words) == 0 {
+		return []Hit{}, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.docs) == 0 {
+		return []Hit{}, nil
+	}
+	avgLen := m.totalLen / float64(len(m.docs))
+
+	var scores map[string]float64
+	for i, word := range words {
+		terms := make(map[string]float64)
+		for term := range m.postings {
+			if factor := matchFactor(term, word, i == len(words)-1); factor > 0 {
+				terms[term] = factor
+			}
+		}
+
+		// A document matches the word through its best-scoring term.
+		wordScores := make(map[string]float64)
+		for term, factor := range terms {
+			p := m.postings[term]
+			idf := math.Log(1 + (float64(len(m.docs))-float64(len(p))+0.5)/(float64(len(p))+0.5))
+			for id, freq := range p {
+				doc := m.docs[id]
+				score := factor * idf * freq * (bm25K1 + 1) / (freq + bm25K1*(1-bm25B+bm25B*doc.length/avgLen))
+				if score > wordScores[id] {
+					wordScores[id] = score
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+		for id := range scores {
+			if s, ok := wordScores[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	return rank(scores, limit), nil
+}
+
+// rank sorts scored documents best first, breaking ties by ID for stable
+// results, and truncates them to limit
+func rank(scores map[string]float64, limit int) []Hit {
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
@@ -0,0 +1,113 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// FoldDiacritics makes search ignore accents, so "cafe" finds "café" and
+// "resume" finds "résumé". The server sets it from SEARCH_FOLD_DIACRITICS
+// at startup, before anything is indexed; external indexes must be rebuilt
+// after it changes.
+var FoldDiacritics = true
+
+// Normalize puts text in the form it is indexed and searched in: NFC,
+// full-width Latin letters and digits narrowed, case-folded (so "Straße"
+// and "STRASSE" are the same) and, with FoldDiacritics, without the
+// accents on Latin, Greek and Cyrillic letters.
+func Normalize(text string) string {
+	text = width.Fold.String(text)
+	if FoldDiacritics {
+		text = strings.Map(func(r rune) rune {
+			if isDiacritic(r) {
+				return -1
+			}
+			return r
+		}, norm.NFD.String(text))
+	}
+	return norm.NFC.String(cases.Fold().String(text))
+}
+
+// isDiacritic reports whether r is one of the combining accents that
+// NFD splits off European letters. Marks of other scripts, such as the
+// Japanese voicing marks, change the letter and are kept.
+func isDiacritic(r rune) bool {
+	return r >= 0x0300 && r <= 0x036F
+}
+
+// isCJK reports whether r is written without spaces between words, in
+// Chinese, Japanese or Korean text
+func isCJK(r rune) bool {
+	// The prolonged sound mark and the iteration mark belong to the
+	// Common script but sit inside Japanese words.
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r == 'ー' || r == '々'
+}
+
+// isWordRune reports whether r belongs to a word: letters, digits and the
+// marks combined with them
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// term is a normalized word of a text with its byte range there
+type term struct {
+	text       string
+	start, end int
+}
+
+// terms splits text into normalized words. Runs of CJK characters have
+// no spaces to split them, so they become overlapping pairs of
+// characters ("官方文档" is "官方", "方文" and "文档"), which lets any
+// word within them be found; a lone CJK character is a word by itself.
+func terms(text string) []term {
+	var result []term
+	add := func(start, end int) {
+		if t := Normalize(text[start:end]); t != "" {
+			result = append(result, term{t, start, end})
+		}
+	}
+	for _, w := range wordSpans(text) {
+		start := w.start
+		for start < w.end {
+			r, _ := utf8.DecodeRuneInString(text[start:])
+			end := start
+			for end < w.end {
+				r2, size := utf8.DecodeRuneInString(text[end:])
+				// Marks stay with the character before them
+				if isCJK(r2) != isCJK(r) && !unicode.IsMark(r2) {
+					break
+				}
+				end += size
+			}
+			if isCJK(r) {
+				addBigrams(text[start:end], start, add)
+			} else {
+				add(start, end)
+			}
+			start = end
+		}
+	}
+	return result
+}
+
+// addBigrams adds the overlapping character pairs of a run of CJK text
+// found at offset, or the run itself when it is a single character
+func addBigrams(run string, offset int, add func(start, end int)) {
+	var starts []int
+	for i := range run {
+		starts = append(starts, offset+i)
+	}
+	starts = append(starts, offset+len(run))
+	if len(starts) == 2 {
+		add(starts[0], starts[1])
+		return
+	}
+	for i := 0; i+2 < len(starts); i++ {
+		add(starts[i], starts[i+2])
+	}
+}
@@ -0,0 +1,133 @@
+// Package search indexes bookmarks for ranked full-text search, so search
+// quality does not depend on the store backend.
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// Document is the searchable form of a bookmark
+type Document struct {
+	ID      string
+	Title   string
+	URL     string
+	Tags    []string
+	Notes   string
+	Content string // extracted page text, when available
+}
+
+// FromBookmark builds the document for b
+func FromBookmark(b model.Bookmark) Document {
+	return Document{ID: b.ID, Title: b.Title, URL: b.URL, Tags: b.Tags, Notes: b.Notes}
+}
+
+// Hit is a matching document with its relevance score; higher is better
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Index maintains a search index over bookmarks
+type Index interface {
+	// Index adds or replaces documents
+	Index(ctx context.Context, docs ...Document) error
+	// Delete removes documents by ID; unknown IDs are ignored
+	Delete(ctx context.Context, ids ...string) error
+	// Search returns up to limit hits for query, best first; limit <= 0
+	// returns all of them
+	Search(ctx context.Context, query string, limit int) ([]Hit, error)
+}
+
+// Config selects and configures a search backend
+type Config struct {
+	Backend string // "" (substring filtering, no index), "embedded", "elasticsearch" or "meilisearch"
+
+	// FoldDiacritics sets the package's FoldDiacritics
+	FoldDiacritics bool
+
+	// Elasticsearch / OpenSearch
+	URL      string
+	Index    string
+	Username string
+	Password string
+	APIKey   string
+
+	// Meilisearch
+	MeilisearchURL    string
+	MeilisearchIndex  string
+	MeilisearchAPIKey string
+}
+
+// Archive returns the configuration of the index of archived page text,
+// which lives next to the bookmark index on the same backend
+func (c Config) Archive() Config {
+	if c.Index == "" {
+		c.Index = "bookmarks"
+	}
+	if c.MeilisearchIndex == "" {
+		c.MeilisearchIndex = "bookmarks"
+	}
+	c.Index += "-archive"
+	c.MeilisearchIndex += "-archive"
+	return c
+}
+
+// Open returns the index selected by cfg.Backend, or nil when search
+// should fall back to substring filtering
+func Open(ctx context.Context, cfg Config) (Index, error) {
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "embedded":
+		return NewMemory(), nil
+	case "elasticsearch", "opensearch":
+		return NewElasticsearch(ctx, cfg)
+	case "meilisearch":
+		return NewMeilisearch(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown search backend %q", cfg.Backend)
+	}
+}
+
+// domain returns the host of a URL without a leading "www."
+func domain(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// ignoredTokens are too common in URLs to say anything about a page
+var ignoredTokens = map[string]bool{"http": true, "https": true, "www": true, "com": true, "html": true}
+
+// tokenize splits text into normalized words, the terms it is indexed
+// and searched by
+func tokenize(text string) []string {
+	ts := terms(text)
+	tokens := make([]string, len(ts))
+	for i, t := range ts {
+		tokens[i] = t.text
+	}
+	return tokens
+}
+
+// urlTokens returns the words in a URL's host and path
+func urlTokens(raw string) []string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	var tokens []string
+	for _, t := range tokenize(u.Hostname() + " " + u.Path) {
+		if !ignoredTokens[t] {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,61 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Vocabulary for generated bookmarks, loosely modelled on a developer's
+// library so tag and domain distributions look realistic.
+var (
+	genDomains = []string{
+		"github.com", "news.ycombinator.com", "medium.com", "dev.to", "stackoverflow.com",
+		"go.dev", "developer.mozilla.org", "en.wikipedia.org", "youtube.com", "arxiv.org",
+		"blog.cloudflare.com", "martinfowler.com", "css-tricks.com", "lwn.net", "nytimes.com",
+		"substack.com", "reddit.com", "docs.python.org", "kubernetes.io", "react.dev",
+	}
+	genTags = []string{
+		"go", "rust", "javascript", "typescript", "python", "react", "css", "database",
+		"postgres", "kubernetes", "devops", "security", "design", "ai", "ml", "career",
+		"productivity", "tutorial", "reference", "video", "paper", "news", "read-later", "tools",
+	}
+	genWords = []string{
+		"understanding", "building", "scaling", "debugging", "introduction", "guide", "deep",
+		"dive", "into", "modern", "practical", "fast", "concurrency", "performance", "patterns",
+		"architecture", "testing", "observability", "caching", "queues", "APIs", "web", "systems",
+		"distributed", "lessons", "from", "production", "the", "hidden", "cost", "of", "simple",
+	}
+)
+
+// Generate returns n synthetic bookmarks with unique URLs. Domains and tags
+// follow a skewed distribution (a few are very common, most are rare), which
+// is closer to real libraries than a uniform pick. The same seed always
+// yields the same data.
+func Generate(n int, seed int64) []Fixture {
+	rng := rand.New(rand.NewSource(seed))
+	zipfDomain := rand.NewZipf(rng, 1.2, 1, uint64(len(genDomains)-1))
+	zipfTag := rand.NewZipf(rng, 1.1, 1, uint64(len(genTags)-1))
+
+	fixtures := make([]Fixture, 0, n)
+	for i := 0; i < n; i++ {
+		words := make([]string, 3+rng.Intn(6))
+		for j := range words {
+			words[j] = genWords[rng.Intn(len(genWords))]
+		}
+		title := strings.Join(words, " ")
+		title = strings.ToUpper(title[:1]) + title[1:]
+
+		var tags []string
+		for t := rng.Intn(5); t > 0; t-- {
+			tags = append(tags, genTags[zipfTag.Uint64()])
+		}
+
+		fixtures = append(fixtures, Fixture{
+			Title: title,
+			URL:   fmt.Sprintf("https://%s/%s-%d", genDomains[zipfDomain.Uint64()], strings.Join(words[:2], "-"), i),
+			Tags:  tags,
+		})
+	}
+	return fixtures
+}
@@ -0,0 +1,124 @@
+// Package seed loads fixture bookmarks into a store for demos and tests.
+package seed
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/sanitize"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+//go:embed sample.json
+var sampleJSON []byte
+
+// Fixture is a bookmark to be seeded. Notes, read status and highlights
+// are only kept when the store supports them.
+type Fixture struct {
+	Title      string   `json:"title"`
+	URL        string   `json:"url"`
+	Tags       []string `json:"tags,omitempty"`
+	Notes      string   `json:"notes,omitempty"`
+	Read       bool     `json:"read,omitempty"`
+	Highlights []string `json:"highlights,omitempty"`
+}
+
+// Load reads fixtures from a JSON file. The special name "sample" returns
+// the built-in demo bookmarks.
+func Load(path string) ([]Fixture, error) {
+	data := sampleJSON
+	if path != "sample" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var fixtures []Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("parse fixtures %s: %w", path, err)
+	}
+	for i, f := range fixtures {
+		if f.Title == "" || f.URL == "" {
+			return nil, fmt.Errorf("fixture %d in %s: title and url are required", i, path)
+		}
+	}
+	return fixtures, nil
+}
+
+// Apply creates the fixtures that are not in the store yet (matched by
+// URL), so seeding twice doesn't duplicate data. Fixtures often come from
+// files exported elsewhere, so markup is stripped from titles and notes. The bookmarks are created
+// in one transaction, so a store error leaves none of them behind, and
+// have no owner. It returns the number of bookmarks created.
+func Apply(ctx context.Context, s storage.Store, fixtures []Fixture) (int, error) {
+	var created []model.Bookmark
+	var sources []Fixture
+	err := s.InTx(ctx, func(tx storage.Store) error {
+		all, err := tx.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		existing := make(map[string]bool)
+		for _, b := range all {
+			existing[b.URL] = true
+		}
+		for _, f := range fixtures {
+			if existing[f.URL] {
+				continue
+			}
+			title := strings.TrimSpace(sanitize.Plain(f.Title))
+			if title == "" {
+				title = f.URL
+			}
+			b, err := tx.Create(ctx, "", title, f.URL, f.Tags)
+			if err != nil {
+				return err
+			}
+			existing[f.URL] = true
+			created = append(created, b)
+			sources = append(sources, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Notes, read state and highlights live outside the bookmark store,
+	// so they are added once the bookmarks are committed
+	for i, b := range created {
+		applyExtras(ctx, s, b.ID, sources[i])
+	}
+	return len(created), nil
+}
+
+// applyExtras stores what a fixture has beyond its title, URL and tags.
+// Highlights are plain text and become clips.
+func applyExtras(ctx context.Context, s storage.Store, id string, f Fixture) {
+	if notes, ok := s.(storage.NoteStore); ok && f.Notes != "" {
+		if _, err := notes.SetNotes(ctx, id, strings.TrimSpace(sanitize.Plain(f.Notes))); err != nil {
+			log.Printf("Failed to add notes to bookmark %s: %v", id, err)
+		}
+	}
+	if reading, ok := s.(storage.ReadingStore); ok && f.Read {
+		if _, err := reading.SetRead(ctx, id, true); err != nil {
+			log.Printf("Failed to mark bookmark %s read: %v", id, err)
+		}
+	}
+	if clips, ok := s.(storage.ClipStore); ok {
+		for _, h := range f.Highlights {
+			clip := model.Clip{BookmarkID: id, HTML: "<p>" + html.EscapeString(h) + "</p>", Text: h}
+			if _, err := clips.AddClip(ctx, clip); err != nil {
+				log.Printf("Failed to add highlight to bookmark %s: %v", id, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Package sentry is a small client for reporting errors and panics to a
+// Sentry-compatible endpoint (Sentry, GlitchTip, ...) using the envelope API.
+package sentry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client sends events in the background. A nil *Client is valid and
+// discards everything, so callers don't need to check whether reporting
+// is configured.
+type Client struct {
+	dsn         string
+	endpoint    string
+	authHeader  string
+	environment string
+	serverName  string
+	httpClient  *http.Client
+
+	mu     sync.RWMutex
+	closed bool
+	events chan *Event
+	wg     sync.WaitGroup
+}
+
+// Event is the subset of the Sentry event payload we send
+type Event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   float64                `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Platform    string                 `json:"platform"`
+	Logger      string                 `json:"logger,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+	Exception   []Exception            `json:"exception,omitempty"`
+	Request     *Request               `json:"request,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+}
+
+// Exception describes an error or panic value
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Stacktrace lists frames from outermost to innermost, as Sentry expects
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is a single stack frame
+type Frame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+}
+
+// Request carries the HTTP request context of an event
+type Request struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// New parses dsn (https://<key>@<host>/<project>) and starts the sender
+func New(dsn, environment string) (*Client, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry DSN: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid sentry DSN: missing public key")
+	}
+	idx := strings.LastIndex(u.Path, "/")
+	if idx < 0 || u.Path[idx+1:] == "" {
+		return nil, fmt.Errorf("invalid sentry DSN: missing project ID")
+	}
+	project := u.Path[idx+1:]
+	basePath := u.Path[:idx]
+
+	hostname, _ := os.Hostname()
+	c := &Client{
+		dsn:      dsn,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, basePath, project),
+		authHeader: fmt.Sprintf("Sentry sentry_version=7, sentry_key=%s, sentry_client=web-collector/1.0",
+			u.User.Username()),
+		environment: environment,
+		serverName:  hostname,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		events:      make(chan *Event, 100),
+	}
+	c.wg.Add(1)
+	go c.loop()
+	return c, nil
+}
+
+// CaptureError reports err with optional request context
+func (c *Client) CaptureError(err error, r *http.Request, extra map[string]interface{}) {
+	if c == nil || err == nil {
+		return
+	}
+	c.enqueue(&Event{
+		Level:     "error",
+		Exception: []Exception{{Type: fmt.Sprintf("%T", err), Value: err.Error(), Stacktrace: stacktrace(3)}},
+		Request:   requestInfo(r),
+		Extra:     extra,
+	})
+}
+
+// CapturePanic reports a recovered panic value. Call it from the deferred
+// function that recovered, so the stack still points at the panic site.
+func (c *Client) CapturePanic(value interface{}, r *http.Request) {
+	if c == nil {
+		return
+	}
+	c.enqueue(&Event{
+		Level:     "fatal",
+		Exception: []Exception{{Type: "panic", Value: fmt.Sprint(value), Stacktrace: stacktrace(4)}},
+		Request:   requestInfo(r),
+	})
+}
+
+func (c *Client) enqueue(e *Event) {
+	e.EventID = newEventID()
+	e.Timestamp = float64(time.Now().UnixNano()) / 1e9
+	e.Platform = "go"
+	e.ServerName = c.serverName
+	e.Environment = c.environment
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.events <- e:
+	default:
+		log.Printf("sentry: queue full, dropping event %s", e.EventID)
+	}
+}
+
+// Close flushes queued events, waiting at most timeout
+func (c *Client) Close(timeout time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.events)
+	}
+	c.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+func (c *Client) loop() {
+	defer c.wg.Done()
+	for e := range c.events {
+		if err := c.send(e); err != nil {
+			log.Printf("sentry: failed to send event %s: %v", e.EventID, err)
+		}
+	}
+}
+
+func (c *Client) send(e *Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	header, _ := json.Marshal(map[string]string{
+		"event_id": e.EventID,
+		"dsn":      c.dsn,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339),
+	})
+	itemHeader, _ := json.Marshal(map[string]interface{}{
+		"type":   "event",
+		"length": len(payload),
+	})
+
+	var body bytes.Buffer
+	body.Write(header)
+	body.WriteByte('\n')
+	body.Write(itemHeader)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", c.authHeader)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// requestInfo extracts request context, leaving out credentials
+func requestInfo(r *http.Request) *Request {
+	if r == nil {
+		return nil
+	}
+	headers := make(map[string]string)
+	for name, values := range r.Header {
+		switch strings.ToLower(name) {
+		case "authorization", "cookie", "x-api-key":
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return &Request{
+		URL:         scheme + "://" + r.Host + r.URL.Path,
+		Method:      r.Method,
+		QueryString: r.URL.RawQuery,
+		Headers:     headers,
+	}
+}
+
+func stacktrace(skip int) *Stacktrace {
+	pcs := make([]uintptr, 50)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var result []Frame
+	for {
+		f, more := frames.Next()
+		result = append(result, Frame{Function: f.Function, Filename: f.File, Lineno: f.Line})
+		if !more {
+			break
+		}
+	}
+	// Sentry wants the innermost frame last.
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return &Stacktrace{Frames: result}
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// RequireBookmarkAccess lets a request on the bookmark named by the :id
+// route parameter through only when the caller has at least access to it,
+// as storage.CheckBookmarkAccess decides. Bookmarks the caller cannot see
+// answer 404 like missing ones; a missing bookmark is left to the handler.
+func RequireBookmarkAccess(access string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b, err := store.GetByID(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, storage.ErrBookmarkNotFound) {
+			c.Next()
+			return
+		}
+		if err == nil {
+			err = storage.CheckBookmarkAccess(c.Request.Context(), collections, currentActor(c), b, access)
+		}
+		if err != nil {
+			bookmarkError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// visibleBookmarks keeps the bookmarks the caller can view
+func visibleBookmarks(c *gin.Context, bookmarks []model.Bookmark) []model.Bookmark {
+	return storage.VisibleBookmarks(c.Request.Context(), collections, currentActor(c), bookmarks)
+}
+
+// canViewBookmark reports whether the caller can view b
+func canViewBookmark(c *gin.Context, b model.Bookmark) bool {
+	return storage.CheckBookmarkAccess(c.Request.Context(), collections, currentActor(c), b, model.AccessView) == nil
+}
+
+// visibleResults keeps the search results the caller can view
+func visibleResults(c *gin.Context, results []searchResult) []searchResult {
+	bookmarks := make([]model.Bookmark, len(results))
+	for i, r := range results {
+		bookmarks[i] = r.Bookmark
+	}
+	visible := make(map[string]bool, len(results))
+	for _, b := range visibleBookmarks(c, bookmarks) {
+		visible[b.ID] = true
+	}
+	kept := results[:0]
+	for _, r := range results {
+		if visible[r.ID] {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// accessCallers are the callers every route is tried as: anonymous, an
+// account the shared collection is shared with to view, one it is shared
+// with to edit, and the owner of both bookmarks. The owner goes last, so
+// deleting comes after everyone else has tried.
+var accessCallers = []string{"anonymous", "viewer", "editor", "owner"}
+
+const accessPassword = "secretpassword1"
+
+// accessFixture is a router over a fresh store holding a bookmark in a
+// collection shared with the viewer and the editor, and one shared with
+// no one. Tags mark them in responses that do not carry IDs.
+type accessFixture struct {
+	router   *gin.Engine
+	accounts map[string]model.Account
+	// shared and unshared are the two bookmarks, both owned by the owner
+	shared, unshared model.Bookmark
+}
+
+func newAccessFixture(t *testing.T) *accessFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("BLOB_DIR", t.TempDir())
+	f := &accessFixture{router: SetupRouter(LoadConfig()), accounts: make(map[string]model.Account)}
+
+	ctx := context.Background()
+	for _, caller := range accessCallers[1:] {
+		account, err := CreateAccount(ctx, accounts, caller+"@example.com", accessPassword, model.RoleUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.accounts[caller] = account
+	}
+	owner := f.accounts["owner"].ID
+	var err error
+	if f.shared, err = store.Create(ctx, owner, "Team plans", "https://example.com/team", []string{"teamwork"}); err != nil {
+		t.Fatal(err)
+	}
+	if f.unshared, err = store.Create(ctx, owner, "Private notes", "https://example.com/private", []string{"diary"}); err != nil {
+		t.Fatal(err)
+	}
+	col, err := collections.CreateCollection(ctx, "Team", "", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := collections.AddToCollection(ctx, col.ID, []string{f.shared.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := collections.ShareCollection(ctx, col.ID, f.accounts["viewer"].ID, model.AccessView); err != nil {
+		t.Fatal(err)
+	}
+	if err := collections.ShareCollection(ctx, col.ID, f.accounts["editor"].ID, model.AccessEdit); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// reset puts both bookmarks back as they were created, out of the trash
+// if need be and each with a due reminder, so every caller finds the
+// same state
+func (f *accessFixture) reset(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	for _, b := range []model.Bookmark{f.shared, f.unshared} {
+		if _, err := store.GetByID(ctx, b.ID); errors.Is(err, storage.ErrBookmarkNotFound) {
+			if _, err := trash.Restore(ctx, b.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := store.Update(ctx, b.ID, b.Title, b.URL, b.Tags); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := reminders.SetReminder(ctx, model.Reminder{BookmarkID: b.ID, RemindAt: time.Now().Add(-time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// do sends a request as caller
+func (f *accessFixture) do(caller, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if caller != "anonymous" {
+		req.SetBasicAuth(f.accounts[caller].Email, accessPassword)
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+// TestAccess tries routes as each caller. SetupRouter can only run once
+// per process, so the checks share one fixture.
+func TestAccess(t *testing.T) {
+	f := newAccessFixture(t)
+	t.Run("bookmark routes", func(t *testing.T) { bookmarkRouteAccess(t, f) })
+	t.Run("lists", func(t *testing.T) { listAccess(t, f) })
+	t.Run("tag routes", func(t *testing.T) { tagRouteAccess(t, f) })
+	t.Run("exports", func(t *testing.T) { exportAccess(t, f) })
+	t.Run("hook routes", func(t *testing.T) { hookRouteAccess(t, f) })
+}
+
+// bookmarkRouteAccess checks that single-bookmark routes need view
+// access to read, edit access to change and ownership to delete
+func bookmarkRouteAccess(t *testing.T, f *accessFixture) {
+	shared, unshared := "/api/v1/bookmarks/"+f.shared.ID, "/api/v1/bookmarks/"+f.unshared.ID
+	reminder := `{"remind_at":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`
+
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+		// want is the status for each caller, in accessCallers order
+		want [4]int
+	}{
+		{"get shared", http.MethodGet, shared, "", [4]int{404, 200, 200, 200}},
+		{"get unshared", http.MethodGet, unshared, "", [4]int{404, 404, 404, 200}},
+		{"update shared", http.MethodPut, shared, `{"title":"Renamed"}`, [4]int{404, 403, 200, 200}},
+		{"update unshared", http.MethodPut, unshared, `{"title":"Renamed"}`, [4]int{404, 404, 404, 200}},
+		{"mark shared read", http.MethodPut, shared + "/read", `{"read":true}`, [4]int{404, 403, 200, 200}},
+		{"set shared notes", http.MethodPut, shared + "/notes", `{"notes":"Agenda"}`, [4]int{404, 403, 200, 200}},
+		{"set shared reminder", http.MethodPut, shared + "/reminder", reminder, [4]int{404, 403, 200, 200}},
+		{"set unshared reminder", http.MethodPut, unshared + "/reminder", reminder, [4]int{404, 404, 404, 200}},
+		{"cancel shared reminder", http.MethodDelete, shared + "/reminder", "", [4]int{404, 403, 200, 200}},
+		{"cancel unshared reminder", http.MethodDelete, unshared + "/reminder", "", [4]int{404, 404, 404, 200}},
+		{"delete unshared", http.MethodDelete, unshared, "", [4]int{404, 404, 404, 200}},
+		{"delete shared", http.MethodDelete, shared, "", [4]int{404, 403, 403, 200}},
+	}
+	for _, tt := range tests {
+		for i, caller := range accessCallers {
+			t.Run(tt.name+"/"+caller, func(t *testing.T) {
+				f.reset(t)
+				w := f.do(caller, tt.method, tt.path, tt.body)
+				if w.Code != tt.want[i] {
+					t.Errorf("status = %d, want %d: %s", w.Code, tt.want[i], w.Body)
+				}
+			})
+		}
+	}
+}
+
+// listAccess checks that routes listing bookmarks, or terms taken
+// from them, only show each caller the bookmarks it can view: signed-in
+// callers see the shared bookmark, and only the owner the unshared one
+func listAccess(t *testing.T, f *accessFixture) {
+	f.reset(t)
+
+	tests := []struct {
+		name string
+		path string
+		// want is the status for each caller, in accessCallers order
+		want [4]int
+	}{
+		{"bookmarks", "/api/v1/bookmarks", [4]int{200, 200, 200, 200}},
+		{"bookmarks by domain", "/api/v1/bookmarks/by-domain", [4]int{200, 200, 200, 200}},
+		{"tags", "/api/v1/tags", [4]int{401, 200, 200, 200}},
+		{"tag tree", "/api/v1/tags/tree", [4]int{401, 200, 200, 200}},
+		{"suggest", "/api/v1/suggest", [4]int{401, 200, 200, 200}},
+		{"reminders", "/api/v1/reminders", [4]int{401, 200, 200, 200}},
+		{"hook samples", "/api/v1/hooks/samples", [4]int{401, 200, 200, 200}},
+	}
+	for _, tt := range tests {
+		for i, caller := range accessCallers {
+			t.Run(tt.name+"/"+caller, func(t *testing.T) {
+				w := f.do(caller, http.MethodGet, tt.path, "")
+				if w.Code != tt.want[i] {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tt.want[i], w.Body)
+				}
+				if w.Code != http.StatusOK {
+					return
+				}
+				body := w.Body.String()
+				if got, want := strings.Contains(body, "teamwork"), caller != "anonymous"; got != want {
+					t.Errorf("shows shared bookmark = %v, want %v", got, want)
+				}
+				if got, want := strings.Contains(body, "diary"), caller == "owner"; got != want {
+					t.Errorf("shows unshared bookmark = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+// tagRouteAccess checks that renaming a tag only changes the
+// bookmarks the caller can edit
+func tagRouteAccess(t *testing.T, f *accessFixture) {
+	ctx := context.Background()
+
+	tests := []struct {
+		caller string
+		tag    string
+		want   int
+		// renamed is whether the bookmark carrying tag was renamed
+		renamed bool
+	}{
+		{"anonymous", "teamwork", 401, false},
+		{"viewer", "teamwork", 404, false},
+		{"viewer", "diary", 404, false},
+		{"editor", "teamwork", 200, true},
+		{"editor", "diary", 404, false},
+		{"owner", "teamwork", 200, true},
+		{"owner", "diary", 200, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caller+"/"+tt.tag, func(t *testing.T) {
+			f.reset(t)
+			w := f.do(tt.caller, http.MethodPut, "/api/v1/tags/"+tt.tag, `{"name":"renamed"}`)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			id := f.shared.ID
+			if tt.tag == "diary" {
+				id = f.unshared.ID
+			}
+			b, err := store.GetByID(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hasTag(b.Tags, "renamed"); got != tt.renamed {
+				t.Errorf("renamed = %v, want %v (tags %v)", got, tt.renamed, b.Tags)
+			}
+		})
+	}
+}
+
+// exportAccess checks that an export holds the bookmarks the account
+// that started it can view, and that only that account can see it
+func exportAccess(t *testing.T, f *accessFixture) {
+	f.reset(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	exports.start(ctx, &wg)
+	defer wg.Wait()
+	defer cancel()
+
+	if w := f.do("anonymous", http.MethodPost, "/api/v1/exports", `{"format":"json"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous export status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	jobs := make(map[string]ExportJob)
+	for _, caller := range accessCallers[1:] {
+		w := f.do(caller, http.MethodPost, "/api/v1/exports", `{"format":"json"}`)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("%s export status = %d, want %d: %s", caller, w.Code, http.StatusAccepted, w.Body)
+		}
+		var resp struct{ Data ExportJob }
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		jobs[caller] = f.awaitExport(t, caller, resp.Data.ID)
+	}
+
+	for _, starter := range accessCallers[1:] {
+		job := jobs[starter]
+		for _, caller := range accessCallers {
+			t.Run(starter+"/"+caller, func(t *testing.T) {
+				want := http.StatusNotFound
+				switch caller {
+				case "anonymous":
+					want = http.StatusUnauthorized
+				case starter:
+					want = http.StatusOK
+				}
+				if w := f.do(caller, http.MethodGet, "/api/v1/exports/"+job.ID, ""); w.Code != want {
+					t.Errorf("status = %d, want %d", w.Code, want)
+				}
+				w := f.do(caller, http.MethodGet, job.DownloadURL, "")
+				if w.Code != want {
+					t.Fatalf("download status = %d, want %d", w.Code, want)
+				}
+				if w.Code != http.StatusOK {
+					return
+				}
+				if body := w.Body.String(); !strings.Contains(body, "teamwork") || strings.Contains(body, "diary") != (caller == "owner") {
+					t.Errorf("export of %s holds the wrong bookmarks: %s", caller, body)
+				}
+			})
+		}
+	}
+}
+
+// awaitExport polls an export as caller until it is done
+func (f *accessFixture) awaitExport(t *testing.T, caller, id string) ExportJob {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var resp struct{ Data ExportJob }
+		w := f.do(caller, http.MethodGet, "/api/v1/exports/"+id, "")
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		switch resp.Data.Status {
+		case exportDone:
+			return resp.Data
+		case exportFailed:
+			t.Fatalf("export failed: %s", resp.Data.Error)
+		}
+	}
+	t.Fatalf("export %s did not finish", id)
+	return ExportJob{}
+}
+
+// hookRouteAccess checks that an account's hooks are hidden from
+// other accounts
+func hookRouteAccess(t *testing.T, f *accessFixture) {
+	hook, err := hookStore.AddHook(context.Background(), model.Hook{
+		AccountID: f.accounts["owner"].ID,
+		Event:     model.HookBookmarkCreated,
+		TargetURL: "https://hooks.example.com/owner",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		method, path string
+		// want is the status for each caller, in accessCallers order
+		want [4]int
+		// lists is whether a 200 lists the caller's hooks
+		lists bool
+	}{
+		{"list", http.MethodGet, "/api/v1/hooks", [4]int{401, 200, 200, 200}, true},
+		{"deliveries", http.MethodGet, "/api/v1/hooks/" + hook.ID + "/deliveries", [4]int{401, 404, 404, 200}, false},
+		{"unsubscribe", http.MethodDelete, "/api/v1/hooks/" + hook.ID, [4]int{401, 404, 404, 200}, false},
+	}
+	for _, tt := range tests {
+		for i, caller := range accessCallers {
+			t.Run(tt.name+"/"+caller, func(t *testing.T) {
+				w := f.do(caller, tt.method, tt.path, "")
+				if w.Code != tt.want[i] {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tt.want[i], w.Body)
+				}
+				if tt.lists && w.Code == http.StatusOK {
+					if got, want := strings.Contains(w.Body.String(), hook.TargetURL), caller == "owner"; got != want {
+						t.Errorf("shows owner's hook = %v, want %v", got, want)
+					}
+				}
+			})
+		}
+	}
+}
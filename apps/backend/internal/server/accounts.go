@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength is the shortest password accepted for new accounts
+const minPasswordLength = 12
+
+// Global account store; nil when the storage driver has no account support
+var accounts storage.AccountStore
+
+// OpenAccounts returns the account store backing s, if it has one
+func OpenAccounts(s storage.Store) (storage.AccountStore, bool) {
+	a, ok := s.(storage.AccountStore)
+	return a, ok
+}
+
+// CreateAdmin validates the credentials and stores a new admin account
+func CreateAdmin(ctx context.Context, a storage.AccountStore, email, password string) (model.Account, error) {
+	return CreateAccount(ctx, a, email, password, model.RoleAdmin)
+}
+
+// CreateAccount validates the credentials and stores a new account with
+// the given role
+func CreateAccount(ctx context.Context, a storage.AccountStore, email, password, role string) (model.Account, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || addr.Address != strings.TrimSpace(email) {
+		return model.Account{}, fmt.Errorf("invalid email address %q", email)
+	}
+	if len(password) < minPasswordLength {
+		return model.Account{}, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return model.Account{}, err
+	}
+	return a.CreateAccount(ctx, addr.Address, string(hash), role)
+}
+
+// bootstrapAdmin creates the first admin from ADMIN_EMAIL/ADMIN_PASSWORD
+// on a fresh deployment. It does nothing once any admin exists, so the
+// variables can safely stay set across restarts.
+func bootstrapAdmin(ctx context.Context, cfg *Config) error {
+	if cfg.AdminEmail == "" && cfg.AdminPassword == "" {
+		return nil
+	}
+	if accounts == nil {
+		return fmt.Errorf("the %q storage driver does not support accounts", cfg.Database.Driver)
+	}
+	if accounts.CountAccounts(ctx, model.RoleAdmin) > 0 {
+		return nil
+	}
+	account, err := CreateAdmin(ctx, accounts, cfg.AdminEmail, cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+	log.Printf("Created initial admin account %s", account.Email)
+	return nil
+}
+
+// authenticateAccount checks email/password credentials against all
+// accounts
+func authenticateAccount(ctx context.Context, email, password string) (model.Account, bool) {
+	if accounts == nil {
+		return model.Account{}, false
+	}
+	account, found := accounts.GetAccountByEmail(ctx, email)
+	if !found {
+		// Hash anyway so response timing doesn't reveal which emails exist.
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return model.Account{}, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)) != nil {
+		return model.Account{}, false
+	}
+	return account, true
+}
+
+// authenticateAdmin checks email/password credentials against admin accounts
+func authenticateAdmin(ctx context.Context, email, password string) bool {
+	account, ok := authenticateAccount(ctx, email, password)
+	return ok && account.Role == model.RoleAdmin
+}
+
+// accountKey is the gin context key holding the signed-in account
+const accountKey = "account"
+
+// credentialsKey is the gin context key holding the checked HTTP Basic
+// credentials of a request
+const credentialsKey = "credentials"
+
+// checkedCredentials is the outcome of checking a request's credentials
+type checkedCredentials struct {
+	account model.Account
+	valid   bool
+}
+
+// basicAccount checks the request's HTTP Basic credentials, once per
+// request however many middlewares ask. present is false without
+// credentials; valid is false when they are wrong.
+func basicAccount(c *gin.Context) (account model.Account, present, valid bool) {
+	email, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return model.Account{}, false, false
+	}
+	if value, ok := c.Get(credentialsKey); ok {
+		checked := value.(checkedCredentials)
+		return checked.account, true, checked.valid
+	}
+	account, valid = authenticateAccount(c.Request.Context(), email, password)
+	c.Set(credentialsKey, checkedCredentials{account: account, valid: valid})
+	return account, true, valid
+}
+
+// Authenticate signs requests in with HTTP Basic account credentials.
+// Requests without credentials continue anonymously; wrong credentials
+// are rejected rather than silently downgraded.
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account, present, valid := basicAccount(c)
+		if !present {
+			c.Next()
+			return
+		}
+		if !valid {
+			respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Unauthorized")
+			return
+		}
+		c.Set(accountKey, account)
+		c.Next()
+	}
+}
+
+// RequireSignIn rejects anonymous requests; it runs after Authenticate
+func RequireSignIn() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(accountKey); !ok {
+			c.Header("WWW-Authenticate", `Basic realm="web-collector"`)
+			respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Sign in required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentActor returns who the request acts for
+func currentActor(c *gin.Context) storage.Actor {
+	value, _ := c.Get(accountKey)
+	account, ok := value.(model.Account)
+	if !ok {
+		return storage.Actor{}
+	}
+	return storage.Actor{AccountID: account.ID, Admin: account.Role == model.RoleAdmin}
+}
+
+// accountActor returns the actor for work done on behalf of an account
+// outside a request, such as delivering its hooks
+func accountActor(ctx context.Context, accountID string) storage.Actor {
+	actor := storage.Actor{AccountID: accountID}
+	if accounts != nil && accountID != "" {
+		if account, found := accounts.GetAccount(ctx, accountID); found {
+			actor.Admin = account.Role == model.RoleAdmin
+		}
+	}
+	return actor
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("web-collector"), bcrypt.DefaultCost)
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global activity store; nil when the storage driver keeps no feed
+var activityLog storage.ActivityStore
+
+const (
+	// defaultActivityLimit and maxActivityLimit bound a page of the feed
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+// RequireActivity hides the activity feed when the store keeps none
+func RequireActivity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if activityLog == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Activity feeds are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// recordActivity adds an entry by the request's actor to the feed. The
+// change it describes has already happened, so failures are only logged.
+func recordActivity(c *gin.Context, a model.Activity) {
+	recordActivityAs(c.Request.Context(), currentActor(c).AccountID, a)
+}
+
+// recordActivityAs adds an entry by accountID, for changes made outside a
+// signed-in request
+func recordActivityAs(ctx context.Context, accountID string, a model.Activity) {
+	if activityLog == nil {
+		return
+	}
+	a.AccountID = accountID
+	if _, err := activityLog.RecordActivity(ctx, a); err != nil {
+		log.Printf("Failed to record %s activity: %v", a.Kind, err)
+	}
+}
+
+// handleGetActivity returns the signed-in account's activity feed, newest
+// first: its own entries and, unless ?scope=own, those of everyone else
+// on bookmarks and on collections the account can see. Pages are
+// requested with ?before=<created_at of the last entry>&limit=.
+func handleGetActivity(c *gin.Context) {
+	before := time.Now()
+	if raw := c.Query("before"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "before must be an RFC 3339 timestamp")
+			return
+		}
+		before = t
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultActivityLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultActivityLimit
+	}
+	limit = min(limit, maxActivityLimit)
+
+	actor := currentActor(c)
+	ownOnly := c.Query("scope") == "own"
+	var visible map[string]bool
+	if collections != nil && !ownOnly {
+		visible = make(map[string]bool)
+		for _, col := range storage.Restrict(collections, actor).ListCollections(c.Request.Context()) {
+			visible[col.ID] = true
+		}
+	}
+
+	// Entries the account cannot see are skipped, so keep reading pages
+	// until this one is full or the feed runs out.
+	feed := []model.Activity{}
+	for len(feed) < limit {
+		page, err := activityLog.ListActivity(c.Request.Context(), before, limit)
+		if err != nil {
+			log.Printf("Activity feed failed: %v", err)
+			respondError(c, http.StatusInternalServerError, apierr.Internal, "Activity feed failed")
+			return
+		}
+		for _, a := range page {
+			if len(feed) < limit && activityVisible(a, actor.AccountID, ownOnly, visible) {
+				feed = append(feed, a)
+			}
+		}
+		if len(page) < limit {
+			break
+		}
+		before = page[len(page)-1].CreatedAt
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    feed,
+	})
+}
+
+// activityVisible reports whether accountID sees a in its feed. Bookmarks
+// are visible instance-wide; collection entries need access to the
+// collection.
+func activityVisible(a model.Activity, accountID string, ownOnly bool, collections map[string]bool) bool {
+	if a.AccountID == accountID {
+		return true
+	}
+	if ownOnly {
+		return false
+	}
+	if a.CollectionID != "" {
+		return collections[a.CollectionID]
+	}
+	return true
+}
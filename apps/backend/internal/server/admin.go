@@ -0,0 +1,43 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// AdminAuth middleware guards operational endpoints. Callers authenticate
+// with the static ADMIN_TOKEN as a bearer token, or with an admin account's
+// email and password via HTTP Basic auth. With neither configured the
+// admin API is disabled entirely.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if email, password, ok := c.Request.BasicAuth(); ok {
+			if !authenticateAdmin(c.Request.Context(), email, password) {
+				respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Unauthorized")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if token == "" && (accounts == nil || accounts.CountAccounts(c.Request.Context(), model.RoleAdmin) == 0) {
+			respondError(c, http.StatusForbidden, apierr.FeatureDisabled, "Admin API is disabled")
+			return
+		}
+
+		// Without ADMIN_TOKEN only admin accounts can sign in; an empty
+		// token must never match an empty header.
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Unauthorized")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// TestAdminAuthAfterBootstrap checks that once an admin account exists
+// without ADMIN_TOKEN, admin routes still require credentials
+func TestAdminAuthAfterBootstrap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	saved := accounts
+	defer func() { accounts = saved }()
+	accounts = storage.NewMemoryStore()
+	cfg := &Config{AdminEmail: "admin@example.com", AdminPassword: "secretpassword1"}
+	if err := bootstrapAdmin(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/api/v1/admin/stats", AdminAuth(""), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		header func(*http.Request)
+		want   int
+	}{
+		{"no credentials", func(*http.Request) {}, http.StatusUnauthorized},
+		{"empty bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer ") }, http.StatusUnauthorized},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth(cfg.AdminEmail, "wrongpassword1") }, http.StatusUnauthorized},
+		{"admin account", func(r *http.Request) { r.SetBasicAuth(cfg.AdminEmail, cfg.AdminPassword) }, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats", nil)
+			tt.header(req)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
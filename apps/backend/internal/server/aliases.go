@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global alias store; nil when the storage driver cannot keep URL aliases
+var aliases storage.AliasStore
+
+// RequireAliases hides the alias routes when the store cannot keep URL
+// aliases
+func RequireAliases() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if aliases == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "URL aliases are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleLookupBookmark returns the bookmark saved under ?url=, as its URL
+// or as one of its aliases. The URL is normalized the way saving it would
+// be; bookmarks saved before the URL rules changed are found as given.
+func handleLookupBookmark(c *gin.Context) {
+	url := strings.TrimSpace(c.Query("url"))
+	if url == "" {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "url is required")
+		return
+	}
+	bookmark, err := aliases.FindByURL(c.Request.Context(), normalizeURL(c.Request.Context(), url))
+	if errors.Is(err, storage.ErrBookmarkNotFound) {
+		bookmark, err = aliases.FindByURL(c.Request.Context(), url)
+	}
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	if !canViewBookmark(c, bookmark) {
+		respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
+
+// handleAddAlias attaches an alternate URL to a bookmark
+func handleAddAlias(c *gin.Context) {
+	var req model.AliasRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	bookmark, err := aliases.AddAlias(c.Request.Context(), c.Param("id"), normalizeURL(c.Request.Context(), req.URL))
+	if err != nil {
+		aliasError(c, err)
+		return
+	}
+	aliasChanged(bookmark.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
+
+// handleRemoveAlias detaches the alias given as ?url= from a bookmark
+func handleRemoveAlias(c *gin.Context) {
+	bookmark, err := aliases.RemoveAlias(c.Request.Context(), c.Param("id"), strings.TrimSpace(c.Query("url")))
+	if err != nil {
+		aliasError(c, err)
+		return
+	}
+	aliasChanged(bookmark.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
+
+func aliasChanged(id string) {
+	publishBookmarkEvent("updated", id)
+}
+
+func aliasError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrBookmarkNotFound):
+		respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found")
+	case errors.Is(err, storage.ErrAliasNotFound):
+		respondError(c, http.StatusNotFound, apierr.AliasNotFound, "Alias not found")
+	case errors.Is(err, storage.ErrAliasTaken):
+		respondError(c, http.StatusConflict, apierr.AliasTaken, err.Error())
+	default:
+		log.Printf("Alias operation failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Alias operation failed")
+	}
+}
+
+// aliasedBookmark returns the existing bookmark that has url as an alias,
+// which saving url again should resolve to instead of a duplicate. A
+// bookmark actor cannot view is not returned, so saving the URL creates
+// the actor's own bookmark instead
+func aliasedBookmark(ctx context.Context, actor storage.Actor, url string) (model.Bookmark, bool, error) {
+	if aliases == nil {
+		return model.Bookmark{}, false, nil
+	}
+	b, err := aliases.FindByURL(ctx, url)
+	if errors.Is(err, storage.ErrBookmarkNotFound) || (err == nil && b.URL == url) {
+		return model.Bookmark{}, false, nil
+	}
+	if err != nil {
+		return model.Bookmark{}, false, err
+	}
+	if storage.CheckBookmarkAccess(ctx, collections, actor, b, model.AccessView) != nil {
+		return model.Bookmark{}, false, nil
+	}
+	return b, true, nil
+}
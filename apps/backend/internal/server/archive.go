@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global archive store; nil when the storage driver cannot archive
+// bookmarks
+var archiveStore storage.ArchiveStore
+
+// RequireArchive hides the archive routes when the store cannot archive
+// bookmarks
+func RequireArchive() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if archiveStore == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Archiving is not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleArchiveBookmark archives a bookmark or brings it back from
+// {"archived": bool}, or toggles it when the body is empty
+func handleArchiveBookmark(c *gin.Context) {
+	id := c.Param("id")
+	var req model.ArchiveRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	var bookmark model.Bookmark
+	err := writeBookmark(c, id, func(tx storage.Store) error {
+		current, err := tx.GetByID(c.Request.Context(), id)
+		if err != nil {
+			return err
+		}
+		archived := !current.Archived
+		if req.Archived != nil {
+			archived = *req.Archived
+		}
+		bookmark, err = tx.(storage.ArchiveStore).SetArchived(c.Request.Context(), id, archived)
+		return err
+	})
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	publishBookmarkEvent("updated", id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
+
+// handleSetArchivePolicy sets after how many untouched days the bookmarks
+// in the signed-in account's collections are archived; 0 turns it off
+func handleSetArchivePolicy(c *gin.Context) {
+	var req model.ArchivePolicyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	account, err := accounts.SetArchivePolicy(c.Request.Context(), currentActor(c).AccountID, *req.AfterDays)
+	if err != nil {
+		log.Printf("Archive policy update failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Archive policy update failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    account,
+	})
+}
+
+// archiveStaleBookmarks applies every account's auto-archive policy.
+// Bookmarks have no owner, so a policy covers the bookmarks in the
+// collections its account owns.
+func archiveStaleBookmarks(ctx context.Context) (string, error) {
+	if archiveStore == nil || accounts == nil || collections == nil {
+		return "auto-archive is not supported by this storage driver", nil
+	}
+
+	now := time.Now()
+	var policies, archived int
+	for _, account := range accounts.ListAccounts(ctx) {
+		if ctx.Err() != nil {
+			break
+		}
+		if account.ArchiveAfterDays <= 0 {
+			continue
+		}
+		policies++
+		stale, err := archiveStore.ArchiveStale(ctx, ownedBookmarks(ctx, account.ID), now.AddDate(0, 0, -account.ArchiveAfterDays))
+		if err != nil {
+			return "", err
+		}
+		for _, b := range stale {
+			publishBookmarkEvent("updated", b.ID)
+		}
+		archived += len(stale)
+	}
+	return fmt.Sprintf("archived %d bookmarks under %d policies", archived, policies), ctx.Err()
+}
+
+// ownedBookmarks returns the IDs of the bookmarks in the collections
+// owned by accountID and below them
+func ownedBookmarks(ctx context.Context, accountID string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, col := range collections.ListCollections(ctx) {
+		if col.OwnerID != accountID {
+			continue
+		}
+		members, err := collections.CollectionBookmarks(ctx, col.ID, true)
+		if err != nil {
+			continue
+		}
+		for _, id := range members {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
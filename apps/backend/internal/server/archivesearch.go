@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/blob"
+	"github.com/hereisth/web-collector/apps/backend/internal/extract"
+	"github.com/hereisth/web-collector/apps/backend/internal/retention"
+	"github.com/hereisth/web-collector/apps/backend/internal/search"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global index of archived page text, searched with ?scope=archive; nil
+// without a search backend. Only each bookmark's newest snapshot is
+// indexed.
+var archiveIndex search.Index
+
+const (
+	// maxSnapshotText bounds the text indexed per snapshot
+	maxSnapshotText = 256 << 10
+	// archiveHighlightLimit is how many archive results carry highlighted
+	// fragments, which means reading their snapshots again
+	archiveHighlightLimit = 20
+)
+
+// snapshotTypes are the snapshot files whose text is indexed, by
+// extension; "" is a version stored as a single file without one
+var snapshotTypes = map[string]bool{
+	"":       true,
+	".html":  true,
+	".htm":   true,
+	".xhtml": true,
+	".txt":   true,
+	".md":    true,
+}
+
+// rebuildArchiveIndex indexes the newest snapshot of every bookmark,
+// returning how many were indexed
+func rebuildArchiveIndex(ctx context.Context) (int, error) {
+	objects, err := blobs.List(ctx, archivePrefix)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		docs    []search.Document
+		indexed int
+	)
+	for id, files := range retention.Latest(objects, archivePrefix) {
+		if _, err := store.GetByID(ctx, id); errors.Is(err, storage.ErrBookmarkNotFound) {
+			continue
+		} else if err != nil {
+			return indexed, err
+		}
+		text, err := snapshotText(ctx, files)
+		if err != nil {
+			log.Printf("Failed to read snapshot of bookmark %s: %v", id, err)
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		// Only the page text is indexed: the bookmark itself is searched
+		// without a scope.
+		docs = append(docs, search.Document{ID: id, Content: text})
+		if len(docs) == reindexBatchSize {
+			if err := archiveIndex.Index(ctx, docs...); err != nil {
+				return indexed, err
+			}
+			indexed += len(docs)
+			docs = docs[:0]
+		}
+	}
+	if len(docs) > 0 {
+		if err := archiveIndex.Index(ctx, docs...); err != nil {
+			return indexed, err
+		}
+		indexed += len(docs)
+	}
+	return indexed, nil
+}
+
+// snapshotText returns the readable text of a snapshot's page files
+func snapshotText(ctx context.Context, files []blob.Object) (string, error) {
+	var parts []string
+	size := 0
+	for _, f := range files {
+		ext := strings.ToLower(path.Ext(f.Key))
+		if !snapshotTypes[ext] || size >= maxSnapshotText {
+			continue
+		}
+		r, err := blobs.Get(ctx, f.Key)
+		if err != nil {
+			return "", err
+		}
+		var text string
+		switch ext {
+		case ".txt", ".md":
+			var data []byte
+			data, err = io.ReadAll(io.LimitReader(r, int64(maxSnapshotText-size)))
+			text = strings.Join(strings.Fields(string(data)), " ")
+		default:
+			text = extract.Text(r, maxSnapshotText-size)
+		}
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		if text != "" {
+			parts = append(parts, text)
+			size += len(text)
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// searchArchive returns the bookmarks whose archived page text matches
+// query, in relevance order, keeping only those that pass filter. The
+// best results carry fragments of the text around the matches.
+func searchArchive(ctx context.Context, query string, filter bookmarkFilter) ([]searchResult, error) {
+	hits, err := archiveIndex.Search(ctx, query, 0)
+	if err != nil {
+		return nil, err
+	}
+	var objects []blob.Object
+	if len(hits) > 0 {
+		if objects, err = blobs.List(ctx, archivePrefix); err != nil {
+			return nil, err
+		}
+	}
+	latest := retention.Latest(objects, archivePrefix)
+
+	result := make([]searchResult, 0, len(hits))
+	for _, hit := range hits {
+		b, err := store.GetByID(ctx, hit.ID)
+		if errors.Is(err, storage.ErrBookmarkNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !filter.matches(b) || latest[hit.ID] == nil {
+			continue
+		}
+		r := searchResult{Bookmark: b, Score: hit.Score}
+		if len(result) < archiveHighlightLimit {
+			if text, err := snapshotText(ctx, latest[hit.ID]); err == nil {
+				r.Highlights = search.Highlights(search.Document{Content: text}, query)
+			}
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/sanitize"
+)
+
+func init() {
+	// Report JSON field names (e.g. "url") instead of Go struct field names
+	// (e.g. "URL") in validation messages.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+		v.RegisterValidation("weburl", validateWebURL)
+		v.RegisterValidation("noscripturl", validateNoScriptURL)
+	}
+}
+
+// BodyLimit middleware rejects request bodies larger than maxBytes. Declared
+// lengths are checked up front; chunked bodies are cut off while reading.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBytes {
+			respondErrorDetails(c, http.StatusRequestEntityTooLarge, apierr.PayloadTooLarge, "Request body too large", fmt.Sprintf("body must not exceed %d bytes", maxBytes))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// bindJSON decodes and validates the request body into obj. On failure it
+// writes the error response and returns false, so handlers can simply return.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+	respondBodyError(c, err)
+	return false
+}
+
+// cleanTitle strips markup from the title in a request's field, since
+// titles are shown on public pages and to other members of a collection.
+// An empty title is left alone; one that was nothing but markup is
+// refused, writing the error response and returning false.
+func cleanTitle(c *gin.Context, field string, title *string) bool {
+	if *title == "" {
+		return true
+	}
+	*title = strings.TrimSpace(sanitize.Plain(*title))
+	if *title != "" {
+		return true
+	}
+	msg := field + " must contain text"
+	resp := apierr.New(apierr.ValidationFailed, "Invalid request body")
+	resp.Details = msg
+	resp.Fields = []apierr.FieldError{{Field: field, Rule: "text", Message: msg}}
+	c.AbortWithStatusJSON(http.StatusBadRequest, resp)
+	return false
+}
+
+// respondBodyError writes the error response for a body that could not be
+// read or decoded.
+func respondBodyError(c *gin.Context, err error) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		respondErrorDetails(c, http.StatusRequestEntityTooLarge, apierr.PayloadTooLarge, "Request body too large", fmt.Sprintf("body must not exceed %d bytes", maxErr.Limit))
+		return
+	}
+
+	fields := bindingFieldErrors(err)
+	if len(fields) == 0 {
+		respondErrorDetails(c, http.StatusBadRequest, apierr.InvalidRequestBody, "Invalid request body", formatBindingError(err))
+		return
+	}
+	resp := apierr.New(apierr.ValidationFailed, "Invalid request body")
+	resp.Details = formatBindingError(err)
+	resp.Fields = fields
+	c.AbortWithStatusJSON(http.StatusBadRequest, resp)
+}
+
+// bindingFieldErrors lists the fields a binding error is about: every
+// field that broke a validation rule, or the field whose JSON value has
+// the wrong type, with rule "type". It returns nil for bodies that are
+// not JSON at all.
+func bindingFieldErrors(err error) []apierr.FieldError {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make([]apierr.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, apierr.FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Message: fieldErrorMessage(fe)})
+		}
+		return fields
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []apierr.FieldError{{Field: typeErr.Field, Rule: "type", Message: typeErrorMessage(typeErr)}}
+	}
+	return nil
+}
+
+// fieldPath is the JSON path of a field within the request body, such
+// as "url", "tags[2]" or "roots.children[0].title"
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// formatBindingError turns validator and decoding errors into a short,
+// human-readable sentence without leaking Go type names.
+func formatBindingError(err error) string {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		msgs := make([]string, 0, len(verrs))
+		for _, fe := range verrs {
+			msgs = append(msgs, fieldErrorMessage(fe))
+		}
+		return strings.Join(msgs, "; ")
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return typeErrorMessage(typeErr)
+	}
+	if errors.Is(err, io.EOF) {
+		return "request body is empty"
+	}
+	return "request body is not valid JSON"
+}
+
+// typeErrorMessage describes a JSON value of the wrong type, naming the
+// kind of value expected rather than a Go type
+func typeErrorMessage(typeErr *json.UnmarshalTypeError) string {
+	kind := typeErr.Type.Kind().String()
+	switch typeErr.Type.Kind() {
+	case reflect.Slice, reflect.Array:
+		kind = "list"
+	case reflect.Map, reflect.Struct:
+		kind = "object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		kind = "number"
+	case reflect.Bool:
+		kind = "boolean"
+	}
+	return fmt.Sprintf("%s must be a %s", typeErr.Field, kind)
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	field := fieldPath(fe)
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s%s", field, fe.Param(), boundUnit(fe))
+	case "min":
+		return fmt.Sprintf("%s must be at least %s%s", field, fe.Param(), boundUnit(fe))
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "email":
+		return fmt.Sprintf("%s must be an email address", field)
+	case "url":
+		return fmt.Sprintf("%s must be a URL", field)
+	case "weburl":
+		return fmt.Sprintf("%s must be an http(s) URL with a valid host", field)
+	case "noscripturl":
+		return fmt.Sprintf("%s must not be a javascript: or data: URL", field)
+	default:
+		return fmt.Sprintf("%s is invalid (%s)", field, fe.Tag())
+	}
+}
+
+// boundUnit names what a min/max bound counts: characters for strings,
+// items for slices and nothing for numbers
+func boundUnit(fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/blob"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global blob move store; nil when the storage driver never changes
+// bookmark IDs
+var blobMoves storage.BlobMoveStore
+
+// bookmarkBlobPrefixes are where blobs are kept under a bookmark's ID
+var bookmarkBlobPrefixes = []string{thumbnailPrefix, archivePrefix}
+
+// moveBookmarkBlobs moves the thumbnails and archived pages of bookmarks
+// whose IDs a migration changed to their new IDs, and returns how many
+// blobs it moved. Instances take turns, so only one moves blobs at a time.
+// A change is completed once all its blobs are moved, so moves cut short
+// carry on at the next start. Blob stores cannot rename, so each blob is
+// copied and the original deleted; copies take the time of the move as
+// their modification time.
+func moveBookmarkBlobs(ctx context.Context) (int, error) {
+	if blobMoves == nil {
+		return 0, nil
+	}
+	unlock, err := blobMoves.LockBlobMoves(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	moves, err := blobMoves.PendingBlobMoves(ctx)
+	if err != nil || len(moves) == 0 {
+		return 0, err
+	}
+
+	// Each prefix is listed once, and the listing kept up to date as
+	// blobs move, since a bookmark's ID may have changed more than once
+	listed := make(map[string]map[string][]blob.Object, len(bookmarkBlobPrefixes))
+	for _, prefix := range bookmarkBlobPrefixes {
+		objects, err := blobs.List(ctx, prefix)
+		if err != nil {
+			return 0, err
+		}
+		byID := make(map[string][]blob.Object)
+		for _, obj := range objects {
+			id, _, _ := strings.Cut(strings.TrimPrefix(obj.Key, prefix), "/")
+			byID[id] = append(byID[id], obj)
+		}
+		listed[prefix] = byID
+	}
+
+	moved := 0
+	for _, m := range moves {
+		for _, prefix := range bookmarkBlobPrefixes {
+			byID := listed[prefix]
+			from := prefix + m.OldID + "/"
+			for _, obj := range byID[m.OldID] {
+				to := blob.Object{Key: prefix + m.NewID + "/" + strings.TrimPrefix(obj.Key, from), Size: obj.Size}
+				if err := moveBlob(ctx, obj, to.Key); err != nil {
+					return moved, err
+				}
+				byID[m.NewID] = append(byID[m.NewID], to)
+				moved++
+			}
+			delete(byID, m.OldID)
+		}
+		if err := blobMoves.CompleteBlobMove(ctx, m.Seq); err != nil {
+			return moved, err
+		}
+	}
+	if moved > 0 {
+		invalidateResponses(ctx)
+	}
+	return moved, nil
+}
+
+// moveBlob copies obj to key and deletes the original
+func moveBlob(ctx context.Context, obj blob.Object, key string) error {
+	r, err := blobs.Get(ctx, obj.Key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := blobs.Put(ctx, key, r, obj.Size); err != nil {
+		return err
+	}
+	return blobs.Delete(ctx, obj.Key)
+}
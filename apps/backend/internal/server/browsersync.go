@@ -0,0 +1,362 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/bookmarktree"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// browserSyncReport counts what a sync changed on each side
+type browserSyncReport struct {
+	Created            int                   `json:"created"`
+	Updated            int                   `json:"updated"`
+	FoldersCreated     int                   `json:"folders_created"`
+	AddedToBrowser     int                   `json:"added_to_browser"`
+	RemovedFromServer  int                   `json:"removed_from_collections"`
+	DroppedFromBrowser int                   `json:"dropped_from_browser"`
+	Conflicts          []browserSyncConflict `json:"conflicts"`
+}
+
+// browserSyncConflict is a change made on both sides since the last
+// sync, or one that could not be applied, and how it was resolved
+type browserSyncConflict struct {
+	Title      string `json:"title"`
+	URL        string `json:"url,omitempty"`
+	Resolution string `json:"resolution"`
+}
+
+// browserSync merges a browser's bookmark tree with the collections an
+// account can see. Root folders map to top-level collections named after
+// them, folders to collections and bookmarks to bookmarks by URL.
+type browserSync struct {
+	ctx   context.Context
+	cs    storage.CollectionStore
+	actor storage.Actor
+	// since is when the browser last synced; zero for a first sync,
+	// which only adds to both sides
+	since time.Time
+	// children lists the collections below each collection, "" for the
+	// top level
+	children map[string][]model.Collection
+	report   browserSyncReport
+}
+
+func newBrowserSync(c *gin.Context, since time.Time) *browserSync {
+	s := &browserSync{
+		ctx:      c.Request.Context(),
+		cs:       collectionsFor(c),
+		actor:    currentActor(c),
+		since:    since,
+		children: make(map[string][]model.Collection),
+		report:   browserSyncReport{Conflicts: []browserSyncConflict{}},
+	}
+	for _, col := range s.cs.ListCollections(c.Request.Context()) {
+		s.children[col.ParentID] = append(s.children[col.ParentID], col)
+	}
+	return s
+}
+
+// changedSince reports whether t is after the last sync; everything is
+// new on a first sync
+func (s *browserSync) changedSince(t time.Time) bool {
+	return s.since.IsZero() || t.After(s.since)
+}
+
+func (s *browserSync) conflict(title, url, resolution string) {
+	s.report.Conflicts = append(s.report.Conflicts, browserSyncConflict{Title: title, URL: url, Resolution: resolution})
+}
+
+// rootCollection returns the top-level collection a root folder maps to,
+// creating it when asked
+func (s *browserSync) rootCollection(name string, create bool) (model.Collection, bool) {
+	for _, col := range s.children[""] {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	if !create {
+		return model.Collection{}, false
+	}
+	col, err := s.cs.CreateCollection(s.ctx, name, "", s.actor.AccountID)
+	if err != nil {
+		s.conflict(name, "", "folder not created: "+err.Error())
+		return model.Collection{}, false
+	}
+	s.children[""] = append(s.children[""], col)
+	s.report.FoldersCreated++
+	return col, true
+}
+
+// folder renders a collection, with everything below it, as a folder
+func (s *browserSync) folder(col model.Collection) *bookmarktree.Node {
+	n := &bookmarktree.Node{Title: col.Name, Added: col.CreatedAt}
+	for _, child := range s.children[col.ID] {
+		n.Children = append(n.Children, s.folder(child))
+	}
+	ids, _ := s.cs.CollectionBookmarks(s.ctx, col.ID, false)
+	for _, id := range ids {
+		if b, err := store.GetByID(s.ctx, id); err == nil {
+			n.Children = append(n.Children, bookmarkNode(b))
+		}
+	}
+	return n
+}
+
+func bookmarkNode(b model.Bookmark) *bookmarktree.Node {
+	return &bookmarktree.Node{Title: b.Title, URL: b.URL, Tags: b.Tags, Added: b.CreatedAt, Modified: b.UpdatedAt}
+}
+
+// export fills t's roots from the collections named after them; other
+// top-level collections become folders in Other bookmarks
+func (s *browserSync) export(t *bookmarktree.Tree) {
+	roots := make(map[string]bool)
+	for _, root := range t.Roots {
+		roots[root.Title] = true
+		if col, found := s.rootCollection(root.Title, false); found {
+			root.Children = s.folder(col).Children
+		}
+	}
+	other := t.Root(bookmarktree.RootOther)
+	for _, col := range s.children[""] {
+		if !roots[col.Name] {
+			other.Children = append(other.Children, s.folder(col))
+		}
+	}
+}
+
+// merge reconciles a folder with its collection, changing both to match.
+// Bookmarks and folders new on one side are added to the other. Ones the
+// other side had at the last sync were deleted there, so they are
+// removed, unless they were edited since. A title edited on only one
+// side wins; edited on both, the server's is kept. Collections are never
+// deleted, and bookmarks are only taken out of collections.
+func (s *browserSync) merge(n *bookmarktree.Node, colID string) {
+	members := make(map[string]bool)
+	ids, _ := s.cs.CollectionBookmarks(s.ctx, colID, false)
+	for _, id := range ids {
+		members[id] = true
+	}
+	byName := make(map[string]model.Collection)
+	for _, col := range s.children[colID] {
+		byName[col.Name] = col
+	}
+
+	var (
+		kept    []*bookmarktree.Node
+		seen    = make(map[string]bool)
+		matched = make(map[string]bool)
+	)
+	for _, child := range n.Children {
+		if !child.Folder() {
+			if s.mergeBookmark(child, colID, members, seen) {
+				kept = append(kept, child)
+			}
+			continue
+		}
+		col, found := byName[child.Title]
+		if !found {
+			var err error
+			if col, err = s.cs.CreateCollection(s.ctx, child.Title, colID, s.actor.AccountID); err != nil {
+				s.conflict(child.Title, "", "folder not created: "+err.Error())
+				kept = append(kept, child)
+				continue
+			}
+			s.report.FoldersCreated++
+		}
+		matched[col.ID] = true
+		s.merge(child, col.ID)
+		kept = append(kept, child)
+	}
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		b, err := store.GetByID(s.ctx, id)
+		if err != nil {
+			continue
+		}
+		switch {
+		case s.changedSince(b.CreatedAt):
+			kept = append(kept, bookmarkNode(b))
+			s.report.AddedToBrowser++
+		case b.UpdatedAt.After(s.since):
+			kept = append(kept, bookmarkNode(b))
+			s.conflict(b.Title, b.URL, "kept: deleted in the browser but edited on the server")
+		default:
+			if err := s.cs.RemoveFromCollection(s.ctx, colID, id); err != nil {
+				kept = append(kept, bookmarkNode(b))
+				s.conflict(b.Title, b.URL, "kept: "+err.Error())
+				continue
+			}
+			publishCollectionEventAs(s.actor.AccountID, model.CollectionEvent{
+				Type:         model.EventBookmarkRemoved,
+				CollectionID: colID,
+				BookmarkID:   id,
+			})
+			s.report.RemovedFromServer++
+		}
+	}
+	for _, col := range s.children[colID] {
+		if matched[col.ID] {
+			continue
+		}
+		if !s.changedSince(col.CreatedAt) {
+			s.conflict(col.Name, "", "kept: folder deleted in the browser is still a collection")
+		}
+		kept = append(kept, s.folder(col))
+	}
+	n.Children = kept
+}
+
+// mergeBookmark reconciles a bookmark in a folder with the server and
+// reports whether it stays in the browser
+func (s *browserSync) mergeBookmark(n *bookmarktree.Node, colID string, members, seen map[string]bool) bool {
+	b, found := savedBookmark(s.ctx, s.actor, n.URL)
+	if !found {
+		if !s.changedSince(n.Added) {
+			// Saved at the last sync, so deleted on the server since
+			s.report.DroppedFromBrowser++
+			return false
+		}
+		save := saveIntegrationLink(s.ctx, "browser sync", integrationLink{
+			URL:       n.URL,
+			Title:     n.Title,
+			Tags:      n.Tags,
+			AccountID: s.actor.AccountID,
+		}, "")
+		if save.Refusal != "" {
+			s.conflict(n.Title, n.URL, "not saved: "+save.Refusal)
+			return true
+		}
+		b = save.Bookmark
+		if !save.Existing {
+			s.report.Created++
+		}
+	}
+	if seen[b.ID] {
+		// The same page twice in one folder is one membership
+		return false
+	}
+	seen[b.ID] = true
+
+	if !members[b.ID] {
+		if err := s.cs.AddToCollection(s.ctx, colID, []string{b.ID}); err != nil {
+			s.conflict(b.Title, b.URL, "not added to the collection: "+err.Error())
+		} else {
+			publishCollectionEventAs(s.actor.AccountID, model.CollectionEvent{
+				Type:         model.EventBookmarkAdded,
+				CollectionID: colID,
+				BookmarkID:   b.ID,
+				Bookmark:     &b,
+			})
+		}
+	}
+
+	if n.Title != "" && n.Title != b.Title && !s.since.IsZero() {
+		browserChanged := n.Modified.After(s.since) || n.Added.After(s.since)
+		serverChanged := b.UpdatedAt.After(s.since)
+		switch {
+		case browserChanged && serverChanged:
+			s.conflict(b.Title, b.URL, "server title kept over \""+n.Title+"\"")
+		case browserChanged:
+			if updated, err := store.Update(s.ctx, b.ID, truncate(n.Title, 500), b.URL, b.Tags); err == nil {
+				indexBookmark(s.ctx, updated)
+				publishBookmarkEvent("updated", b.ID)
+				recordActivityAs(s.ctx, s.actor.AccountID, model.Activity{Kind: model.ActivityEdited, BookmarkID: b.ID, Detail: "via browser sync"})
+				b = updated
+				s.report.Updated++
+			}
+		}
+	}
+	n.Title, n.URL = b.Title, b.URL
+	if len(b.Tags) > 0 {
+		n.Tags = b.Tags
+	}
+	return true
+}
+
+// handleExportBrowserTree returns the collections the signed-in account
+// can see as a browser bookmark tree, ?format=chrome (the default) or
+// firefox, ready to put in place of the browser's own
+func handleExportBrowserTree(c *gin.Context) {
+	tree, err := bookmarktree.Empty(c.DefaultQuery("format", bookmarktree.Chrome))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, err.Error())
+		return
+	}
+	newBrowserSync(c, time.Time{}).export(tree)
+	writeBrowserTree(c, tree)
+}
+
+// handleSyncBrowserTree merges a browser's bookmark tree, a Chrome
+// Bookmarks file or a Firefox JSON backup, with the collections the
+// signed-in account can see. ?since= is the synced_at of the browser's
+// previous sync; without it, the sync only adds to both sides. It
+// returns the merged tree in the same format to write back to the
+// browser, what changed on each side and the conflicts.
+func handleSyncBrowserTree(c *gin.Context) {
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "since must be an RFC 3339 timestamp")
+			return
+		}
+		since = t
+	}
+	data, err := c.GetRawData()
+	if err != nil {
+		respondBodyError(c, err)
+		return
+	}
+	tree, err := bookmarktree.Parse(data)
+	if err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, apierr.ValidationFailed, "Invalid bookmark tree", err.Error())
+		return
+	}
+
+	syncedAt := time.Now().UTC()
+	s := newBrowserSync(c, since)
+	for _, root := range tree.Roots {
+		// Empty roots need no collection until something is put in them
+		if col, found := s.rootCollection(root.Title, len(root.Children) > 0); found {
+			s.merge(root, col.ID)
+		}
+	}
+	merged, err := tree.Marshal()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Bookmark tree could not be written")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"format":    tree.Format,
+			"tree":      json.RawMessage(merged),
+			"report":    s.report,
+			"synced_at": syncedAt,
+		},
+	})
+}
+
+func writeBrowserTree(c *gin.Context, tree *bookmarktree.Tree) {
+	data, err := tree.Marshal()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Bookmark tree could not be written")
+		return
+	}
+	filename := "Bookmarks"
+	if tree.Format == bookmarktree.Firefox {
+		filename = "bookmarks.json"
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/json", data)
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	cacheKeyPrefix     = "wc:resp:"
+	cacheGenerationKey = "wc:resp:generation"
+)
+
+// cachedHeaders are the response headers a cache hit replays besides
+// Content-Type: the validators clients make conditional writes with, the
+// paging headers of lists, and Vary for caches downstream
+var cachedHeaders = []string{"ETag", "Last-Modified", "Link", "X-Total-Count", "Vary"}
+
+// cachedResponse is what gets stored in Redis for a cached GET
+type cachedResponse struct {
+	Status      int                 `json:"status"`
+	ContentType string              `json:"content_type"`
+	Headers     map[string][]string `json:"headers,omitempty"`
+	Body        []byte              `json:"body"`
+}
+
+// ResponseCache caches successful GET responses in Redis. Entries are keyed
+// by the signed-in account and full request URI and expire after ttl. Every
+// bookmark or collection change bumps a shared generation counter, which
+// invalidates every cached entry across all server instances at once.
+type ResponseCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// Global response cache; nil unless REDIS_URL is set
+var responseCache *ResponseCache
+
+// NewResponseCache connects to the Redis server at redisURL
+func NewResponseCache(redisURL string, ttl time.Duration) (*ResponseCache, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &ResponseCache{client: client, ttl: ttl}, nil
+}
+
+// Middleware serves cached GET responses. It runs after Authenticate, so
+// entries are keyed by who is asking.
+func (rc *ResponseCache) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key, err := rc.key(c)
+		if err != nil {
+			// Redis unavailable: serve uncached rather than failing requests.
+			log.Printf("Response cache unavailable: %v", err)
+			c.Next()
+			return
+		}
+
+		if raw, err := rc.client.Get(ctx, key).Bytes(); err == nil {
+			var cached cachedResponse
+			if json.Unmarshal(raw, &cached) == nil {
+				for name, values := range cached.Headers {
+					c.Writer.Header()[name] = values
+				}
+				c.Header("X-Cache", "HIT")
+				c.Data(cached.Status, cached.ContentType, cached.Body)
+				c.Abort()
+				return
+			}
+		}
+
+		rec := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Header("X-Cache", "MISS")
+		c.Next()
+
+		if rec.Status() != http.StatusOK {
+			return
+		}
+		headers := make(map[string][]string)
+		for _, name := range cachedHeaders {
+			if values := rec.Header().Values(name); len(values) > 0 {
+				headers[http.CanonicalHeaderKey(name)] = values
+			}
+		}
+		raw, err := json.Marshal(cachedResponse{
+			Status:      rec.Status(),
+			ContentType: rec.Header().Get("Content-Type"),
+			Headers:     headers,
+			Body:        rec.body.Bytes(),
+		})
+		if err != nil {
+			return
+		}
+		if err := rc.client.Set(ctx, key, raw, rc.ttl).Err(); err != nil {
+			log.Printf("Response cache write failed: %v", err)
+		}
+	}
+}
+
+// key builds the cache key from the current generation, the signed-in
+// account and the request URI, so one account never gets another's
+// responses.
+func (rc *ResponseCache) key(c *gin.Context) (string, error) {
+	gen, err := rc.client.Get(c.Request.Context(), cacheGenerationKey).Result()
+	if err == redis.Nil {
+		gen = "0"
+	} else if err != nil {
+		return "", err
+	}
+
+	r := c.Request
+	actor := currentActor(c)
+	h := sha256.New()
+	h.Write([]byte(actor.AccountID))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatBool(actor.Admin)))
+	h.Write([]byte{0})
+	h.Write([]byte(r.Header.Get("Accept")))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RequestURI()))
+	return cacheKeyPrefix + gen + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (rc *ResponseCache) invalidate(ctx context.Context) {
+	if err := rc.client.Incr(ctx, cacheGenerationKey).Err(); err != nil {
+		log.Printf("Response cache invalidation failed: %v", err)
+	}
+}
+
+// invalidateResponses drops every cached response, when there is a cache
+func invalidateResponses(ctx context.Context) {
+	if responseCache != nil {
+		responseCache.invalidate(ctx)
+	}
+}
+
+// Close closes the Redis connection pool
+func (rc *ResponseCache) Close() error {
+	return rc.client.Close()
+}
+
+// recordingWriter tees the response body so it can be cached
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/ical"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global calendar token store; nil when the storage driver cannot keep
+// calendar tokens
+var calendarTokens storage.CalendarTokenStore
+
+// calendarTokenPrefix marks calendar tokens, so one pasted in the wrong
+// place is recognizable
+const calendarTokenPrefix = "cal_"
+
+const (
+	// calendarPast and calendarAhead bound the reminders in the feed
+	calendarPast  = 90 * 24 * time.Hour
+	calendarAhead = 365 * 24 * time.Hour
+	// onThisDayPast and onThisDayAhead bound the days given a "saved on
+	// this day" entry
+	onThisDayPast  = 7
+	onThisDayAhead = 30
+	// calendarRefresh is how often calendar apps are asked to refetch
+	calendarRefresh = time.Hour
+	// reminderLength is how long a reminder's event lasts
+	reminderLength = 15 * time.Minute
+)
+
+// RequireCalendar hides the calendar routes when the store cannot keep
+// calendar tokens
+func RequireCalendar() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if calendarTokens == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Calendar feeds are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleCreateCalendarToken gives the signed-in account a new calendar
+// token, replacing any it had, and returns the feed URL to subscribe to.
+// The token is only shown in this response.
+func handleCreateCalendarToken(c *gin.Context) {
+	token := calendarTokenPrefix + randomID()
+	if err := calendarTokens.SetCalendarToken(c.Request.Context(), currentActor(c).AccountID, hashToken(token)); err != nil {
+		log.Printf("Calendar token update failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Calendar token update failed")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data": gin.H{
+			"token": token,
+			"url":   "/api/v1/calendar.ics?" + url.Values{"token": {token}}.Encode(),
+		},
+	})
+}
+
+// handleRevokeCalendarToken removes the signed-in account's calendar
+// token, which stops its feed
+func handleRevokeCalendarToken(c *gin.Context) {
+	if err := calendarTokens.SetCalendarToken(c.Request.Context(), currentActor(c).AccountID, ""); err != nil {
+		log.Printf("Calendar token update failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Calendar token update failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Calendar token revoked",
+	})
+}
+
+// handleCalendarFeed serves an iCalendar feed of the reminders the
+// account given by ?token= has set, from 90 days back to a year ahead.
+// With ?on_this_day=true, each day of the coming month with bookmarks
+// saved on the same date in earlier years gets an all-day entry listing
+// them. Calendar apps cannot sign in, so the token is the only
+// credential; it can do nothing else and is redacted from the request
+// log.
+func handleCalendarFeed(c *gin.Context) {
+	token := c.Query("token")
+	account, ok := calendarTokens.AccountByCalendarToken(c.Request.Context(), hashToken(token))
+	if token == "" || !ok {
+		respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Invalid calendar token")
+		return
+	}
+	onThisDay, _ := strconv.ParseBool(c.Query("on_this_day"))
+
+	now := time.Now()
+	cal := &ical.Calendar{
+		Name:    "web-collector",
+		ProdID:  "-//web-collector//Reminders//EN",
+		Refresh: calendarRefresh,
+	}
+	if reminders != nil {
+		events, err := reminderEvents(c.Request.Context(), account.ID, now)
+		if err != nil {
+			reminderError(c, err)
+			return
+		}
+		cal.Events = append(cal.Events, events...)
+	}
+	if onThisDay && collections != nil {
+		cal.Events = append(cal.Events, onThisDayEvents(c.Request.Context(), account.ID, now)...)
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="web-collector.ics"`)
+	c.Status(http.StatusOK)
+	if err := cal.Write(c.Writer, now); err != nil {
+		log.Printf("Calendar feed failed: %v", err)
+	}
+}
+
+// reminderEvents returns an event for each of an account's reminders in
+// the feed's window. Pending reminders carry an alarm.
+func reminderEvents(ctx context.Context, accountID string, now time.Time) ([]ical.Event, error) {
+	due, err := reminders.DueReminders(ctx, now.Add(calendarAhead))
+	if err != nil {
+		return nil, err
+	}
+	var events []ical.Event
+	for _, r := range due {
+		if r.AccountID != accountID || r.RemindAt.Before(now.Add(-calendarPast)) {
+			continue
+		}
+		b, err := store.GetByID(ctx, r.BookmarkID)
+		if err != nil {
+			continue
+		}
+		description := b.URL
+		if b.Notes != "" {
+			description += "\n\n" + b.Notes
+		}
+		events = append(events, ical.Event{
+			UID:         "reminder-" + b.ID + "@web-collector",
+			Start:       r.RemindAt,
+			Duration:    reminderLength,
+			Summary:     "Read: " + b.Title,
+			Description: description,
+			URL:         b.URL,
+			Alarm:       r.DeliveredAt == nil && r.RemindAt.After(now),
+		})
+	}
+	return events, nil
+}
+
+// onThisDayEvents returns an all-day event for each day around now on
+// whose date, in earlier years, the account saved bookmarks
+func onThisDayEvents(ctx context.Context, accountID string, now time.Time) []ical.Event {
+	byDate := make(map[string][]model.Bookmark) // MM-DD -> bookmarks
+	for _, b := range accountBookmarks(ctx, accountID, nil) {
+		key := b.CreatedAt.UTC().Format("01-02")
+		byDate[key] = append(byDate[key], b)
+	}
+
+	var events []ical.Event
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for offset := -onThisDayPast; offset <= onThisDayAhead; offset++ {
+		day := today.AddDate(0, 0, offset)
+		var earlier []model.Bookmark
+		for _, b := range byDate[day.Format("01-02")] {
+			if b.CreatedAt.UTC().Year() < day.Year() {
+				earlier = append(earlier, b)
+			}
+		}
+		if len(earlier) == 0 {
+			continue
+		}
+		sort.Slice(earlier, func(i, j int) bool { return earlier[i].CreatedAt.Before(earlier[j].CreatedAt) })
+
+		lines := make([]string, 0, len(earlier))
+		for _, b := range earlier {
+			lines = append(lines, fmt.Sprintf("%d: %s\n%s", b.CreatedAt.UTC().Year(), b.Title, b.URL))
+		}
+		summary := "On this day: 1 saved bookmark"
+		if len(earlier) > 1 {
+			summary = fmt.Sprintf("On this day: %d saved bookmarks", len(earlier))
+		}
+		events = append(events, ical.Event{
+			UID:         "on-this-day-" + day.Format("20060102") + "@web-collector",
+			Start:       day,
+			AllDay:      true,
+			Summary:     summary,
+			Description: strings.Join(lines, "\n\n"),
+		})
+	}
+	return events
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global capture token store; nil when the storage driver cannot keep
+// capture tokens
+var captureTokens storage.CaptureTokenStore
+
+// captureTokenPrefix marks capture tokens, so one pasted in the wrong
+// place is recognizable
+const captureTokenPrefix = "cap_"
+
+// RequireCaptureTokens hides the capture routes when the store cannot
+// keep capture tokens
+func RequireCaptureTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if captureTokens == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Capture tokens are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// hashToken is how capture and calendar tokens are stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// handleCreateCaptureToken gives the signed-in account a new capture
+// token, replacing any it had. The token is only shown in this response.
+func handleCreateCaptureToken(c *gin.Context) {
+	token := captureTokenPrefix + randomID()
+	if err := captureTokens.SetCaptureToken(c.Request.Context(), currentActor(c).AccountID, hashToken(token)); err != nil {
+		log.Printf("Capture token update failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Capture token update failed")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    gin.H{"token": token},
+	})
+}
+
+// handleRevokeCaptureToken removes the signed-in account's capture token
+func handleRevokeCaptureToken(c *gin.Context) {
+	if err := captureTokens.SetCaptureToken(c.Request.Context(), currentActor(c).AccountID, ""); err != nil {
+		log.Printf("Capture token update failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Capture token update failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Capture token revoked",
+	})
+}
+
+// handleCapture saves a bookmark in one call authenticated by a capture
+// token in ?token=, for share-sheet automations such as iOS Shortcuts or
+// Tasker that cannot easily send credentials. url, title, tags
+// (comma-separated) and notes are read from the query or a form body. A
+// capture token can do nothing else, and it is redacted from the request
+// log.
+func handleCapture(c *gin.Context) {
+	token := c.Query("token")
+	account, ok := captureTokens.AccountByCaptureToken(c.Request.Context(), hashToken(token))
+	if token == "" || !ok {
+		respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Invalid capture token")
+		return
+	}
+	param := func(name string) string {
+		if v, ok := c.GetQuery(name); ok {
+			return strings.TrimSpace(v)
+		}
+		return strings.TrimSpace(c.PostForm(name))
+	}
+	url := param("url")
+	if url == "" || len(url) > 2048 {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "url is required and at most 2048 characters")
+		return
+	}
+	notes := param("notes")
+	if r := []rune(notes); len(r) > 10000 {
+		notes = string(r[:10000])
+	}
+
+	save := saveIntegrationLink(c.Request.Context(), "capture", integrationLink{
+		URL:       url,
+		Title:     param("title"),
+		Tags:      model.SplitTags(param("tags")),
+		Notes:     notes,
+		AccountID: account.ID,
+	}, "")
+	if save.Refusal != "" {
+		respondError(c, http.StatusForbidden, apierr.SaveRefused, strings.TrimSuffix(save.Refusal, "."))
+		return
+	}
+	status := http.StatusCreated
+	if save.Existing {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"data":    save.Bookmark,
+	})
+}
+
+// redactQuery hides capture and calendar tokens in a logged query string
+func redactQuery(raw string) string {
+	if !strings.Contains(raw, "token=") {
+		return raw
+	}
+	params := strings.Split(raw, "&")
+	for i, p := range params {
+		if strings.HasPrefix(p, "token=") {
+			params[i] = "token=REDACTED"
+		}
+	}
+	return strings.Join(params, "&")
+}
@@ -0,0 +1,313 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/activitypub"
+	"github.com/hereisth/web-collector/apps/backend/internal/blob"
+	"github.com/hereisth/web-collector/apps/backend/internal/coord"
+	"github.com/hereisth/web-collector/apps/backend/internal/discord"
+	"github.com/hereisth/web-collector/apps/backend/internal/encrypt"
+	"github.com/hereisth/web-collector/apps/backend/internal/flags"
+	"github.com/hereisth/web-collector/apps/backend/internal/mailin"
+	"github.com/hereisth/web-collector/apps/backend/internal/notify"
+	"github.com/hereisth/web-collector/apps/backend/internal/scheduler"
+	"github.com/hereisth/web-collector/apps/backend/internal/search"
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+	"github.com/hereisth/web-collector/apps/backend/internal/sentry"
+	"github.com/hereisth/web-collector/apps/backend/internal/slack"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+	"github.com/hereisth/web-collector/apps/backend/internal/telegram"
+	"github.com/hereisth/web-collector/apps/backend/internal/webmention"
+)
+
+// Config check outcomes
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// ConfigCheck is one line of the `server check-config` report
+type ConfigCheck struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// insecureJWTSecrets are placeholder values that must not reach production
+var insecureJWTSecrets = map[string]bool{
+	"secret":                      true,
+	"your-secret-key-change-this": true,
+}
+
+// CheckConfig validates cfg and probes the services it points at, without
+// starting the HTTP server.
+func CheckConfig(cfg *Config) []ConfigCheck {
+	var checks []ConfigCheck
+	add := func(name, status, format string, args ...interface{}) {
+		checks = append(checks, ConfigCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	// Database
+	switch cfg.Database.Driver {
+	case "", "memory":
+		add("database", CheckWarn, "in-memory store; data is lost on restart")
+	case "postgres":
+		m, db, err := storage.OpenMigrator(cfg.Database.DSN())
+		if err != nil {
+			add("database", CheckFail, "%v", err)
+			break
+		}
+		pending, err := m.Pending(context.Background())
+		db.Close()
+		switch {
+		case err != nil:
+			add("database", CheckFail, "check migrations: %v", err)
+		case pending > 0 && !cfg.Database.AutoMigrate:
+			add("database", CheckFail, "connected; %d pending migration(s), run `server migrate up`", pending)
+		case pending > 0:
+			add("database", CheckWarn, "connected; %d pending migration(s) will be applied at startup", pending)
+		default:
+			add("database", CheckOK, "connected to %s:%s/%s, schema up to date", cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName)
+		}
+		if cfg.Database.ReplicaDSN != "" {
+			if _, db, err := storage.OpenMigrator(cfg.Database.ReplicaDSN); err != nil {
+				add("database replica", CheckFail, "%v", err)
+			} else {
+				db.Close()
+				add("database replica", CheckOK, "connected")
+			}
+		}
+	default:
+		// Third-party drivers are only checked for being compiled in; how
+		// to probe them is up to the driver.
+		if !slices.Contains(storage.Drivers(), cfg.Database.Driver) {
+			add("database", CheckFail, "unknown driver %q (available: %v)", cfg.Database.Driver, storage.Drivers())
+		} else {
+			add("database", CheckOK, "driver %q is registered", cfg.Database.Driver)
+		}
+	}
+
+	// Search
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	_, err := search.Open(ctx, cfg.Search)
+	cancel()
+	if err != nil {
+		add("search", CheckFail, "%v", err)
+	} else if cfg.Search.Backend == "" {
+		add("search", CheckOK, "substring matching, no index")
+	} else {
+		add("search", CheckOK, "%s index", cfg.Search.Backend)
+	}
+
+	// JWT
+	switch {
+	case insecureJWTSecrets[cfg.JWTSecret]:
+		add("jwt secret", CheckFail, "JWT_SECRET is a placeholder; the server will not start")
+	case len(cfg.JWTSecret) < 32:
+		add("jwt secret", CheckWarn, "JWT_SECRET is only %d bytes; use at least 32", len(cfg.JWTSecret))
+	default:
+		add("jwt secret", CheckOK, "%d bytes", len(cfg.JWTSecret))
+	}
+	if d, err := time.ParseDuration(cfg.JWTExpiration); err != nil || d <= 0 {
+		add("jwt expiration", CheckFail, "JWT_EXPIRATION %q is not a positive duration", cfg.JWTExpiration)
+	} else {
+		add("jwt expiration", CheckOK, "%v", d)
+	}
+
+	// Storage paths
+	switch cfg.Blob.Driver {
+	case "", "local":
+		if err := checkWritableDir(cfg.Blob.Dir); err != nil {
+			add("blob storage", CheckFail, "%s: %v", cfg.Blob.Dir, err)
+		} else {
+			add("blob storage", CheckOK, "%s is writable", cfg.Blob.Dir)
+		}
+	default:
+		blobs, err := blob.Open(cfg.Blob)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			_, err = blobs.Usage(ctx, "exports/")
+			cancel()
+		}
+		if err != nil {
+			add("blob storage", CheckFail, "%s: %v", cfg.Blob.Driver, err)
+		} else if cfg.Blob.Driver == "s3" {
+			add("blob storage", CheckOK, "s3 bucket %s is reachable", cfg.Blob.S3Bucket)
+		} else {
+			add("blob storage", CheckOK, "%s is reachable", cfg.Blob.Driver)
+		}
+	}
+	switch {
+	case cfg.TLSCertFile == "" && cfg.TLSKeyFile == "":
+		add("tls", CheckOK, "disabled")
+	case cfg.TLSCertFile == "" || cfg.TLSKeyFile == "":
+		add("tls", CheckFail, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	default:
+		if _, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			add("tls", CheckFail, "%v", err)
+		} else {
+			add("tls", CheckOK, "certificate loaded")
+		}
+	}
+
+	if cfg.EncryptionKey != "" {
+		if _, err := encrypt.ParseKey(cfg.EncryptionKey); err != nil {
+			add("encryption key", CheckFail, "ENCRYPTION_KEY: %v", err)
+		} else {
+			add("encryption key", CheckOK, "files are encrypted at rest")
+		}
+	} else {
+		add("encryption key", CheckWarn, "ENCRYPTION_KEY not set; files are stored in plaintext")
+	}
+
+	// CORS
+	if cors, err := parseCORSOrigins(cfg.CORSAllowedOrigins); err != nil {
+		add("cors", CheckFail, "CORS_ALLOWED_ORIGINS: %v", err)
+	} else if cors.any {
+		add("cors", CheckWarn, "any origin is allowed")
+	} else if len(cors.exact) == 0 && len(cors.patterns) == 0 {
+		add("cors", CheckWarn, "no origin is allowed")
+	} else {
+		add("cors", CheckOK, "%s", cfg.CORSAllowedOrigins)
+	}
+
+	// Proxies
+	if proxies, err := parseTrustedProxies(cfg.TrustedProxies); err != nil {
+		add("trusted proxies", CheckFail, "TRUSTED_PROXIES: %v", err)
+	} else if len(proxies) == 0 {
+		add("trusted proxies", CheckOK, "none; X-Forwarded-For is ignored")
+	} else {
+		add("trusted proxies", CheckOK, "%s", strings.Join(proxies, ", "))
+	}
+
+	// Optional services and settings parsed at startup
+	if cfg.RedisURL != "" {
+		if rc, err := coord.NewRedis(cfg.RedisURL); err != nil {
+			add("redis", CheckFail, "%v", err)
+		} else {
+			rc.Close()
+			add("redis", CheckOK, "connected")
+		}
+	}
+	if cfg.SentryDSN != "" {
+		if client, err := sentry.New(cfg.SentryDSN, cfg.SentryEnvironment); err != nil {
+			add("sentry", CheckFail, "%v", err)
+		} else {
+			client.Close(0)
+			add("sentry", CheckOK, "DSN is valid")
+		}
+	}
+	if _, ok := logLevels[cfg.LogLevel]; !ok {
+		add("log level", CheckFail, "LOG_LEVEL %q is not one of debug, info, warn, error", cfg.LogLevel)
+	}
+	if cfg.TrashRetention < 30*24*time.Hour {
+		add("trash retention", CheckWarn, "deleted bookmarks are purged after %s, less than 30 days", cfg.TrashRetention)
+	}
+	if _, err := flags.Parse(cfg.FeatureFlags); err != nil {
+		add("feature flags", CheckFail, "%v", err)
+	}
+	for _, job := range [][2]string{
+		{"dead-link-check", cfg.Jobs.DeadLinkCheck},
+		{"export-cleanup", cfg.Jobs.ExportCleanup},
+		{"archive-gc", cfg.Jobs.ArchiveGC},
+		{"search-sync", cfg.Jobs.SearchSync},
+		{"reminders", cfg.Jobs.Reminders},
+		{"auto-archive", cfg.Jobs.AutoArchive},
+		{"purge-trash", cfg.Jobs.PurgeTrash},
+		{"poll-discord", cfg.Jobs.PollDiscord},
+		{"push-readwise", cfg.Jobs.PushReadwise},
+		{"pull-integrations", cfg.Jobs.PullIntegrations},
+		{"hook-retries", cfg.Jobs.HookRetries},
+	} {
+		if job[1] == "" {
+			continue
+		}
+		if _, err := scheduler.Parse(job[1]); err != nil {
+			add("job "+job[0], CheckFail, "%v", err)
+		}
+	}
+	if n, err := notify.Open(cfg.Notify); err != nil {
+		add("notifications", CheckFail, "%v", err)
+	} else if n == nil {
+		add("notifications", CheckWarn, "no SMTP_ADDR or REMINDER_WEBHOOK_URL; reminders are only listed at /api/v1/reminders")
+	} else {
+		add("notifications", CheckOK, "reminder delivery configured")
+	}
+	if bot, err := telegram.Open(cfg.Telegram); err != nil {
+		add("telegram", CheckFail, "%v", err)
+	} else if bot != nil && strings.TrimSpace(cfg.Telegram.AllowedChats) == "" {
+		add("telegram", CheckWarn, "no TELEGRAM_ALLOWED_CHATS; the bot saves nothing and replies with the chat ID")
+	} else if bot != nil {
+		add("telegram", CheckOK, "bot configured")
+	}
+	if app, err := slack.Open(cfg.Slack); err != nil {
+		add("slack", CheckFail, "%v", err)
+	} else if app != nil && cfg.Slack.CollectionID == "" {
+		add("slack", CheckWarn, "no SLACK_COLLECTION_ID; /collect saves bookmarks outside any collection")
+	} else if app != nil && !app.CanUnfurl() {
+		add("slack", CheckWarn, "no SLACK_BOT_TOKEN; saved links are not unfurled")
+	} else if app != nil {
+		add("slack", CheckOK, "app configured")
+	}
+	if bot, err := discord.Open(cfg.Discord); err != nil {
+		add("discord", CheckFail, "%v", err)
+	} else if bot != nil && len(bot.Channels()) == 0 {
+		add("discord", CheckWarn, "no DISCORD_CHANNELS; the bot watches nothing and /save is refused everywhere")
+	} else if bot != nil && cfg.Jobs.PollDiscord == "" {
+		add("discord", CheckWarn, "JOB_POLL_DISCORD is empty; channels are not watched, only /save works")
+	} else if bot != nil {
+		add("discord", CheckOK, "watching %d channels", len(bot.Channels()))
+	}
+	if in, err := mailin.Open(cfg.MailIn); err != nil {
+		add("inbound mail", CheckFail, "%v", err)
+	} else if in != nil && strings.TrimSpace(cfg.MailIn.Senders) == "" {
+		add("inbound mail", CheckOK, "accepting mail from account addresses")
+	} else if in != nil {
+		add("inbound mail", CheckOK, "accepting mail from account addresses and MAIL_IN_SENDERS")
+	}
+	if fed, err := activitypub.Open(cfg.Federation); err != nil {
+		add("federation", CheckFail, "%v", err)
+	} else if fed != nil && (cfg.Database.Driver == "" || cfg.Database.Driver == "memory") {
+		add("federation", CheckWarn, "the in-memory store forgets actor keys and followers on restart")
+	} else if fed != nil {
+		add("federation", CheckOK, "publishing public profiles as @username@%s", fed.Host())
+	}
+	if wm, err := webmention.Open(cfg.Webmention); err != nil {
+		add("webmention", CheckFail, "%v", err)
+	} else if wm != nil {
+		add("webmention", CheckOK, "public bookmark pages under %s/u/", wm.SiteURL())
+	}
+	if cfg.SeedData != "" {
+		if _, err := seed.Load(cfg.SeedData); err != nil {
+			add("seed data", CheckFail, "%v", err)
+		}
+	}
+	if cfg.AdminToken == "" && cfg.AdminEmail == "" {
+		add("admin", CheckWarn, "no ADMIN_TOKEN or ADMIN_EMAIL; admin API only reachable with existing accounts")
+	} else if cfg.AdminPassword != "" && len(cfg.AdminPassword) < minPasswordLength {
+		add("admin", CheckFail, "ADMIN_PASSWORD must be at least %d characters", minPasswordLength)
+	}
+
+	return checks
+}
+
+// checkWritableDir creates dir if needed and verifies a file can be written
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/sanitize"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global clip store; nil when the storage driver cannot keep clips
+var clips storage.ClipStore
+
+// RequireClips hides the clip routes when the store cannot keep clips
+func RequireClips() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if clips == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Clips are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleAddClip stores an HTML fragment captured from a bookmarked page.
+// Clients sanitize what they send, but the fragment is sanitized again
+// here since it is rendered back as HTML.
+func handleAddClip(c *gin.Context) {
+	var req model.ClipRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	clean := sanitize.HTML(req.HTML)
+	text := sanitize.Text(clean)
+	if strings.TrimSpace(text) == "" && !strings.Contains(clean, "<img") {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Clip has no content")
+		return
+	}
+
+	clip, err := clips.AddClip(c.Request.Context(), model.Clip{BookmarkID: c.Param("id"), HTML: clean, Text: text})
+	if err != nil {
+		clipError(c, err)
+		return
+	}
+	publishBookmarkEvent("updated", clip.BookmarkID)
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    clip,
+	})
+}
+
+// handleGetClips returns a bookmark's clips, oldest first. Their HTML is
+// sanitized again on the way out, covering clips saved before the
+// sanitizer learned of a new way to inject script.
+func handleGetClips(c *gin.Context) {
+	result, err := clips.BookmarkClips(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		clipError(c, err)
+		return
+	}
+	for i := range result {
+		result[i].HTML = sanitize.HTML(result[i].HTML)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// handleDeleteClip removes one of a bookmark's clips
+func handleDeleteClip(c *gin.Context) {
+	if err := clips.DeleteClip(c.Request.Context(), c.Param("id"), c.Param("clipId")); err != nil {
+		clipError(c, err)
+		return
+	}
+	publishBookmarkEvent("updated", c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Clip deleted",
+	})
+}
+
+func clipError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrBookmarkNotFound):
+		respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found")
+	case errors.Is(err, storage.ErrClipNotFound):
+		respondError(c, http.StatusNotFound, apierr.ClipNotFound, "Clip not found")
+	default:
+		log.Printf("Clip operation failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Clip operation failed")
+	}
+}
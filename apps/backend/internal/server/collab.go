@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// collectionEventsChannel carries JSON model.CollectionEvent messages
+// between instances
+const collectionEventsChannel = "collections"
+
+const (
+	// eventBuffer is how many events a slow watcher may fall behind before
+	// events are dropped for it
+	eventBuffer = 64
+	// eventHeartbeat keeps idle event streams open through proxies
+	eventHeartbeat = 25 * time.Second
+)
+
+// collectionFeed fans collection events out to the watchers connected to
+// this instance. It subscribes to the coordinator once rather than once
+// per watcher.
+var collectionFeed = &collectionHub{watchers: make(map[chan model.CollectionEvent]string)}
+
+type collectionHub struct {
+	mu       sync.Mutex
+	watchers map[chan model.CollectionEvent]string // -> collection ID
+}
+
+// watch returns a channel of the events of one collection and a function
+// to stop watching
+func (h *collectionHub) watch(id string) (<-chan model.CollectionEvent, func()) {
+	ch := make(chan model.CollectionEvent, eventBuffer)
+	h.mu.Lock()
+	h.watchers[ch] = id
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.watchers, ch)
+		h.mu.Unlock()
+	}
+}
+
+// dispatch delivers an event from any instance to the local watchers
+func (h *collectionHub) dispatch(message string) {
+	var ev model.CollectionEvent
+	if err := json.Unmarshal([]byte(message), &ev); err != nil {
+		log.Printf("Invalid collection event: %v", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, id := range h.watchers {
+		if id != ev.CollectionID {
+			continue
+		}
+		select {
+		case ch <- ev:
+		default:
+			// The watcher reconnects and refetches once it notices the gap.
+		}
+	}
+}
+
+// publishCollectionEvent notifies the watchers of a collection on every
+// instance
+func publishCollectionEvent(c *gin.Context, ev model.CollectionEvent) {
+	publishCollectionEventAs(currentActor(c).AccountID, ev)
+}
+
+// publishCollectionEventAs publishes ev as made by actorID, for changes
+// made outside a signed-in request
+func publishCollectionEventAs(actorID string, ev model.CollectionEvent) {
+	ev.ActorID = actorID
+	ev.At = time.Now()
+	if ev.Type == model.EventBookmarkAdded {
+		federationDeliveries.enqueue(ev.CollectionID, ev.BookmarkID)
+		webmentions.enqueue(ev.BookmarkID)
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("Failed to encode collection event: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	// Collection changes decide who can see which bookmarks
+	invalidateResponses(ctx)
+	if err := coordinator.Publish(ctx, collectionEventsChannel, string(data)); err != nil {
+		log.Printf("Failed to publish collection event: %v", err)
+	}
+}
+
+// handleCollectionEvents streams a collection's change events as
+// server-sent events until the client disconnects. Events are not
+// replayed, so clients fetch the collection after connecting and apply
+// events from there.
+func handleCollectionEvents(c *gin.Context) {
+	id := c.Param("id")
+	if _, found := collectionsFor(c).GetCollection(c.Request.Context(), id); !found {
+		collectionError(c, storage.ErrCollectionNotFound)
+		return
+	}
+
+	events, stop := collectionFeed.watch(id)
+	defer stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, ": watching\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		case ev := <-events:
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", ev.Type, data)
+			if ev.Type == model.EventCollectionDeleted {
+				c.Writer.Flush()
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// descendants returns the IDs of the collections below id
+func descendants(all []model.Collection, id string) []string {
+	var result []string
+	below := map[string]bool{id: true}
+	for grew := true; grew; {
+		grew = false
+		for _, col := range all {
+			if below[col.ParentID] && !below[col.ID] {
+				below[col.ID] = true
+				result = append(result, col.ID)
+				grew = true
+			}
+		}
+	}
+	return result
+}
+
+// createCollectionBookmark creates a bookmark directly in a collection
+// the caller can edit
+func createCollectionBookmark(c *gin.Context, id string, req *model.CreateBookmarkRequest) {
+	if err := storage.CheckAccess(c.Request.Context(), collections, currentActor(c), id, model.AccessEdit); err != nil {
+		collectionError(c, err)
+		return
+	}
+	if !cleanTitle(c, "title", &req.Title) {
+		return
+	}
+	req.URL = normalizeURL(c.Request.Context(), req.URL)
+	if !allowedDomain(c, req.URL) {
+		return
+	}
+	bookmark, err := createBookmark(c.Request.Context(), currentActor(c).AccountID, req.Title, req.URL, req.Tags)
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	bookmark = withSelection(c.Request.Context(), bookmark, req.Selection)
+	indexBookmark(c.Request.Context(), bookmark)
+	publishBookmarkEvent("created", bookmark.ID)
+	thumbnails.enqueue(bookmark.ID)
+	readingTimes.enqueue(bookmark.ID)
+	if err := collections.AddToCollection(c.Request.Context(), id, []string{bookmark.ID}); err != nil {
+		collectionError(c, err)
+		return
+	}
+	publishCollectionEvent(c, model.CollectionEvent{
+		Type:         model.EventBookmarkAdded,
+		CollectionID: id,
+		BookmarkID:   bookmark.ID,
+		Bookmark:     &bookmark,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
+
+// handleUpdateCollectionBookmark edits a bookmark through a collection
+// the caller can edit, so collaborators need no access beyond the
+// collection. Concurrent edits are not merged: the last write wins, and
+// every watcher receives it.
+func handleUpdateCollectionBookmark(c *gin.Context) {
+	var req model.UpdateBookmarkRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	id, bookmarkID := c.Param("id"), c.Param("bookmarkId")
+	if err := storage.CheckAccess(c.Request.Context(), collections, currentActor(c), id, model.AccessEdit); err != nil {
+		collectionError(c, err)
+		return
+	}
+	ids, err := collections.CollectionBookmarks(c.Request.Context(), id, false)
+	if err != nil {
+		collectionError(c, err)
+		return
+	}
+	if !slices.Contains(ids, bookmarkID) {
+		collectionError(c, storage.ErrBookmarkNotFound)
+		return
+	}
+	if !cleanUpdate(c, &req) {
+		return
+	}
+
+	var bookmark model.Bookmark
+	err = writeBookmark(c, bookmarkID, func(tx storage.Store) error {
+		var err error
+		bookmark, err = updateBookmark(c.Request.Context(), tx, bookmarkID, req)
+		return err
+	})
+	if err != nil {
+		collectionError(c, err)
+		return
+	}
+	indexBookmark(c.Request.Context(), bookmark)
+	publishBookmarkEvent("updated", bookmarkID)
+	if req.URL != nil {
+		thumbnails.enqueue(bookmarkID)
+		readingTimes.enqueue(bookmarkID)
+	}
+	if req.Notes != nil {
+		webmentions.enqueue(bookmarkID)
+	}
+	setBookmarkValidators(c, bookmark)
+	publishCollectionEvent(c, model.CollectionEvent{
+		Type:         model.EventBookmarkUpdated,
+		CollectionID: id,
+		BookmarkID:   bookmarkID,
+		Bookmark:     &bookmark,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
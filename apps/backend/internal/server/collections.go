@@ -0,0 +1,413 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global collection store; nil when the storage driver has no collection
+// support
+var collections storage.CollectionStore
+
+// collectionsFor returns the collection store as seen by the request's
+// actor, which enforces their access to each collection
+func collectionsFor(c *gin.Context) storage.CollectionStore {
+	return storage.Restrict(collections, currentActor(c))
+}
+
+// RequireCollections hides the collection routes when the store cannot
+// hold collections
+func RequireCollections() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if collections == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Collections are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// collectionError writes the response for an error from the collection
+// store
+func collectionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		respondError(c, http.StatusNotFound, apierr.CollectionNotFound, "Collection not found")
+	case errors.Is(err, storage.ErrBookmarkNotFound):
+		respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, err.Error())
+	case errors.Is(err, storage.ErrShareNotFound):
+		respondError(c, http.StatusNotFound, apierr.ShareNotFound, "Share not found")
+	case errors.Is(err, storage.ErrForbidden):
+		respondError(c, http.StatusForbidden, apierr.Forbidden, err.Error())
+	case errors.Is(err, storage.ErrCollectionCycle):
+		respondError(c, http.StatusConflict, apierr.Conflict, err.Error())
+	case errors.Is(err, errPreconditionFailed):
+		respondError(c, http.StatusPreconditionFailed, apierr.PreconditionFailed, "Bookmark has changed since it was read")
+	default:
+		log.Printf("Collection operation failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Collection operation failed")
+	}
+}
+
+// handleGetCollections returns every collection the caller can see;
+// clients build the tree from parent_id
+func handleGetCollections(c *gin.Context) {
+	cols := collectionsFor(c).ListCollections(c.Request.Context())
+	p, ok := paginate(c, len(cols))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       cols[p.start:p.end],
+		"pagination": p,
+	})
+}
+
+// handleCreateCollection adds a collection, optionally below a parent
+func handleCreateCollection(c *gin.Context) {
+	var req model.CreateCollectionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	collection, err := collectionsFor(c).CreateCollection(c.Request.Context(), req.Name, req.ParentID, "")
+	if err != nil {
+		collectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    collection,
+	})
+}
+
+// handleGetCollection returns a single collection
+func handleGetCollection(c *gin.Context) {
+	collection, found := collectionsFor(c).GetCollection(c.Request.Context(), c.Param("id"))
+	if !found {
+		collectionError(c, storage.ErrCollectionNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    collection,
+	})
+}
+
+// handleUpdateCollection renames a collection
+func handleUpdateCollection(c *gin.Context) {
+	var req model.UpdateCollectionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	collection, err := collectionsFor(c).RenameCollection(c.Request.Context(), c.Param("id"), req.Name)
+	if err != nil {
+		collectionError(c, err)
+		return
+	}
+	publishCollectionEvent(c, model.CollectionEvent{
+		Type:         model.EventCollectionUpdated,
+		CollectionID: collection.ID,
+		Collection:   &collection,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    collection,
+	})
+}
+
+// handleDeleteCollection removes a collection and everything below it.
+// The bookmarks themselves are kept.
+func handleDeleteCollection(c *gin.Context) {
+	cs := collectionsFor(c)
+	id := c.Param("id")
+	subtree := descendants(cs.ListCollections(c.Request.Context()), id)
+	if err := cs.DeleteCollection(c.Request.Context(), id); err != nil {
+		collectionError(c, err)
+		return
+	}
+	for _, deleted := range append(subtree, id) {
+		publishCollectionEvent(c, model.CollectionEvent{
+			Type:         model.EventCollectionDeleted,
+			CollectionID: deleted,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Collection deleted",
+	})
+}
+
+// handleMoveCollection moves a collection and its subtree under a new
+// parent
+func handleMoveCollection(c *gin.Context) {
+	var req model.MoveCollectionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	collection, err := collectionsFor(c).MoveCollection(c.Request.Context(), c.Param("id"), req.ParentID)
+	if err != nil {
+		collectionError(c, err)
+		return
+	}
+	publishCollectionEvent(c, model.CollectionEvent{
+		Type:         model.EventCollectionUpdated,
+		CollectionID: collection.ID,
+		Collection:   &collection,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    collection,
+	})
+}
+
+// handleMergeCollections folds the source collection's children and
+// bookmarks into the target and deletes the source
+func handleMergeCollections(c *gin.Context) {
+	var req model.MergeCollectionsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	collection, err := collectionsFor(c).MergeCollections(c.Request.Context(), req.Source, req.Target)
+	if err != nil {
+		collectionError(c, err)
+		return
+	}
+	// Watchers of the source can follow it into the target.
+	publishCollectionEvent(c, model.CollectionEvent{
+		Type:         model.EventCollectionDeleted,
+		CollectionID: req.Source,
+		Collection:   &collection,
+	})
+	publishCollectionEvent(c, model.CollectionEvent{
+		Type:         model.EventCollectionUpdated,
+		CollectionID: collection.ID,
+		Collection:   &collection,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    collection,
+	})
+}
+
+// handleGetCollectionBookmarks lists the bookmarks in a collection with
+// their reactions there; with ?recursive=true it includes the bookmarks of
+// every descendant
+func handleGetCollectionBookmarks(c *gin.Context) {
+	id := c.Param("id")
+	ids, err := collectionsFor(c).CollectionBookmarks(c.Request.Context(), id, c.Query("recursive") == "true")
+	if err != nil {
+		collectionError(c, err)
+		return
+	}
+	var tally map[string][]model.ReactionCount
+	if reactions != nil {
+		all, err := reactions.CollectionReactions(c.Request.Context(), id)
+		if err != nil {
+			collectionError(c, err)
+			return
+		}
+		tally = tallyReactions(all, currentActor(c).AccountID)
+	}
+	bookmarks := make([]collectionBookmark, 0, len(ids))
+	for _, bookmarkID := range ids {
+		if b, err := store.GetByID(c.Request.Context(), bookmarkID); err == nil {
+			counts := tally[bookmarkID]
+			if counts == nil {
+				counts = []model.ReactionCount{}
+			}
+			bookmarks = append(bookmarks, collectionBookmark{Bookmark: b, Reactions: counts})
+		}
+	}
+	p, ok := paginate(c, len(bookmarks))
+	if !ok {
+		return
+	}
+	page := bookmarks[p.start:p.end]
+	plain := make([]model.Bookmark, len(page))
+	for i, b := range page {
+		plain[i] = b.Bookmark
+	}
+	respondBookmarkList(c, p, page, plain)
+}
+
+// handleAddCollectionBookmarks adds existing bookmarks to a collection,
+// or creates a bookmark in it
+func handleAddCollectionBookmarks(c *gin.Context) {
+	var req model.CollectionBookmarksRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if (len(req.BookmarkIDs) == 0) == (req.Bookmark == nil) {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Give either bookmark_ids or a bookmark to create")
+		return
+	}
+	id := c.Param("id")
+	if req.Bookmark != nil {
+		createCollectionBookmark(c, id, req.Bookmark)
+		return
+	}
+
+	if err := collectionsFor(c).AddToCollection(c.Request.Context(), id, req.BookmarkIDs); err != nil {
+		collectionError(c, err)
+		return
+	}
+	for _, bookmarkID := range req.BookmarkIDs {
+		ev := model.CollectionEvent{Type: model.EventBookmarkAdded, CollectionID: id, BookmarkID: bookmarkID}
+		if b, err := store.GetByID(c.Request.Context(), bookmarkID); err == nil {
+			ev.Bookmark = &b
+		}
+		publishCollectionEvent(c, ev)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Bookmarks added",
+	})
+}
+
+// handleRemoveCollectionBookmark takes a bookmark out of a collection
+func handleRemoveCollectionBookmark(c *gin.Context) {
+	id, bookmarkID := c.Param("id"), c.Param("bookmarkId")
+	if err := collectionsFor(c).RemoveFromCollection(c.Request.Context(), id, bookmarkID); err != nil {
+		collectionError(c, err)
+		return
+	}
+	publishCollectionEvent(c, model.CollectionEvent{
+		Type:         model.EventBookmarkRemoved,
+		CollectionID: id,
+		BookmarkID:   bookmarkID,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Bookmark removed from collection",
+	})
+}
+
+// handleGetCollectionShares lists who a collection is shared with; only
+// its owners see the shares
+func handleGetCollectionShares(c *gin.Context) {
+	cs := collectionsFor(c)
+	id := c.Param("id")
+	if _, found := cs.GetCollection(c.Request.Context(), id); !found {
+		collectionError(c, storage.ErrCollectionNotFound)
+		return
+	}
+	shares := []model.CollectionShare{}
+	for _, sh := range cs.ListShares(c.Request.Context()) {
+		if sh.CollectionID == id {
+			shares = append(shares, sh)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    shares,
+	})
+}
+
+// handleShareCollection grants an account, named by email, or the whole
+// workspace view or edit access to a collection and everything below it,
+// or makes it publicly viewable
+func handleShareCollection(c *gin.Context) {
+	var req model.ShareCollectionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	targets := 0
+	for _, set := range []bool{req.Email != "", req.Workspace, req.Public} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Share with exactly one of an email, the workspace or the public")
+		return
+	}
+	if req.Public && req.Access != model.AccessView {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Public shares are view-only")
+		return
+	}
+
+	grantee := model.GranteeWorkspace
+	switch {
+	case req.Public:
+		grantee = model.GranteePublic
+	case req.Email != "":
+		var account model.Account
+		found := false
+		if accounts != nil {
+			account, found = accounts.GetAccountByEmail(c.Request.Context(), req.Email)
+		}
+		if !found {
+			respondError(c, http.StatusNotFound, apierr.AccountNotFound, "Account not found")
+			return
+		}
+		grantee = account.ID
+	}
+
+	share := model.CollectionShare{CollectionID: c.Param("id"), Grantee: grantee, Access: req.Access}
+	if err := collectionsFor(c).ShareCollection(c.Request.Context(), share.CollectionID, share.Grantee, share.Access); err != nil {
+		collectionError(c, err)
+		return
+	}
+	invalidateResponses(c.Request.Context())
+	recordActivity(c, model.Activity{
+		Kind:         model.ActivityShared,
+		CollectionID: share.CollectionID,
+		Detail:       fmt.Sprintf("%s access for %s", share.Access, granteeName(c.Request.Context(), share.Grantee)),
+	})
+	if share.Grantee == model.GranteePublic {
+		// Bookmarks with notes are now public pages mentioning their URLs.
+		if ids, err := collections.CollectionBookmarks(c.Request.Context(), share.CollectionID, false); err == nil {
+			for _, id := range ids {
+				webmentions.enqueue(id)
+			}
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    share,
+	})
+}
+
+// handleUnshareCollection revokes a share; the grantee is an account ID,
+// "workspace" or "public"
+func handleUnshareCollection(c *gin.Context) {
+	if err := collectionsFor(c).UnshareCollection(c.Request.Context(), c.Param("id"), c.Param("grantee")); err != nil {
+		collectionError(c, err)
+		return
+	}
+	invalidateResponses(c.Request.Context())
+	recordActivity(c, model.Activity{
+		Kind:         model.ActivityUnshared,
+		CollectionID: c.Param("id"),
+		Detail:       "revoked access for " + granteeName(c.Request.Context(), c.Param("grantee")),
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Share revoked",
+	})
+}
+
+// granteeName describes a share's grantee for the activity feed
+func granteeName(ctx context.Context, grantee string) string {
+	switch grantee {
+	case model.GranteeWorkspace:
+		return "the workspace"
+	case model.GranteePublic:
+		return "the public"
+	}
+	if accounts != nil {
+		if account, found := accounts.GetAccount(ctx, grantee); found {
+			return account.Email
+		}
+	}
+	return "account " + grantee
+}
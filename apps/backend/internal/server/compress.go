@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// compressibleTypes lists the content types worth compressing. Images and
+// other already-compressed payloads are passed through untouched.
+var compressibleTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/javascript",
+	"application/rss+xml",
+	"application/atom+xml",
+	"text/",
+	"image/svg+xml",
+}
+
+var gzipPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	},
+}
+
+var brotliPool = sync.Pool{
+	New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	},
+}
+
+// Compress middleware encodes text-like responses with brotli or gzip,
+// depending on what the client advertises in Accept-Encoding.
+func Compress(minLength int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			minLength:      minLength,
+		}
+		c.Writer = cw
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		defer cw.Close()
+
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks the preferred supported encoding, ignoring
+// anything the client explicitly disabled with q=0.
+func negotiateEncoding(header string) string {
+	var gzipOK, brOK bool
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(params, " ", "") == "q=0" {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "br":
+			brOK = true
+		case "gzip":
+			gzipOK = true
+		}
+	}
+	switch {
+	case brOK:
+		return "br"
+	case gzipOK:
+		return "gzip"
+	}
+	return ""
+}
+
+func isCompressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, t := range compressibleTypes {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the first minLength bytes so tiny responses are
+// sent as-is, then switches to a pooled encoder for the rest of the body.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding  string
+	minLength int
+
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) < w.minLength {
+		return len(data), nil
+	}
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// start decides whether to compress and flushes anything buffered so far.
+func (w *compressWriter) start() error {
+	w.decided = true
+
+	header := w.Header()
+	if header.Get("Content-Encoding") == "" && isCompressible(header.Get("Content-Type")) &&
+		w.Status() != http.StatusNoContent && w.Status() != http.StatusNotModified {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+
+		switch w.encoding {
+		case "br":
+			bw := brotliPool.Get().(*brotli.Writer)
+			bw.Reset(w.ResponseWriter)
+			w.encoder = bw
+		default:
+			gw := gzipPool.Get().(*gzip.Writer)
+			gw.Reset(w.ResponseWriter)
+			w.encoder = gw
+		}
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// Close flushes the encoder and returns it to its pool.
+func (w *compressWriter) Close() {
+	if !w.decided {
+		// The whole body fit under the threshold; send it uncompressed.
+		w.minLength = 0
+		w.decided = true
+		if len(w.buf) > 0 {
+			w.ResponseWriter.Write(w.buf)
+			w.buf = nil
+		}
+		return
+	}
+	if w.encoder == nil {
+		return
+	}
+	w.encoder.Close()
+	switch e := w.encoder.(type) {
+	case *brotli.Writer:
+		brotliPool.Put(e)
+	case *gzip.Writer:
+		gzipPool.Put(e)
+	}
+	w.encoder = nil
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.start()
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.Hijack()
+}
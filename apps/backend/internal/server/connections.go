@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global integration store; nil when the storage driver cannot keep
+// accounts' connections to other services
+var integrationStore storage.IntegrationStore
+
+// pushTimeout bounds one push of an account's bookmarks to a service
+const pushTimeout = 30 * time.Minute
+
+// RequireIntegrations hides the routes connecting accounts to other
+// services when the store cannot keep the connections
+func RequireIntegrations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if integrationStore == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Integrations are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleListIntegrations returns the services the signed-in account is
+// connected to
+func handleListIntegrations(c *gin.Context) {
+	result, err := integrationStore.AccountIntegrations(c.Request.Context(), currentActor(c).AccountID)
+	if err != nil {
+		integrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// handleDeleteIntegration disconnects the signed-in account from a
+// service, forgetting its token. What was pushed stays in the service.
+func handleDeleteIntegration(c *gin.Context) {
+	if err := integrationStore.DeleteIntegration(c.Request.Context(), currentActor(c).AccountID, c.Param("service")); err != nil {
+		integrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Integration removed",
+	})
+}
+
+func integrationError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrIntegrationNotFound) {
+		respondError(c, http.StatusNotFound, apierr.IntegrationNotFound, "Integration not found")
+		return
+	}
+	log.Printf("Integration operation failed: %v", err)
+	respondError(c, http.StatusInternalServerError, apierr.Internal, "Integration operation failed")
+}
+
+// checkPushCollections reports whether the signed-in account can see
+// every collection it asks to push, answering the request when not
+func checkPushCollections(c *gin.Context, ids []string) bool {
+	for _, id := range ids {
+		if err := storage.CheckAccess(c.Request.Context(), collections, currentActor(c), id, model.AccessView); err != nil {
+			collectionError(c, err)
+			return false
+		}
+	}
+	return true
+}
+
+// integrationPushes tracks the pushes and pulls running on this
+// instance, so an account syncs with a service once at a time
+var integrationPushes = &pushTracker{running: make(map[string]bool)}
+
+type pushTracker struct {
+	mu      sync.Mutex
+	running map[string]bool // account ID + service
+}
+
+// start runs push in the background for the signed-in account's
+// connection to service and records how it ended. It answers 202, or 409
+// when a sync with the service is already running.
+func (t *pushTracker) start(c *gin.Context, service string, push pushFunc) {
+	i, err := integrationStore.GetIntegration(c.Request.Context(), currentActor(c).AccountID, service)
+	if err != nil {
+		integrationError(c, err)
+		return
+	}
+	if !t.begin(i) {
+		respondError(c, http.StatusConflict, apierr.Conflict, "A sync is already running")
+		return
+	}
+	go func() {
+		defer t.end(i)
+		t.run(i, push)
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Sync started",
+	})
+}
+
+// pushFunc sends an account's bookmarks to the service it is connected
+// to, or pulls them from it
+type pushFunc func(ctx context.Context, i model.Integration) error
+
+// begin reports whether a push for i may start, marking it running
+func (t *pushTracker) begin(i model.Integration) bool {
+	key := i.AccountID + "/" + i.Service
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running[key] {
+		return false
+	}
+	t.running[key] = true
+	return true
+}
+
+// end marks the push for i finished
+func (t *pushTracker) end(i model.Integration) {
+	t.mu.Lock()
+	delete(t.running, i.AccountID+"/"+i.Service)
+	t.mu.Unlock()
+}
+
+// run pushes with a timeout and records the outcome
+func (t *pushTracker) run(i model.Integration, push pushFunc) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+
+	started := time.Now()
+	errMsg := ""
+	err := push(ctx, i)
+	if err != nil {
+		log.Printf("Sync with %s for account %s failed: %v", i.Service, i.AccountID, err)
+		errMsg = err.Error()
+	}
+	if err := integrationStore.RecordIntegrationSync(ctx, i.AccountID, i.Service, started, errMsg); err != nil && !errors.Is(err, storage.ErrIntegrationNotFound) {
+		log.Printf("Failed to record sync with %s for account %s: %v", i.Service, i.AccountID, err)
+	}
+	return err
+}
+
+// accountBookmarks returns the bookmarks in the given collections and
+// the collections below them that an account can still see, each once.
+// With no collections given, it returns those in every collection the
+// account can see.
+func accountBookmarks(ctx context.Context, accountID string, collectionIDs []string) []model.Bookmark {
+	actor := storage.Actor{AccountID: accountID}
+	if account, found := accounts.GetAccount(ctx, accountID); found {
+		actor.Admin = account.Role == model.RoleAdmin
+	}
+	visible := storage.Restrict(collections, actor)
+	if len(collectionIDs) == 0 {
+		for _, col := range visible.ListCollections(ctx) {
+			collectionIDs = append(collectionIDs, col.ID)
+		}
+	}
+
+	var result []model.Bookmark
+	seen := make(map[string]bool)
+	for _, id := range collectionIDs {
+		ids, err := visible.CollectionBookmarks(ctx, id, true)
+		if err != nil {
+			continue
+		}
+		for _, bookmarkID := range ids {
+			if seen[bookmarkID] {
+				continue
+			}
+			seen[bookmarkID] = true
+			if b, err := store.GetByID(ctx, bookmarkID); err == nil {
+				result = append(result, b)
+			}
+		}
+	}
+	return result
+}
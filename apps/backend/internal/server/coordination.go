@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/coord"
+	"github.com/hereisth/web-collector/apps/backend/internal/scheduler"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// bookmarkEventsChannel carries "<action>:<id>" messages about changed
+// bookmarks so every instance can drop stale cache entries and keep its
+// search index current.
+const bookmarkEventsChannel = "bookmarks"
+
+// jobLockTTL bounds how long a crashed instance can block a job elsewhere
+const jobLockTTL = time.Hour
+
+// Global coordinator; Redis-backed when REDIS_URL is set so that several
+// replicas can share job locks and change events.
+var coordinator coord.Coordinator = coord.NewLocal()
+
+// withLock makes a job run on only one instance at a time
+func withLock(name string, fn scheduler.JobFunc) scheduler.JobFunc {
+	return func(ctx context.Context) (string, error) {
+		release, acquired, err := coordinator.TryLock(ctx, "job:"+name, jobLockTTL)
+		if err != nil {
+			return "", fmt.Errorf("acquire job lock: %w", err)
+		}
+		if !acquired {
+			return "skipped: running on another instance", nil
+		}
+		defer release()
+		return fn(ctx)
+	}
+}
+
+// publishBookmarkEvent notifies all instances that a bookmark changed, and
+// queues the REST hooks subscribed to the change. The bookmark and cached
+// responses are dropped from the caches before it returns, so the writer's
+// next read sees the change without waiting for the event to arrive.
+func publishBookmarkEvent(action, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	evictBookmark(id)
+	invalidateResponses(ctx)
+	if err := coordinator.Publish(ctx, bookmarkEventsChannel, action+":"+id); err != nil {
+		log.Printf("Failed to publish bookmark event: %v", err)
+	}
+	hookDeliveries.enqueue(action, id)
+}
+
+// handleBookmarkEvent applies a change event from any instance
+func handleBookmarkEvent(message string) {
+	action, id, ok := strings.Cut(message, ":")
+	if !ok {
+		return
+	}
+	evictBookmark(id)
+	invalidateResponses(context.Background())
+	if action == "deleted" {
+		unindexBookmark(context.Background(), id)
+	} else if b, err := store.GetByID(context.Background(), id); err == nil {
+		indexBookmark(context.Background(), b)
+	}
+}
+
+// evictBookmark drops a bookmark from this instance's cache, for writes
+// made through stores the cache does not wrap and for other instances'
+// writes
+func evictBookmark(id string) {
+	if cached, ok := store.(*storage.CachedStore); ok {
+		cached.Invalidate(id)
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// corsPolicy is the parsed CORS_ALLOWED_ORIGINS list
+type corsPolicy struct {
+	// any is set by a "*" entry
+	any      bool
+	exact    map[string]bool
+	patterns []originPattern
+}
+
+// originPattern is an entry with a wildcard: "https://*.example.com"
+// allows every subdomain of example.com, but not example.com itself, and
+// "http://localhost:*" allows every port
+type originPattern struct {
+	scheme string
+	// domain is the host after "*.", or the whole host when only the
+	// port is a wildcard
+	domain    string
+	subdomain bool
+	// port is "" for the scheme's default port and "*" for any port
+	port string
+}
+
+// parseCORSOrigins parses a comma-separated list of origins, each "*" or
+// scheme://host[:port] where the host may start with "*." and the port
+// may be "*". An empty list allows no cross-origin requests.
+func parseCORSOrigins(list string) (*corsPolicy, error) {
+	p := &corsPolicy{exact: make(map[string]bool)}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(entry), "/"))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			p.any = true
+			continue
+		}
+		scheme, host, ok := strings.Cut(entry, "://")
+		if !ok || (scheme != "http" && scheme != "https" && scheme != "chrome-extension" && scheme != "moz-extension") || host == "" {
+			return nil, fmt.Errorf("%q must look like https://example.com", entry)
+		}
+		if strings.ContainsAny(host, "/?#@") {
+			return nil, fmt.Errorf("%q must not contain a path or query", entry)
+		}
+		if !strings.Contains(host, "*") {
+			if _, err := url.Parse(entry); err != nil {
+				return nil, err
+			}
+			p.exact[entry] = true
+			continue
+		}
+
+		pattern := originPattern{scheme: scheme, domain: host}
+		if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+			pattern.domain, pattern.port = host[:i], host[i+1:]
+		}
+		pattern.domain = strings.Trim(pattern.domain, "[]")
+		if rest, ok := strings.CutPrefix(pattern.domain, "*."); ok {
+			pattern.domain, pattern.subdomain = rest, true
+		}
+		if pattern.domain == "" || strings.Contains(pattern.domain, "*") || (pattern.port != "*" && strings.Contains(pattern.port, "*")) {
+			return nil, fmt.Errorf("%q: only a leading \"*.\" in the host or a \"*\" port is allowed", entry)
+		}
+		p.patterns = append(p.patterns, pattern)
+	}
+	return p, nil
+}
+
+// allows reports whether requests from origin, the value of an Origin
+// header, may read responses
+func (p *corsPolicy) allows(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	origin = strings.ToLower(origin)
+	if p.any || p.exact[origin] {
+		return true
+	}
+	if len(p.patterns) == 0 {
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host, port := u.Hostname(), u.Port()
+	for _, pattern := range p.patterns {
+		if pattern.scheme != u.Scheme || (pattern.port != "*" && pattern.port != port) {
+			continue
+		}
+		if pattern.subdomain {
+			if strings.HasSuffix(host, "."+pattern.domain) {
+				return true
+			}
+		} else if host == pattern.domain {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/discord"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global Discord bot; nil unless DISCORD_BOT_TOKEN is set
+var discordBot *discord.Bot
+
+// discordSavedEmoji is the reaction left on messages whose link was saved
+const discordSavedEmoji = "✅"
+
+// RequireDiscord hides the interactions endpoint when no bot is configured
+// or it has no public key to verify interactions with
+func RequireDiscord() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if discordBot == nil || !discordBot.Interactive() {
+			respondError(c, http.StatusNotFound, apierr.FeatureDisabled, "Discord bot is not configured")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleDiscordInteraction answers the /save command, which saves a link
+// into the collection mapped to the channel it is run in. Discord checks
+// that the endpoint rejects requests with a bad signature, so those are
+// answered with 401 before anything else.
+func handleDiscordInteraction(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIntegrationRequestBytes))
+	if err != nil || !discordBot.Verify(c.Request.Header, body) {
+		respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Unauthorized")
+		return
+	}
+	var interaction discord.Interaction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Invalid interaction")
+		return
+	}
+	if interaction.Type == discord.InteractionPing {
+		c.JSON(http.StatusOK, discord.Pong())
+		return
+	}
+	c.JSON(http.StatusOK, saveDiscordCommand(c.Request.Context(), interaction))
+}
+
+// saveDiscordCommand saves the link given to /save and returns the reply
+func saveDiscordCommand(ctx context.Context, i discord.Interaction) discord.Response {
+	if i.Type != discord.InteractionCommand || i.Data.Name != "save" {
+		return discord.Ephemeral("Unknown command.")
+	}
+	collectionID, ok := discordBot.Collection(i.ChannelID)
+	if !ok {
+		return discord.Ephemeral(fmt.Sprintf("This channel (ID %s) is not mapped to a collection. Add it to DISCORD_CHANNELS.", i.ChannelID))
+	}
+	link := integrationLink{URL: strings.TrimSpace(i.Option("url")), Title: i.Option("title")}
+	if link.URL == "" {
+		return discord.Ephemeral("Usage: /save url:<link> [title:<title>] [tags:<tag, tag>]")
+	}
+	for _, tag := range strings.FieldsFunc(i.Option("tags"), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if tag = strings.TrimPrefix(tag, "#"); tag != "" {
+			link.Tags = append(link.Tags, tag)
+		}
+	}
+
+	save := saveIntegrationLink(ctx, "Discord", link, collectionID)
+	if save.Refusal != "" || save.Existing {
+		return discord.Ephemeral(save.reply())
+	}
+	return discord.Reply(save.reply())
+}
+
+// pollDiscordChannels saves the links posted in the watched channels since
+// the last run and reacts to their messages. Messages from bots and links
+// that are already saved are skipped, so an instance that starts watching
+// late does not save a link twice.
+func pollDiscordChannels(ctx context.Context) (string, error) {
+	if discordBot == nil {
+		return "no Discord bot configured", nil
+	}
+	var saved, failed int
+	channels := discordBot.Channels()
+	for _, channelID := range channels {
+		if ctx.Err() != nil {
+			break
+		}
+		messages, err := discordBot.NewMessages(ctx, channelID)
+		if err != nil {
+			log.Printf("Failed to read Discord channel %s: %v", channelID, err)
+			failed++
+			continue
+		}
+		collectionID, _ := discordBot.Collection(channelID)
+		for _, m := range messages {
+			if m.Author.Bot {
+				continue
+			}
+			link, ok := discord.ParseLink(m.Content)
+			if !ok {
+				continue
+			}
+			if _, found := savedBookmark(ctx, storage.Actor{}, link.URL); found {
+				continue
+			}
+			save := saveIntegrationLink(ctx, "Discord", integrationLink{URL: link.URL, Title: link.Title, Tags: link.Tags}, collectionID)
+			if save.Refusal != "" {
+				log.Printf("Discord link in channel %s not saved: %s", channelID, save.Refusal)
+				continue
+			}
+			saved++
+			if err := discordBot.React(ctx, channelID, m.ID, discordSavedEmoji); err != nil {
+				log.Printf("Failed to react to Discord message %s: %v", m.ID, err)
+			}
+		}
+	}
+	report := fmt.Sprintf("saved %d links from %d channels", saved, len(channels))
+	if failed > 0 {
+		report += fmt.Sprintf(", %d channels could not be read", failed)
+	}
+	return report, ctx.Err()
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global domain rule store; nil when the storage driver keeps no rules
+var domainRules storage.DomainRuleStore
+
+// RequireDomainRules hides the domain rule routes when the store keeps no
+// rules
+func RequireDomainRules() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if domainRules == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Domain rules are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// instanceRules and accountRules pick whose rules a route manages: the
+// instance-wide ones on the admin API, the signed-in account's on the
+// profile
+func instanceRules(*gin.Context) string { return "" }
+
+func accountRules(c *gin.Context) string { return currentActor(c).AccountID }
+
+// allowedDomain checks url against the instance-wide rules and those of
+// the signed-in account, and rejects the request when they forbid it
+func allowedDomain(c *gin.Context, url string) bool {
+	reason, err := domainForbidden(c.Request.Context(), currentActor(c).AccountID, url)
+	if err != nil {
+		domainRuleError(c, err)
+		return false
+	}
+	if reason != "" {
+		respondError(c, http.StatusForbidden, apierr.DomainBlocked, reason)
+		return false
+	}
+	return true
+}
+
+// domainForbidden explains why the instance-wide rules or those of
+// accountID forbid saving url, or returns "" when they allow it
+func domainForbidden(ctx context.Context, accountID, url string) (string, error) {
+	if domainRules == nil {
+		return "", nil
+	}
+	domain := model.URLDomain(url)
+	scopes := []string{""}
+	if accountID != "" {
+		scopes = append(scopes, accountID)
+	}
+	for _, scope := range scopes {
+		rules, err := domainRules.DomainRules(ctx, scope)
+		if err != nil {
+			return "", err
+		}
+		if !model.DomainAllowed(rules, domain) {
+			site := domain
+			if site == "" {
+				site = "this URL"
+			}
+			return fmt.Sprintf("Saving bookmarks from %s is not allowed", site), nil
+		}
+	}
+	return "", nil
+}
+
+// handleGetDomainRules lists the rules of a scope
+func handleGetDomainRules(scope func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := domainRules.DomainRules(c.Request.Context(), scope(c))
+		if err != nil {
+			domainRuleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    rules,
+		})
+	}
+}
+
+// handleSetDomainRule blocks or allows a domain within a scope. A full
+// URL may be given instead of a domain.
+func handleSetDomainRule(scope func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req model.DomainRuleRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		domain := model.NormalizeDomain(req.Domain)
+		if strings.Contains(domain, "://") {
+			domain = model.URLDomain(domain)
+		}
+		if domain == "" || strings.ContainsAny(domain, "/:?# ") {
+			respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "domain must be a host name such as example.com")
+			return
+		}
+
+		rule, err := domainRules.SetDomainRule(c.Request.Context(), model.DomainRule{Domain: domain, Mode: req.Mode, AccountID: scope(c)})
+		if err != nil {
+			domainRuleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    rule,
+		})
+	}
+}
+
+// handleDeleteDomainRule removes a scope's rule for :domain
+func handleDeleteDomainRule(scope func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := domainRules.DeleteDomainRule(c.Request.Context(), scope(c), model.NormalizeDomain(c.Param("domain"))); err != nil {
+			domainRuleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Domain rule removed",
+		})
+	}
+}
+
+func domainRuleError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrDomainRuleNotFound) {
+		respondError(c, http.StatusNotFound, apierr.DomainRuleNotFound, "Domain rule not found")
+		return
+	}
+	log.Printf("Domain rule operation failed: %v", err)
+	respondError(c, http.StatusInternalServerError, apierr.Internal, "Domain rule operation failed")
+}
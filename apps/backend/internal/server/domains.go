@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// domainGroup is the bookmarks saved from one site
+type domainGroup struct {
+	Domain    string           `json:"domain"`
+	Count     int              `json:"count"`
+	Bookmarks []model.Bookmark `json:"bookmarks"`
+}
+
+// handleGetBookmarksByDomain groups the bookmarks by site, largest group
+// first. It takes the same ?q= and filters as the bookmark list; bookmarks
+// whose URL has no host are grouped under "".
+func handleGetBookmarksByDomain(c *gin.Context) {
+	all, err := store.GetAll(c.Request.Context())
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	groups := make(map[string]*domainGroup)
+	for _, b := range filterBookmarks(visibleBookmarks(c, all), c.Query("q"), parseBookmarkFilter(c)) {
+		d := b.Domain()
+		g, ok := groups[d]
+		if !ok {
+			g = &domainGroup{Domain: d}
+			groups[d] = g
+		}
+		g.Count++
+		g.Bookmarks = append(g.Bookmarks, b)
+	}
+
+	result := make([]domainGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Domain < result[j].Domain
+	})
+	p, ok := paginate(c, len(result))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       result[p.start:p.end],
+		"pagination": p,
+	})
+}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+)
+
+// respondError answers the request with an error response of kind code
+// and stops the handlers after the current one
+func respondError(c *gin.Context, status int, code apierr.Code, message string) {
+	c.AbortWithStatusJSON(status, apierr.New(code, message))
+}
+
+// respondErrorDetails is respondError with more about the error, such as
+// the underlying failure, in the details field
+func respondErrorDetails(c *gin.Context, status int, code apierr.Code, message string, details any) {
+	resp := apierr.New(code, message)
+	resp.Details = details
+	c.AbortWithStatusJSON(status, resp)
+}
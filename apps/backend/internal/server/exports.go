@@ -0,0 +1,441 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/blob"
+	"github.com/hereisth/web-collector/apps/backend/internal/encrypt"
+	"github.com/hereisth/web-collector/apps/backend/internal/export"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Export job states
+const (
+	exportPending = "pending"
+	exportRunning = "running"
+	exportDone    = "done"
+	exportFailed  = "failed"
+)
+
+// ExportJob tracks an asynchronous export
+type ExportJob struct {
+	ID          string     `json:"id"`
+	Format      string     `json:"format"`
+	Tag         string     `json:"tag,omitempty"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Count       int        `json:"count,omitempty"`
+	Size        int64      `json:"size,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+
+	key string
+	// actor is the account that started the export; only its bookmarks
+	// are exported and only it can see the job
+	actor storage.Actor
+}
+
+// CreateExportRequest represents the request body for starting an export
+type CreateExportRequest struct {
+	Format string `json:"format" binding:"required"`
+	// Tag limits the export to bookmarks carrying this tag
+	Tag string `json:"tag" binding:"max=100"`
+}
+
+// exportManager runs export jobs on a bounded queue and serves the results
+// through signed, expiring download links. Finished files live in blob
+// storage under exports/.
+type exportManager struct {
+	mu     sync.Mutex
+	jobs   map[string]*ExportJob
+	queue  chan *ExportJob
+	blobs  blob.Store
+	secret []byte
+	ttl    time.Duration
+	// key encrypts export files at rest when set
+	key []byte
+}
+
+// Global export manager, configured in SetupRouter and started by StartJobs
+var exports = newExportManager(nil, "", 24*time.Hour, nil)
+
+func newExportManager(blobs blob.Store, secret string, ttl time.Duration, key []byte) *exportManager {
+	return &exportManager{
+		jobs:   make(map[string]*ExportJob),
+		queue:  make(chan *ExportJob, 64),
+		blobs:  blobs,
+		secret: []byte(secret),
+		ttl:    ttl,
+		key:    key,
+	}
+}
+
+// start runs the export worker until ctx is cancelled
+func (m *exportManager) start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case job := <-m.queue:
+				m.run(ctx, job)
+			}
+		}
+	}()
+}
+
+// enqueue registers a new job exporting actor's bookmarks; it fails when
+// the queue is full
+func (m *exportManager) enqueue(actor storage.Actor, format, tag string) (*ExportJob, error) {
+	job := &ExportJob{
+		ID:        randomID(),
+		Format:    format,
+		Tag:       tag,
+		Status:    exportPending,
+		CreatedAt: time.Now(),
+		actor:     actor,
+	}
+
+	m.mu.Lock()
+	m.jobs[job.ID] = job
+	m.mu.Unlock()
+
+	select {
+	case m.queue <- job:
+		return job, nil
+	default:
+		m.mu.Lock()
+		delete(m.jobs, job.ID)
+		m.mu.Unlock()
+		return nil, fmt.Errorf("export queue is full, try again later")
+	}
+}
+
+func (m *exportManager) run(ctx context.Context, job *ExportJob) {
+	m.setStatus(job, exportRunning, "")
+
+	format, _ := export.Lookup(job.Format)
+	// Exports include archived bookmarks, so the tag is matched directly
+	// rather than through the list filter
+	bookmarks, err := store.GetAll(ctx)
+	if err != nil {
+		log.Printf("Export %s failed: %v", job.ID, err)
+		m.setStatus(job, exportFailed, err.Error())
+		return
+	}
+	bookmarks = storage.VisibleBookmarks(ctx, collections, job.actor, bookmarks)
+	if tag := model.NormalizeTag(job.Tag); tag != "" {
+		kept := bookmarks[:0]
+		for _, b := range bookmarks {
+			if hasTagWithin(b.Tags, tag) {
+				kept = append(kept, b)
+			}
+		}
+		bookmarks = kept
+	}
+
+	key := "exports/" + job.ID + format.Extension
+	size, err := m.store(ctx, key, format, bookmarks)
+	if err != nil {
+		log.Printf("Export %s failed: %v", job.ID, err)
+		reporter.CaptureError(err, nil, map[string]interface{}{"export_id": job.ID})
+		m.setStatus(job, exportFailed, err.Error())
+		return
+	}
+
+	now := time.Now()
+	expires := now.Add(m.ttl)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.key = key
+	job.Count = len(bookmarks)
+	job.Size = size
+	job.Status = exportDone
+	job.CompletedAt = &now
+	job.ExpiresAt = &expires
+}
+
+// store renders bookmarks into a temporary file, since object stores need
+// to know the size up front, then uploads it under key. It returns the
+// plaintext size.
+func (m *exportManager) store(ctx context.Context, key string, format export.Format, bookmarks []model.Bookmark) (int64, error) {
+	f, err := os.CreateTemp("", "web-collector-export-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	size, err := m.write(ctx, f, format, bookmarks)
+	if err != nil {
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+	if err := m.blobs.Put(ctx, key, f, info.Size()); err != nil {
+		return 0, fmt.Errorf("upload export: %w", err)
+	}
+	return size, nil
+}
+
+// write renders bookmarks to w, encrypting them if a key is configured,
+// and returns the plaintext size
+func (m *exportManager) write(ctx context.Context, w io.Writer, format export.Format, bookmarks []model.Bookmark) (int64, error) {
+	counter := &countingWriter{}
+	if m.key == nil {
+		counter.w = w
+		err := render(ctx, counter, format, bookmarks)
+		return counter.n, err
+	}
+
+	ew, err := encrypt.NewWriter(w, m.key)
+	if err != nil {
+		return 0, err
+	}
+	counter.w = ew
+	if err := render(ctx, counter, format, bookmarks); err != nil {
+		return 0, err
+	}
+	return counter.n, ew.Close()
+}
+
+func render(ctx context.Context, w io.Writer, format export.Format, bookmarks []model.Bookmark) error {
+	if format.WriteWithClips != nil {
+		return format.WriteWithClips(w, bookmarks, func(id string) []model.Clip {
+			return bookmarkClips(ctx, id)
+		})
+	}
+	return format.Write(w, bookmarks)
+}
+
+// bookmarkClips returns a bookmark's clips, or none when the store cannot
+// keep clips
+func bookmarkClips(ctx context.Context, id string) []model.Clip {
+	if clips == nil {
+		return nil
+	}
+	result, err := clips.BookmarkClips(ctx, id)
+	if err != nil {
+		log.Printf("Failed to load clips of bookmark %s: %v", id, err)
+	}
+	return result
+}
+
+// open returns a reader over the plaintext of a stored export
+func (m *exportManager) open(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := m.blobs.Get(ctx, key)
+	if err != nil || m.key == nil {
+		return f, err
+	}
+	r, err := encrypt.NewReader(f, m.key)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, f}, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (m *exportManager) setStatus(job *ExportJob, status, errMsg string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.Status = status
+	job.Error = errMsg
+	if status == exportFailed {
+		now := time.Now()
+		job.CompletedAt = &now
+	}
+}
+
+// get returns a copy of actor's job with a freshly signed download URL
+func (m *exportManager) get(actor storage.Actor, id string) (ExportJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok || job.actor.AccountID != actor.AccountID {
+		return ExportJob{}, false
+	}
+	result := *job
+	if job.Status == exportDone && job.ExpiresAt != nil {
+		expires := strconv.FormatInt(job.ExpiresAt.Unix(), 10)
+		result.DownloadURL = fmt.Sprintf("/api/v1/exports/%s/download?%s", job.ID, url.Values{
+			"expires":   {expires},
+			"signature": {m.sign(job.ID, expires)},
+		}.Encode())
+	}
+	return result, true
+}
+
+func (m *exportManager) sign(id, expires string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(id + "|" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks a download signature and its expiry
+func (m *exportManager) verify(id, expires, signature string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(m.sign(id, expires)))
+}
+
+// pending returns the number of jobs waiting in the queue
+func (m *exportManager) pending() int {
+	return len(m.queue)
+}
+
+// cleanup deletes expired export files and forgets old jobs
+func (m *exportManager) cleanup(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for id, job := range m.jobs {
+		expired := job.ExpiresAt != nil && now.After(*job.ExpiresAt)
+		staleFailure := job.Status == exportFailed && now.Sub(job.CreatedAt) > m.ttl
+		if !expired && !staleFailure {
+			continue
+		}
+		if job.key != "" {
+			if err := m.blobs.Delete(ctx, job.key); err != nil {
+				log.Printf("Failed to remove export %s: %v", id, err)
+			}
+		}
+		delete(m.jobs, id)
+		removed++
+	}
+	return fmt.Sprintf("removed %d expired exports", removed), nil
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// handleCreateExport enqueues an asynchronous export job
+func handleCreateExport(c *gin.Context) {
+	var req CreateExportRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if _, ok := export.Lookup(req.Format); !ok {
+		respondErrorDetails(c, http.StatusBadRequest, apierr.ValidationFailed, "Unsupported export format", fmt.Sprintf("format must be one of %v", export.Names()))
+		return
+	}
+
+	job, err := exports.enqueue(currentActor(c), req.Format, req.Tag)
+	if err != nil {
+		respondError(c, http.StatusServiceUnavailable, apierr.Unavailable, err.Error())
+		return
+	}
+
+	c.Header("Location", "/api/v1/exports/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// handleGetExport returns the status of an export job
+func handleGetExport(c *gin.Context) {
+	job, found := exports.get(currentActor(c), c.Param("id"))
+	if !found {
+		respondError(c, http.StatusNotFound, apierr.ExportNotFound, "Export not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// handleDownloadExport serves a finished export to the account that
+// started it, given a valid link
+func handleDownloadExport(c *gin.Context) {
+	id := c.Param("id")
+	if !exports.verify(id, c.Query("expires"), c.Query("signature")) {
+		respondError(c, http.StatusForbidden, apierr.Forbidden, "Download link is invalid or has expired")
+		return
+	}
+
+	exports.mu.Lock()
+	job, ok := exports.jobs[id]
+	var (
+		key, name string
+		format    export.Format
+		size      int64
+	)
+	if ok && job.Status == exportDone && job.actor.AccountID == currentActor(c).AccountID {
+		format, _ = export.Lookup(job.Format)
+		key = job.key
+		size = job.Size
+		name = "bookmarks-" + job.CreatedAt.Format("20060102-150405") + format.Extension
+	}
+	exports.mu.Unlock()
+
+	if key == "" {
+		respondError(c, http.StatusNotFound, apierr.ExportNotFound, "Export not found")
+		return
+	}
+
+	r, err := exports.open(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("Failed to open export %s: %v", id, err)
+		reporter.CaptureError(err, c.Request, map[string]interface{}{"export_id": id})
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to read export")
+		return
+	}
+	defer r.Close()
+	c.DataFromReader(http.StatusOK, size, format.ContentType, r, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, name),
+	})
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/flags"
+)
+
+// Global feature flags, parsed from Config by applySettings
+var features atomic.Pointer[flags.Set]
+
+func init() {
+	f, _ := flags.Parse("")
+	features.Store(f)
+}
+
+// RequireFeature middleware hides routes behind a feature flag
+func RequireFeature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !features.Load().Enabled(name, currentUser(c)) {
+			respondError(c, http.StatusNotFound, apierr.FeatureDisabled, "Feature not enabled")
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentUser identifies the caller for per-user feature overrides. Until
+// accounts exist, clients may identify themselves with X-User-ID.
+func currentUser(c *gin.Context) string {
+	return c.GetHeader("X-User-ID")
+}
+
+// handleGetFeatures returns the feature flags in effect for the caller
+func handleGetFeatures(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    features.Load().Evaluate(currentUser(c)),
+	})
+}
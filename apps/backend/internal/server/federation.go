@@ -0,0 +1,349 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/activitypub"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global federation; nil unless FEDERATION_URL is set
+var federation *activitypub.Federation
+
+// Global follower store; nil when the storage driver cannot keep
+// followers
+var followerStore storage.FederationStore
+
+// maxInboxBody bounds the activities accepted by an inbox
+const maxInboxBody = 256 << 10
+
+// RequireFederation hides the ActivityPub routes unless federation is
+// configured and the store can keep followers
+func RequireFederation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if federation == nil || followerStore == nil || accounts == nil {
+			respondError(c, http.StatusNotFound, apierr.FeatureDisabled, "Federation is not enabled")
+			return
+		}
+		c.Next()
+	}
+}
+
+// wantsActivityPub reports whether a request asks for an ActivityPub
+// document rather than a page
+func wantsActivityPub(c *gin.Context) bool {
+	accept := c.GetHeader("Accept")
+	return followerStore != nil &&
+		(strings.Contains(accept, activitypub.ContentType) || strings.Contains(accept, "application/ld+json"))
+}
+
+// renderActivityPub writes an ActivityPub document
+func renderActivityPub(c *gin.Context, status int, doc interface{}) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(status, activitypub.ContentType+"; charset=utf-8", data)
+}
+
+// actorSigners caches the signers of the accounts' actors, whose keys are
+// created the first time they are needed
+var actorSigners = struct {
+	sync.Mutex
+	byAccount map[string]activitypub.Signer
+}{byAccount: make(map[string]activitypub.Signer)}
+
+// actorSigner returns the signer of an account's actor
+func actorSigner(ctx context.Context, account model.Account) (activitypub.Signer, error) {
+	actorSigners.Lock()
+	defer actorSigners.Unlock()
+	if s, ok := actorSigners.byAccount[account.ID]; ok {
+		return s, nil
+	}
+	key, err := activitypub.GenerateKey()
+	if err != nil {
+		return activitypub.Signer{}, err
+	}
+	// Another instance may have stored a key first; everyone uses that one.
+	if key, err = followerStore.InitActorKey(ctx, account.ID, key); err != nil {
+		return activitypub.Signer{}, err
+	}
+	s, err := activitypub.NewSigner(federation.KeyID(account.Username), key)
+	if err != nil {
+		return activitypub.Signer{}, err
+	}
+	actorSigners.byAccount[account.ID] = s
+	return s, nil
+}
+
+// serveActor answers with an account's actor document
+func serveActor(c *gin.Context, account model.Account) {
+	signer, err := actorSigner(c.Request.Context(), account)
+	var publicKey string
+	if err == nil {
+		publicKey, err = signer.PublicKeyPEM()
+	}
+	if err != nil {
+		log.Printf("Actor key for account %s: %v", account.ID, err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Actor unavailable")
+		return
+	}
+	renderActivityPub(c, http.StatusOK, federation.Actor(account.Username, publicKey, account.CreatedAt))
+}
+
+// handleWebFinger resolves a fediverse handle, acct:username@host, to the
+// actor of an account with a public profile
+func handleWebFinger(c *gin.Context) {
+	username, ok := federation.Resource(c.Query("resource"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "resource must be acct:username@"+federation.Host())
+		return
+	}
+	account, found := publicAccount(c.Request.Context(), username)
+	if !found {
+		respondError(c, http.StatusNotFound, apierr.ProfileNotFound, "Profile not found")
+		return
+	}
+	data, err := json.Marshal(federation.WebFinger(account.Username))
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Data(http.StatusOK, "application/jrd+json; charset=utf-8", data)
+}
+
+// federatedAccount looks up the account of an ActivityPub route,
+// answering 404 when its profile is not public
+func federatedAccount(c *gin.Context) (model.Account, bool) {
+	account, found := publicAccount(c.Request.Context(), c.Param("username"))
+	if !found {
+		respondError(c, http.StatusNotFound, apierr.ProfileNotFound, "Profile not found")
+	}
+	return account, found
+}
+
+// handleOutbox lists an account's most recent public bookmarks as the
+// activities that published them
+func handleOutbox(c *gin.Context) {
+	account, ok := federatedAccount(c)
+	if !ok {
+		return
+	}
+	_, bookmarks := publicBookmarks(c.Request.Context(), account)
+	total := len(bookmarks)
+	if total > profileBookmarkLimit {
+		bookmarks = bookmarks[:profileBookmarkLimit]
+	}
+	items := make([]interface{}, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		items = append(items, federation.Create(account.Username, federation.Note(account.Username, b)))
+	}
+	id := federation.ActorID(account.Username) + "/outbox"
+	renderActivityPub(c, http.StatusOK, activitypub.OrderedCollection(id, total, items))
+}
+
+// handleFollowers reports how many actors follow an account, without
+// listing them
+func handleFollowers(c *gin.Context) {
+	account, ok := federatedAccount(c)
+	if !ok {
+		return
+	}
+	followers, err := followerStore.Followers(c.Request.Context(), account.ID)
+	if err != nil {
+		log.Printf("Failed to list followers of account %s: %v", account.ID, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	id := federation.ActorID(account.Username) + "/followers"
+	renderActivityPub(c, http.StatusOK, activitypub.OrderedCollection(id, len(followers), nil))
+}
+
+// handleInbox receives the activities other servers send an account:
+// follows, their undoing, and the deletion of following actors. Anything
+// else is accepted and ignored. Activities must be signed by the actor
+// sending them.
+func handleInbox(c *gin.Context) {
+	account, ok := federatedAccount(c)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxInboxBody+1))
+	if err != nil || len(body) > maxInboxBody {
+		respondError(c, http.StatusRequestEntityTooLarge, apierr.PayloadTooLarge, "Activity too large")
+		return
+	}
+	var activity activitypub.Activity
+	if err := json.Unmarshal(body, &activity); err != nil || activity.Actor == "" {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Body must be an ActivityPub activity")
+		return
+	}
+
+	signer, err := actorSigner(c.Request.Context(), account)
+	if err != nil {
+		log.Printf("Actor key for account %s: %v", account.ID, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	keyID, signed := activitypub.SignatureKeyID(c.Request)
+	if !signed {
+		respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Activities must carry an HTTP signature")
+		return
+	}
+	actor, err := federation.FetchActor(c.Request.Context(), keyID, signer)
+	if err != nil {
+		// A deleted actor's key is gone with it, so its Delete cannot be
+		// checked; deliveries to it end its follow once its inbox is gone.
+		if activity.Type == "Delete" {
+			c.Status(http.StatusAccepted)
+			return
+		}
+		log.Printf("Inbox of %s: %v", account.Username, err)
+		respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "The signing key could not be fetched")
+		return
+	}
+	if actor.ID != activity.Actor || actor.PublicKey.Owner != actor.ID {
+		respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "The activity is not signed by its actor")
+		return
+	}
+	if err := activitypub.Verify(c.Request, body, actor.PublicKey.PublicKeyPEM); err != nil {
+		respondError(c, http.StatusUnauthorized, apierr.Unauthorized, err.Error())
+		return
+	}
+
+	switch {
+	case activity.Type == "Follow" && activity.ObjectID() == federation.ActorID(account.Username):
+		inbox := actor.Endpoints.SharedInbox
+		if inbox == "" {
+			inbox = actor.Inbox
+		}
+		err = followerStore.AddFollower(c.Request.Context(), model.Follower{AccountID: account.ID, ActorID: actor.ID, Inbox: inbox})
+		if err == nil {
+			accept := federation.Accept(account.Username, json.RawMessage(body))
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				defer cancel()
+				if err := federation.Deliver(ctx, actor.Inbox, accept, signer); err != nil {
+					log.Printf("Failed to accept follow by %s: %v", actor.ID, err)
+				}
+			}()
+		}
+	case activity.Type == "Undo" && activity.ObjectType() == "Follow",
+		activity.Type == "Delete" && activity.ObjectID() == actor.ID:
+		err = followerStore.RemoveFollower(c.Request.Context(), account.ID, actor.ID)
+	}
+	if err != nil {
+		log.Printf("Inbox of %s: %v", account.Username, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// federationDelivery is a bookmark added to a collection, which followers
+// hear about if the collection is public
+type federationDelivery struct {
+	collectionID string
+	bookmarkID   string
+}
+
+// federationManager delivers new public bookmarks to followers in the
+// background
+type federationManager struct {
+	queue chan federationDelivery
+}
+
+// Global federation delivery manager, started by StartJobs
+var federationDeliveries = &federationManager{queue: make(chan federationDelivery, 256)}
+
+// start runs the delivery worker until ctx is cancelled
+func (m *federationManager) start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case d := <-m.queue:
+				m.deliver(ctx, d)
+			}
+		}
+	}()
+}
+
+// enqueue schedules publishing a bookmark added to a collection. Additions
+// beyond the queue's capacity are dropped; they are still in the outbox.
+func (m *federationManager) enqueue(collectionID, bookmarkID string) {
+	if federation == nil || followerStore == nil || accounts == nil || collections == nil {
+		return
+	}
+	select {
+	case m.queue <- federationDelivery{collectionID, bookmarkID}:
+	default:
+		log.Printf("Federation queue full, dropped bookmark %s", bookmarkID)
+	}
+}
+
+// deliver sends a Create of the bookmark's Note to the inboxes of its
+// owner's followers, once per shared inbox. An inbox answering 410 Gone
+// loses its followers.
+func (m *federationManager) deliver(ctx context.Context, d federationDelivery) {
+	col, public := storage.Restrict(collections, storage.Actor{}).GetCollection(ctx, d.collectionID)
+	if !public {
+		return
+	}
+	account, found := accounts.GetAccount(ctx, col.OwnerID)
+	if !found || !account.PublicProfile || account.Username == "" {
+		return
+	}
+	b, err := store.GetByID(ctx, d.bookmarkID)
+	if err != nil {
+		return
+	}
+	followers, err := followerStore.Followers(ctx, account.ID)
+	if err != nil {
+		log.Printf("Failed to list followers of account %s: %v", account.ID, err)
+		return
+	}
+	if len(followers) == 0 {
+		return
+	}
+	signer, err := actorSigner(ctx, account)
+	if err != nil {
+		log.Printf("Actor key for account %s: %v", account.ID, err)
+		return
+	}
+
+	create := federation.Create(account.Username, federation.Note(account.Username, b))
+	byInbox := make(map[string][]string)
+	for _, f := range followers {
+		byInbox[f.Inbox] = append(byInbox[f.Inbox], f.ActorID)
+	}
+	for inbox, actors := range byInbox {
+		err := federation.Deliver(ctx, inbox, create, signer)
+		switch {
+		case errors.Is(err, activitypub.ErrGone):
+			for _, actor := range actors {
+				if err := followerStore.RemoveFollower(ctx, account.ID, actor); err != nil {
+					log.Printf("Failed to remove follower %s: %v", actor, err)
+				}
+			}
+		case err != nil:
+			log.Printf("Federation delivery of bookmark %s failed: %v", b.ID, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global revision store; nil when the storage driver keeps no history
+var revisions storage.RevisionStore
+
+// RequireHistory hides the history routes when the store keeps no
+// bookmark history
+func RequireHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if revisions == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Bookmark history is not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleGetBookmarkHistory returns a bookmark's revisions, oldest first
+func handleGetBookmarkHistory(c *gin.Context) {
+	history, err := revisions.BookmarkHistory(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		historyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    history,
+	})
+}
+
+// handleRevertBookmark restores the title, URL and tags of a revision.
+// The revert is an edit like any other and is recorded as a new revision.
+func handleRevertBookmark(c *gin.Context) {
+	id := c.Param("id")
+	history, err := revisions.BookmarkHistory(c.Request.Context(), id)
+	if err != nil {
+		historyError(c, err)
+		return
+	}
+	version, _ := strconv.Atoi(c.Param("version"))
+	var target *model.Revision
+	for i := range history {
+		if history[i].Version == version {
+			target = &history[i]
+		}
+	}
+	if target == nil {
+		respondError(c, http.StatusNotFound, apierr.RevisionNotFound, "Revision not found")
+		return
+	}
+
+	current, _ := store.GetByID(c.Request.Context(), id)
+	bookmark, err := store.Update(c.Request.Context(), id, target.Title, target.URL, target.Tags)
+	if err != nil {
+		historyError(c, err)
+		return
+	}
+	indexBookmark(c.Request.Context(), bookmark)
+	publishBookmarkEvent("updated", id)
+	recordActivity(c, model.Activity{
+		Kind:       model.ActivityEdited,
+		BookmarkID: id,
+		Detail:     fmt.Sprintf("reverted to version %d", version),
+	})
+	if current.URL != bookmark.URL {
+		thumbnails.enqueue(id)
+		readingTimes.enqueue(id)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
+
+func historyError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrBookmarkNotFound) {
+		respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found")
+		return
+	}
+	log.Printf("Bookmark history failed: %v", err)
+	respondError(c, http.StatusInternalServerError, apierr.Internal, "Bookmark history failed")
+}
@@ -0,0 +1,469 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/safehttp"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global hook store; nil when the storage driver cannot keep hooks
+var hookStore storage.HookStore
+
+const (
+	// maxHookSamples bounds the sample bookmarks returned for an event
+	maxHookSamples = 3
+	// hookLease is how long a delivery being attempted is kept from the
+	// retry job
+	hookLease = time.Minute
+	// maxHookAttempts is how many times a delivery is tried before it is
+	// dead; with the delays below the last try is about 8 hours after the
+	// first
+	maxHookAttempts = 10
+	// hookRetryBase is the delay after the first failed attempt, doubled
+	// after each further one up to hookRetryMax
+	hookRetryBase = time.Minute
+	hookRetryMax  = 4 * time.Hour
+	// hookRetryBatch bounds the deliveries retried per run of the job
+	hookRetryBatch = 100
+	// hookLogRetention is how long settled deliveries stay in the log
+	hookLogRetention = 30 * 24 * time.Hour
+	// defaultDeliveryLimit and maxDeliveryLimit bound listed deliveries
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// RequireHooks hides the REST hook routes when the store cannot keep
+// hooks
+func RequireHooks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hookStore == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "REST hooks are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleGetHooks returns the signed-in account's hooks
+func handleGetHooks(c *gin.Context) {
+	hooks, err := hookStore.AccountHooks(c.Request.Context(), currentActor(c).AccountID)
+	if err != nil {
+		hookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    hooks,
+	})
+}
+
+// handleSubscribeHook subscribes a target URL to an event. Automation
+// platforms keep the returned ID to unsubscribe when the automation is
+// turned off. Targets on private networks are refused, as deliveries
+// would let callers probe them.
+func handleSubscribeHook(c *gin.Context) {
+	var req model.HookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !strings.HasPrefix(req.TargetURL, "https://") && !strings.HasPrefix(req.TargetURL, "http://") {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "target_url must be an http(s) URL")
+		return
+	}
+	if !publicURL(c.Request.Context(), req.TargetURL) {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "target_url must point to a public address")
+		return
+	}
+	hook, err := hookStore.AddHook(c.Request.Context(), model.Hook{
+		AccountID: currentActor(c).AccountID,
+		Event:     req.Event,
+		TargetURL: req.TargetURL,
+	})
+	if err != nil {
+		hookError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    hook,
+	})
+}
+
+// handleUnsubscribeHook removes one of the signed-in account's hooks
+func handleUnsubscribeHook(c *gin.Context) {
+	if err := hookStore.DeleteHook(c.Request.Context(), currentActor(c).AccountID, c.Param("id")); err != nil {
+		hookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Hook removed",
+	})
+}
+
+// handleGetHookDeliveries returns the delivery log of one of the
+// signed-in account's hooks, newest first, optionally only the
+// deliveries in ?status=
+func handleGetHookDeliveries(c *gin.Context) {
+	listHookDeliveries(c, c.Param("id"), c.Query("status"))
+}
+
+// handleGetDeadHookDeliveries returns the deliveries to any of the
+// signed-in account's hooks that ran out of attempts, newest first
+func handleGetDeadHookDeliveries(c *gin.Context) {
+	listHookDeliveries(c, "", model.HookDeliveryDead)
+}
+
+func listHookDeliveries(c *gin.Context, hookID, status string) {
+	switch status {
+	case "", model.HookDeliveryPending, model.HookDeliveryDelivered, model.HookDeliveryDead:
+	default:
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "status must be pending, delivered or dead")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDeliveryLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultDeliveryLimit
+	}
+	deliveries, err := hookStore.HookDeliveries(c.Request.Context(), currentActor(c).AccountID, hookID, status, min(limit, maxDeliveryLimit))
+	if err != nil {
+		hookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    deliveries,
+	})
+}
+
+// handleRedeliverHook posts a logged delivery's payload again and answers
+// with the outcome. The delivery starts over with a full set of attempts;
+// a pending one is left to the retry job.
+func handleRedeliverHook(c *gin.Context) {
+	d, err := hookStore.GetHookDelivery(c.Request.Context(), currentActor(c).AccountID, c.Param("id"))
+	if err != nil {
+		hookError(c, err)
+		return
+	}
+	if d.Status == model.HookDeliveryPending {
+		respondError(c, http.StatusConflict, apierr.Conflict, "The delivery is already waiting for its next attempt")
+		return
+	}
+	d.Attempts = 0
+	hookDeliveries.attempt(c.Request.Context(), &d)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    d,
+	})
+}
+
+// handleGetHookSamples returns recent bookmarks as an event's payloads
+// would carry them, which platforms show while an automation is set up.
+// Like the actions, it answers with the bare list.
+func handleGetHookSamples(c *gin.Context) {
+	event := c.DefaultQuery("event", model.HookBookmarkCreated)
+	samples := []model.FlatBookmark{}
+	all, err := store.GetAll(c.Request.Context())
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	all = visibleBookmarks(c, all)
+	for i := len(all) - 1; i >= 0 && len(samples) < maxHookSamples; i-- {
+		flat := model.Flatten(all[i])
+		flat.Event = event
+		samples = append(samples, flat)
+	}
+	c.JSON(http.StatusOK, samples)
+}
+
+// handleSaveAction saves a bookmark from a flat payload, as JSON or form
+// fields, and answers with the bare flat bookmark. A URL saved before,
+// as a bookmark or an alias, returns the existing bookmark.
+func handleSaveAction(c *gin.Context) {
+	var req model.SaveActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		respondBodyError(c, err)
+		return
+	}
+	if existing, found := savedBookmark(c.Request.Context(), currentActor(c), req.URL); found {
+		c.JSON(http.StatusOK, model.Flatten(existing))
+		return
+	}
+	save := saveIntegrationLink(c.Request.Context(), "automation", integrationLink{
+		URL:       req.URL,
+		Title:     req.Title,
+		Tags:      model.SplitTags(req.Tags),
+		Notes:     req.Notes,
+		AccountID: currentActor(c).AccountID,
+	}, "")
+	if save.Refusal != "" {
+		respondError(c, http.StatusForbidden, apierr.SaveRefused, strings.TrimSuffix(save.Refusal, "."))
+		return
+	}
+	status := http.StatusCreated
+	if save.Existing {
+		status = http.StatusOK
+	}
+	c.JSON(status, model.Flatten(save.Bookmark))
+}
+
+// handleFindAction looks a bookmark up by ?url= and answers with a bare
+// list holding it, or an empty list, which is how platforms expect a
+// search to report that nothing was found
+func handleFindAction(c *gin.Context) {
+	found := []model.FlatBookmark{}
+	if b, ok := savedBookmark(c.Request.Context(), currentActor(c), strings.TrimSpace(c.Query("url"))); ok {
+		found = append(found, model.Flatten(b))
+	}
+	c.JSON(http.StatusOK, found)
+}
+
+func hookError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrHookNotFound) {
+		respondError(c, http.StatusNotFound, apierr.HookNotFound, "Hook not found")
+		return
+	}
+	if errors.Is(err, storage.ErrHookDeliveryNotFound) {
+		respondError(c, http.StatusNotFound, apierr.DeliveryNotFound, "Delivery not found")
+		return
+	}
+	log.Printf("Hook operation failed: %v", err)
+	respondError(c, http.StatusInternalServerError, apierr.Internal, "Hook operation failed")
+}
+
+// hookEvents maps bookmark change actions to the hook events they fire
+var hookEvents = map[string]string{
+	"created": model.HookBookmarkCreated,
+	"updated": model.HookBookmarkUpdated,
+	"deleted": model.HookBookmarkDeleted,
+}
+
+// hookEvent is a bookmark change waiting for its hooks
+type hookEvent struct {
+	payload model.FlatBookmark
+	// access checks an account's access to the bookmark as the change
+	// left it
+	access func(ctx context.Context, actor storage.Actor) error
+}
+
+// hookManager posts hook payloads in the background
+type hookManager struct {
+	queue   chan hookEvent
+	client  *http.Client
+	workers int
+}
+
+// Global hook delivery manager, started by StartJobs
+var hookDeliveries = newHookManager(2)
+
+func newHookManager(workers int) *hookManager {
+	return &hookManager{
+		queue:   make(chan hookEvent, 256),
+		client:  safehttp.Client(10 * time.Second),
+		workers: workers,
+	}
+}
+
+// start runs the delivery workers until ctx is cancelled
+func (m *hookManager) start(ctx context.Context, wg *sync.WaitGroup) {
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case ev := <-m.queue:
+					m.deliver(ctx, ev)
+				}
+			}
+		}()
+	}
+}
+
+// enqueue schedules the hooks for a bookmark change. The payload is taken
+// now, so it shows the bookmark as the change left it; a deleted bookmark
+// is looked up in the trash to know who could see it. Changes beyond the
+// queue's capacity are dropped.
+func (m *hookManager) enqueue(action, id string) {
+	event, ok := hookEvents[action]
+	if !ok || hookStore == nil {
+		return
+	}
+	ev := hookEvent{payload: model.FlatBookmark{ID: id}}
+	if event == model.HookBookmarkDeleted {
+		d, found := deletedBookmark(context.Background(), id)
+		if !found {
+			return
+		}
+		ev.access = func(ctx context.Context, actor storage.Actor) error {
+			return storage.CheckDeletedAccess(ctx, collections, actor, d, model.AccessView)
+		}
+	} else {
+		b, err := store.GetByID(context.Background(), id)
+		if err != nil {
+			return
+		}
+		ev.payload = model.Flatten(b)
+		ev.access = func(ctx context.Context, actor storage.Actor) error {
+			return storage.CheckBookmarkAccess(ctx, collections, actor, b, model.AccessView)
+		}
+	}
+	ev.payload.Event = event
+	select {
+	case m.queue <- ev:
+	default:
+		log.Printf("Hook queue full, dropped %s of bookmark %s", event, id)
+	}
+}
+
+// deliver logs a delivery of a payload to every hook subscribed to its
+// event whose account can view the bookmark, and makes the first attempt.
+// The delivery's next attempt is set a little ahead meanwhile, so the
+// retry job only picks it up if this instance stops before recording the
+// outcome.
+func (m *hookManager) deliver(ctx context.Context, ev hookEvent) {
+	payload := ev.payload
+	hooks, err := hookStore.EventHooks(ctx, payload.Event)
+	if err != nil {
+		log.Printf("Failed to list %s hooks: %v", payload.Event, err)
+		return
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	for _, h := range hooks {
+		if ev.access(ctx, accountActor(ctx, h.AccountID)) != nil {
+			continue
+		}
+		next := time.Now().Add(hookLease)
+		d, err := hookStore.AddHookDelivery(ctx, model.HookDelivery{
+			HookID:        h.ID,
+			Event:         payload.Event,
+			Payload:       body,
+			Status:        model.HookDeliveryPending,
+			NextAttemptAt: &next,
+		})
+		if err != nil {
+			if !errors.Is(err, storage.ErrHookNotFound) {
+				log.Printf("Failed to log delivery to hook %s: %v", h.ID, err)
+			}
+			continue
+		}
+		m.attempt(ctx, &d)
+	}
+}
+
+// attempt posts a delivery's payload and records the outcome. A failed
+// attempt is retried after a delay that doubles each time, until
+// maxHookAttempts have failed and the delivery is dead. A target
+// answering 410 Gone has been removed on the platform's side, so its
+// hook is removed too, and with it the delivery.
+func (m *hookManager) attempt(ctx context.Context, d *model.HookDelivery) {
+	status, err := m.post(ctx, d.TargetURL, d.Payload)
+	if ctx.Err() != nil {
+		// Shutting down: the delivery stays pending for the retry job.
+		return
+	}
+	if err == nil && status == http.StatusGone {
+		if err := hookStore.DeleteHook(ctx, d.AccountID, d.HookID); err != nil && !errors.Is(err, storage.ErrHookNotFound) {
+			log.Printf("Failed to remove gone hook %s: %v", d.HookID, err)
+		}
+		return
+	}
+
+	d.Attempts++
+	d.LastStatus, d.LastError, d.NextAttemptAt = status, "", nil
+	switch {
+	case err != nil:
+		d.LastError = err.Error()
+	case status >= 300:
+		d.LastError = fmt.Sprintf("target answered %d", status)
+	}
+	switch {
+	case d.LastError == "":
+		d.Status = model.HookDeliveryDelivered
+	case d.Attempts >= maxHookAttempts:
+		d.Status = model.HookDeliveryDead
+		log.Printf("Hook %s delivery %s failed %d times, giving up: %s", d.HookID, d.ID, d.Attempts, d.LastError)
+	default:
+		d.Status = model.HookDeliveryPending
+		next := time.Now().Add(hookRetryDelay(d.Attempts))
+		d.NextAttemptAt = &next
+	}
+	if err := hookStore.UpdateHookDelivery(ctx, *d); err != nil && !errors.Is(err, storage.ErrHookDeliveryNotFound) {
+		log.Printf("Failed to record hook delivery %s: %v", d.ID, err)
+	}
+}
+
+// hookRetryDelay is how long to wait after the given number of failed
+// attempts
+func hookRetryDelay(attempts int) time.Duration {
+	delay := hookRetryBase << (attempts - 1)
+	if delay <= 0 || delay > hookRetryMax {
+		return hookRetryMax
+	}
+	return delay
+}
+
+// retryHookDeliveries makes the next attempt of the deliveries that are
+// due, then prunes the log of settled deliveries past hookLogRetention
+func retryHookDeliveries(ctx context.Context) (string, error) {
+	if hookStore == nil {
+		return "hooks are not supported by this storage driver", nil
+	}
+	due, err := hookStore.DueHookDeliveries(ctx, time.Now(), hookRetryBatch)
+	if err != nil {
+		return "", err
+	}
+	var delivered, dead int
+	for i := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		hookDeliveries.attempt(ctx, &due[i])
+		switch due[i].Status {
+		case model.HookDeliveryDelivered:
+			delivered++
+		case model.HookDeliveryDead:
+			dead++
+		}
+	}
+	pruned, err := hookStore.PruneHookDeliveries(ctx, time.Now().Add(-hookLogRetention))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("retried %d hook deliveries (%d delivered, %d dead), pruned %d", len(due), delivered, dead, pruned), nil
+}
+
+func (m *hookManager) post(ctx context.Context, target string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "web-collector-hooks/1.0")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("post %s: %w", target, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
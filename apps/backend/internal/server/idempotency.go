@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+)
+
+// maxIdempotencyKeyLength rejects keys that are clearly not UUID-like tokens
+const maxIdempotencyKeyLength = 255
+
+// idempotencyRecord is the stored outcome of the first request with a key
+type idempotencyRecord struct {
+	requestHash string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// IdempotencyStore remembers responses to POST requests carrying an
+// Idempotency-Key header so that client retries replay the original
+// response instead of creating duplicates.
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	records map[string]*idempotencyRecord
+}
+
+// NewIdempotencyStore keeps responses for ttl
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		ttl:     ttl,
+		records: make(map[string]*idempotencyRecord),
+	}
+}
+
+// Middleware honours the Idempotency-Key header. Requests without it pass
+// through unchanged.
+func (s *IdempotencyStore) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			respondErrorDetails(c, http.StatusBadRequest, apierr.ValidationFailed, "Invalid Idempotency-Key", "key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			respondBodyError(c, err)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Scope keys per caller and endpoint so clients can't collide
+		// with each other.
+		h := sha256.New()
+		h.Write([]byte(c.GetHeader("Authorization")))
+		h.Write([]byte{0})
+		h.Write([]byte(c.Request.Method + " " + c.FullPath()))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		scopedKey := hex.EncodeToString(h.Sum(nil))
+		bodyHash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(bodyHash[:])
+
+		record, fresh := s.begin(scopedKey, requestHash)
+		if !fresh {
+			switch {
+			case record.requestHash != requestHash:
+				respondError(c, http.StatusUnprocessableEntity, apierr.IdempotencyKeyReused, "Idempotency-Key reused with a different request body")
+			case !record.done:
+				respondError(c, http.StatusConflict, apierr.Conflict, "A request with this Idempotency-Key is still in progress")
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.status, record.contentType, record.body)
+				c.Abort()
+			}
+			return
+		}
+
+		rec := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		// Only remember definitive outcomes; server errors may be retried.
+		if rec.Status() >= http.StatusInternalServerError {
+			s.forget(scopedKey)
+			return
+		}
+		s.finish(scopedKey, rec.Status(), rec.Header().Get("Content-Type"), rec.body.Bytes())
+	}
+}
+
+// begin returns the existing record for key, or reserves a new one and
+// reports fresh=true.
+func (s *IdempotencyStore) begin(key, requestHash string) (idempotencyRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if r, ok := s.records[key]; ok && now.Before(r.expiresAt) {
+		return *r, false
+	}
+	if len(s.records) > 10000 {
+		for k, r := range s.records {
+			if now.After(r.expiresAt) {
+				delete(s.records, k)
+			}
+		}
+	}
+	s.records[key] = &idempotencyRecord{requestHash: requestHash, expiresAt: now.Add(s.ttl)}
+	return idempotencyRecord{}, true
+}
+
+func (s *IdempotencyStore) finish(key string, status int, contentType string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.records[key]; ok {
+		r.done = true
+		r.status = status
+		r.contentType = contentType
+		r.body = append([]byte(nil), body...)
+	}
+}
+
+func (s *IdempotencyStore) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+}
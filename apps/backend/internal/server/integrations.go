@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/sanitize"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// maxIntegrationRequestBytes bounds the signed bodies read from chat
+// integrations
+const maxIntegrationRequestBytes = 1 << 20
+
+// integrationLink is a link received from a chat integration, with the
+// title, tags and notes the message gave it. AccountID is the account the
+// save is recorded under, when the integration can tell.
+type integrationLink struct {
+	URL       string
+	Title     string
+	Tags      []string
+	Notes     string
+	AccountID string
+}
+
+// integrationSave is the outcome of saving an integrationLink. Refusal
+// explains why nothing was saved; Existing is set when the link was
+// already saved under an alias.
+type integrationSave struct {
+	Bookmark model.Bookmark
+	Existing bool
+	Refusal  string
+}
+
+// saveIntegrationLink saves a link received from source, such as
+// "Telegram", the way the API saves bookmarks: the URL is normalized and
+// checked against the instance-wide domain rules, and saving an alias
+// returns the bookmark it belongs to. New bookmarks are added to
+// collectionID when it is set.
+func saveIntegrationLink(ctx context.Context, source string, link integrationLink, collectionID string) integrationSave {
+	url := normalizeURL(ctx, link.URL)
+	if len(url) > 2048 {
+		return integrationSave{Refusal: "That link is too long to save."}
+	}
+	reason, err := domainForbidden(ctx, "", url)
+	if err != nil {
+		log.Printf("%s save failed: %v", source, err)
+		return integrationSave{Refusal: "Saving failed, please try again later."}
+	}
+	if reason != "" {
+		return integrationSave{Refusal: reason + "."}
+	}
+	existing, found, err := aliasedBookmark(ctx, accountActor(ctx, link.AccountID), url)
+	if err != nil {
+		log.Printf("%s save failed: %v", source, err)
+		return integrationSave{Refusal: "Saving failed, please try again later."}
+	}
+	if found {
+		return integrationSave{Bookmark: existing, Existing: true}
+	}
+
+	title := truncate(strings.TrimSpace(sanitize.Plain(link.Title)), 500)
+	if title == "" {
+		title = url
+	}
+	tags := link.Tags
+	if len(tags) > 50 {
+		tags = tags[:50]
+	}
+	for i := range tags {
+		tags[i] = truncate(tags[i], 100)
+	}
+
+	bookmark, err := createBookmark(ctx, link.AccountID, title, url, tags)
+	if errors.Is(err, storage.ErrQuotaExceeded) {
+		return integrationSave{Refusal: quotaMessage(err) + "."}
+	}
+	if err != nil {
+		log.Printf("%s save failed: %v", source, err)
+		return integrationSave{Refusal: "Saving failed, please try again later."}
+	}
+	bookmark = withSelection(ctx, bookmark, link.Notes)
+	indexBookmark(ctx, bookmark)
+	recordActivityAs(ctx, link.AccountID, model.Activity{Kind: model.ActivitySaved, BookmarkID: bookmark.ID, Detail: "via " + source})
+	publishBookmarkEvent("created", bookmark.ID)
+	thumbnails.enqueue(bookmark.ID)
+	readingTimes.enqueue(bookmark.ID)
+
+	if collectionID != "" && collections != nil {
+		if err := collections.AddToCollection(ctx, collectionID, []string{bookmark.ID}); err != nil {
+			log.Printf("%s save: adding bookmark %s to collection %s: %v", source, bookmark.ID, collectionID, err)
+		} else {
+			publishCollectionEventAs(link.AccountID, model.CollectionEvent{
+				Type:         model.EventBookmarkAdded,
+				CollectionID: collectionID,
+				BookmarkID:   bookmark.ID,
+				Bookmark:     &bookmark,
+			})
+		}
+	}
+	return integrationSave{Bookmark: bookmark}
+}
+
+// savedBookmark returns the bookmark actor can view that is saved under
+// url, normalized the way saving it would be, as its URL or an alias.
+// Bookmarks saved by other accounts are never matched, so their owners'
+// titles and IDs don't leak and the caller saves its own copy instead
+func savedBookmark(ctx context.Context, actor storage.Actor, url string) (model.Bookmark, bool) {
+	normalized := normalizeURL(ctx, url)
+	if aliases != nil {
+		b, err := aliases.FindByURL(ctx, normalized)
+		if errors.Is(err, storage.ErrBookmarkNotFound) {
+			b, err = aliases.FindByURL(ctx, url)
+		}
+		switch {
+		case errors.Is(err, storage.ErrBookmarkNotFound):
+			return model.Bookmark{}, false
+		case err != nil:
+			log.Printf("Looking up saved bookmark %s: %v", url, err)
+			return model.Bookmark{}, false
+		case storage.CheckBookmarkAccess(ctx, collections, actor, b, model.AccessView) == nil:
+			return b, true
+		}
+		// The first match belongs to someone else; look for the actor's own
+	}
+	all, err := store.GetAll(ctx)
+	if err != nil {
+		log.Printf("Looking up saved bookmark %s: %v", url, err)
+		return model.Bookmark{}, false
+	}
+	for _, b := range storage.VisibleBookmarks(ctx, collections, actor, all) {
+		if b.URL == normalized || b.URL == url ||
+			slices.Contains(b.Aliases, normalized) || slices.Contains(b.Aliases, url) {
+			return b, true
+		}
+	}
+	return model.Bookmark{}, false
+}
+
+// reply describes the outcome for a chat message
+func (s integrationSave) reply() string {
+	switch {
+	case s.Refusal != "":
+		return s.Refusal
+	case s.Existing:
+		return "Already saved: " + s.Bookmark.Title
+	}
+	reply := "Saved: " + s.Bookmark.Title
+	if len(s.Bookmark.Tags) > 0 {
+		reply += "\nTags: " + strings.Join(s.Bookmark.Tags, ", ")
+	}
+	return reply
+}
+
+// truncate cuts s to at most n runes
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/retention"
+	"github.com/hereisth/web-collector/apps/backend/internal/safehttp"
+	"github.com/hereisth/web-collector/apps/backend/internal/scheduler"
+)
+
+// JobsConfig holds cron schedules for background maintenance jobs. An empty
+// schedule disables the job.
+type JobsConfig struct {
+	DeadLinkCheck    string
+	ExportCleanup    string
+	ArchiveGC        string
+	SearchSync       string
+	Reminders        string
+	AutoArchive      string
+	PurgeTrash       string
+	PollDiscord      string
+	PushReadwise     string
+	PullIntegrations string
+	HookRetries      string
+}
+
+// Global job scheduler, started by StartJobs
+var jobs = scheduler.New()
+
+// archivePrefix is where archived page versions live in blob storage, as
+// archives/<bookmark id>/<version>/...
+const archivePrefix = "archives/"
+
+// registerJobs adds the maintenance jobs enabled in cfg to the scheduler.
+func registerJobs(cfg *Config) error {
+	if err := jobs.Add("dead-link-check", cfg.Jobs.DeadLinkCheck, withLock("dead-link-check", checkDeadLinks)); err != nil {
+		return err
+	}
+	// Export files live on the local disk of each instance, so cleanup runs
+	// everywhere rather than under a shared lock.
+	if err := jobs.Add("export-cleanup", cfg.Jobs.ExportCleanup, exports.cleanup); err != nil {
+		return err
+	}
+	if err := jobs.Add("archive-gc", cfg.Jobs.ArchiveGC, withLock("archive-gc", func(ctx context.Context) (string, error) {
+		return collectArchives(ctx, cfg.ArchiveRetention)
+	})); err != nil {
+		return err
+	}
+	// The embedded index lives in each instance's memory, so only external
+	// engines are synced under a shared lock.
+	syncSearch := syncSearchIndex
+	if cfg.Search.Backend != "embedded" {
+		syncSearch = withLock("search-sync", syncSearch)
+	}
+	if err := jobs.Add("search-sync", cfg.Jobs.SearchSync, syncSearch); err != nil {
+		return err
+	}
+	if err := jobs.Add("reminders", cfg.Jobs.Reminders, withLock("reminders", deliverReminders)); err != nil {
+		return err
+	}
+	if err := jobs.Add("auto-archive", cfg.Jobs.AutoArchive, withLock("auto-archive", archiveStaleBookmarks)); err != nil {
+		return err
+	}
+	if err := jobs.Add("purge-trash", cfg.Jobs.PurgeTrash, withLock("purge-trash", func(ctx context.Context) (string, error) {
+		return purgeTrash(ctx, cfg.TrashRetention)
+	})); err != nil {
+		return err
+	}
+	if err := jobs.Add("poll-discord", cfg.Jobs.PollDiscord, withLock("poll-discord", pollDiscordChannels)); err != nil {
+		return err
+	}
+	if err := jobs.Add("push-readwise", cfg.Jobs.PushReadwise, withLock("push-readwise", pushReadwiseHighlights)); err != nil {
+		return err
+	}
+	if err := jobs.Add("pull-integrations", cfg.Jobs.PullIntegrations, withLock("pull-integrations", pullIntegrations)); err != nil {
+		return err
+	}
+	return jobs.Add("hook-retries", cfg.Jobs.HookRetries, withLock("hook-retries", retryHookDeliveries))
+}
+
+// collectArchives deletes archived versions that fall outside policy and
+// reports the space reclaimed
+func collectArchives(ctx context.Context, policy retention.Policy) (string, error) {
+	if !policy.Enabled() {
+		return "no retention limits configured", nil
+	}
+	report, err := retention.Sweep(ctx, blobs, archivePrefix, policy)
+	if err != nil {
+		return "", fmt.Errorf("%w (%s)", err, report)
+	}
+	return report.String(), nil
+}
+
+// StartJobs runs the background scheduler and export workers until ctx is
+// cancelled. The returned function stops them and waits for running jobs
+// to finish.
+func StartJobs(ctx context.Context) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+
+	jobs.OnError(func(name string, err error) {
+		reporter.CaptureError(err, nil, map[string]interface{}{"job": name})
+	})
+	coordinator.Subscribe(ctx, bookmarkEventsChannel, handleBookmarkEvent)
+	coordinator.Subscribe(ctx, maintenanceChannel, handleMaintenanceEvent)
+	coordinator.Subscribe(ctx, collectionEventsChannel, collectionFeed.dispatch)
+	jobs.Start(ctx)
+	exports.start(ctx, &wg)
+	thumbnails.start(ctx, &wg)
+	readingTimes.start(ctx, &wg)
+	hookDeliveries.start(ctx, &wg)
+	federationDeliveries.start(ctx, &wg)
+	webmentions.start(ctx, &wg)
+
+	return func() {
+		cancel()
+		jobs.Stop()
+		wg.Wait()
+		reporter.Close(5 * time.Second)
+	}
+}
+
+// checkDeadLinks requests every bookmarked URL and reports the ones that
+// fail or answer with an error status. Bookmarked URLs come from users, so
+// URLs resolving to private addresses are never requested and count as
+// unreachable.
+func checkDeadLinks(ctx context.Context) (string, error) {
+	bookmarks, err := store.GetAll(ctx)
+	if err != nil {
+		return "", err
+	}
+	client := safehttp.Client(15 * time.Second)
+
+	var (
+		mu   sync.Mutex
+		dead []string
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, 8)
+	)
+	for _, b := range bookmarks {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id, url string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if !linkAlive(ctx, client, url) {
+				mu.Lock()
+				dead = append(dead, id)
+				mu.Unlock()
+			}
+		}(b.ID, b.URL)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if len(dead) == 0 {
+		return fmt.Sprintf("checked %d bookmarks, all reachable", len(bookmarks)), nil
+	}
+	return fmt.Sprintf("checked %d bookmarks, %d unreachable: %s",
+		len(bookmarks), len(dead), strings.Join(dead, ", ")), nil
+}
+
+func linkAlive(ctx context.Context, client *http.Client, url string) bool {
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return false
+		}
+		req.Header.Set("User-Agent", "web-collector-linkcheck/1.0")
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		// Some servers reject HEAD outright; retry those with GET.
+		if method == http.MethodHead && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+			continue
+		}
+		return resp.StatusCode < 400
+	}
+	return false
+}
+
+// handleGetJobs returns the schedule and last-run status of every job
+func handleGetJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    jobs.Statuses(),
+	})
+}
+
+// handleRunJob triggers a job immediately
+func handleRunJob(c *gin.Context) {
+	if err := jobs.RunNow(context.Background(), c.Param("name")); err != nil {
+		respondError(c, http.StatusConflict, apierr.Conflict, err.Error())
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Job started",
+	})
+}
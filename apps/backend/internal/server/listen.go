@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// sdListenFDsStart is the first file descriptor passed by systemd socket
+// activation (see sd_listen_fds(3))
+const sdListenFDsStart = 3
+
+// Listen opens the listener the HTTP server should accept on. In order of
+// precedence it uses a socket inherited from systemd socket activation, a
+// unix socket at SERVER_SOCKET, or TCP on SERVER_PORT. The returned string
+// describes the address for logging.
+func Listen(cfg *Config) (net.Listener, string, error) {
+	if ln, err := systemdListener(); err != nil {
+		return nil, "", err
+	} else if ln != nil {
+		return ln, "systemd socket " + ln.Addr().String(), nil
+	}
+
+	if cfg.ServerSocket != "" {
+		// A stale socket from an unclean shutdown would make Listen fail.
+		if info, err := os.Stat(cfg.ServerSocket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(cfg.ServerSocket)
+		}
+		ln, err := net.Listen("unix", cfg.ServerSocket)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := os.Chmod(cfg.ServerSocket, cfg.ServerSocketMode); err != nil {
+			ln.Close()
+			return nil, "", err
+		}
+		return ln, "unix socket " + cfg.ServerSocket, nil
+	}
+
+	ln, err := net.Listen("tcp", ":"+cfg.ServerPort)
+	if err != nil {
+		return nil, "", err
+	}
+	return ln, "port " + cfg.ServerPort, nil
+}
+
+// systemdListener returns the socket passed by systemd, or nil when the
+// process was not socket-activated
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("systemd passed %d sockets, expected 1", n)
+	}
+	// Don't let child processes believe they were activated too.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(uintptr(sdListenFDsStart), "systemd-socket")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("systemd socket: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+)
+
+// LoadShed middleware caps the number of requests handled concurrently.
+// Requests beyond maxInFlight are rejected immediately with 503 and a
+// Retry-After hint instead of queueing up and exhausting memory.
+func LoadShed(maxInFlight, retryAfterSeconds int) gin.HandlerFunc {
+	slots := make(chan struct{}, maxInFlight)
+	retryAfter := strconv.Itoa(retryAfterSeconds)
+
+	return func(c *gin.Context) {
+		// Keep health checks answering so orchestrators don't restart a
+		// busy but healthy instance. Event streams stay open for as long as
+		// a client watches, so they would hold a slot indefinitely.
+		if c.Request.URL.Path == "/health" || isEventStream(c) {
+			c.Next()
+			return
+		}
+
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			c.Next()
+		default:
+			c.Header("Retry-After", retryAfter)
+			respondErrorDetails(c, http.StatusServiceUnavailable, apierr.Overloaded, "Server is overloaded", "Too many concurrent requests, retry after "+retryAfter+"s")
+		}
+	}
+}
+
+// eventStreamRoutes are the routes that answer with a long-lived event
+// stream
+var eventStreamRoutes = map[string]bool{
+	"/api/v1/collections/:id/events": true,
+}
+
+// isEventStream reports whether the request was routed to an event stream.
+// It goes by the matched route rather than the Accept header, which any
+// client could set to slip past the limits.
+func isEventStream(c *gin.Context) bool {
+	return eventStreamRoutes[c.FullPath()]
+}
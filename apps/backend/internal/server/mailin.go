@@ -0,0 +1,90 @@
+package server
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/mailin"
+)
+
+var (
+	// Global inbound mail handler; nil unless MAIL_IN_SECRET is set
+	inbox *mailin.Inbox
+	// inboxCollectionID is the collection forwarded mail is saved into
+	inboxCollectionID string
+)
+
+// maxInboundMailBytes bounds a forwarded message, attachments included
+const maxInboundMailBytes = 10 << 20
+
+// RequireInbox hides the inbound mail webhook when it is not configured
+func RequireInbox() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if inbox == nil {
+			respondError(c, http.StatusNotFound, apierr.FeatureDisabled, "Inbound mail is not configured")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleInboundMail saves the link in a message forwarded to the inbound
+// address, with the message body as the bookmark's notes. The provider
+// posts the raw message, either as the request body or, as SendGrid does,
+// in an "email" form field. Mail from unknown senders, or without a link,
+// is answered with 200 all the same, since providers retry anything else
+// and retrying will not change the outcome.
+func handleInboundMail(c *gin.Context) {
+	if !inbox.Authentic(c.Param("secret")) {
+		respondError(c, http.StatusNotFound, apierr.NotFound, "Not found")
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxInboundMailBytes)
+	var raw io.Reader = c.Request.Body
+	if mediaType, _, _ := mime.ParseMediaType(c.ContentType()); strings.HasPrefix(mediaType, "multipart/") || mediaType == "application/x-www-form-urlencoded" {
+		raw = strings.NewReader(c.PostForm("email"))
+	}
+	msg, err := mailin.Parse(raw)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Message could not be read: "+err.Error())
+		return
+	}
+
+	var accountID string
+	if accounts != nil {
+		if account, found := accounts.GetAccountByEmail(c.Request.Context(), msg.From); found {
+			accountID = account.ID
+		}
+	}
+	if accountID == "" && !inbox.Sender(msg.From) {
+		log.Printf("Inbound mail from %q ignored: not an account or listed sender", msg.From)
+		respondError(c, http.StatusOK, apierr.Forbidden, "Sender may not save bookmarks")
+		return
+	}
+	link, ok := mailin.ParseLink(msg)
+	if !ok {
+		respondError(c, http.StatusOK, apierr.ValidationFailed, "Message has no link to save")
+		return
+	}
+
+	save := saveIntegrationLink(c.Request.Context(), "email", integrationLink{
+		URL:       link.URL,
+		Title:     link.Title,
+		Tags:      link.Tags,
+		Notes:     link.Notes,
+		AccountID: accountID,
+	}, inboxCollectionID)
+	if save.Refusal != "" {
+		respondError(c, http.StatusOK, apierr.SaveRefused, save.Refusal)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    save.Bookmark,
+	})
+}
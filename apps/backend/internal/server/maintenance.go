@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+)
+
+// Maintenance modes
+const (
+	maintenanceOff      = "off"
+	maintenanceReadOnly = "read-only"
+	maintenanceFull     = "full"
+)
+
+// maintenanceChannel broadcasts mode changes made through the admin API
+// to every instance
+const maintenanceChannel = "maintenance"
+
+const defaultMaintenanceMessage = "web-collector is undergoing maintenance and will be back shortly."
+
+// MaintenanceState describes the current maintenance mode
+type MaintenanceState struct {
+	Mode    string     `json:"mode"`
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// SetMaintenanceRequest represents the request body for changing the mode
+type SetMaintenanceRequest struct {
+	Mode    string `json:"mode" binding:"required,oneof=off read-only full"`
+	Message string `json:"message" binding:"max=500"`
+}
+
+var maintenance atomic.Pointer[MaintenanceState]
+
+func init() {
+	maintenance.Store(&MaintenanceState{Mode: maintenanceOff})
+}
+
+// setMaintenance switches modes, keeping the original start time when the
+// mode doesn't change
+func setMaintenance(mode, message string) error {
+	switch mode {
+	case "", maintenanceOff:
+		maintenance.Store(&MaintenanceState{Mode: maintenanceOff})
+		return nil
+	case maintenanceReadOnly, maintenanceFull:
+	default:
+		return fmt.Errorf("maintenance mode must be one of off, read-only, full (got %q)", mode)
+	}
+
+	current := maintenance.Load()
+	since := time.Now()
+	if current.Mode == mode && current.Since != nil {
+		since = *current.Since
+	}
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	maintenance.Store(&MaintenanceState{Mode: mode, Message: message, Since: &since})
+	return nil
+}
+
+// Maintenance middleware answers 503 while the API is in maintenance. In
+// read-only mode reads keep working and only writes are rejected.
+func Maintenance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := maintenance.Load()
+		switch state.Mode {
+		case maintenanceOff:
+			c.Next()
+			return
+		case maintenanceReadOnly:
+			switch c.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				c.Next()
+				return
+			}
+		}
+
+		c.Header("Retry-After", "300")
+		respondErrorDetails(c, http.StatusServiceUnavailable, apierr.Maintenance, "Service under maintenance", state)
+	}
+}
+
+// handleMaintenanceEvent applies a mode change published by any instance
+func handleMaintenanceEvent(message string) {
+	var state MaintenanceState
+	if err := json.Unmarshal([]byte(message), &state); err != nil {
+		return
+	}
+	if err := setMaintenance(state.Mode, state.Message); err != nil {
+		log.Printf("Ignoring maintenance event: %v", err)
+	}
+}
+
+// handleGetMaintenance returns the current maintenance mode
+func handleGetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    maintenance.Load(),
+	})
+}
+
+// handleSetMaintenance changes the maintenance mode on all instances
+func handleSetMaintenance(c *gin.Context) {
+	var req SetMaintenanceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := setMaintenance(req.Mode, req.Message); err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, err.Error())
+		return
+	}
+	state := maintenance.Load()
+	log.Printf("Maintenance mode set to %s", state.Mode)
+
+	payload, _ := json.Marshal(state)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := coordinator.Publish(ctx, maintenanceChannel, string(payload)); err != nil {
+		log.Printf("Failed to publish maintenance change: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    state,
+	})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var startTime = time.Now()
+
+// handleMetrics exposes process and application counters in the
+// Prometheus text format
+func handleMetrics(c *gin.Context) {
+	var buf bytes.Buffer
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("wc_http_responses_total", "counter", "HTTP responses by status class.")
+	for class, n := range requestStats.totals() {
+		if class > 0 {
+			fmt.Fprintf(&buf, "wc_http_responses_total{class=\"%dxx\"} %d\n", class, n)
+		}
+	}
+
+	if all, err := store.GetAll(c.Request.Context()); err == nil {
+		metric("wc_bookmarks", "gauge", "Number of stored bookmarks.")
+		fmt.Fprintf(&buf, "wc_bookmarks %d\n", len(all))
+	}
+
+	metric("wc_export_queue_depth", "gauge", "Export jobs waiting to run.")
+	fmt.Fprintf(&buf, "wc_export_queue_depth %d\n", exports.pending())
+
+	statuses := jobs.Statuses()
+	metric("wc_job_runs_total", "counter", "Completed runs per background job.")
+	for _, s := range statuses {
+		fmt.Fprintf(&buf, "wc_job_runs_total{job=%q} %d\n", s.Name, s.Runs)
+	}
+	metric("wc_job_failures_total", "counter", "Failed runs per background job.")
+	for _, s := range statuses {
+		fmt.Fprintf(&buf, "wc_job_failures_total{job=%q} %d\n", s.Name, s.Failures)
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	metric("go_goroutines", "gauge", "Number of goroutines.")
+	fmt.Fprintf(&buf, "go_goroutines %d\n", runtime.NumGoroutine())
+	metric("go_memstats_heap_alloc_bytes", "gauge", "Bytes of allocated heap objects.")
+	fmt.Fprintf(&buf, "go_memstats_heap_alloc_bytes %d\n", mem.HeapAlloc)
+	metric("process_uptime_seconds", "gauge", "Seconds since the server started.")
+	fmt.Fprintf(&buf, "process_uptime_seconds %.0f\n", time.Since(startTime).Seconds())
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/export"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// mimeCSV is the media type of CSV bookmark lists
+const mimeCSV = "text/csv"
+
+// listFormats are the media types bookmark lists can be read in; JSON,
+// first, is the default
+var listFormats = []string{gin.MIMEJSON, gin.MIMEXML, gin.MIMEXML2, mimeCSV}
+
+// bookmarkListXML is the XML form of a page of bookmarks
+type bookmarkListXML struct {
+	XMLName    xml.Name         `xml:"bookmarks"`
+	Total      int              `xml:"total,attr"`
+	Page       int              `xml:"page,attr"`
+	PerPage    int              `xml:"per_page,attr"`
+	TotalPages int              `xml:"total_pages,attr"`
+	Bookmarks  []model.Bookmark `xml:"bookmark"`
+}
+
+// respondBookmarkList writes a page of a bookmark list in the format the
+// Accept header asks for. JSON carries data, whose entries may add to
+// each bookmark, such as search scores; XML and CSV carry bookmarks, the
+// same page without the additions, and CSV has the columns of the CSV
+// export. Media types other than these get JSON.
+func respondBookmarkList(c *gin.Context, p pagination, data any, bookmarks []model.Bookmark) {
+	c.Writer.Header().Add("Vary", "Accept")
+	switch c.NegotiateFormat(listFormats...) {
+	case gin.MIMEXML, gin.MIMEXML2:
+		c.XML(http.StatusOK, bookmarkListXML{
+			Total:      p.Total,
+			Page:       p.Page,
+			PerPage:    p.PerPage,
+			TotalPages: p.TotalPages,
+			Bookmarks:  bookmarks,
+		})
+	case mimeCSV:
+		format, _ := export.Lookup("csv")
+		c.Header("Content-Type", format.ContentType)
+		c.Status(http.StatusOK)
+		if err := format.Write(c.Writer, bookmarks); err != nil {
+			log.Printf("Write CSV bookmark list: %v", err)
+		}
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"success":    true,
+			"data":       data,
+			"pagination": p,
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/sanitize"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global note store; nil when the storage driver cannot keep notes
+var noteStore storage.NoteStore
+
+// RequireNotes hides the note routes when the store cannot keep notes
+func RequireNotes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if noteStore == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Notes are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleSetNotes replaces a bookmark's notes; empty notes clear them.
+// Notes are plain text, so any markup in them is removed.
+func handleSetNotes(c *gin.Context) {
+	var req model.NotesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	id := c.Param("id")
+	var bookmark model.Bookmark
+	err := writeBookmark(c, id, func(tx storage.Store) error {
+		var err error
+		bookmark, err = tx.(storage.NoteStore).SetNotes(c.Request.Context(), id, strings.TrimSpace(sanitize.Plain(req.Notes)))
+		return err
+	})
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	indexBookmark(c.Request.Context(), bookmark)
+	publishBookmarkEvent("updated", id)
+	recordActivity(c, model.Activity{Kind: model.ActivityEdited, BookmarkID: id})
+	webmentions.enqueue(id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
+
+// withSelection keeps the text selected when a bookmark was saved as its
+// first note. Stores that cannot keep notes save the bookmark without it.
+func withSelection(ctx context.Context, b model.Bookmark, selection string) model.Bookmark {
+	selection = strings.TrimSpace(sanitize.Plain(selection))
+	if selection == "" || noteStore == nil || b.ID == "" {
+		return b
+	}
+	noted, err := noteStore.SetNotes(ctx, b.ID, selection)
+	if err != nil {
+		log.Printf("Keeping selection of bookmark %s: %v", b.ID, err)
+		return b
+	}
+	return noted
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/notion"
+)
+
+// notionPace spaces out pushed pages to stay under Notion's average of
+// three requests a second; each page takes two
+const notionPace = 700 * time.Millisecond
+
+// handleConnectNotion connects the signed-in account to a Notion database
+// with an integration token the database is shared with, replacing an
+// earlier connection. The database is checked before it is saved.
+func handleConnectNotion(c *gin.Context) {
+	var req model.NotionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	databaseID, ok := notion.ParseDatabaseID(req.DatabaseID)
+	if !ok {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "database_id must be a Notion database ID or URL")
+		return
+	}
+	if !checkPushCollections(c, req.CollectionIDs) {
+		return
+	}
+	if _, err := notion.Open(c.Request.Context(), req.Token, databaseID); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, apierr.UpstreamFailed, "Notion database could not be used", err.Error())
+		return
+	}
+
+	i, err := integrationStore.SetIntegration(c.Request.Context(), model.Integration{
+		AccountID:     currentActor(c).AccountID,
+		Service:       model.IntegrationNotion,
+		Token:         req.Token,
+		Target:        databaseID,
+		CollectionIDs: req.CollectionIDs,
+	})
+	if err != nil {
+		integrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    i,
+	})
+}
+
+// handlePushNotion pushes the bookmarks in the signed-in account's chosen
+// collections to its Notion database in the background. Pages already
+// there are updated; GET /profile/integrations shows how the push ended.
+func handlePushNotion(c *gin.Context) {
+	integrationPushes.start(c, model.IntegrationNotion, pushNotion)
+}
+
+// pushNotion maps each bookmark's title, URL, tags and notes to the
+// database's properties, stopping at the first bookmark Notion refuses
+func pushNotion(ctx context.Context, i model.Integration) error {
+	db, err := notion.Open(ctx, i.Token, i.Target)
+	if err != nil {
+		return err
+	}
+	for n, b := range accountBookmarks(ctx, i.AccountID, i.CollectionIDs) {
+		if n > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(notionPace):
+			}
+		}
+		page := notion.Page{Title: b.Title, URL: b.URL, Tags: b.Tags, Notes: b.Notes}
+		if err := db.Push(ctx, page); err != nil {
+			return fmt.Errorf("pushing %s: %w", b.URL, err)
+		}
+	}
+	return nil
+}
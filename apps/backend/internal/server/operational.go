@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerOperational mounts metrics, profiling and admin API routes on rg
+func registerOperational(rg *gin.RouterGroup) {
+	rg.GET("/metrics", handleMetrics)
+	registerPprof(rg)
+	registerAdminAPI(rg.Group("/api/v1/admin"))
+}
+
+func registerAdminAPI(admin *gin.RouterGroup) {
+	admin.GET("/stats", handleAdminStats)
+	admin.GET("/jobs", handleGetJobs)
+	admin.POST("/jobs/:name/run", handleRunJob)
+	admin.GET("/maintenance", handleGetMaintenance)
+	admin.PUT("/maintenance", handleSetMaintenance)
+	admin.GET("/domain-rules", RequireDomainRules(), handleGetDomainRules(instanceRules))
+	admin.PUT("/domain-rules", RequireDomainRules(), handleSetDomainRule(instanceRules))
+	admin.DELETE("/domain-rules/:domain", RequireDomainRules(), handleDeleteDomainRule(instanceRules))
+	admin.GET("/url-rules", RequireURLRules(), handleGetURLRules)
+	admin.PUT("/url-rules", RequireURLRules(), handleSetURLRules)
+}
+
+// SetupAdminRouter returns the router for the internal admin listener
+// (ADMIN_ADDR). It must be called after SetupRouter, which initializes the
+// shared state these endpoints report on. /metrics is left open for
+// scrapers since the listener is expected to be reachable only from inside
+// the deployment; everything else still requires admin credentials.
+func SetupAdminRouter(cfg *Config) *gin.Engine {
+	r := gin.New()
+	r.Use(Logger())
+	r.Use(Recovery())
+
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "ok",
+			"service": "web-collector-admin",
+		})
+	})
+	r.GET("/metrics", handleMetrics)
+
+	protected := r.Group("", AdminAuth(cfg.AdminToken))
+	registerPprof(protected)
+	registerAdminAPI(protected.Group("/api/v1/admin"))
+
+	return r
+}
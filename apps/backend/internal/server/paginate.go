@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+)
+
+const (
+	// defaultPerPage and maxPerPage bound a page of a list
+	defaultPerPage = 50
+	maxPerPage     = 200
+)
+
+// pagination describes the page of a list a response holds
+type pagination struct {
+	Total      int `json:"total"`
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	TotalPages int `json:"total_pages"`
+
+	// start and end bound the page within the list
+	start, end int
+}
+
+// paginate reads ?page= and ?per_page= for a list of total items, which
+// default to the first page of defaultPerPage items. It sets the X-Total-Count header and a Link header (RFC 5988) pointing
+// to the first, previous, next and last pages; on invalid parameters it
+// responds 400 and returns false.
+func paginate(c *gin.Context, total int) (pagination, bool) {
+	page, ok := pageParam(c, "page", 1, 0)
+	if !ok {
+		return pagination{}, false
+	}
+	perPage, ok := pageParam(c, "per_page", defaultPerPage, maxPerPage)
+	if !ok {
+		return pagination{}, false
+	}
+
+	p := pagination{Total: total, Page: page, PerPage: perPage, TotalPages: max((total+perPage-1)/perPage, 1)}
+	p.start = min((page-1)*perPage, total)
+	p.end = min(p.start+perPage, total)
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	links := []string{pageLink(c, 1, perPage, "first")}
+	if page > 1 {
+		links = append(links, pageLink(c, min(page-1, p.TotalPages), perPage, "prev"))
+	}
+	if page < p.TotalPages {
+		links = append(links, pageLink(c, page+1, perPage, "next"))
+	}
+	links = append(links, pageLink(c, p.TotalPages, perPage, "last"))
+	c.Header("Link", strings.Join(links, ", "))
+	return p, true
+}
+
+// pageParam reads a positive paging parameter, def when absent; max 0
+// leaves it unbounded
+func pageParam(c *gin.Context, name string, def, max int) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || (max > 0 && n > max) {
+		msg := name + " must be a positive number"
+		if max > 0 {
+			msg = name + " must be between 1 and " + strconv.Itoa(max)
+		}
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, msg)
+		return 0, false
+	}
+	return n, true
+}
+
+// pageLink returns a Link header entry for another page of the request's
+// list, keeping its other query parameters
+func pageLink(c *gin.Context, page, perPage int, rel string) string {
+	u := *c.Request.URL
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page))
+	q.Set("per_page", strconv.Itoa(perPage))
+	u.RawQuery = q.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+}
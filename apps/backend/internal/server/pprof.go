@@ -0,0 +1,20 @@
+package server
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerPprof mounts the net/http/pprof handlers under /debug/pprof
+func registerPprof(rg gin.IRoutes) {
+	rg.GET("/debug/pprof/", gin.WrapF(pprof.Index))
+	rg.GET("/debug/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+	rg.GET("/debug/pprof/profile", gin.WrapF(pprof.Profile))
+	rg.GET("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+	rg.POST("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+	rg.GET("/debug/pprof/trace", gin.WrapF(pprof.Trace))
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		rg.GET("/debug/pprof/"+name, gin.WrapH(pprof.Handler(name)))
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// bookmarkETag returns the entity tag of a bookmark's current state. It
+// covers every field, so it changes with any edit, including those that
+// happen within the same second.
+func bookmarkETag(b model.Bookmark) string {
+	raw, _ := json.Marshal(b)
+	sum := sha256.Sum256(raw)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// setBookmarkValidators sends the ETag and Last-Modified of b, for
+// clients to make their next write to it conditional
+func setBookmarkValidators(c *gin.Context, b model.Bookmark) {
+	c.Header("ETag", bookmarkETag(b))
+	c.Header("Last-Modified", b.UpdatedAt.UTC().Format(http.TimeFormat))
+}
+
+// preconditionsKey holds the conditions of a request's write
+const preconditionsKey = "preconditions"
+
+// errPreconditionFailed is returned by writeBookmark when the bookmark
+// changed since the client read it
+var errPreconditionFailed = errors.New("bookmark has changed since it was read")
+
+// preconditions are a request's If-Match and If-Unmodified-Since headers
+type preconditions struct {
+	ifMatch      string
+	ifUnmodified string
+}
+
+// CheckPreconditions makes the route's write honour If-Match and
+// If-Unmodified-Since, answering 412 when the bookmark changed since the
+// client read it. The handler checks them with writeBookmark, in the same
+// transaction as its write, so no other write can come in between.
+func CheckPreconditions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := preconditions{ifMatch: c.GetHeader("If-Match"), ifUnmodified: c.GetHeader("If-Unmodified-Since")}
+		if p != (preconditions{}) {
+			c.Set(preconditionsKey, p)
+		}
+		c.Next()
+	}
+}
+
+// writeBookmark runs write in a transaction, after checking the request's
+// preconditions against bookmark id as the transaction sees it
+func writeBookmark(c *gin.Context, id string, write func(tx storage.Store) error) error {
+	ctx := c.Request.Context()
+	return store.InTx(ctx, func(tx storage.Store) error {
+		value, ok := c.Get(preconditionsKey)
+		if !ok {
+			return write(tx)
+		}
+		p := value.(preconditions)
+		b, err := tx.GetByID(ctx, id)
+		switch {
+		case errors.Is(err, storage.ErrBookmarkNotFound) && p.ifMatch != "":
+			// No current representation can match
+			return errPreconditionFailed
+		case errors.Is(err, storage.ErrBookmarkNotFound):
+			// The write reports the missing bookmark
+			return write(tx)
+		case err != nil:
+			return err
+		}
+		if !p.hold(b) {
+			return errPreconditionFailed
+		}
+		return write(tx)
+	})
+}
+
+// hold reports whether b is still as the client read it. If-Unmodified-Since
+// is ignored alongside If-Match, as RFC 9110 asks.
+func (p preconditions) hold(b model.Bookmark) bool {
+	if p.ifMatch != "" {
+		return etagMatches(p.ifMatch, bookmarkETag(b))
+	}
+	since, err := http.ParseTime(p.ifUnmodified)
+	return err != nil || !b.UpdatedAt.Truncate(time.Second).After(since)
+}
+
+// etagMatches reports whether an If-Match header names etag, using the
+// strong comparison If-Match requires
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// profileBookmarkLimit caps the recent bookmarks shown on a profile
+const profileBookmarkLimit = 50
+
+// usernamePattern keeps usernames safe to put in a URL path
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{2,31}$`)
+
+// publicPageCSP lets the public pages apply their inline styles and
+// nothing else, so markup that got into a title or note cannot run script
+// or load anything even if it escaped sanitizing
+const publicPageCSP = "default-src 'none'; style-src 'unsafe-inline'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// publicProfile is what a public profile page shows
+type publicProfile struct {
+	Username    string             `json:"username"`
+	Collections []model.Collection `json:"collections"`
+	Bookmarks   []model.Bookmark   `json:"bookmarks"`
+}
+
+// handleGetProfile returns the signed-in account
+func handleGetProfile(c *gin.Context) {
+	account, _ := c.Get(accountKey)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    account,
+	})
+}
+
+// handleUpdateProfile sets the signed-in account's username and whether
+// its profile page is public
+func handleUpdateProfile(c *gin.Context) {
+	var req model.UpdateProfileRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	username := strings.ToLower(req.Username)
+	if !usernamePattern.MatchString(username) {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Usernames are 3 to 32 letters, digits, '-' or '_', starting with a letter or digit")
+		return
+	}
+
+	account, err := accounts.UpdateProfile(c.Request.Context(), currentActor(c).AccountID, username, req.Public)
+	switch {
+	case errors.Is(err, storage.ErrUsernameTaken):
+		respondError(c, http.StatusConflict, apierr.UsernameTaken, err.Error())
+		return
+	case err != nil:
+		log.Printf("Profile update failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Profile update failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    account,
+	})
+}
+
+// handlePublicProfile shows an account's public collections and the most
+// recent bookmarks in them, as HTML for browsers and JSON otherwise.
+// Fediverse servers asking for ActivityPub get the account's actor.
+// Accounts that have not opted in look the same as unknown ones.
+func handlePublicProfile(c *gin.Context) {
+	account, found := publicAccount(c.Request.Context(), c.Param("username"))
+	if !found {
+		respondError(c, http.StatusNotFound, apierr.ProfileNotFound, "Profile not found")
+		return
+	}
+	if federation != nil && wantsActivityPub(c) {
+		serveActor(c, account)
+		return
+	}
+
+	cols, bookmarks := publicBookmarks(c.Request.Context(), account)
+	if len(bookmarks) > profileBookmarkLimit {
+		bookmarks = bookmarks[:profileBookmarkLimit]
+	}
+	profile := publicProfile{
+		Username:    account.Username,
+		Collections: cols,
+		Bookmarks:   bookmarks,
+	}
+
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Header("Content-Security-Policy", publicPageCSP)
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Status(http.StatusOK)
+		if err := profileTemplate.Execute(c.Writer, profile); err != nil {
+			log.Printf("Render profile: %v", err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    profile,
+	})
+}
+
+// publicAccount returns the account with a username if its profile is
+// public
+func publicAccount(ctx context.Context, username string) (model.Account, bool) {
+	if accounts == nil {
+		return model.Account{}, false
+	}
+	account, found := accounts.GetAccountByUsername(ctx, username)
+	return account, found && account.PublicProfile && account.Username != ""
+}
+
+// publicBookmarks returns an account's public collections and the
+// bookmarks in them, newest first
+func publicBookmarks(ctx context.Context, account model.Account) ([]model.Collection, []model.Bookmark) {
+	cols, bookmarks := []model.Collection{}, []model.Bookmark{}
+	if collections == nil {
+		return cols, bookmarks
+	}
+	// What an anonymous visitor can see is exactly what is public.
+	visitor := storage.Restrict(collections, storage.Actor{})
+	seen := make(map[string]bool)
+	for _, col := range visitor.ListCollections(ctx) {
+		if col.OwnerID != account.ID {
+			continue
+		}
+		cols = append(cols, col)
+		ids, err := visitor.CollectionBookmarks(ctx, col.ID, false)
+		if err != nil {
+			continue
+		}
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if b, err := store.GetByID(ctx, id); err == nil {
+				bookmarks = append(bookmarks, b)
+			}
+		}
+	}
+	sort.SliceStable(bookmarks, func(i, j int) bool {
+		return bookmarks[i].CreatedAt.After(bookmarks[j].CreatedAt)
+	})
+	return cols, bookmarks
+}
+
+// publicBookmark returns one of an account's public bookmarks
+func publicBookmark(ctx context.Context, account model.Account, id string) (model.Bookmark, bool) {
+	_, bookmarks := publicBookmarks(ctx, account)
+	for _, b := range bookmarks {
+		if b.ID == id {
+			return b, true
+		}
+	}
+	return model.Bookmark{}, false
+}
+
+// publicOwner returns the account a bookmark is public for: the owner of
+// a public collection holding it, when that account's profile is public
+func publicOwner(ctx context.Context, bookmarkID string) (model.Account, bool) {
+	if accounts == nil || collections == nil {
+		return model.Account{}, false
+	}
+	visitor := storage.Restrict(collections, storage.Actor{})
+	for _, col := range visitor.ListCollections(ctx) {
+		ids, err := visitor.CollectionBookmarks(ctx, col.ID, false)
+		if err != nil || !slices.Contains(ids, bookmarkID) {
+			continue
+		}
+		if account, found := accounts.GetAccount(ctx, col.OwnerID); found && account.PublicProfile && account.Username != "" {
+			return account, true
+		}
+	}
+	return model.Account{}, false
+}
+
+// publicBookmarkPage is what a public bookmark's page shows
+type publicBookmarkPage struct {
+	Username string          `json:"username"`
+	Bookmark model.Bookmark  `json:"bookmark"`
+	Mentions []model.Mention `json:"mentions"`
+}
+
+// handlePublicBookmark shows one of an account's public bookmarks with
+// its notes, marked up as an h-entry for IndieWeb readers. It is the
+// source of the Webmentions sent for the bookmark and the target of the
+// ones received, and the Note federated for it.
+func handlePublicBookmark(c *gin.Context) {
+	account, found := publicAccount(c.Request.Context(), c.Param("username"))
+	var bookmark model.Bookmark
+	if found {
+		bookmark, found = publicBookmark(c.Request.Context(), account, c.Param("id"))
+	}
+	if !found {
+		respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found")
+		return
+	}
+	if federation != nil && wantsActivityPub(c) {
+		renderActivityPub(c, http.StatusOK, federation.Note(account.Username, bookmark))
+		return
+	}
+
+	page := publicBookmarkPage{Username: account.Username, Bookmark: bookmark, Mentions: []model.Mention{}}
+	if mentionStore != nil {
+		if mentions, err := mentionStore.BookmarkMentions(c.Request.Context(), bookmark.ID); err == nil {
+			page.Mentions = mentions
+		}
+	}
+	if mentioner != nil && mentionStore != nil {
+		c.Header("Link", "<"+mentioner.SiteURL()+`/webmention>; rel="webmention"`)
+	}
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Header("Content-Security-Policy", publicPageCSP)
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Status(http.StatusOK)
+		if err := bookmarkTemplate.Execute(c.Writer, page); err != nil {
+			log.Printf("Render bookmark page: %v", err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    page,
+	})
+}
+
+var profileTemplate = template.Must(template.New("profile").Funcs(template.FuncMap{
+	"host": func(raw string) string {
+		if u, err := url.Parse(raw); err == nil {
+			return u.Host
+		}
+		return ""
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Username}}'s bookmarks</title>
+<style>
+body { font: 16px/1.5 system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+nav { margin: 1rem 0; line-height: 2; }
+ul { list-style: none; padding: 0; }
+li { margin: .75rem 0; }
+.meta { display: block; color: #777; font-size: .85rem; }
+a { color: #0b57d0; }
+</style>
+</head>
+<body>
+<h1>{{.Username}}</h1>
+{{if .Collections}}<nav>Collections: {{range $i, $c := .Collections}}{{if $i}} &middot; {{end}}{{$c.Name}}{{end}}</nav>{{end}}
+<ul>
+{{range .Bookmarks}}  <li>
+    <a href="{{.URL}}" rel="noopener nofollow">{{.Title}}</a>
+    <span class="meta">{{host .URL}} &middot; {{.CreatedAt.Format "2006-01-02"}}{{range .Tags}} &middot; #{{.}}{{end}}</span>
+  </li>
+{{else}}  <li>No public bookmarks yet.</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+var bookmarkTemplate = template.Must(template.New("bookmark").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Bookmark.Title}}</title>
+<style>
+body { font: 16px/1.5 system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.e-content { white-space: pre-wrap; }
+.meta { color: #777; font-size: .85rem; }
+ul { list-style: none; padding: 0; }
+a { color: #0b57d0; }
+</style>
+</head>
+<body>
+<article class="h-entry">
+<h1 class="p-name"><a class="u-bookmark-of" href="{{.Bookmark.URL}}" rel="noopener nofollow">{{.Bookmark.Title}}</a></h1>
+{{with .Bookmark.Notes}}<div class="e-content">{{.}}</div>{{end}}
+<p class="meta">Saved by <a class="p-author h-card" href="/u/{{.Username}}">{{.Username}}</a> on <time class="dt-published" datetime="{{.Bookmark.CreatedAt.UTC.Format "2006-01-02T15:04:05Z07:00"}}">{{.Bookmark.CreatedAt.Format "2006-01-02"}}</time>{{range .Bookmark.Tags}} &middot; <span class="p-category">#{{.}}</span>{{end}}</p>
+</article>
+{{if .Mentions}}<h2>Mentioned by</h2>
+<ul>
+{{range .Mentions}}  <li><a href="{{.Source}}" rel="noopener nofollow ugc">{{if .Title}}{{.Title}}{{else}}{{.Source}}{{end}}</a></li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/pull"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// maxPullPages bounds how far back one pull reads; the first pull of a
+// long history imports its newest items only
+const maxPullPages = 10
+
+// pullService describes a service saved items are pulled from
+type pullService struct {
+	// name is how bookmarks and logs refer to the service
+	name string
+	// needsToken and needsUsername say which credentials it takes
+	needsToken, needsUsername bool
+	source                    func(i model.Integration) pull.Source
+}
+
+var pullServices = map[string]pullService{
+	model.IntegrationGitHub: {
+		name:       "GitHub",
+		needsToken: true,
+		source:     func(i model.Integration) pull.Source { return pull.GitHub(i.Token) },
+	},
+	model.IntegrationHackerNews: {
+		name:          "Hacker News",
+		needsUsername: true,
+		source:        func(i model.Integration) pull.Source { return pull.HackerNews(i.Target) },
+	},
+	model.IntegrationReddit: {
+		name:          "Reddit",
+		needsToken:    true,
+		needsUsername: true,
+		source:        func(i model.Integration) pull.Source { return pull.Reddit(i.Target, i.Token) },
+	},
+}
+
+// handleConnectPull returns the handler connecting the signed-in account
+// to service, replacing an earlier connection. Items pulled go into a
+// collection the account can edit. The credentials are checked before
+// they are saved; the first pull then imports the newest saved items.
+func handleConnectPull(service string) gin.HandlerFunc {
+	svc := pullServices[service]
+	return func(c *gin.Context) {
+		var req model.PullRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		req.Token, req.Username = strings.TrimSpace(req.Token), strings.TrimSpace(req.Username)
+		if (svc.needsToken && req.Token == "") || (svc.needsUsername && req.Username == "") {
+			var needs []string
+			if svc.needsToken {
+				needs = append(needs, "token")
+			}
+			if svc.needsUsername {
+				needs = append(needs, "username")
+			}
+			respondError(c, http.StatusBadRequest, apierr.ValidationFailed, svc.name+" needs a "+strings.Join(needs, " and a "))
+			return
+		}
+		if err := storage.CheckAccess(c.Request.Context(), collections, currentActor(c), req.CollectionID, model.AccessEdit); err != nil {
+			collectionError(c, err)
+			return
+		}
+
+		i := model.Integration{
+			AccountID:     currentActor(c).AccountID,
+			Service:       service,
+			CollectionIDs: []string{req.CollectionID},
+		}
+		if svc.needsToken {
+			i.Token = req.Token
+		}
+		if svc.needsUsername {
+			i.Target = req.Username
+		}
+		if err := svc.source(i).Check(c.Request.Context()); err != nil {
+			respondErrorDetails(c, http.StatusBadRequest, apierr.UpstreamFailed, svc.name+" could not be reached with these credentials", err.Error())
+			return
+		}
+
+		i, err := integrationStore.SetIntegration(c.Request.Context(), i)
+		if err != nil {
+			integrationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    i,
+		})
+	}
+}
+
+// handlePull returns the handler pulling the signed-in account's new
+// items from service in the background, without waiting for the
+// pull-integrations job
+func handlePull(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		integrationPushes.start(c, service, pullItems)
+	}
+}
+
+// pullItems saves the items an account saved in a service since the last
+// pull into its inbox collection, oldest first. Items already bookmarked
+// are skipped, and so are those the domain rules forbid.
+func pullItems(ctx context.Context, i model.Integration) error {
+	svc, ok := pullServices[i.Service]
+	if !ok {
+		return fmt.Errorf("unknown service %q", i.Service)
+	}
+	if len(i.CollectionIDs) == 0 {
+		return errors.New("no inbox collection")
+	}
+	inbox := i.CollectionIDs[0]
+	actor := storage.Actor{AccountID: i.AccountID}
+	if account, found := accounts.GetAccount(ctx, i.AccountID); found {
+		actor.Admin = account.Role == model.RoleAdmin
+	}
+	if err := storage.CheckAccess(ctx, collections, actor, inbox, model.AccessEdit); err != nil {
+		return fmt.Errorf("inbox collection: %w", err)
+	}
+
+	items, err := pull.Since(ctx, svc.source(i), i.Cursor, maxPullPages)
+	if err != nil {
+		return err
+	}
+	for n := len(items) - 1; n >= 0; n-- {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		item := items[n]
+		if !isWebURL(item.URL) {
+			continue
+		}
+		if _, found := savedBookmark(ctx, actor, item.URL); found {
+			continue
+		}
+		saved := saveIntegrationLink(ctx, svc.name, integrationLink{
+			URL:       item.URL,
+			Title:     item.Title,
+			Tags:      item.Tags,
+			Notes:     item.Notes,
+			AccountID: i.AccountID,
+		}, inbox)
+		if saved.Refusal != "" {
+			log.Printf("%s pull for account %s skipped %s: %s", svc.name, i.AccountID, item.URL, saved.Refusal)
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return integrationStore.SetIntegrationCursor(ctx, i.AccountID, i.Service, items[0].Key)
+}
+
+// pullIntegrations pulls every connected account's new items from the
+// services it is connected to, skipping connections already syncing
+func pullIntegrations(ctx context.Context) (string, error) {
+	if integrationStore == nil || collections == nil || accounts == nil {
+		return "integrations, collections or accounts are not supported by this storage driver", nil
+	}
+	var pulled, failed int
+	for service := range pullServices {
+		connected, err := integrationStore.ServiceIntegrations(ctx, service)
+		if err != nil {
+			return "", err
+		}
+		for _, i := range connected {
+			if ctx.Err() != nil {
+				break
+			}
+			if !integrationPushes.begin(i) {
+				continue
+			}
+			if err := integrationPushes.run(i, pullItems); err != nil {
+				failed++
+			} else {
+				pulled++
+			}
+			integrationPushes.end(i)
+		}
+	}
+	if failed > 0 {
+		return "", fmt.Errorf("pulled items for %d connections, %d failed", pulled, failed)
+	}
+	return fmt.Sprintf("pulled items for %d connections", pulled), nil
+}
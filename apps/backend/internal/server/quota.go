@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/retention"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// quotas are the per-account limits the store enforces, kept here to
+// report them
+var quotas storage.Quotas
+
+// archiveQuota caps the bytes of archived page versions kept for the
+// bookmarks of one account; 0 disables it. Snapshots are written outside
+// this server, so the limit is checked when saving new bookmarks, each of
+// which would be archived too.
+var archiveQuota int64
+
+// quotaUsage is how much of one quota an account uses. Limit is left out
+// when the quota is disabled.
+type quotaUsage struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit,omitempty"`
+}
+
+// quotaMessage turns an ErrQuotaExceeded error into a response message
+func quotaMessage(err error) string {
+	msg := err.Error()
+	return strings.ToUpper(msg[:1]) + msg[1:]
+}
+
+// archiveUsage returns the bytes taken up by the archived page versions
+// of the bookmarks owned by accountID
+func archiveUsage(ctx context.Context, accountID string) (int64, error) {
+	all, err := store.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	objects, err := blobs.List(ctx, archivePrefix)
+	if err != nil {
+		return 0, err
+	}
+	sizes := retention.Sizes(objects, archivePrefix)
+	var used int64
+	for _, b := range all {
+		if b.OwnerID == accountID {
+			used += sizes[b.ID]
+		}
+	}
+	return used, nil
+}
+
+// checkArchiveQuota returns ErrQuotaExceeded when the archived pages of
+// accountID's bookmarks leave no room for another bookmark
+func checkArchiveQuota(ctx context.Context, accountID string) error {
+	if accountID == "" || archiveQuota <= 0 {
+		return nil
+	}
+	used, err := archiveUsage(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if used >= archiveQuota {
+		return fmt.Errorf("%w: archived pages can take up at most %d bytes", storage.ErrQuotaExceeded, archiveQuota)
+	}
+	return nil
+}
+
+// createBookmark saves a new bookmark owned by accountID. The store
+// enforces the other quotas itself; the archive quota is checked first.
+func createBookmark(ctx context.Context, accountID, title, url string, tags []string) (model.Bookmark, error) {
+	if err := checkArchiveQuota(ctx, accountID); err != nil {
+		return model.Bookmark{}, err
+	}
+	return store.Create(ctx, accountID, title, url, tags)
+}
+
+// handleGetUsage reports how much of each quota the signed-in account
+// uses. The tags quota is per bookmark, so its use is the most tags on any
+// one of the account's bookmarks.
+func handleGetUsage(c *gin.Context) {
+	ctx := c.Request.Context()
+	accountID := currentActor(c).AccountID
+
+	all, err := store.GetAll(ctx)
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	bookmarks := quotaUsage{Limit: int64(quotas.MaxBookmarks)}
+	tags := quotaUsage{Limit: int64(quotas.MaxTags)}
+	for _, b := range all {
+		if b.OwnerID != accountID {
+			continue
+		}
+		bookmarks.Used++
+		tags.Used = max(tags.Used, int64(len(b.Tags)))
+	}
+	archive := quotaUsage{Limit: archiveQuota}
+	if archive.Used, err = archiveUsage(ctx, accountID); err != nil {
+		bookmarkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"bookmarks":         bookmarks,
+			"tags_per_bookmark": tags,
+			"archive_bytes":     archive,
+		},
+	})
+}
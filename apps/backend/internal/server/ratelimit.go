@@ -0,0 +1,176 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+)
+
+// RateLimiter enforces a fixed-window request quota per signed-in account,
+// falling back to the client IP for anonymous callers. A second, usually
+// larger, quota per client IP is checked first, before any credentials are
+// hashed, so a flood of sign-in attempts cannot keep the CPU busy. A limit
+// of zero or less disables either quota.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	ipLimit   int
+	window    time.Duration
+	windows   map[string]*rateWindow
+	ipWindows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter allows limit requests per caller and ipLimit requests per
+// client IP in each window
+func NewRateLimiter(limit, ipLimit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:     limit,
+		ipLimit:   ipLimit,
+		window:    window,
+		windows:   make(map[string]*rateWindow),
+		ipWindows: make(map[string]*rateWindow),
+	}
+}
+
+// Global rate limiter, configured from Config by applySettings
+var rateLimiter = NewRateLimiter(0, 0, time.Minute)
+
+// configure changes the quotas; counting restarts with the new settings
+func (rl *RateLimiter) configure(limit, ipLimit int, window time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if limit == rl.limit && ipLimit == rl.ipLimit && window == rl.window {
+		return
+	}
+	rl.limit = limit
+	rl.ipLimit = ipLimit
+	rl.window = window
+	rl.windows = make(map[string]*rateWindow)
+	rl.ipWindows = make(map[string]*rateWindow)
+}
+
+// allow counts a request for key and returns the limit in effect, the
+// remaining quota and the time the current window resets.
+func (rl *RateLimiter) allow(key string, now time.Time) (bool, int, int, time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.count(rl.windows, rl.limit, key, now)
+}
+
+// allowIP counts a request from a client IP against the per-IP quota
+func (rl *RateLimiter) allowIP(ip string, now time.Time) (bool, time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	allowed, _, _, reset := rl.count(rl.ipWindows, rl.ipLimit, ip, now)
+	return allowed, reset
+}
+
+func (rl *RateLimiter) count(windows map[string]*rateWindow, limit int, key string, now time.Time) (bool, int, int, time.Time) {
+	if limit <= 0 {
+		return true, 0, 0, time.Time{}
+	}
+
+	w, ok := windows[key]
+	if !ok || now.Sub(w.start) >= rl.window {
+		if len(windows) > 10000 {
+			rl.sweep(windows, now)
+		}
+		w = &rateWindow{start: now.Truncate(rl.window)}
+		windows[key] = w
+	}
+	reset := w.start.Add(rl.window)
+
+	if w.count >= limit {
+		return false, limit, 0, reset
+	}
+	w.count++
+	return true, limit, limit - w.count, reset
+}
+
+// sweep drops windows that have already expired
+func (rl *RateLimiter) sweep(windows map[string]*rateWindow, now time.Time) {
+	for key, w := range windows {
+		if now.Sub(w.start) >= rl.window {
+			delete(windows, key)
+		}
+	}
+}
+
+// rateLimitKey identifies the caller: the account its credentials sign in
+// to, or else its IP. Only checked credentials earn their own quota, so
+// a client cannot escape its limit by sending a new token or password
+// with every request. The IP is the connection's unless it comes from one
+// of the TRUSTED_PROXIES, so X-Forwarded-For cannot pick a bucket.
+func rateLimitKey(c *gin.Context) string {
+	if account, _, valid := basicAccount(c); valid {
+		return "account:" + account.ID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware enforces the quota and reports it with X-RateLimit-* headers
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		if allowed, reset := rl.allowIP(c.ClientIP(), now); !allowed {
+			rateLimited(c, reset)
+			return
+		}
+
+		allowed, limit, remaining, reset := rl.allow(rateLimitKey(c), now)
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+		if !allowed {
+			rateLimited(c, reset)
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimited rejects a request until reset
+func rateLimited(c *gin.Context, reset time.Time) {
+	retryAfter := int(time.Until(reset).Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	respondErrorDetails(c, http.StatusTooManyRequests, apierr.RateLimited, "Rate limit exceeded", "Too many requests, retry after "+strconv.Itoa(retryAfter)+"s")
+}
+
+// parseTrustedProxies parses a comma-separated list of proxy IPs and CIDR
+// ranges whose X-Forwarded-For headers are believed
+func parseTrustedProxies(list string) ([]string, error) {
+	var proxies []string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR range", entry)
+		}
+		proxies = append(proxies, entry)
+	}
+	return proxies, nil
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global reaction store; nil when the storage driver has no reaction
+// support
+var reactions storage.ReactionStore
+
+// maxEmojiRunes allows emoji built from several code points, like flags
+// and skin tone or ZWJ sequences, while keeping reactions short
+const maxEmojiRunes = 8
+
+// validEmoji reports whether s looks like a single emoji: symbols plus the
+// joiners and modifiers emoji sequences are made of, but no letters,
+// digits or spaces
+func validEmoji(s string) bool {
+	if s == "" || utf8.RuneCountInString(s) > maxEmojiRunes {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.So, r), unicode.Is(unicode.Sk, r):
+		case r == 0x200D, r == 0x20E3, r >= 0xFE00 && r <= 0xFE0F:
+		case r >= 0xE0020 && r <= 0xE007F: // tag sequences in subdivision flags
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// collectionBookmark is a bookmark listed in a collection with the
+// reactions it received there
+type collectionBookmark struct {
+	model.Bookmark
+	Reactions []model.ReactionCount `json:"reactions"`
+}
+
+// tallyReactions counts the reactions per bookmark and emoji, most used
+// first, marking those by accountID
+func tallyReactions(rs []model.Reaction, accountID string) map[string][]model.ReactionCount {
+	counts := make(map[string]map[string]*model.ReactionCount)
+	for _, r := range rs {
+		byEmoji := counts[r.BookmarkID]
+		if byEmoji == nil {
+			byEmoji = make(map[string]*model.ReactionCount)
+			counts[r.BookmarkID] = byEmoji
+		}
+		rc := byEmoji[r.Emoji]
+		if rc == nil {
+			rc = &model.ReactionCount{Emoji: r.Emoji}
+			byEmoji[r.Emoji] = rc
+		}
+		rc.Count++
+		if accountID != "" && r.AccountID == accountID {
+			rc.Reacted = true
+		}
+	}
+
+	result := make(map[string][]model.ReactionCount, len(counts))
+	for bookmarkID, byEmoji := range counts {
+		list := make([]model.ReactionCount, 0, len(byEmoji))
+		for _, rc := range byEmoji {
+			list = append(list, *rc)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Count != list[j].Count {
+				return list[i].Count > list[j].Count
+			}
+			return list[i].Emoji < list[j].Emoji
+		})
+		result[bookmarkID] = list
+	}
+	return result
+}
+
+// handleReaction adds (PUT) or withdraws (DELETE) the caller's emoji
+// reaction to a bookmark in a collection. Anyone who can view the
+// collection may react.
+func handleReaction(c *gin.Context) {
+	if reactions == nil {
+		respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Reactions are not supported by this storage driver")
+		return
+	}
+	id, bookmarkID, emoji := c.Param("id"), c.Param("bookmarkId"), c.Param("emoji")
+	if !validEmoji(emoji) {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Reactions must be a single emoji")
+		return
+	}
+	actor := currentActor(c)
+	if err := storage.CheckAccess(c.Request.Context(), collections, actor, id, model.AccessView); err != nil {
+		collectionError(c, err)
+		return
+	}
+
+	var err error
+	if c.Request.Method == http.MethodDelete {
+		err = reactions.RemoveReaction(c.Request.Context(), id, bookmarkID, actor.AccountID, emoji)
+	} else {
+		err = reactions.AddReaction(c.Request.Context(), id, bookmarkID, actor.AccountID, emoji)
+	}
+	if err != nil {
+		collectionError(c, err)
+		return
+	}
+	all, err := reactions.CollectionReactions(c.Request.Context(), id)
+	if err != nil {
+		collectionError(c, err)
+		return
+	}
+
+	publishCollectionEvent(c, model.CollectionEvent{
+		Type:         model.EventBookmarkReactions,
+		CollectionID: id,
+		BookmarkID:   bookmarkID,
+		Reactions:    tallyReactions(all, "")[bookmarkID],
+	})
+	tally := tallyReactions(all, actor.AccountID)[bookmarkID]
+	if tally == nil {
+		tally = []model.ReactionCount{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tally,
+	})
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global reading store; nil when the storage driver cannot track reading
+var reading storage.ReadingStore
+
+// RequireReading hides the read-it-later routes when the store cannot
+// track reading
+func RequireReading() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if reading == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Reading state is not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleMarkRead sets a bookmark's read state from {"is_read": bool}, or
+// toggles it when the body is empty
+func handleMarkRead(c *gin.Context) {
+	id := c.Param("id")
+	var req model.MarkReadRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	var bookmark model.Bookmark
+	err := writeBookmark(c, id, func(tx storage.Store) error {
+		current, err := tx.GetByID(c.Request.Context(), id)
+		if err != nil {
+			return err
+		}
+		read := !current.IsRead
+		if req.IsRead != nil {
+			read = *req.IsRead
+		}
+		bookmark, err = tx.(storage.ReadingStore).SetRead(c.Request.Context(), id, read)
+		return err
+	})
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	publishBookmarkEvent("updated", id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
+
+// handleUpdateProgress records where reading of a bookmark stopped, so a
+// client on another device can resume from there
+func handleUpdateProgress(c *gin.Context) {
+	id := c.Param("id")
+	var req model.UpdateProgressRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var bookmark model.Bookmark
+	err := writeBookmark(c, id, func(tx storage.Store) error {
+		var err error
+		bookmark, err = tx.(storage.ReadingStore).SetProgress(c.Request.Context(), id, model.ReadingProgress{
+			Percent:   *req.Percent,
+			Anchor:    req.Anchor,
+			UpdatedAt: time.Now().UTC(),
+		})
+		return err
+	})
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	publishBookmarkEvent("updated", id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
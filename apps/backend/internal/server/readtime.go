@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/extract"
+	"github.com/hereisth/web-collector/apps/backend/internal/flags"
+	"github.com/hereisth/web-collector/apps/backend/internal/safehttp"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// readingTimeManager counts the words of bookmarked pages in the
+// background, which gives bookmarks their reading time estimate
+type readingTimeManager struct {
+	mu      sync.Mutex
+	queued  map[string]bool
+	queue   chan string
+	client  *http.Client
+	workers int
+}
+
+// Global reading time manager, started by StartJobs
+var readingTimes = newReadingTimeManager(2)
+
+func newReadingTimeManager(workers int) *readingTimeManager {
+	return &readingTimeManager{
+		queued:  make(map[string]bool),
+		queue:   make(chan string, 256),
+		client:  safehttp.Client(20 * time.Second),
+		workers: workers,
+	}
+}
+
+// start runs the word count workers until ctx is cancelled
+func (m *readingTimeManager) start(ctx context.Context, wg *sync.WaitGroup) {
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-m.queue:
+					if err := m.count(ctx, id); err != nil {
+						log.Printf("Reading time for bookmark %s failed: %v", id, err)
+					}
+					m.mu.Lock()
+					delete(m.queued, id)
+					m.mu.Unlock()
+				}
+			}
+		}()
+	}
+}
+
+// enqueue schedules a word count for a bookmark when the feature is
+// enabled and the store can keep it. Like thumbnails, requests beyond the
+// queue's capacity are dropped.
+func (m *readingTimeManager) enqueue(id string) {
+	if reading == nil || !features.Load().Enabled(flags.ReadingTime, "") {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.queued[id] {
+		return
+	}
+	select {
+	case m.queue <- id:
+		m.queued[id] = true
+	default:
+	}
+}
+
+// count fetches the bookmark's page and stores its word count
+func (m *readingTimeManager) count(ctx context.Context, id string) error {
+	bookmark, err := store.GetByID(ctx, id)
+	if errors.Is(err, storage.ErrBookmarkNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	body, contentType, err := fetchLimited(ctx, m.client, "web-collector-reader/1.0", bookmark.URL, maxPageBytes)
+	if err != nil {
+		return fmt.Errorf("fetch page: %w", err)
+	}
+	if contentType != "" {
+		if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return nil
+		}
+	}
+
+	updated, err := reading.SetWordCount(ctx, id, extract.Words(bytes.NewReader(body)))
+	if errors.Is(err, storage.ErrBookmarkNotFound) {
+		// Deleted while its page was fetched
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	publishBookmarkEvent("updated", updated.ID)
+	return nil
+}
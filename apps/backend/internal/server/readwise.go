@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/readwise"
+)
+
+// handleConnectReadwise connects the signed-in account to Readwise with
+// an access token, replacing an earlier connection. The token is checked
+// before it is saved. The next push sends every highlight again, which
+// Readwise skips when it has them.
+func handleConnectReadwise(c *gin.Context) {
+	var req model.ReadwiseRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !checkPushCollections(c, req.CollectionIDs) {
+		return
+	}
+	if err := readwise.New(req.Token).Check(c.Request.Context()); err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, apierr.UpstreamFailed, "Readwise could not be reached with this token", err.Error())
+		return
+	}
+
+	i, err := integrationStore.SetIntegration(c.Request.Context(), model.Integration{
+		AccountID:     currentActor(c).AccountID,
+		Service:       model.IntegrationReadwise,
+		Token:         req.Token,
+		CollectionIDs: req.CollectionIDs,
+	})
+	if err != nil {
+		integrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    i,
+	})
+}
+
+// handlePushReadwise sends the signed-in account's new highlights to
+// Readwise in the background, without waiting for the push-readwise job
+func handlePushReadwise(c *gin.Context) {
+	integrationPushes.start(c, model.IntegrationReadwise, pushReadwise)
+}
+
+// pushReadwise sends the clips made since the last successful push of
+// the bookmarks in the account's collections. Clips are the highlights;
+// a bookmark's notes go with its first highlight.
+func pushReadwise(ctx context.Context, i model.Integration) error {
+	var highlights []readwise.Highlight
+	for _, b := range accountBookmarks(ctx, i.AccountID, i.CollectionIDs) {
+		note := strings.TrimSpace(b.Notes)
+		for _, clip := range bookmarkClips(ctx, b.ID) {
+			text := strings.TrimSpace(clip.Text)
+			if text == "" || (i.LastSyncAt != nil && !clip.CreatedAt.After(*i.LastSyncAt)) {
+				continue
+			}
+			highlights = append(highlights, readwise.Highlight{
+				Text:          text,
+				Title:         b.Title,
+				SourceURL:     b.URL,
+				Note:          note,
+				HighlightedAt: clip.CreatedAt,
+			})
+			note = ""
+		}
+	}
+	if len(highlights) == 0 {
+		return nil
+	}
+	return readwise.New(i.Token).Send(ctx, highlights)
+}
+
+// pushReadwiseHighlights sends every connected account's new highlights
+// to Readwise, skipping accounts whose push is already running
+func pushReadwiseHighlights(ctx context.Context) (string, error) {
+	if integrationStore == nil || collections == nil || clips == nil {
+		return "integrations, collections or clips are not supported by this storage driver", nil
+	}
+	connected, err := integrationStore.ServiceIntegrations(ctx, model.IntegrationReadwise)
+	if err != nil {
+		return "", err
+	}
+	var pushed, failed int
+	for _, i := range connected {
+		if ctx.Err() != nil {
+			break
+		}
+		if !integrationPushes.begin(i) {
+			continue
+		}
+		if err := integrationPushes.run(i, pushReadwise); err != nil {
+			failed++
+		} else {
+			pushed++
+		}
+		integrationPushes.end(i)
+	}
+	if failed > 0 {
+		return "", fmt.Errorf("pushed highlights for %d accounts, %d failed", pushed, failed)
+	}
+	return fmt.Sprintf("pushed highlights for %d accounts", pushed), nil
+}
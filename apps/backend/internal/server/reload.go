@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/flags"
+)
+
+// Request log levels, from most to least verbose
+const (
+	logLevelInfo  = iota // every request
+	logLevelWarn         // 4xx and 5xx responses
+	logLevelError        // 5xx responses only
+)
+
+var logLevels = map[string]int32{
+	"debug": logLevelInfo,
+	"info":  logLevelInfo,
+	"warn":  logLevelWarn,
+	"error": logLevelError,
+}
+
+// Settings that Reload can change while the server is running
+var (
+	corsOrigins atomic.Value // *corsPolicy
+	logLevel    atomic.Int32
+
+	// configuredMaintenance is the MAINTENANCE_MODE last applied, so a
+	// reload only overrides a mode set through the admin API when the
+	// configured value actually changed
+	configuredMaintenance string
+)
+
+func init() {
+	corsOrigins.Store(&corsPolicy{})
+}
+
+// shouldLogRequest reports whether the Logger middleware records a
+// response with status under the current log level
+func shouldLogRequest(status int) bool {
+	switch logLevel.Load() {
+	case logLevelError:
+		return status >= 500
+	case logLevelWarn:
+		return status >= 400
+	default:
+		return true
+	}
+}
+
+// applySettings installs the reloadable parts of cfg. Everything is
+// validated first, so an invalid config leaves the current settings intact.
+func applySettings(cfg *Config) error {
+	level, ok := logLevels[cfg.LogLevel]
+	if !ok {
+		return fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error (got %q)", cfg.LogLevel)
+	}
+	f, err := flags.Parse(cfg.FeatureFlags)
+	if err != nil {
+		return fmt.Errorf("FEATURE_FLAGS: %w", err)
+	}
+	if (cfg.RateLimit > 0 || cfg.IPRateLimit > 0) && cfg.RateLimitWindow <= 0 {
+		return fmt.Errorf("RATE_LIMIT_WINDOW must be positive")
+	}
+	switch cfg.MaintenanceMode {
+	case maintenanceOff, maintenanceReadOnly, maintenanceFull:
+	default:
+		return fmt.Errorf("MAINTENANCE_MODE must be one of off, read-only, full (got %q)", cfg.MaintenanceMode)
+	}
+	cors, err := parseCORSOrigins(cfg.CORSAllowedOrigins)
+	if err != nil {
+		return fmt.Errorf("CORS_ALLOWED_ORIGINS: %w", err)
+	}
+
+	corsOrigins.Store(cors)
+	logLevel.Store(level)
+	features.Store(f)
+	rateLimiter.configure(cfg.RateLimit, cfg.IPRateLimit, cfg.RateLimitWindow)
+	if cfg.MaintenanceMode != configuredMaintenance {
+		configuredMaintenance = cfg.MaintenanceMode
+		setMaintenance(cfg.MaintenanceMode, "")
+	}
+	return nil
+}
+
+// Reload applies CORS origins, rate limits, log level, maintenance mode and
+// feature flags
+// from cfg to the running server. Other settings need a restart.
+func Reload(cfg *Config) error {
+	if err := applySettings(cfg); err != nil {
+		return err
+	}
+	log.Printf("Configuration reloaded (cors=%s rate_limit=%d/%v ip_rate_limit=%d log_level=%s maintenance=%s feature_flags=%q)",
+		cfg.CORSAllowedOrigins, cfg.RateLimit, cfg.RateLimitWindow, cfg.IPRateLimit, cfg.LogLevel, maintenance.Load().Mode, cfg.FeatureFlags)
+	return nil
+}
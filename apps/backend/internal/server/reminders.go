@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/notify"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+var (
+	// Global reminder store; nil when the storage driver has no reminders
+	reminders storage.ReminderStore
+	// Global notifier for due reminders; nil when no channel is configured
+	notifier notify.Notifier
+)
+
+// RequireReminders hides the reminder routes when the store cannot keep
+// reminders
+func RequireReminders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if reminders == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Reminders are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// dueReminder is a due reminder together with its bookmark
+type dueReminder struct {
+	model.Reminder
+	Bookmark model.Bookmark `json:"bookmark"`
+}
+
+// handleSetReminder sets or replaces a bookmark's reminder. The signed-in
+// account, if any, is emailed when it is due.
+func handleSetReminder(c *gin.Context) {
+	var req model.SetReminderRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	reminder, err := reminders.SetReminder(c.Request.Context(), model.Reminder{
+		BookmarkID: c.Param("id"),
+		AccountID:  currentActor(c).AccountID,
+		RemindAt:   req.RemindAt.UTC(),
+	})
+	if err != nil {
+		reminderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reminder,
+	})
+}
+
+// handleDeleteReminder cancels a bookmark's reminder
+func handleDeleteReminder(c *gin.Context) {
+	if err := reminders.DeleteReminder(c.Request.Context(), c.Param("id")); err != nil {
+		reminderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reminder deleted",
+	})
+}
+
+// handleGetReminders lists the reminders that are due, delivered or not,
+// with their bookmarks, for the bookmarks the caller can view. Reminders
+// stay listed until they are deleted.
+func handleGetReminders(c *gin.Context) {
+	due, err := reminders.DueReminders(c.Request.Context(), time.Now())
+	if err != nil {
+		reminderError(c, err)
+		return
+	}
+	result := make([]dueReminder, 0, len(due))
+	for _, r := range due {
+		if b, err := store.GetByID(c.Request.Context(), r.BookmarkID); err == nil && canViewBookmark(c, b) {
+			result = append(result, dueReminder{Reminder: r, Bookmark: b})
+		}
+	}
+	p, ok := paginate(c, len(result))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       result[p.start:p.end],
+		"pagination": p,
+	})
+}
+
+func reminderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrBookmarkNotFound):
+		respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found")
+	case errors.Is(err, storage.ErrReminderNotFound):
+		respondError(c, http.StatusNotFound, apierr.ReminderNotFound, "Reminder not found")
+	default:
+		log.Printf("Reminder operation failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Reminder operation failed")
+	}
+}
+
+// deliverReminders sends every due reminder not yet delivered. Reminders
+// whose delivery fails are retried on the next run.
+func deliverReminders(ctx context.Context) (string, error) {
+	if reminders == nil || notifier == nil {
+		return "no reminder delivery configured", nil
+	}
+	now := time.Now()
+	due, err := reminders.DueReminders(ctx, now)
+	if err != nil {
+		return "", err
+	}
+
+	var sent, failed int
+	for _, r := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		if r.DeliveredAt != nil {
+			continue
+		}
+		b, err := store.GetByID(ctx, r.BookmarkID)
+		if err != nil {
+			continue
+		}
+		err = notifier.Send(ctx, reminderMessage(ctx, r, b))
+		if errors.Is(err, notify.ErrNoRecipient) {
+			// Only listed at /api/v1/reminders
+			continue
+		}
+		if err != nil {
+			log.Printf("Reminder for bookmark %s failed: %v", r.BookmarkID, err)
+			failed++
+			continue
+		}
+		if err := reminders.MarkReminderDelivered(ctx, r.BookmarkID, now); err != nil {
+			return "", err
+		}
+		sent++
+	}
+
+	report := fmt.Sprintf("%d reminders sent", sent)
+	if failed > 0 {
+		return report, fmt.Errorf("%d of %d reminders failed", failed, sent+failed)
+	}
+	return report, ctx.Err()
+}
+
+// reminderMessage builds the notification for a due reminder, addressed
+// to the account that set it when there is one
+func reminderMessage(ctx context.Context, r model.Reminder, b model.Bookmark) notify.Message {
+	msg := notify.Message{
+		Subject: "Reminder: " + b.Title,
+		Text:    fmt.Sprintf("You asked to be reminded to read this:\n\n%s\n%s\n", b.Title, b.URL),
+		Data:    dueReminder{Reminder: r, Bookmark: b},
+	}
+	if r.AccountID != "" && accounts != nil {
+		if account, found := accounts.GetAccount(ctx, r.AccountID); found {
+			msg.To = account.Email
+		}
+	}
+	return msg
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/search"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global search index, or nil when ?q= falls back to substring filtering.
+// Opened in SetupRouter and kept in sync by the bookmark handlers.
+var searchIndex search.Index
+
+// reindexBatchSize bounds the documents sent to the index per call, which
+// keeps bulk requests to external engines small
+const reindexBatchSize = 500
+
+// rebuildSearchIndex loads every bookmark into the index and returns how
+// many there were
+func rebuildSearchIndex(ctx context.Context) (int, error) {
+	bookmarks, err := store.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for start := 0; start < len(bookmarks); start += reindexBatchSize {
+		end := min(start+reindexBatchSize, len(bookmarks))
+		docs := make([]search.Document, 0, end-start)
+		for _, b := range bookmarks[start:end] {
+			docs = append(docs, search.FromBookmark(b))
+		}
+		if err := searchIndex.Index(ctx, docs...); err != nil {
+			return 0, err
+		}
+	}
+	return len(bookmarks), nil
+}
+
+// syncSearchIndex re-sends every bookmark to the index, repairing writes
+// that failed or were lost while the search engine was unavailable. The
+// archive index is refreshed too, which is how new snapshots become
+// searchable.
+func syncSearchIndex(ctx context.Context) (string, error) {
+	if searchIndex == nil {
+		return "no search backend configured", nil
+	}
+	indexed, err := rebuildSearchIndex(ctx)
+	if err != nil {
+		return "", err
+	}
+	report := fmt.Sprintf("indexed %d bookmarks", indexed)
+	if archiveIndex != nil {
+		n, err := rebuildArchiveIndex(ctx)
+		if err != nil {
+			return "", fmt.Errorf("archive index: %w", err)
+		}
+		report += fmt.Sprintf(" and %d snapshots", n)
+	}
+	return report, nil
+}
+
+// indexBookmark adds or refreshes a bookmark in the search index
+func indexBookmark(ctx context.Context, b model.Bookmark) {
+	if searchIndex == nil {
+		return
+	}
+	if err := searchIndex.Index(ctx, search.FromBookmark(b)); err != nil {
+		log.Printf("Failed to index bookmark %s: %v", b.ID, err)
+	}
+}
+
+// unindexBookmark removes a bookmark from the search index
+func unindexBookmark(ctx context.Context, id string) {
+	if searchIndex == nil {
+		return
+	}
+	if err := searchIndex.Delete(ctx, id); err != nil {
+		log.Printf("Failed to remove bookmark %s from search index: %v", id, err)
+	}
+	if archiveIndex == nil {
+		return
+	}
+	if err := archiveIndex.Delete(ctx, id); err != nil {
+		log.Printf("Failed to remove bookmark %s from archive index: %v", id, err)
+	}
+}
+
+// searchResult is a bookmark matching a search, with its relevance score
+// and HTML fragments showing where the query matched
+type searchResult struct {
+	model.Bookmark
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// searchBookmarks returns the bookmarks matching query in relevance order,
+// keeping only those that pass filter
+func searchBookmarks(ctx context.Context, query string, filter bookmarkFilter) ([]searchResult, error) {
+	hits, err := searchIndex.Search(ctx, query, 0)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]searchResult, 0, len(hits))
+	for _, hit := range hits {
+		b, err := store.GetByID(ctx, hit.ID)
+		if errors.Is(err, storage.ErrBookmarkNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !filter.matches(b) {
+			continue
+		}
+		result = append(result, searchResult{
+			Bookmark:   b,
+			Score:      hit.Score,
+			Highlights: search.Highlights(search.FromBookmark(b), query),
+		})
+	}
+	return result, nil
+}
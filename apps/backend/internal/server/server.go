@@ -1,54 +1,268 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"sync"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/activitypub"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/blob"
+	"github.com/hereisth/web-collector/apps/backend/internal/coord"
+	"github.com/hereisth/web-collector/apps/backend/internal/discord"
+	"github.com/hereisth/web-collector/apps/backend/internal/encrypt"
+	"github.com/hereisth/web-collector/apps/backend/internal/flags"
+	"github.com/hereisth/web-collector/apps/backend/internal/mailin"
 	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/notify"
+	"github.com/hereisth/web-collector/apps/backend/internal/retention"
+	"github.com/hereisth/web-collector/apps/backend/internal/sanitize"
+	"github.com/hereisth/web-collector/apps/backend/internal/search"
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+	"github.com/hereisth/web-collector/apps/backend/internal/sentry"
+	"github.com/hereisth/web-collector/apps/backend/internal/slack"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+	"github.com/hereisth/web-collector/apps/backend/internal/telegram"
+	"github.com/hereisth/web-collector/apps/backend/internal/webmention"
 )
 
 // Config holds application configuration
 type Config struct {
-	ServerPort        string
-	ServerHost        string
-	GinMode           string
-	Database          DatabaseConfig
-	JWTSecret         string
-	JWTExpiration     string
+	ServerPort         string
+	ServerHost         string
+	ServerSocket       string
+	ServerSocketMode   os.FileMode
+	GinMode            string
+	Database           DatabaseConfig
+	JWTSecret          string
+	JWTExpiration      string
 	CORSAllowedOrigins string
+	TLSCertFile        string
+	TLSKeyFile         string
+	CompressMinLength  int
+	MaxBodyBytes       int64
+	RequestTimeout     time.Duration
+	URLDNSCheck        bool
+	AdminToken         string
+	AdminAddr          string
+	AdminEmail         string
+	AdminPassword      string
+	RedisURL           string
+	ResponseCacheTTL   time.Duration
+	BookmarkCacheSize  int
+	BookmarkCacheTTL   time.Duration
+	Blob               blob.Config
+	Search             search.Config
+	ExportTTL          time.Duration
+	ArchiveRetention   retention.Policy
+	ArchiveQuota       int64
+	TrashRetention     time.Duration
+	EncryptionKey      string
+	FeatureFlags       string
+	RateLimit          int
+	IPRateLimit        int
+	RateLimitWindow    time.Duration
+	TrustedProxies     string
+	LogLevel           string
+	MaintenanceMode    string
+	MaxInFlight        int
+	LoadShedRetryAfter int
+	SentryDSN          string
+	SentryEnvironment  string
+	IdempotencyTTL     time.Duration
+	SeedData           string
+	Jobs               JobsConfig
+	Notify             notify.Config
+	Telegram           telegram.Config
+	Slack              slack.Config
+	Discord            discord.Config
+	MailIn             mailin.Config
+	Federation         activitypub.Config
+	Webmention         webmention.Config
 }
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
+	Driver   string
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// ReplicaDSN is an optional read-only replica used for queries
+	ReplicaDSN string
+
+	// Connection pool and timeouts
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	QueryTimeout    time.Duration
+	AutoMigrate     bool
+	Options         map[string]string
+
+	// Quotas limit what each account keeps in the store
+	Quotas storage.Quotas
+}
+
+// DSN returns the PostgreSQL connection string
+func (d DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.DBName, d.SSLMode)
 }
 
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
 	return &Config{
-		ServerPort:        getEnv("SERVER_PORT", "8080"),
-		ServerHost:        getEnv("SERVER_HOST", "0.0.0.0"),
-		GinMode:           getEnv("GIN_MODE", "debug"),
-		JWTSecret:         getEnv("JWT_SECRET", "secret"),
-		JWTExpiration:     getEnv("JWT_EXPIRATION", "24h"),
+		ServerPort:         getEnv("SERVER_PORT", "8080"),
+		ServerHost:         getEnv("SERVER_HOST", "0.0.0.0"),
+		ServerSocket:       getEnv("SERVER_SOCKET", ""),
+		ServerSocketMode:   getEnvFileMode("SERVER_SOCKET_MODE", 0o660),
+		GinMode:            getEnv("GIN_MODE", "debug"),
+		JWTSecret:          getEnv("JWT_SECRET", "secret"),
+		JWTExpiration:      getEnv("JWT_EXPIRATION", "24h"),
 		CORSAllowedOrigins: getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3000"),
+		TLSCertFile:        getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:         getEnv("TLS_KEY_FILE", ""),
+		CompressMinLength:  getEnvInt("COMPRESS_MIN_LENGTH", 1024),
+		MaxBodyBytes:       int64(getEnvInt("MAX_BODY_BYTES", 1<<20)),
+		RequestTimeout:     getEnvDuration("REQUEST_TIMEOUT", 30*time.Second),
+		URLDNSCheck:        getEnv("URL_DNS_CHECK", "false") == "true",
+		AdminToken:         getEnv("ADMIN_TOKEN", ""),
+		AdminAddr:          getEnv("ADMIN_ADDR", ""),
+		AdminEmail:         getEnv("ADMIN_EMAIL", ""),
+		AdminPassword:      getEnv("ADMIN_PASSWORD", ""),
+		RedisURL:           getEnv("REDIS_URL", ""),
+		ResponseCacheTTL:   getEnvDuration("RESPONSE_CACHE_TTL", 30*time.Second),
+		BookmarkCacheSize:  getEnvInt("BOOKMARK_CACHE_SIZE", 1024),
+		BookmarkCacheTTL:   getEnvDuration("BOOKMARK_CACHE_TTL", time.Minute),
+		// EXPORT_DIR predates blob storage and is still honored
+		Blob: blob.Config{
+			Driver:      getEnv("BLOB_DRIVER", "local"),
+			Dir:         getEnv("BLOB_DIR", getEnv("EXPORT_DIR", filepath.Join(os.TempDir(), "web-collector"))),
+			S3Endpoint:  getEnv("S3_ENDPOINT", ""),
+			S3Region:    getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:    getEnv("S3_BUCKET", ""),
+			S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+			S3PathStyle: getEnv("S3_PATH_STYLE", "false") == "true",
+			Options:     getEnvOptions("BLOB_OPTION_"),
+		},
+		Search: search.Config{
+			Backend:           getEnv("SEARCH_BACKEND", ""),
+			FoldDiacritics:    getEnv("SEARCH_FOLD_DIACRITICS", "true") == "true",
+			URL:               getEnv("ELASTICSEARCH_URL", "http://localhost:9200"),
+			Index:             getEnv("ELASTICSEARCH_INDEX", "bookmarks"),
+			Username:          getEnv("ELASTICSEARCH_USERNAME", ""),
+			Password:          getEnv("ELASTICSEARCH_PASSWORD", ""),
+			APIKey:            getEnv("ELASTICSEARCH_API_KEY", ""),
+			MeilisearchURL:    getEnv("MEILISEARCH_URL", "http://localhost:7700"),
+			MeilisearchIndex:  getEnv("MEILISEARCH_INDEX", "bookmarks"),
+			MeilisearchAPIKey: getEnv("MEILISEARCH_API_KEY", ""),
+		},
+		ExportTTL: getEnvDuration("EXPORT_TTL", 24*time.Hour),
+		ArchiveRetention: retention.Policy{
+			MaxAge:       getEnvDuration("ARCHIVE_MAX_AGE", 0),
+			MaxBytes:     int64(getEnvInt("ARCHIVE_MAX_BYTES", 0)),
+			KeepVersions: getEnvInt("ARCHIVE_KEEP_VERSIONS", 0),
+		},
+		ArchiveQuota:       int64(getEnvInt("QUOTA_ARCHIVE_BYTES", 0)),
+		TrashRetention:     getEnvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		EncryptionKey:      getEnv("ENCRYPTION_KEY", ""),
+		FeatureFlags:       getEnv("FEATURE_FLAGS", ""),
+		RateLimit:          getEnvInt("RATE_LIMIT", 600),
+		IPRateLimit:        getEnvInt("IP_RATE_LIMIT", 1200),
+		RateLimitWindow:    getEnvDuration("RATE_LIMIT_WINDOW", time.Minute),
+		TrustedProxies:     getEnv("TRUSTED_PROXIES", ""),
+		LogLevel:           getEnv("LOG_LEVEL", "info"),
+		MaintenanceMode:    getEnv("MAINTENANCE_MODE", "off"),
+		MaxInFlight:        getEnvInt("MAX_IN_FLIGHT", 256),
+		LoadShedRetryAfter: getEnvInt("LOAD_SHED_RETRY_AFTER", 5),
+		SentryDSN:          getEnv("SENTRY_DSN", ""),
+		SentryEnvironment:  getEnv("SENTRY_ENVIRONMENT", "development"),
+		IdempotencyTTL:     getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		SeedData:           getEnv("SEED_DATA", ""),
 		Database: DatabaseConfig{
+			Driver:   getEnv("DB_DRIVER", "memory"),
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "5432"),
 			User:     getEnv("DB_USER", "postgres"),
 			Password: getEnv("DB_PASSWORD", "postgres"),
 			DBName:   getEnv("DB_NAME", "web_collector"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			ReplicaDSN: getEnv("DB_REPLICA_DSN", ""),
+
+			MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+			QueryTimeout:    getEnvDuration("DB_QUERY_TIMEOUT", 5*time.Second),
+			AutoMigrate:     getEnv("DB_AUTO_MIGRATE", "false") == "true",
+			Options:         getEnvOptions("DB_OPTION_"),
+
+			Quotas: storage.Quotas{
+				MaxBookmarks: getEnvInt("QUOTA_BOOKMARKS", 0),
+				MaxTags:      getEnvInt("QUOTA_TAGS_PER_BOOKMARK", 0),
+			},
+		},
+		Jobs: JobsConfig{
+			DeadLinkCheck:    getEnv("JOB_DEAD_LINK_CHECK", "0 4 * * *"),
+			ExportCleanup:    getEnv("JOB_EXPORT_CLEANUP", "@hourly"),
+			ArchiveGC:        getEnv("JOB_ARCHIVE_GC", "30 3 * * *"),
+			SearchSync:       getEnv("JOB_SEARCH_SYNC", "@every 15m"),
+			Reminders:        getEnv("JOB_REMINDERS", "@every 1m"),
+			AutoArchive:      getEnv("JOB_AUTO_ARCHIVE", "15 4 * * *"),
+			PurgeTrash:       getEnv("JOB_PURGE_TRASH", "45 4 * * *"),
+			PollDiscord:      getEnv("JOB_POLL_DISCORD", "@every 1m"),
+			PushReadwise:     getEnv("JOB_PUSH_READWISE", "@hourly"),
+			PullIntegrations: getEnv("JOB_PULL_INTEGRATIONS", "30 5 * * *"),
+			HookRetries:      getEnv("JOB_HOOK_RETRIES", "@every 1m"),
+		},
+		Notify: notify.Config{
+			SMTPAddr:     getEnv("SMTP_ADDR", ""),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			SMTPFrom:     getEnv("SMTP_FROM", ""),
+			EmailTo:      getEnv("REMINDER_EMAIL_TO", ""),
+			WebhookURL:   getEnv("REMINDER_WEBHOOK_URL", ""),
+		},
+		Telegram: telegram.Config{
+			Token:         getEnv("TELEGRAM_BOT_TOKEN", ""),
+			WebhookSecret: getEnv("TELEGRAM_WEBHOOK_SECRET", ""),
+			WebhookURL:    getEnv("TELEGRAM_WEBHOOK_URL", ""),
+			AllowedChats:  getEnv("TELEGRAM_ALLOWED_CHATS", ""),
+		},
+		Slack: slack.Config{
+			SigningSecret: getEnv("SLACK_SIGNING_SECRET", ""),
+			BotToken:      getEnv("SLACK_BOT_TOKEN", ""),
+			CollectionID:  getEnv("SLACK_COLLECTION_ID", ""),
+		},
+		Discord: discord.Config{
+			Token:     getEnv("DISCORD_BOT_TOKEN", ""),
+			PublicKey: getEnv("DISCORD_PUBLIC_KEY", ""),
+			Channels:  getEnv("DISCORD_CHANNELS", ""),
+		},
+		MailIn: mailin.Config{
+			Secret:       getEnv("MAIL_IN_SECRET", ""),
+			Senders:      getEnv("MAIL_IN_SENDERS", ""),
+			CollectionID: getEnv("MAIL_IN_COLLECTION_ID", ""),
+		},
+		Federation: activitypub.Config{
+			URL: getEnv("FEDERATION_URL", ""),
+		},
+		Webmention: webmention.Config{
+			SiteURL: getEnv("WEBMENTION_SITE_URL", ""),
 		},
 	}
 }
@@ -60,13 +274,67 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// CORS middleware
-func CORS(allowedOrigins string) gin.HandlerFunc {
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+		log.Printf("Warning: invalid integer for %s: %q, using %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvOptions collects variables named prefix+NAME into a map keyed by
+// the lowercased NAME, for driver-specific settings
+func getEnvOptions(prefix string) map[string]string {
+	options := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if name := strings.TrimPrefix(key, prefix); name != key && name != "" {
+			options[strings.ToLower(name)] = value
+		}
+	}
+	return options
+}
+
+func getEnvFileMode(key string, defaultValue os.FileMode) os.FileMode {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseUint(value, 8, 32); err == nil {
+			return os.FileMode(n)
+		}
+		log.Printf("Warning: invalid octal file mode for %s: %q, using %o", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Warning: invalid duration for %s: %q, using %s", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// CORS middleware; the allowed origins come from Config and can be
+// changed by Reload. A request's Origin is echoed back only when the list
+// allows it, so responses vary by Origin unless any origin is allowed.
+func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", allowedOrigins)
+		policy := corsOrigins.Load().(*corsPolicy)
+		if policy.any {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Writer.Header().Add("Vary", "Origin")
+			if origin := c.GetHeader("Origin"); policy.allows(origin) {
+				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-User-ID, Idempotency-Key, If-Match, If-Unmodified-Since")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After, X-Total-Count, Link, ETag, Last-Modified")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -93,8 +361,12 @@ func Logger() gin.HandlerFunc {
 		method := c.Request.Method
 		statusCode := c.Writer.Status()
 
+		if !shouldLogRequest(statusCode) {
+			return
+		}
+
 		if raw != "" {
-			path = path + "?" + raw
+			path = path + "?" + redactQuery(raw)
 		}
 
 		log.Printf("[%s] %s %s %d %v",
@@ -113,125 +385,248 @@ func Recovery() gin.HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printf("Panic recovered: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"success": false,
-					"error":   "Internal server error",
-					"message": "Something went wrong",
-				})
-				c.Abort()
+				reporter.CapturePanic(err, c.Request)
+				respondError(c, http.StatusInternalServerError, apierr.Internal, "Internal server error")
 			}
 		}()
 		c.Next()
 	}
 }
 
-// BookmarkStore is a simple in-memory store for bookmarks (for development)
-type BookmarkStore struct {
-	mu        sync.RWMutex
-	bookmarks []model.Bookmark
-	nextID    int
-}
-
-// NewBookmarkStore creates a new bookmark store with sample data
-func NewBookmarkStore() *BookmarkStore {
-	return &BookmarkStore{
-		bookmarks: []model.Bookmark{
-			{ID: "1", Title: "Google", URL: "https://google.com", CreatedAt: time.Now()},
-			{ID: "2", Title: "GitHub", URL: "https://github.com", CreatedAt: time.Now()},
-			{ID: "3", Title: "Go 官方文档", URL: "https://go.dev/doc/", CreatedAt: time.Now()},
-		},
-		nextID: 4,
-	}
-}
+// Global error reporter; nil when Sentry is not configured
+var reporter *sentry.Client
 
-// GetAll returns all bookmarks
-func (s *BookmarkStore) GetAll() []model.Bookmark {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	// Return a copy to avoid data races
-	result := make([]model.Bookmark, len(s.bookmarks))
-	copy(result, s.bookmarks)
-	return result
-}
+// Global bookmark store, replaced by the configured backend in SetupRouter
+var store storage.Store = storage.NewMemoryStore()
 
-// Create adds a new bookmark
-func (s *BookmarkStore) Create(title, url string) model.Bookmark {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Global blob store for exports and archives, opened in SetupRouter
+var blobs blob.Store
 
-	bookmark := model.Bookmark{
-		ID:        fmt.Sprintf("%d", s.nextID),
-		Title:     title,
-		URL:       url,
-		CreatedAt: time.Now(),
+// OpenStore opens the store backend registered under cfg.Driver
+func OpenStore(cfg DatabaseConfig) (storage.Store, error) {
+	driver := cfg.Driver
+	if driver == "" {
+		driver = "memory"
 	}
-	s.nextID++
-	s.bookmarks = append(s.bookmarks, bookmark)
-	return bookmark
+	return storage.Open(driver, storage.Config{
+		DSN:             cfg.DSN(),
+		ReplicaDSN:      cfg.ReplicaDSN,
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		QueryTimeout:    cfg.QueryTimeout,
+		AutoMigrate:     cfg.AutoMigrate,
+		Options:         cfg.Options,
+		Quotas:          cfg.Quotas,
+	})
 }
 
-// GetByID returns a bookmark by ID
-func (s *BookmarkStore) GetByID(id string) (model.Bookmark, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// SetupRouter configures and returns the Gin router
+func SetupRouter(cfg *Config) *gin.Engine {
+	// Setup Gin mode
+	if cfg.GinMode == "release" {
+		gin.SetMode(gin.ReleaseMode)
+	}
 
-	for _, b := range s.bookmarks {
-		if b.ID == id {
-			return b, true
+	if cfg.SentryDSN != "" {
+		client, err := sentry.New(cfg.SentryDSN, cfg.SentryEnvironment)
+		if err != nil {
+			log.Fatal("Invalid Sentry configuration: ", err)
 		}
+		reporter = client
 	}
-	return model.Bookmark{}, false
-}
-
-// Update updates an existing bookmark
-func (s *BookmarkStore) Update(id, title, url string) (model.Bookmark, bool) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
 
-	for i, b := range s.bookmarks {
-		if b.ID == id {
-			if title != "" {
-				s.bookmarks[i].Title = title
+	if cfg.RedisURL != "" {
+		rc, err := coord.NewRedis(cfg.RedisURL)
+		if err != nil {
+			log.Printf("Warning: Redis coordination unavailable, running single-instance: %v", err)
+		} else {
+			coordinator = rc
+			if cfg.Database.Driver == "" || cfg.Database.Driver == "memory" {
+				log.Println("Warning: the in-memory store is not shared between instances; use DB_DRIVER=postgres for multi-instance deployments")
 			}
-			if url != "" {
-				s.bookmarks[i].URL = url
-			}
-			return s.bookmarks[i], true
+		}
+		responseCache, err = NewResponseCache(cfg.RedisURL, cfg.ResponseCacheTTL)
+		if err != nil {
+			log.Printf("Warning: response cache disabled: %v", err)
 		}
 	}
-	return model.Bookmark{}, false
-}
 
-// Delete removes a bookmark by ID
-func (s *BookmarkStore) Delete(id string) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s, err := OpenStore(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to open store: ", err)
+	}
+	accounts, _ = OpenAccounts(s)
+	collections, _ = s.(storage.CollectionStore)
+	tagStore, _ = s.(storage.TagStore)
+	reactions, _ = s.(storage.ReactionStore)
+	reading, _ = s.(storage.ReadingStore)
+	reminders, _ = s.(storage.ReminderStore)
+	archiveStore, _ = s.(storage.ArchiveStore)
+	aliases, _ = s.(storage.AliasStore)
+	revisions, _ = s.(storage.RevisionStore)
+	activityLog, _ = s.(storage.ActivityStore)
+	trash, _ = s.(storage.TrashStore)
+	domainRules, _ = s.(storage.DomainRuleStore)
+	urlRules, _ = s.(storage.URLRuleStore)
+	noteStore, _ = s.(storage.NoteStore)
+	clips, _ = s.(storage.ClipStore)
+	hookStore, _ = s.(storage.HookStore)
+	captureTokens, _ = s.(storage.CaptureTokenStore)
+	integrationStore, _ = s.(storage.IntegrationStore)
+	calendarTokens, _ = s.(storage.CalendarTokenStore)
+	followerStore, _ = s.(storage.FederationStore)
+	mentionStore, _ = s.(storage.MentionStore)
+	blobMoves, _ = s.(storage.BlobMoveStore)
+	trashRetention = cfg.TrashRetention
+	quotas = cfg.Database.Quotas
+	archiveQuota = cfg.ArchiveQuota
+	if err := bootstrapAdmin(context.Background(), cfg); err != nil {
+		log.Fatal("Failed to create initial admin: ", err)
+	}
+	if cfg.SeedData != "" {
+		fixtures, err := seed.Load(cfg.SeedData)
+		if err != nil {
+			log.Fatal("Failed to load seed data: ", err)
+		}
+		created, err := seed.Apply(context.Background(), s, fixtures)
+		if err != nil {
+			log.Fatal("Failed to seed data: ", err)
+		}
+		log.Printf("Seeded %d bookmarks from %s", created, cfg.SeedData)
+	}
+	if cfg.BookmarkCacheSize > 0 {
+		s = storage.NewCachedStore(s, cfg.BookmarkCacheSize, cfg.BookmarkCacheTTL)
+	}
+	store = s
 
-	for i, b := range s.bookmarks {
-		if b.ID == id {
-			s.bookmarks = append(s.bookmarks[:i], s.bookmarks[i+1:]...)
-			return true
+	search.FoldDiacritics = cfg.Search.FoldDiacritics
+	searchIndex, err = search.Open(context.Background(), cfg.Search)
+	if err != nil {
+		log.Fatal("Failed to open search index: ", err)
+	}
+	if searchIndex != nil {
+		if _, err := rebuildSearchIndex(context.Background()); err != nil {
+			log.Fatal("Failed to build search index: ", err)
+		}
+		archiveIndex, err = search.Open(context.Background(), cfg.Search.Archive())
+		if err != nil {
+			log.Fatal("Failed to open archive search index: ", err)
 		}
 	}
-	return false
-}
 
-// Global bookmark store (in production, this would be a database)
-var store = NewBookmarkStore()
+	if err := applySettings(cfg); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
 
-// SetupRouter configures and returns the Gin router
-func SetupRouter(cfg *Config) *gin.Engine {
-	// Setup Gin mode
-	if cfg.GinMode == "release" {
-		gin.SetMode(gin.ReleaseMode)
+	var encryptionKey []byte
+	if cfg.EncryptionKey != "" {
+		key, err := encrypt.ParseKey(cfg.EncryptionKey)
+		if err != nil {
+			log.Fatal("Invalid ENCRYPTION_KEY: ", err)
+		}
+		encryptionKey = key
+	}
+	notifier, err = notify.Open(cfg.Notify)
+	if err != nil {
+		log.Fatal("Invalid notification settings: ", err)
+	}
+	telegramBot, err = telegram.Open(cfg.Telegram)
+	if err != nil {
+		log.Fatal("Invalid Telegram settings: ", err)
+	}
+	if telegramBot != nil && cfg.Telegram.WebhookURL != "" {
+		registerTelegramWebhook(cfg.Telegram.WebhookURL)
+	}
+	slackApp, err = slack.Open(cfg.Slack)
+	if err != nil {
+		log.Fatal("Invalid Slack settings: ", err)
+	}
+	slackCollectionID = cfg.Slack.CollectionID
+	discordBot, err = discord.Open(cfg.Discord)
+	if err != nil {
+		log.Fatal("Invalid Discord settings: ", err)
+	}
+	inbox, err = mailin.Open(cfg.MailIn)
+	if err != nil {
+		log.Fatal("Invalid inbound mail settings: ", err)
+	}
+	inboxCollectionID = cfg.MailIn.CollectionID
+	federation, err = activitypub.Open(cfg.Federation)
+	if err != nil {
+		log.Fatal("Invalid federation settings: ", err)
+	}
+	mentioner, err = webmention.Open(cfg.Webmention)
+	if err != nil {
+		log.Fatal("Invalid Webmention settings: ", err)
 	}
+	blobs, err = blob.Open(cfg.Blob)
+	if err != nil {
+		log.Fatal("Failed to open blob storage: ", err)
+	}
+	// Export download links are signed with the JWT secret, so a
+	// placeholder would let anyone forge them
+	if insecureJWTSecrets[cfg.JWTSecret] {
+		log.Fatal("JWT_SECRET is a placeholder; set it to a random value of at least 32 bytes")
+	}
+	exports = newExportManager(blobs, cfg.JWTSecret, cfg.ExportTTL, encryptionKey)
+	// Blobs follow bookmark IDs a migration changed, and archive search
+	// fills in, while the server is already up; until then a moved
+	// bookmark's thumbnail and archived pages are missing. Moves that fail
+	// are tried again at the next start. The archive index is built after
+	// the moves, so it holds the new IDs.
+	go func() {
+		if n, err := moveBookmarkBlobs(context.Background()); err != nil {
+			log.Printf("Failed to move blobs to new bookmark IDs: %v", err)
+		} else if n > 0 {
+			log.Printf("Moved %d blobs to new bookmark IDs", n)
+		}
+		if archiveIndex == nil {
+			return
+		}
+		n, err := rebuildArchiveIndex(context.Background())
+		if err != nil {
+			log.Printf("Failed to build archive search index: %v", err)
+			return
+		}
+		log.Printf("Indexed %d archived snapshots", n)
+	}()
 
-	r := gin.Default()
+	if err := registerJobs(cfg); err != nil {
+		log.Fatal("Invalid job schedule: ", err)
+	}
+
+	// gin's own request log prints query strings as they are, so it skips
+	// the capture endpoint, whose token Logger redacts.
+	r := gin.New()
+	r.Use(gin.LoggerWithConfig(gin.LoggerConfig{SkipPaths: []string{"/api/v1/capture", "/api/v1/calendar.ics"}}), gin.Recovery())
+
+	// Serve HTTP/2 over cleartext as well; TLS listeners negotiate h2 via ALPN.
+	r.UseH2C = true
+
+	// Client IPs, which rate limits are keyed on, come from X-Forwarded-For
+	// only when a trusted proxy sent it
+	proxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err == nil {
+		err = r.SetTrustedProxies(proxies)
+	}
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
 
 	// Middleware
-	r.Use(CORS(cfg.CORSAllowedOrigins))
+	r.Use(CORS())
 	r.Use(Logger())
 	r.Use(Recovery())
+	r.Use(RequestStats())
+	if cfg.MaxInFlight > 0 {
+		r.Use(LoadShed(cfg.MaxInFlight, cfg.LoadShedRetryAfter))
+	}
+	r.Use(Compress(cfg.CompressMinLength))
+	r.Use(BodyLimit(cfg.MaxBodyBytes))
+	r.Use(RequestTimeout(cfg.RequestTimeout))
+	urlDNSCheck = cfg.URLDNSCheck
 
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
@@ -241,63 +636,397 @@ func SetupRouter(cfg *Config) *gin.Engine {
 		})
 	})
 
+	// Operational endpoints stay on the public listener unless a separate
+	// admin listener is configured
+	if cfg.AdminAddr == "" {
+		registerOperational(r.Group("", AdminAuth(cfg.AdminToken)))
+	}
+
+	// Public profile pages
+	r.GET("/u/:username", rateLimiter.Middleware(), Maintenance(), handlePublicProfile)
+	r.GET("/u/:username/bookmarks/:id", rateLimiter.Middleware(), Maintenance(), handlePublicBookmark)
+	r.POST("/webmention", rateLimiter.Middleware(), Maintenance(), RequireWebmention(), handleReceiveWebmention)
+
+	// ActivityPub: public profiles as actors that can be followed
+	fediverse := r.Group("", rateLimiter.Middleware(), Maintenance(), RequireFederation())
+	fediverse.GET("/.well-known/webfinger", handleWebFinger)
+	fediverse.GET("/u/:username/outbox", handleOutbox)
+	fediverse.GET("/u/:username/followers", handleFollowers)
+	fediverse.POST("/u/:username/inbox", handleInbox)
+
 	// API routes
 	v1 := r.Group("/api/v1")
+	v1.Use(rateLimiter.Middleware())
+	v1.Use(Maintenance())
 	{
 		v1.GET("/ping", func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "pong"})
 		})
 
-		// Bookmark routes
-		v1.GET("/bookmarks", handleGetBookmarks)
-		v1.POST("/bookmarks", handleCreateBookmark)
-		v1.GET("/bookmarks/:id", handleGetBookmark)
-		v1.PUT("/bookmarks/:id", handleUpdateBookmark)
-		v1.DELETE("/bookmarks/:id", handleDeleteBookmark)
+		// Retries with the same Idempotency-Key replay the first response
+		idempotency := NewIdempotencyStore(cfg.IdempotencyTTL)
+
+		// Bookmark routes. Signing in is optional for bookmarks and
+		// attributes changes in the activity feed; tags span many owners'
+		// bookmarks, so they need it. Both are served from the response
+		// cache when there is one.
+		bookmarks := v1.Group("/bookmarks", Authenticate())
+		tags := v1.Group("/tags", Authenticate(), RequireSignIn())
+		if responseCache != nil {
+			bookmarks.Use(responseCache.Middleware())
+			tags.Use(responseCache.Middleware())
+		}
+		bookmarks.GET("", handleGetBookmarks)
+		bookmarks.POST("", idempotency.Middleware(), handleCreateBookmark)
+		bookmarks.POST("/tags", handleBulkTag)
+		bookmarks.GET("/lookup", RequireAliases(), handleLookupBookmark)
+		bookmarks.GET("/by-domain", handleGetBookmarksByDomain)
+		// Single bookmarks need the caller's access to them: view to read,
+		// edit to change and owner to delete
+		canView := RequireBookmarkAccess(model.AccessView)
+		canEdit := RequireBookmarkAccess(model.AccessEdit)
+		canDelete := RequireBookmarkAccess(model.AccessOwner)
+		bookmarks.GET("/:id", canView, handleGetBookmark)
+		bookmarks.PUT("/:id", canEdit, CheckPreconditions(), handleUpdateBookmark)
+		bookmarks.DELETE("/:id", canDelete, CheckPreconditions(), handleDeleteBookmark)
+		bookmarks.PUT("/:id/read", RequireReading(), canEdit, CheckPreconditions(), handleMarkRead)
+		bookmarks.PUT("/:id/progress", RequireReading(), canEdit, CheckPreconditions(), handleUpdateProgress)
+		bookmarks.PUT("/:id/archive", RequireArchive(), canEdit, CheckPreconditions(), handleArchiveBookmark)
+		bookmarks.PUT("/:id/notes", RequireNotes(), canEdit, CheckPreconditions(), handleSetNotes)
+		bookmarks.GET("/:id/clips", RequireClips(), canView, handleGetClips)
+		bookmarks.POST("/:id/clips", RequireClips(), canEdit, handleAddClip)
+		bookmarks.DELETE("/:id/clips/:clipId", RequireClips(), canEdit, handleDeleteClip)
+		bookmarks.GET("/:id/mentions", RequireMentions(), canView, handleGetMentions)
+		bookmarks.POST("/:id/aliases", RequireAliases(), canEdit, handleAddAlias)
+		bookmarks.DELETE("/:id/aliases", RequireAliases(), canEdit, handleRemoveAlias)
+		bookmarks.GET("/:id/history", RequireHistory(), canView, handleGetBookmarkHistory)
+		bookmarks.POST("/:id/history/:version/revert", RequireHistory(), canEdit, handleRevertBookmark)
+		bookmarks.GET("/:id/thumbnail", RequireFeature(flags.Thumbnails), canView, handleGetThumbnail)
+		bookmarks.PUT("/:id/reminder", RequireReminders(), canEdit, handleSetReminder)
+		bookmarks.DELETE("/:id/reminder", RequireReminders(), canEdit, handleDeleteReminder)
+
+		// Collection routes
+		cols := v1.Group("/collections", RequireCollections(), Authenticate())
+		cols.GET("", handleGetCollections)
+		cols.POST("", handleCreateCollection)
+		cols.POST("/merge", handleMergeCollections)
+		cols.GET("/:id", handleGetCollection)
+		cols.PUT("/:id", handleUpdateCollection)
+		cols.DELETE("/:id", handleDeleteCollection)
+		cols.POST("/:id/move", handleMoveCollection)
+		cols.GET("/:id/bookmarks", handleGetCollectionBookmarks)
+		cols.POST("/:id/bookmarks", handleAddCollectionBookmarks)
+		cols.PUT("/:id/bookmarks/:bookmarkId", CheckPreconditions(), handleUpdateCollectionBookmark)
+		cols.DELETE("/:id/bookmarks/:bookmarkId", handleRemoveCollectionBookmark)
+		cols.GET("/:id/events", handleCollectionEvents)
+		cols.PUT("/:id/bookmarks/:bookmarkId/reactions/:emoji", RequireSignIn(), handleReaction)
+		cols.DELETE("/:id/bookmarks/:bookmarkId/reactions/:emoji", RequireSignIn(), handleReaction)
+		cols.GET("/:id/shares", handleGetCollectionShares)
+		cols.PUT("/:id/shares", handleShareCollection)
+		cols.DELETE("/:id/shares/:grantee", handleUnshareCollection)
+
+		// Tag routes
+		tags.GET("", handleGetTags)
+		tags.GET("/tree", handleGetTagTree)
+		tags.PUT("/*name", handleRenameTag)
+		tags.POST("/merge", handleMergeTags)
+		tags.POST("/move", handleMoveTag)
+
+		profile := v1.Group("/profile", Authenticate(), RequireSignIn())
+		profile.GET("", handleGetProfile)
+		profile.PUT("", handleUpdateProfile)
+		profile.PUT("/archive-policy", handleSetArchivePolicy)
+		profile.GET("/domain-rules", RequireDomainRules(), handleGetDomainRules(accountRules))
+		profile.PUT("/domain-rules", RequireDomainRules(), handleSetDomainRule(accountRules))
+		profile.DELETE("/domain-rules/:domain", RequireDomainRules(), handleDeleteDomainRule(accountRules))
+		profile.POST("/capture-token", RequireCaptureTokens(), handleCreateCaptureToken)
+		profile.DELETE("/capture-token", RequireCaptureTokens(), handleRevokeCaptureToken)
+		profile.POST("/calendar-token", RequireCalendar(), handleCreateCalendarToken)
+		profile.DELETE("/calendar-token", RequireCalendar(), handleRevokeCalendarToken)
+		profile.GET("/integrations", RequireIntegrations(), handleListIntegrations)
+		profile.DELETE("/integrations/:service", RequireIntegrations(), handleDeleteIntegration)
+		profile.PUT("/integrations/notion", RequireIntegrations(), RequireCollections(), handleConnectNotion)
+		profile.POST("/integrations/notion/push", RequireIntegrations(), RequireCollections(), handlePushNotion)
+		profile.PUT("/integrations/readwise", RequireIntegrations(), RequireCollections(), RequireClips(), handleConnectReadwise)
+		profile.POST("/integrations/readwise/push", RequireIntegrations(), RequireCollections(), RequireClips(), handlePushReadwise)
+		for _, service := range []string{model.IntegrationGitHub, model.IntegrationHackerNews, model.IntegrationReddit} {
+			profile.PUT("/integrations/"+service, RequireIntegrations(), RequireCollections(), handleConnectPull(service))
+			profile.POST("/integrations/"+service+"/pull", RequireIntegrations(), RequireCollections(), handlePull(service))
+		}
+
+		v1.GET("/reminders", RequireReminders(), Authenticate(), RequireSignIn(), handleGetReminders)
+		v1.GET("/trash", RequireTrash(), Authenticate(), handleGetTrash)
+		v1.POST("/trash/:id/restore", RequireTrash(), Authenticate(), handleRestoreBookmark)
+		v1.POST("/integrations/telegram", RequireTelegram(), handleTelegramUpdate)
+		v1.POST("/integrations/slack/commands", RequireSlack(), handleSlackCommand)
+		v1.POST("/integrations/slack/events", RequireSlack(), handleSlackEvent)
+		v1.POST("/integrations/discord", RequireDiscord(), handleDiscordInteraction)
+		v1.POST("/integrations/mail/:secret", RequireInbox(), handleInboundMail)
+
+		// REST hooks and flat actions for automation platforms
+		hooks := v1.Group("/hooks", RequireHooks(), Authenticate(), RequireSignIn())
+		hooks.GET("", handleGetHooks)
+		hooks.POST("", handleSubscribeHook)
+		hooks.DELETE("/:id", handleUnsubscribeHook)
+		hooks.GET("/samples", handleGetHookSamples)
+		hooks.GET("/:id/deliveries", handleGetHookDeliveries)
+		hooks.GET("/dead-letters", handleGetDeadHookDeliveries)
+		hooks.POST("/deliveries/:id/redeliver", handleRedeliverHook)
+		actions := v1.Group("/actions", Authenticate(), RequireSignIn())
+		actions.POST("/save", handleSaveAction)
+		actions.GET("/find", handleFindAction)
+		v1.GET("/sync/browser", RequireCollections(), Authenticate(), RequireSignIn(), handleExportBrowserTree)
+		v1.POST("/sync/browser", RequireCollections(), Authenticate(), RequireSignIn(), handleSyncBrowserTree)
+		v1.GET("/capture", RequireCaptureTokens(), handleCapture)
+		v1.POST("/capture", RequireCaptureTokens(), handleCapture)
+		v1.GET("/calendar.ics", RequireCalendar(), handleCalendarFeed)
+		v1.GET("/activity", RequireActivity(), Authenticate(), RequireSignIn(), handleGetActivity)
+		v1.GET("/usage", Authenticate(), RequireSignIn(), handleGetUsage)
+
+		v1.GET("/suggest", Authenticate(), RequireSignIn(), handleSuggest)
+		v1.GET("/unfurl", handleUnfurl)
+		v1.GET("/features", handleGetFeatures)
+
+		// Export routes; each export holds the bookmarks its account can view
+		exportRoutes := v1.Group("/exports", Authenticate(), RequireSignIn())
+		exportRoutes.POST("", handleCreateExport)
+		exportRoutes.GET("/:id", handleGetExport)
+		exportRoutes.GET("/:id/download", handleDownloadExport)
 	}
 
+	r.NoRoute(func(c *gin.Context) {
+		respondError(c, http.StatusNotFound, apierr.NotFound, "Not found")
+	})
 	return r
 }
 
-// handleGetBookmarks returns all bookmarks
+// handleGetBookmarks returns the active bookmarks, or the archived ones
+// with ?archived=true, optionally filtered by a search query (?q=) and a
+// tag (?tag=). With a search index, query results
+// are ranked by relevance and carry a score and highlighted fragments.
+// ?scope=archive searches the text of the bookmarks' archived snapshots
+// instead of the bookmarks themselves.
 func handleGetBookmarks(c *gin.Context) {
-	bookmarks := store.GetAll()
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    bookmarks,
-	})
+	query := strings.TrimSpace(c.Query("q"))
+	switch c.DefaultQuery("scope", "bookmarks") {
+	case "bookmarks":
+	case "archive":
+		if archiveIndex == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Searching archived snapshots needs a search backend (SEARCH_BACKEND)")
+			return
+		}
+		if query == "" {
+			respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "q is required with scope=archive")
+			return
+		}
+		results, err := searchArchive(c.Request.Context(), query, parseBookmarkFilter(c))
+		respondSearch(c, results, err)
+		return
+	default:
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "scope must be bookmarks or archive")
+		return
+	}
+
+	if query != "" && searchIndex != nil {
+		results, err := searchBookmarks(c.Request.Context(), query, parseBookmarkFilter(c))
+		respondSearch(c, results, err)
+		return
+	}
+
+	all, err := store.GetAll(c.Request.Context())
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	bookmarks := filterBookmarks(visibleBookmarks(c, all), c.Query("q"), parseBookmarkFilter(c))
+	p, ok := paginate(c, len(bookmarks))
+	if !ok {
+		return
+	}
+	respondBookmarkList(c, p, bookmarks[p.start:p.end], bookmarks[p.start:p.end])
+}
+
+// respondSearch answers with search results
+func respondSearch(c *gin.Context, results []searchResult, err error) {
+	if err != nil {
+		log.Printf("Search failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Search failed")
+		return
+	}
+	results = visibleResults(c, results)
+	p, ok := paginate(c, len(results))
+	if !ok {
+		return
+	}
+	page := results[p.start:p.end]
+	bookmarks := make([]model.Bookmark, len(page))
+	for i, r := range page {
+		bookmarks[i] = r.Bookmark
+	}
+	respondBookmarkList(c, p, page, bookmarks)
+}
+
+// bookmarkFilter holds the list filters other than the search query
+type bookmarkFilter struct {
+	// Tag keeps bookmarks carrying the tag or a tag below it
+	Tag string
+	// Domain keeps bookmarks from a site, as returned by Bookmark.Domain
+	Domain string
+	// Unread keeps bookmarks not yet read
+	Unread bool
+	// Archived keeps archived bookmarks instead of active ones
+	Archived bool
+	// MinMinutes and MaxMinutes bound the estimated reading time; when
+	// either is set, bookmarks without an estimate are left out
+	MinMinutes, MaxMinutes int
+}
+
+// parseBookmarkFilter reads the filters from ?tag=, ?domain=,
+// ?unread=true, ?archived=true, ?min_minutes= and ?max_minutes=
+func parseBookmarkFilter(c *gin.Context) bookmarkFilter {
+	minMinutes, _ := strconv.Atoi(c.Query("min_minutes"))
+	maxMinutes, _ := strconv.Atoi(c.Query("max_minutes"))
+	return bookmarkFilter{
+		Tag:        model.NormalizeTag(c.Query("tag")),
+		Domain:     strings.TrimPrefix(strings.ToLower(strings.TrimSpace(c.Query("domain"))), "www."),
+		Unread:     c.Query("unread") == "true",
+		Archived:   c.Query("archived") == "true",
+		MinMinutes: max(minMinutes, 0),
+		MaxMinutes: max(maxMinutes, 0),
+	}
+}
+
+// matches reports whether b passes the filter
+func (f bookmarkFilter) matches(b model.Bookmark) bool {
+	if b.Archived != f.Archived {
+		return false
+	}
+	if f.Tag != "" && !hasTagWithin(b.Tags, f.Tag) {
+		return false
+	}
+	if f.Domain != "" && b.Domain() != f.Domain {
+		return false
+	}
+	if f.MinMinutes > 0 || f.MaxMinutes > 0 {
+		if b.ReadingMinutes == 0 || b.ReadingMinutes < f.MinMinutes ||
+			(f.MaxMinutes > 0 && b.ReadingMinutes > f.MaxMinutes) {
+			return false
+		}
+	}
+	return !f.Unread || !b.IsRead
+}
+
+// filterBookmarks keeps bookmarks that pass filter and whose title, URL,
+// tags or notes contain query (case-insensitive, and for the title and
+// notes normalized like search), or match each of its words allowing typos
+func filterBookmarks(bookmarks []model.Bookmark, query string, filter bookmarkFilter) []model.Bookmark {
+	query = strings.ToLower(strings.TrimSpace(query))
+	text := search.Normalize(query)
+	result := make([]model.Bookmark, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		if !filter.matches(b) {
+			continue
+		}
+		if query != "" && !strings.Contains(search.Normalize(b.Title), text) &&
+			!strings.Contains(strings.ToLower(b.URL), query) && !hasTag(b.Tags, query) &&
+			!strings.Contains(search.Normalize(b.Notes), text) &&
+			!search.Matches(b.Title+" "+b.URL+" "+strings.Join(b.Tags, " "), query) {
+			continue
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+// hasTagWithin reports whether one of tags is ancestor or below it
+func hasTagWithin(tags []string, ancestor string) bool {
+	for _, t := range tags {
+		if model.TagWithin(t, ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // handleGetBookmark returns a single bookmark by ID
 func handleGetBookmark(c *gin.Context) {
 	id := c.Param("id")
-	bookmark, found := store.GetByID(id)
-	if !found {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"error":   "Bookmark not found",
-		})
+	bookmark, err := store.GetByID(c.Request.Context(), id)
+	if err != nil {
+		bookmarkError(c, err)
 		return
 	}
+	setBookmarkValidators(c, bookmark)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    bookmark,
 	})
 }
 
-// handleCreateBookmark creates a new bookmark
+// bookmarkError writes the response for an error from the bookmark store
+func bookmarkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrBookmarkNotFound):
+		respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found")
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		respondError(c, http.StatusForbidden, apierr.QuotaExceeded, quotaMessage(err))
+	case errors.Is(err, storage.ErrForbidden):
+		respondError(c, http.StatusForbidden, apierr.Forbidden, "Insufficient access to this bookmark")
+	case errors.Is(err, errPreconditionFailed):
+		respondError(c, http.StatusPreconditionFailed, apierr.PreconditionFailed, "Bookmark has changed since it was read")
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		respondError(c, http.StatusGatewayTimeout, apierr.Timeout, "Request timed out")
+	default:
+		log.Printf("Bookmark operation failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Bookmark operation failed")
+	}
+}
+
+// handleCreateBookmark creates a new bookmark. The URL is normalized
+// first, and saving a URL that is an alias of an existing bookmark the
+// caller can view returns that bookmark instead.
 func handleCreateBookmark(c *gin.Context) {
 	var req model.CreateBookmarkRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error":   "Invalid request body",
-			"details": err.Error(),
+	if !bindJSON(c, &req) || !cleanTitle(c, "title", &req.Title) {
+		return
+	}
+	req.URL = normalizeURL(c.Request.Context(), req.URL)
+	if !checkURLHost(c, "url", req.URL) || !allowedDomain(c, req.URL) {
+		return
+	}
+	existing, found, err := aliasedBookmark(c.Request.Context(), currentActor(c), req.URL)
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	if found {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    existing,
 		})
 		return
 	}
 
-	bookmark := store.Create(req.Title, req.URL)
+	bookmark, err := createBookmark(c.Request.Context(), currentActor(c).AccountID, req.Title, req.URL, req.Tags)
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	bookmark = withSelection(c.Request.Context(), bookmark, req.Selection)
+	indexBookmark(c.Request.Context(), bookmark)
+	recordActivity(c, model.Activity{Kind: model.ActivitySaved, BookmarkID: bookmark.ID})
+	publishBookmarkEvent("created", bookmark.ID)
+	thumbnails.enqueue(bookmark.ID)
+	readingTimes.enqueue(bookmark.ID)
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
 		"data":    bookmark,
@@ -309,41 +1038,106 @@ func handleUpdateBookmark(c *gin.Context) {
 	id := c.Param("id")
 
 	var req model.UpdateBookmarkRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error":   "Invalid request body",
-			"details": err.Error(),
-		})
+	if !bindJSON(c, &req) || !cleanUpdate(c, &req) {
 		return
 	}
 
-	bookmark, found := store.Update(id, req.Title, req.URL)
-	if !found {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"error":   "Bookmark not found",
-		})
+	var bookmark model.Bookmark
+	err := writeBookmark(c, id, func(tx storage.Store) error {
+		var err error
+		bookmark, err = updateBookmark(c.Request.Context(), tx, id, req)
+		return err
+	})
+	if err != nil {
+		bookmarkError(c, err)
 		return
 	}
+	indexBookmark(c.Request.Context(), bookmark)
+	publishBookmarkEvent("updated", id)
+	recordActivity(c, model.Activity{Kind: model.ActivityEdited, BookmarkID: id})
+	if req.URL != nil {
+		thumbnails.enqueue(id)
+		readingTimes.enqueue(id)
+	}
+	if req.Notes != nil {
+		webmentions.enqueue(id)
+	}
 
+	setBookmarkValidators(c, bookmark)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    bookmark,
 	})
 }
 
-// handleDeleteBookmark deletes a bookmark
+// cleanUpdate checks and normalizes the fields present in req. It
+// responds and returns false when one is invalid, or when notes are given
+// and the store cannot keep them.
+func cleanUpdate(c *gin.Context, req *model.UpdateBookmarkRequest) bool {
+	if req.Title != nil && !cleanTitle(c, "title", req.Title) {
+		return false
+	}
+	if req.URL != nil {
+		*req.URL = normalizeURL(c.Request.Context(), *req.URL)
+		if !checkURLHost(c, "url", *req.URL) || !allowedDomain(c, *req.URL) {
+			return false
+		}
+	}
+	if req.Notes != nil && noteStore == nil {
+		respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Notes are not supported by this storage driver")
+		return false
+	}
+	return true
+}
+
+// updateBookmark applies a cleaned update request to bookmark id in one
+// transaction of s, which is the store or a transaction to join. The store
+// keeps notes apart from the other fields, so they are set last.
+func updateBookmark(ctx context.Context, s storage.Store, id string, req model.UpdateBookmarkRequest) (model.Bookmark, error) {
+	var title, url string
+	var tags []string
+	if req.Title != nil {
+		title = *req.Title
+	}
+	if req.URL != nil {
+		url = *req.URL
+	}
+	if req.Tags != nil {
+		// Store.Update keeps the tags when given nil, so empty tags must
+		// not be nil
+		tags = append([]string{}, *req.Tags...)
+	}
+	var bookmark model.Bookmark
+	err := s.InTx(ctx, func(tx storage.Store) error {
+		var err error
+		bookmark, err = tx.Update(ctx, id, title, url, tags)
+		if err != nil || req.Notes == nil {
+			return err
+		}
+		bookmark, err = tx.(storage.NoteStore).SetNotes(ctx, id, strings.TrimSpace(sanitize.Plain(*req.Notes)))
+		return err
+	})
+	if err != nil {
+		return model.Bookmark{}, err
+	}
+	return bookmark, nil
+}
+
+// handleDeleteBookmark deletes a bookmark. Stores with a trash keep it
+// restorable until the purge job runs.
 func handleDeleteBookmark(c *gin.Context) {
 	id := c.Param("id")
 
-	if !store.Delete(id) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"error":   "Bookmark not found",
-		})
+	err := writeBookmark(c, id, func(tx storage.Store) error {
+		return tx.Delete(c.Request.Context(), id)
+	})
+	if err != nil {
+		bookmarkError(c, err)
 		return
 	}
+	unindexBookmark(c.Request.Context(), id)
+	publishBookmarkEvent("deleted", id)
+	thumbnails.remove(c.Request.Context(), id)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
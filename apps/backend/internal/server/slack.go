@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/slack"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+var (
+	// Global Slack app; nil unless SLACK_SIGNING_SECRET is set
+	slackApp *slack.App
+	// slackCollectionID is the shared collection /collect saves into
+	slackCollectionID string
+)
+
+// RequireSlack hides the Slack endpoints when no app is configured
+func RequireSlack() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slackApp == nil {
+			respondError(c, http.StatusNotFound, apierr.FeatureDisabled, "Slack app is not configured")
+			return
+		}
+		c.Next()
+	}
+}
+
+// slackBody reads the request body and checks Slack's signature over it,
+// answering 401 when it does not match
+func slackBody(c *gin.Context) ([]byte, bool) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIntegrationRequestBytes))
+	if err != nil || !slackApp.Verify(c.Request.Header, body, time.Now()) {
+		respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Unauthorized")
+		return nil, false
+	}
+	return body, true
+}
+
+// handleSlackCommand saves the link given to /collect into the shared
+// collection. Slack shows the response to the user, and the outcome of a
+// save to the whole channel.
+func handleSlackCommand(c *gin.Context) {
+	body, ok := slackBody(c)
+	if !ok {
+		return
+	}
+	cmd, err := slack.ParseCommand(body)
+	if err != nil {
+		c.JSON(http.StatusOK, slack.Ephemeral("Slack sent a command that could not be read."))
+		return
+	}
+	link, ok := slack.ParseLink(cmd.Text)
+	if !ok {
+		c.JSON(http.StatusOK, slack.Ephemeral("Usage: "+cmd.Command+" <link> [title] [#tag ...]"))
+		return
+	}
+
+	save := saveIntegrationLink(c.Request.Context(), "Slack", integrationLink{URL: link.URL, Title: link.Title, Tags: link.Tags}, slackCollectionID)
+	if save.Refusal != "" || save.Existing {
+		c.JSON(http.StatusOK, slack.Ephemeral(save.reply()))
+		return
+	}
+	reply := save.reply()
+	if cmd.UserID != "" {
+		reply += "\nShared by <@" + cmd.UserID + ">"
+	}
+	c.JSON(http.StatusOK, slack.InChannel(reply))
+}
+
+// handleSlackEvent answers the Events API: the URL verification
+// challenge, and link_shared events for saved bookmarks, which are
+// unfurled with their title and tags. Slack retries events that are not
+// acknowledged within three seconds, so unfurling happens afterwards.
+func handleSlackEvent(c *gin.Context) {
+	body, ok := slackBody(c)
+	if !ok {
+		return
+	}
+	var event slack.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		c.Status(http.StatusOK)
+		return
+	}
+	switch {
+	case event.Type == "url_verification":
+		c.JSON(http.StatusOK, gin.H{"challenge": event.Challenge})
+		return
+	case event.Type == "event_callback" && event.Event.Type == "link_shared" && slackApp.CanUnfurl():
+		unfurls := make(map[string]slack.Unfurl)
+		for _, l := range event.Event.Links {
+			if b, found := savedBookmark(c.Request.Context(), storage.Actor{}, l.URL); found {
+				unfurls[l.URL] = slackUnfurl(b)
+			}
+		}
+		if len(unfurls) > 0 {
+			go unfurlSlackLinks(event.Event.Channel, event.Event.MessageTS, unfurls)
+		}
+	}
+	c.Status(http.StatusOK)
+}
+
+func unfurlSlackLinks(channel, ts string, unfurls map[string]slack.Unfurl) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := slackApp.Unfurl(ctx, channel, ts, unfurls); err != nil {
+		log.Printf("Failed to unfurl Slack links: %v", err)
+	}
+}
+
+func slackUnfurl(b model.Bookmark) slack.Unfurl {
+	u := slack.Unfurl{Title: b.Title, TitleLink: b.URL}
+	if len(b.Tags) > 0 {
+		u.Text = "Tags: " + strings.Join(b.Tags, ", ")
+	}
+	return u
+}
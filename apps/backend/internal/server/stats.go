@@ -0,0 +1,155 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestCounter keeps hourly request/error counts for the last 24 hours,
+// plus running totals by status class for /metrics
+type requestCounter struct {
+	mu      sync.Mutex
+	buckets [24]requestBucket
+	classes [6]uint64 // indexed by status/100
+}
+
+type requestBucket struct {
+	hour         int64 // unix hour the bucket belongs to
+	total        int
+	clientErrors int
+	serverErrors int
+}
+
+// Global request counter fed by the RequestStats middleware
+var requestStats = &requestCounter{}
+
+func (rc *requestCounter) record(status int, now time.Time) {
+	hour := now.Unix() / 3600
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	b := &rc.buckets[hour%int64(len(rc.buckets))]
+	if b.hour != hour {
+		*b = requestBucket{hour: hour}
+	}
+	b.total++
+	if class := status / 100; class > 0 && class < len(rc.classes) {
+		rc.classes[class]++
+	}
+	switch {
+	case status >= 500:
+		b.serverErrors++
+	case status >= 400:
+		b.clientErrors++
+	}
+}
+
+// last24h sums all buckets that are less than a day old
+func (rc *requestCounter) last24h(now time.Time) requestBucket {
+	current := now.Unix() / 3600
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	var sum requestBucket
+	for _, b := range rc.buckets {
+		if current-b.hour >= int64(len(rc.buckets)) {
+			continue
+		}
+		sum.total += b.total
+		sum.clientErrors += b.clientErrors
+		sum.serverErrors += b.serverErrors
+	}
+	return sum
+}
+
+// totals returns the number of responses per status class since startup
+func (rc *requestCounter) totals() [6]uint64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.classes
+}
+
+// RequestStats middleware counts responses by status class
+func RequestStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		requestStats.record(c.Writer.Status(), time.Now())
+	}
+}
+
+// handleAdminStats returns instance-wide usage and health figures
+func handleAdminStats(c *gin.Context) {
+	now := time.Now()
+	bookmarks, err := store.GetAll(c.Request.Context())
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+
+	growth := map[string]int{"24h": 0, "7d": 0, "30d": 0}
+	daily := make(map[string]int)
+	for _, b := range bookmarks {
+		age := now.Sub(b.CreatedAt)
+		if age <= 24*time.Hour {
+			growth["24h"]++
+		}
+		if age <= 7*24*time.Hour {
+			growth["7d"]++
+		}
+		if age <= 30*24*time.Hour {
+			growth["30d"]++
+			daily[b.CreatedAt.Format("2006-01-02")]++
+		}
+	}
+
+	runningJobs := 0
+	for _, s := range jobs.Statuses() {
+		if s.Running {
+			runningJobs++
+		}
+	}
+
+	exportBytes, err := blobs.Usage(c.Request.Context(), "exports/")
+	if err != nil {
+		log.Printf("Failed to measure export storage: %v", err)
+	}
+	archiveBytes, err := blobs.Usage(c.Request.Context(), archivePrefix)
+	if err != nil {
+		log.Printf("Failed to measure archive storage: %v", err)
+	}
+
+	requests := requestStats.last24h(now)
+	errorRate := 0.0
+	if requests.total > 0 {
+		errorRate = float64(requests.serverErrors) / float64(requests.total)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"bookmarks": gin.H{
+				"total":     len(bookmarks),
+				"created":   growth,
+				"daily_30d": daily,
+			},
+			"storage": gin.H{
+				"exports_bytes":  exportBytes,
+				"archives_bytes": archiveBytes,
+			},
+			"jobs": gin.H{
+				"export_queue_depth": exports.pending(),
+				"running":            runningJobs,
+			},
+			"requests_24h": gin.H{
+				"total":         requests.total,
+				"client_errors": requests.clientErrors,
+				"server_errors": requests.serverErrors,
+				"error_rate":    errorRate,
+			},
+		},
+	})
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+)
+
+const (
+	defaultSuggestLimit = 5
+	maxSuggestLimit     = 20
+)
+
+// titleSuggestion is a bookmark whose title matches the typed prefix
+type titleSuggestion struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// termSuggestion is a tag or domain with the number of bookmarks using it
+type termSuggestion struct {
+	Name     string    `json:"name"`
+	Count    int       `json:"count"`
+	lastUsed time.Time // breaks ties between equally frequent terms
+}
+
+// handleSuggest completes a partially typed query with matching titles,
+// tags and domains. Titles are ranked by recency, tags and domains by how
+// many bookmarks use them, then by recency.
+func handleSuggest(c *gin.Context) {
+	prefix := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	limit, ok := queryLimit(c, defaultSuggestLimit, maxSuggestLimit)
+	if !ok {
+		return
+	}
+
+	titles := []titleSuggestion{}
+	tags := make(map[string]*termSuggestion)
+	domains := make(map[string]*termSuggestion)
+	// Newest first, so the first titles found are the most recent.
+	bookmarks, err := store.GetAll(c.Request.Context())
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	bookmarks = visibleBookmarks(c, bookmarks)
+	for i := len(bookmarks) - 1; i >= 0; i-- {
+		b := bookmarks[i]
+		if len(titles) < limit && titleMatches(b.Title, prefix) {
+			titles = append(titles, titleSuggestion{ID: b.ID, Title: b.Title, URL: b.URL})
+		}
+		for _, tag := range b.Tags {
+			if strings.HasPrefix(tag, prefix) {
+				countTerm(tags, tag, b.CreatedAt)
+			}
+		}
+		if d := b.Domain(); d != "" && strings.HasPrefix(d, prefix) {
+			countTerm(domains, d, b.CreatedAt)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"titles":  titles,
+			"tags":    rankTerms(tags, limit),
+			"domains": rankTerms(domains, limit),
+		},
+	})
+}
+
+// queryLimit parses the optional ?limit= parameter, writing a 400
+// response and returning false when it is out of range
+func queryLimit(c *gin.Context, def, max int) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > max {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "limit must be between 1 and "+strconv.Itoa(max))
+		return 0, false
+	}
+	return n, true
+}
+
+// titleMatches reports whether the title, or one of its words, starts
+// with prefix
+func titleMatches(title, prefix string) bool {
+	title = strings.ToLower(title)
+	if strings.HasPrefix(title, prefix) {
+		return true
+	}
+	for _, word := range strings.Fields(title) {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// countTerm records one more use of a term at the given time
+func countTerm(terms map[string]*termSuggestion, name string, at time.Time) {
+	t, ok := terms[name]
+	if !ok {
+		t = &termSuggestion{Name: name}
+		terms[name] = t
+	}
+	t.Count++
+	if at.After(t.lastUsed) {
+		t.lastUsed = at
+	}
+}
+
+// rankTerms orders terms by use count, then by most recent use
+func rankTerms(terms map[string]*termSuggestion, limit int) []termSuggestion {
+	ranked := make([]termSuggestion, 0, len(terms))
+	for _, t := range terms {
+		ranked = append(ranked, *t)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		if !ranked[i].lastUsed.Equal(ranked[j].lastUsed) {
+			return ranked[i].lastUsed.After(ranked[j].lastUsed)
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+const (
+	defaultTagLimit = 20
+	maxTagLimit     = 1000
+)
+
+// Global bulk tag store; nil when the storage driver cannot update tags
+// transactionally
+var tagStore storage.TagStore
+
+// retagMu serialises tag renames, merges and moves on this instance so
+// two of them cannot interleave their read-modify-write of the same
+// bookmarks
+var retagMu sync.Mutex
+
+// retag rewrites the tags of every bookmark actor can edit with rewrite,
+// which returns its argument for tags it leaves alone, and returns how
+// many bookmarks changed. The bookmarks are rewritten in one transaction,
+// so on error none of them changed. Access is checked before it starts,
+// since collections cannot be read inside it.
+func retag(ctx context.Context, actor storage.Actor, rewrite func(tag string) string) (int, error) {
+	retagMu.Lock()
+	defer retagMu.Unlock()
+
+	all, err := store.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	editable := make(map[string]bool)
+	for _, b := range all {
+		if storage.CheckBookmarkAccess(ctx, collections, actor, b, model.AccessEdit) == nil {
+			editable[b.ID] = true
+		}
+	}
+
+	var updated []model.Bookmark
+	err = store.InTx(ctx, func(tx storage.Store) error {
+		all, err := tx.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, b := range all {
+			tags := make([]string, len(b.Tags))
+			changed := false
+			for i, t := range b.Tags {
+				tags[i] = rewrite(t)
+				changed = changed || tags[i] != t
+			}
+			if !changed || !editable[b.ID] {
+				continue
+			}
+			bookmark, err := tx.Update(ctx, b.ID, "", "", model.NormalizeTags(tags))
+			if errors.Is(err, storage.ErrBookmarkNotFound) {
+				continue // deleted meanwhile
+			}
+			if err != nil {
+				return err
+			}
+			updated = append(updated, bookmark)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, b := range updated {
+		indexBookmark(ctx, b)
+		publishBookmarkEvent("updated", b.ID)
+	}
+	return len(updated), nil
+}
+
+// handleGetTags lists the tags starting with ?q=, most used first, so
+// clients can offer existing tags instead of near-duplicates
+func handleGetTags(c *gin.Context) {
+	prefix := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	limit, ok := queryLimit(c, defaultTagLimit, maxTagLimit)
+	if !ok {
+		return
+	}
+
+	all, err := store.GetAll(c.Request.Context())
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	tags := make(map[string]*termSuggestion)
+	for _, b := range visibleBookmarks(c, all) {
+		for _, tag := range b.Tags {
+			if strings.HasPrefix(tag, prefix) {
+				countTerm(tags, tag, b.CreatedAt)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rankTerms(tags, limit),
+	})
+}
+
+// handleGetTagTree returns the tags as a tree, below ?root= when given
+func handleGetTagTree(c *gin.Context) {
+	root := model.NormalizeTag(c.Query("root"))
+	all, err := store.GetAll(c.Request.Context())
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	counts := make(map[string]int)
+	for _, b := range visibleBookmarks(c, all) {
+		for _, tag := range b.Tags {
+			if root == "" || model.TagWithin(tag, root) {
+				counts[tag]++
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    model.BuildTagTree(counts, root),
+	})
+}
+
+// handleRenameTag renames a tag on every bookmark; the tag's descendants
+// keep their names. Renaming to a tag that is already in use merges the
+// two. The name is the rest of the path, so hierarchical tags need no
+// escaping: PUT /tags/dev/go.
+func handleRenameTag(c *gin.Context) {
+	var req model.RenameTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	from, to := model.NormalizeTag(c.Param("name")), model.NormalizeTag(req.Name)
+	if to == "" || to == from {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "New tag name must be non-empty and differ from the current one")
+		return
+	}
+
+	updated, err := retag(c.Request.Context(), currentActor(c), func(tag string) string {
+		if tag == from {
+			return to
+		}
+		return tag
+	})
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	if updated == 0 {
+		respondError(c, http.StatusNotFound, apierr.TagNotFound, "Tag not found")
+		return
+	}
+	recordActivity(c, model.Activity{Kind: model.ActivityTagged, Detail: fmt.Sprintf("renamed tag %s to %s", from, to)})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    model.TagChange{Tag: to, Updated: updated},
+	})
+}
+
+// handleMergeTags replaces each source tag with the target tag on every
+// bookmark
+func handleMergeTags(c *gin.Context) {
+	var req model.MergeTagsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	target := model.NormalizeTag(req.Target)
+	sources := make(map[string]bool, len(req.Sources))
+	for _, s := range req.Sources {
+		if s = model.NormalizeTag(s); s != "" && s != target {
+			sources[s] = true
+		}
+	}
+	if target == "" || len(sources) == 0 {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Merge needs a target and at least one other source tag")
+		return
+	}
+
+	updated, err := retag(c.Request.Context(), currentActor(c), func(tag string) string {
+		if sources[tag] {
+			return target
+		}
+		return tag
+	})
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	if updated > 0 {
+		merged := make([]string, 0, len(sources))
+		for s := range sources {
+			merged = append(merged, s)
+		}
+		sort.Strings(merged)
+		recordActivity(c, model.Activity{
+			Kind:   model.ActivityTagged,
+			Detail: fmt.Sprintf("merged tags %s into %s", strings.Join(merged, ", "), target),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    model.TagChange{Tag: target, Updated: updated},
+	})
+}
+
+// handleMoveTag moves a tag together with its descendants to a new path,
+// so moving "dev/go" to "lang/go" turns "dev/go/concurrency" into
+// "lang/go/concurrency". Tags that already exist at the destination are
+// merged.
+func handleMoveTag(c *gin.Context) {
+	var req model.MoveTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	from, to := model.NormalizeTag(req.From), model.NormalizeTag(req.To)
+	if from == "" || to == "" || from == to {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Move needs two different, non-empty tags")
+		return
+	}
+	if model.TagWithin(to, from) {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "A tag cannot be moved below itself")
+		return
+	}
+
+	updated, err := retag(c.Request.Context(), currentActor(c), func(tag string) string {
+		if model.TagWithin(tag, from) {
+			return to + strings.TrimPrefix(tag, from)
+		}
+		return tag
+	})
+	if err != nil {
+		bookmarkError(c, err)
+		return
+	}
+	if updated == 0 {
+		respondError(c, http.StatusNotFound, apierr.TagNotFound, "Tag not found")
+		return
+	}
+	recordActivity(c, model.Activity{Kind: model.ActivityTagged, Detail: fmt.Sprintf("moved tag %s to %s", from, to)})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    model.TagChange{Tag: to, Updated: updated},
+	})
+}
+
+// handleBulkTag adds and removes tags on a list of bookmarks in one
+// transaction: either every bookmark is updated or, when one of them does
+// not exist, none is
+func handleBulkTag(c *gin.Context) {
+	if tagStore == nil {
+		respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Bulk tagging is not supported by this storage driver")
+		return
+	}
+	var req model.BulkTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Nothing to do: give tags to add or remove")
+		return
+	}
+	// Bookmarks the caller cannot see are reported like missing ones,
+	// which UpdateTags reports itself
+	for _, id := range req.BookmarkIDs {
+		b, err := store.GetByID(c.Request.Context(), id)
+		if errors.Is(err, storage.ErrBookmarkNotFound) {
+			continue
+		}
+		if err == nil {
+			err = storage.CheckBookmarkAccess(c.Request.Context(), collections, currentActor(c), b, model.AccessEdit)
+		}
+		if errors.Is(err, storage.ErrBookmarkNotFound) {
+			respondError(c, http.StatusNotFound, apierr.NotFound, fmt.Errorf("%w: %s", err, id).Error())
+			return
+		}
+		if err != nil {
+			bookmarkError(c, err)
+			return
+		}
+	}
+
+	updated, err := tagStore.UpdateTags(c.Request.Context(), req.BookmarkIDs, req.Add, req.Remove)
+	if errors.Is(err, storage.ErrBookmarkNotFound) {
+		respondError(c, http.StatusNotFound, apierr.NotFound, err.Error())
+		return
+	}
+	if errors.Is(err, storage.ErrQuotaExceeded) {
+		respondError(c, http.StatusForbidden, apierr.QuotaExceeded, quotaMessage(err))
+		return
+	}
+	if err != nil {
+		log.Printf("Bulk tagging failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Bulk tagging failed")
+		return
+	}
+
+	for _, b := range updated {
+		indexBookmark(c.Request.Context(), b)
+		publishBookmarkEvent("updated", b.ID)
+		recordActivity(c, model.Activity{Kind: model.ActivityTagged, BookmarkID: b.ID, Detail: tagChangeDetail(req.Add, req.Remove)})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// tagChangeDetail describes a bulk tag change for the activity feed, as
+// "+added -removed"
+func tagChangeDetail(add, remove []string) string {
+	parts := make([]string, 0, len(add)+len(remove))
+	for _, t := range model.NormalizeTags(add) {
+		parts = append(parts, "+"+t)
+	}
+	for _, t := range model.NormalizeTags(remove) {
+		parts = append(parts, "-"+t)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/telegram"
+)
+
+// Global Telegram bot; nil unless TELEGRAM_BOT_TOKEN is set
+var telegramBot *telegram.Bot
+
+// RequireTelegram hides the webhook when no bot is configured
+func RequireTelegram() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if telegramBot == nil {
+			respondError(c, http.StatusNotFound, apierr.FeatureDisabled, "Telegram bot is not configured")
+			return
+		}
+		c.Next()
+	}
+}
+
+// registerTelegramWebhook points the bot at webhookURL in the background,
+// so a slow or unreachable Telegram does not hold up startup
+func registerTelegramWebhook(webhookURL string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := telegramBot.SetWebhook(ctx, webhookURL); err != nil {
+			log.Printf("Failed to register Telegram webhook: %v", err)
+			return
+		}
+		log.Printf("Telegram webhook registered at %s", webhookURL)
+	}()
+}
+
+// handleTelegramUpdate saves the link in a message sent to the bot, with
+// its #hashtags as tags, and replies with the outcome. Telegram retries
+// updates that are not answered with 200, so everything past the secret
+// check is answered with 200, including updates that are ignored.
+func handleTelegramUpdate(c *gin.Context) {
+	if !telegramBot.Authentic(c.Request) {
+		respondError(c, http.StatusUnauthorized, apierr.Unauthorized, "Unauthorized")
+		return
+	}
+	var update telegram.Update
+	if err := c.ShouldBindJSON(&update); err != nil || update.Message == nil {
+		c.Status(http.StatusOK)
+		return
+	}
+	m := update.Message
+	c.JSON(http.StatusOK, telegram.NewReply(m, saveTelegramLink(c, m)))
+}
+
+// saveTelegramLink saves the link in m and returns the reply text
+func saveTelegramLink(c *gin.Context, m *telegram.Message) string {
+	if !telegramBot.Allowed(m.Chat.ID) {
+		return fmt.Sprintf("This chat (ID %d) may not save bookmarks. Add its ID to TELEGRAM_ALLOWED_CHATS.", m.Chat.ID)
+	}
+	link, ok := telegram.ParseLink(m)
+	if !ok {
+		return "Send me a link to save it. #hashtags in the message become tags."
+	}
+
+	save := saveIntegrationLink(c.Request.Context(), "Telegram", integrationLink{URL: link.URL, Title: link.Title, Tags: link.Tags}, "")
+	return save.reply()
+}
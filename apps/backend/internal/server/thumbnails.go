@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/blob"
+	"github.com/hereisth/web-collector/apps/backend/internal/flags"
+	"github.com/hereisth/web-collector/apps/backend/internal/safehttp"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+	"github.com/hereisth/web-collector/apps/backend/internal/thumbnail"
+)
+
+const (
+	// maxPageBytes bounds how much of a page is read looking for og:image
+	maxPageBytes = 1 << 20
+	// maxImageBytes bounds the size of a downloaded source image
+	maxImageBytes = 10 << 20
+)
+
+// thumbnailManager renders preview thumbnails in the background. Each size
+// is stored in blob storage as thumbnails/<bookmark id>/<size>.jpg.
+type thumbnailManager struct {
+	mu      sync.Mutex
+	queued  map[string]bool
+	queue   chan string
+	client  *http.Client
+	workers int
+}
+
+// Global thumbnail manager, started by StartJobs
+var thumbnails = newThumbnailManager(2)
+
+func newThumbnailManager(workers int) *thumbnailManager {
+	return &thumbnailManager{
+		queued:  make(map[string]bool),
+		queue:   make(chan string, 256),
+		client:  safehttp.Client(20 * time.Second),
+		workers: workers,
+	}
+}
+
+// thumbnailPrefix is where thumbnails live in blob storage
+const thumbnailPrefix = "thumbnails/"
+
+func thumbnailKey(id, size string) string {
+	return thumbnailPrefix + id + "/" + size + ".jpg"
+}
+
+// start runs the thumbnail workers until ctx is cancelled
+func (m *thumbnailManager) start(ctx context.Context, wg *sync.WaitGroup) {
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-m.queue:
+					if err := m.generate(ctx, id); err != nil {
+						log.Printf("Thumbnail for bookmark %s failed: %v", id, err)
+					}
+					m.mu.Lock()
+					delete(m.queued, id)
+					m.mu.Unlock()
+				}
+			}
+		}()
+	}
+}
+
+// enqueue schedules thumbnails for a bookmark when the feature is enabled.
+// Bookmarks already queued and requests beyond the queue's capacity are
+// dropped; a later request for the thumbnail queues it again.
+func (m *thumbnailManager) enqueue(id string) {
+	if !features.Load().Enabled(flags.Thumbnails, "") {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.queued[id] {
+		return
+	}
+	select {
+	case m.queue <- id:
+		m.queued[id] = true
+	default:
+	}
+}
+
+// generate fetches the bookmark's page, follows its og:image and stores
+// every thumbnail size
+func (m *thumbnailManager) generate(ctx context.Context, id string) error {
+	bookmark, err := store.GetByID(ctx, id)
+	if errors.Is(err, storage.ErrBookmarkNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	page, err := url.Parse(bookmark.URL)
+	if err != nil {
+		return err
+	}
+
+	body, _, err := m.fetch(ctx, bookmark.URL, maxPageBytes)
+	if err != nil {
+		return fmt.Errorf("fetch page: %w", err)
+	}
+	imageURL := thumbnail.FindImage(bytes.NewReader(body), page)
+	if imageURL == "" {
+		return nil
+	}
+
+	data, contentType, err := m.fetch(ctx, imageURL, maxImageBytes)
+	if err != nil {
+		return fmt.Errorf("fetch image: %w", err)
+	}
+	if contentType != "" && !strings.HasPrefix(contentType, "image/") {
+		return fmt.Errorf("og:image %s is %s, not an image", imageURL, contentType)
+	}
+	img, err := thumbnail.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	for _, size := range thumbnail.Sizes {
+		var buf bytes.Buffer
+		if err := thumbnail.Render(&buf, img, size.Width); err != nil {
+			return err
+		}
+		if err := blobs.Put(ctx, thumbnailKey(id, size.Name), &buf, int64(buf.Len())); err != nil {
+			return fmt.Errorf("store %s thumbnail: %w", size.Name, err)
+		}
+	}
+	return nil
+}
+
+// fetch GETs rawURL and returns at most limit bytes of the body
+func (m *thumbnailManager) fetch(ctx context.Context, rawURL string, limit int64) ([]byte, string, error) {
+	return fetchLimited(ctx, m.client, "web-collector-thumbnails/1.0", rawURL, limit)
+}
+
+// fetchLimited GETs rawURL as userAgent and returns at most limit bytes of
+// the body along with its content type
+func fetchLimited(ctx context.Context, client *http.Client, userAgent, rawURL string, limit int64) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	return data, resp.Header.Get("Content-Type"), err
+}
+
+// remove deletes all thumbnails of a bookmark
+func (m *thumbnailManager) remove(ctx context.Context, id string) {
+	for _, size := range thumbnail.Sizes {
+		if err := blobs.Delete(ctx, thumbnailKey(id, size.Name)); err != nil {
+			log.Printf("Failed to delete thumbnail of bookmark %s: %v", id, err)
+		}
+	}
+}
+
+// handleGetThumbnail serves a bookmark's thumbnail. Missing thumbnails
+// are queued for generation, so clients can retry later.
+func handleGetThumbnail(c *gin.Context) {
+	id := c.Param("id")
+	size, ok := thumbnail.LookupSize(c.DefaultQuery("size", "md"))
+	if !ok {
+		names := make([]string, len(thumbnail.Sizes))
+		for i, s := range thumbnail.Sizes {
+			names[i] = s.Name
+		}
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "size must be one of "+strings.Join(names, ", "))
+		return
+	}
+	if _, err := store.GetByID(c.Request.Context(), id); err != nil {
+		bookmarkError(c, err)
+		return
+	}
+
+	r, err := blobs.Get(c.Request.Context(), thumbnailKey(id, size.Name))
+	if err == blob.ErrNotFound {
+		thumbnails.enqueue(id)
+		respondError(c, http.StatusNotFound, apierr.NotFound, "Thumbnail not available")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to open thumbnail of bookmark %s: %v", id, err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to read thumbnail")
+		return
+	}
+	defer r.Close()
+	c.DataFromReader(http.StatusOK, -1, "image/jpeg", r, map[string]string{
+		"Cache-Control": "public, max-age=86400",
+	})
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+)
+
+// RequestTimeout middleware gives each request a deadline, carried by
+// c.Request.Context(), so the store queries and outbound fetches made for
+// it are cancelled once it passes instead of holding the handler. A
+// request cut short before anything was written gets 504. Event streams
+// stay open for as long as a client watches, so they have no deadline.
+func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 || isEventStream(c) {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		tw := &timeoutWriter{ResponseWriter: c.Writer}
+		c.Writer = tw
+
+		c.Next()
+
+		c.Writer = tw.ResponseWriter
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !tw.responded {
+			respondError(c, http.StatusGatewayTimeout, apierr.Timeout, "Request timed out")
+		}
+	}
+}
+
+// timeoutWriter notes whether the handler started a response. Writers
+// further out cannot tell: Compress holds back short bodies, so
+// Written() stays false after a handler has answered.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	responded bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.responded = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.responded = true
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.responded = true
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.responded = true
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global trash store; nil when the storage driver deletes bookmarks
+// outright
+var trash storage.TrashStore
+
+// trashRetention is how long deleted bookmarks stay restorable
+var trashRetention time.Duration
+
+// RequireTrash hides the trash routes when deleted bookmarks cannot be
+// restored
+func RequireTrash() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if trash == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Restoring deleted bookmarks is not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleGetTrash lists the deleted bookmarks the caller can view, with
+// when they were deleted and when they will be purged
+func handleGetTrash(c *gin.Context) {
+	deleted, err := trash.ListDeleted(c.Request.Context())
+	if err != nil {
+		log.Printf("Listing deleted bookmarks failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Listing deleted bookmarks failed")
+		return
+	}
+	actor := currentActor(c)
+	visible := deleted[:0]
+	for _, d := range deleted {
+		if storage.CheckDeletedAccess(c.Request.Context(), collections, actor, d, model.AccessView) == nil {
+			d.PurgeAt = d.DeletedAt.Add(trashRetention)
+			visible = append(visible, d)
+		}
+	}
+	deleted = visible
+	p, ok := paginate(c, len(deleted))
+	if !ok {
+		return
+	}
+	page := deleted[p.start:p.end]
+	bookmarks := make([]model.Bookmark, len(page))
+	for i, d := range page {
+		bookmarks[i] = d.Bookmark
+	}
+	respondBookmarkList(c, p, page, bookmarks)
+}
+
+// handleRestoreBookmark moves a bookmark out of the trash
+func handleRestoreBookmark(c *gin.Context) {
+	// Restoring undoes a delete, so it needs the access deleting did
+	if d, found := deletedBookmark(c.Request.Context(), c.Param("id")); found {
+		switch err := storage.CheckDeletedAccess(c.Request.Context(), collections, currentActor(c), d, model.AccessOwner); {
+		case errors.Is(err, storage.ErrBookmarkNotFound):
+			respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found in trash")
+			return
+		case err != nil:
+			bookmarkError(c, err)
+			return
+		}
+	}
+
+	bookmark, err := trash.Restore(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, storage.ErrNotInTrash) {
+		respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found in trash")
+		return
+	}
+	if errors.Is(err, storage.ErrQuotaExceeded) {
+		respondError(c, http.StatusForbidden, apierr.QuotaExceeded, quotaMessage(err))
+		return
+	}
+	if err != nil {
+		log.Printf("Restoring bookmark failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Restoring bookmark failed")
+		return
+	}
+	indexBookmark(c.Request.Context(), bookmark)
+	publishBookmarkEvent("created", bookmark.ID)
+	thumbnails.enqueue(bookmark.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bookmark,
+	})
+}
+
+// purgeTrash permanently removes bookmarks deleted longer ago than the
+// retention period
+func purgeTrash(ctx context.Context, retention time.Duration) (string, error) {
+	if trash == nil {
+		return "the storage driver has no trash", nil
+	}
+	purged, err := trash.PurgeDeleted(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("purged %d deleted bookmarks", purged), ctx.Err()
+}
+
+// deletedBookmark finds bookmark id in the trash
+func deletedBookmark(ctx context.Context, id string) (model.DeletedBookmark, bool) {
+	if trash == nil {
+		return model.DeletedBookmark{}, false
+	}
+	deleted, err := trash.ListDeleted(ctx)
+	if err != nil {
+		log.Printf("Listing deleted bookmarks failed: %v", err)
+		return model.DeletedBookmark{}, false
+	}
+	for _, d := range deleted {
+		if d.ID == id {
+			return d, true
+		}
+	}
+	return model.DeletedBookmark{}, false
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/safehttp"
+	"github.com/hereisth/web-collector/apps/backend/internal/unfurl"
+)
+
+const (
+	// unfurlCacheSize bounds how many previews are kept
+	unfurlCacheSize = 1024
+	// unfurlTTL is how long a preview is kept
+	unfurlTTL = time.Hour
+	// unfurlFailureTTL is how long a page that could not be fetched is
+	// not tried again
+	unfurlFailureTTL = 5 * time.Minute
+)
+
+// errUnfurlFailed is cached for pages that could not be fetched; the
+// reason is logged by the request that tried
+var errUnfurlFailed = errors.New("the page could not be fetched")
+
+// unfurls keeps recent previews, so a save dialog opened twice on a page
+// fetches it once
+var unfurls = &unfurlCache{
+	order:   list.New(),
+	entries: make(map[string]*list.Element),
+	client:  safehttp.Client(10 * time.Second),
+}
+
+type unfurlCache struct {
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	client  *http.Client
+}
+
+type unfurlEntry struct {
+	url       string
+	preview   unfurl.Preview
+	err       error
+	expiresAt time.Time
+}
+
+// handleUnfurl returns the preview of the page at ?url=: its title,
+// description, image and site name. Only pages on public addresses are
+// fetched.
+func handleUnfurl(c *gin.Context) {
+	raw := strings.TrimSpace(c.Query("url"))
+	if !isWebURL(raw) {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "url must be an http(s) URL")
+		return
+	}
+	preview, err := unfurls.get(c.Request.Context(), normalizeURL(c.Request.Context(), raw))
+	switch {
+	case errors.Is(err, safehttp.ErrForbiddenAddress):
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "url must point to a public address")
+		return
+	case err != nil:
+		respondError(c, http.StatusBadGateway, apierr.UpstreamFailed, "The page could not be fetched")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    preview,
+	})
+}
+
+// get returns the preview of a page, from the cache when it is there
+func (u *unfurlCache) get(ctx context.Context, pageURL string) (unfurl.Preview, error) {
+	u.mu.Lock()
+	if el, ok := u.entries[pageURL]; ok {
+		entry := el.Value.(*unfurlEntry)
+		if time.Now().Before(entry.expiresAt) {
+			u.order.MoveToFront(el)
+			u.mu.Unlock()
+			return entry.preview, entry.err
+		}
+		u.order.Remove(el)
+		delete(u.entries, pageURL)
+	}
+	u.mu.Unlock()
+
+	preview, err := u.fetch(ctx, pageURL)
+	ttl := unfurlTTL
+	if err != nil {
+		if ctx.Err() != nil {
+			// The caller went away; that says nothing about the page.
+			return unfurl.Preview{}, err
+		}
+		if !errors.Is(err, safehttp.ErrForbiddenAddress) {
+			log.Printf("Unfurl %s failed: %v", pageURL, err)
+			err = errUnfurlFailed
+		}
+		ttl = unfurlFailureTTL
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.entries[pageURL] = u.order.PushFront(&unfurlEntry{
+		url:       pageURL,
+		preview:   preview,
+		err:       err,
+		expiresAt: time.Now().Add(ttl),
+	})
+	for u.order.Len() > unfurlCacheSize {
+		oldest := u.order.Back()
+		u.order.Remove(oldest)
+		delete(u.entries, oldest.Value.(*unfurlEntry).url)
+	}
+	return preview, err
+}
+
+// fetch GETs a page and reads its preview. Pages that are not HTML,
+// images for instance, are previewed as their URL and site.
+func (u *unfurlCache) fetch(ctx context.Context, pageURL string) (unfurl.Preview, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return unfurl.Preview{}, err
+	}
+	req.Header.Set("User-Agent", "web-collector-unfurl/1.0")
+	req.Header.Set("Accept", "text/html, */*;q=0.5")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return unfurl.Preview{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return unfurl.Preview{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	// Relative image URLs are relative to where redirects ended
+	final := resp.Request.URL
+	media, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if media != "text/html" && media != "application/xhtml+xml" {
+		preview := unfurl.Parse(nil, final)
+		if strings.HasPrefix(media, "image/") {
+			preview.Image = preview.URL
+		}
+		return preview, nil
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return unfurl.Preview{}, err
+	}
+	return unfurl.Parse(page, final), nil
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/safehttp"
+)
+
+// urlDNSCheck makes bookmark URLs whose host does not resolve invalid;
+// set from Config in SetupRouter
+var urlDNSCheck bool
+
+// urlDNSTimeout bounds the lookup of a bookmark URL's host. A lookup that
+// times out lets the URL through: a slow resolver is no reason to refuse
+// a save.
+const urlDNSTimeout = 3 * time.Second
+
+// scriptSchemes can run code when a saved link is opened, so URLs using
+// them are refused with their own message
+var scriptSchemes = []string{"javascript:", "data:", "vbscript:"}
+
+// validateNoScriptURL is the "noscripturl" binding rule: the URL does not
+// use a scheme that runs code. Leading control characters and spaces are
+// ignored the way browsers ignore them.
+func validateNoScriptURL(fl validator.FieldLevel) bool {
+	s := strings.ToLower(strings.TrimLeftFunc(fl.Field().String(), func(r rune) bool {
+		return r <= ' ' || unicode.IsControl(r)
+	}))
+	// Browsers also drop tabs and newlines inside the scheme
+	s = strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(s)
+	for _, scheme := range scriptSchemes {
+		if strings.HasPrefix(s, scheme) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateWebURL is the "weburl" binding rule: an absolute http(s) URL
+// with a well-formed host and, when one is given, a valid port
+func validateWebURL(fl validator.FieldLevel) bool {
+	u, err := url.Parse(strings.TrimSpace(fl.Field().String()))
+	if err != nil || u.Opaque != "" {
+		return false
+	}
+	if scheme := strings.ToLower(u.Scheme); scheme != "http" && scheme != "https" {
+		return false
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return false
+		}
+	}
+	return validHost(u.Hostname())
+}
+
+// validHost reports whether host is an IP address or a DNS name: dot
+// separated labels of letters, digits and inner hyphens. Letters may be
+// non-ASCII, for internationalized names.
+func validHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	host = strings.TrimSuffix(host, ".")
+	if len(host) == 0 || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if r != '-' && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkURLHost answers the request with a field error and returns false
+// when the DNS check is on and the host of rawURL, the value of field,
+// does not exist
+func checkURLHost(c *gin.Context, field, rawURL string) bool {
+	if !urlDNSCheck {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || net.ParseIP(u.Hostname()) != nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), urlDNSTimeout)
+	defer cancel()
+	_, err = net.DefaultResolver.LookupHost(ctx, u.Hostname())
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		return true
+	}
+	msg := field + " host does not exist"
+	resp := apierr.New(apierr.ValidationFailed, "Invalid request body")
+	resp.Details = msg
+	resp.Fields = []apierr.FieldError{{Field: field, Rule: "dns", Message: msg}}
+	c.AbortWithStatusJSON(http.StatusBadRequest, resp)
+	return false
+}
+
+// publicURL reports whether the host of rawURL is, or resolves only to,
+// public addresses. Hosts that cannot be resolved are not known to be
+// public. Delivery still goes through a safehttp client, since DNS
+// answers can change afterwards.
+func publicURL(ctx context.Context, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		return safehttp.Public(addr)
+	}
+	ctx, cancel := context.WithTimeout(ctx, urlDNSTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return false
+	}
+	for _, addr := range addrs {
+		if !safehttp.Public(addr.Unmap()) {
+			return false
+		}
+	}
+	return true
+}
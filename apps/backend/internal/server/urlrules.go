@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// Global URL rule store; nil when the storage driver keeps no rules, in
+// which case the default rules apply
+var urlRules storage.URLRuleStore
+
+// RequireURLRules hides the rule editing routes when the store keeps no
+// rules
+func RequireURLRules() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if urlRules == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "URL rules are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// normalizeURL applies the URL rules to a URL about to be saved or looked
+// up. When the rules cannot be loaded the defaults apply.
+func normalizeURL(ctx context.Context, raw string) string {
+	rules := model.DefaultURLRules()
+	if urlRules != nil {
+		stored, err := urlRules.URLRules(ctx)
+		if err != nil {
+			log.Printf("Failed to load URL rules, using the defaults: %v", err)
+		} else {
+			rules = stored
+		}
+	}
+	return rules.Normalize(strings.TrimSpace(raw))
+}
+
+// handleGetURLRules returns the URL rules. With ?url= it also returns
+// what that URL normalizes to, for trying rules out.
+func handleGetURLRules(c *gin.Context) {
+	rules, err := urlRules.URLRules(c.Request.Context())
+	if err != nil {
+		log.Printf("Loading URL rules failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Loading URL rules failed")
+		return
+	}
+	resp := gin.H{
+		"success": true,
+		"data":    rules,
+	}
+	if raw := c.Query("url"); raw != "" {
+		resp["normalized"] = rules.Normalize(strings.TrimSpace(raw))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleSetURLRules replaces the URL rules. Bookmarks already saved keep
+// their URLs; the rules apply to what is saved or looked up from now on.
+func handleSetURLRules(c *gin.Context) {
+	var req model.URLRules
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "Invalid parameter pattern: "+err.Error())
+		return
+	}
+	if req.StripParams == nil {
+		req.StripParams = []string{}
+	}
+	if req.Sites == nil {
+		req.Sites = []model.SiteURLRule{}
+	}
+	for i := range req.Sites {
+		req.Sites[i].Domain = model.NormalizeDomain(req.Sites[i].Domain)
+	}
+
+	rules, err := urlRules.SetURLRules(c.Request.Context(), req)
+	if err != nil {
+		log.Printf("Saving URL rules failed: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Saving URL rules failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rules,
+	})
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hereisth/web-collector/apps/backend/internal/apierr"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+	"github.com/hereisth/web-collector/apps/backend/internal/webmention"
+)
+
+// Global Webmention client; nil unless WEBMENTION_SITE_URL is set
+var mentioner *webmention.Client
+
+// Global mention store; nil when the storage driver cannot keep mentions
+var mentionStore storage.MentionStore
+
+// RequireWebmention hides the Webmention endpoint unless Webmentions are
+// configured and the store can keep them
+func RequireWebmention() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if mentioner == nil || mentionStore == nil {
+			respondError(c, http.StatusNotFound, apierr.FeatureDisabled, "Webmentions are not enabled")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireMentions hides the mention routes when the store cannot keep
+// mentions
+func RequireMentions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if mentionStore == nil {
+			respondError(c, http.StatusNotImplemented, apierr.NotSupported, "Webmentions are not supported by this storage driver")
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleGetMentions returns the Webmentions a bookmark's public page has
+// received
+func handleGetMentions(c *gin.Context) {
+	result, err := mentionStore.BookmarkMentions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrBookmarkNotFound) {
+			respondError(c, http.StatusNotFound, apierr.BookmarkNotFound, "Bookmark not found")
+			return
+		}
+		log.Printf("Failed to list mentions: %v", err)
+		respondError(c, http.StatusInternalServerError, apierr.Internal, "Failed to list mentions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// handleReceiveWebmention accepts a Webmention of a public bookmark's
+// page. The source is checked in the background, as the protocol
+// expects; a source that stopped linking to the page loses its mention.
+func handleReceiveWebmention(c *gin.Context) {
+	source, target := strings.TrimSpace(c.PostForm("source")), strings.TrimSpace(c.PostForm("target"))
+	if !isWebURL(source) || !isWebURL(target) || source == target {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "source and target must be different http(s) URLs")
+		return
+	}
+	bookmarkID, ok := mentionTarget(c.Request.Context(), target)
+	if !ok {
+		respondError(c, http.StatusBadRequest, apierr.ValidationFailed, "target is not a public bookmark on this site")
+		return
+	}
+	webmentions.verify(receivedMention{bookmarkID: bookmarkID, source: source, target: target})
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Webmention queued for verification",
+	})
+}
+
+// mentionTarget returns the ID of the public bookmark whose page target
+// is
+func mentionTarget(ctx context.Context, target string) (string, bool) {
+	path, ok := strings.CutPrefix(target, mentioner.SiteURL()+"/u/")
+	if !ok {
+		return "", false
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 || parts[1] != "bookmarks" {
+		return "", false
+	}
+	account, found := publicAccount(ctx, parts[0])
+	if !found {
+		return "", false
+	}
+	if _, found := publicBookmark(ctx, account, parts[2]); !found {
+		return "", false
+	}
+	return parts[2], true
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// receivedMention is a Webmention waiting to be verified
+type receivedMention struct {
+	bookmarkID string
+	source     string
+	target     string
+}
+
+// mentionManager sends and verifies Webmentions in the background
+type mentionManager struct {
+	sends    chan string // bookmark IDs
+	receipts chan receivedMention
+}
+
+// Global Webmention manager, started by StartJobs
+var webmentions = &mentionManager{
+	sends:    make(chan string, 256),
+	receipts: make(chan receivedMention, 256),
+}
+
+// start runs the worker until ctx is cancelled
+func (m *mentionManager) start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case id := <-m.sends:
+				m.send(ctx, id)
+			case r := <-m.receipts:
+				m.receive(ctx, r)
+			}
+		}
+	}()
+}
+
+// enqueue schedules telling a bookmark's URL that the bookmark's public
+// page mentions it, which happens if the bookmark is public and has notes
+// by then. Bookmarks beyond the queue's capacity are dropped.
+func (m *mentionManager) enqueue(bookmarkID string) {
+	if mentioner == nil || accounts == nil || collections == nil {
+		return
+	}
+	select {
+	case m.sends <- bookmarkID:
+	default:
+		log.Printf("Webmention queue full, dropped bookmark %s", bookmarkID)
+	}
+}
+
+// verify schedules checking a received Webmention
+func (m *mentionManager) verify(r receivedMention) {
+	select {
+	case m.receipts <- r:
+	default:
+		log.Printf("Webmention queue full, dropped mention from %s", r.source)
+	}
+}
+
+// send sends the Webmention of a public bookmark with notes to the page
+// it bookmarks. Pages without an endpoint are skipped silently.
+func (m *mentionManager) send(ctx context.Context, bookmarkID string) {
+	b, err := store.GetByID(ctx, bookmarkID)
+	if err != nil || strings.TrimSpace(b.Notes) == "" || strings.HasPrefix(b.URL, mentioner.SiteURL()+"/") {
+		return
+	}
+	account, found := publicOwner(ctx, bookmarkID)
+	if !found {
+		return
+	}
+	source := mentioner.SiteURL() + "/u/" + account.Username + "/bookmarks/" + b.ID
+	err = mentioner.Send(ctx, source, b.URL)
+	if err != nil && !errors.Is(err, webmention.ErrNoEndpoint) {
+		log.Printf("Webmention for bookmark %s failed: %v", b.ID, err)
+	}
+}
+
+// receive verifies a received Webmention, keeping it if its source links
+// to the bookmark's page and forgetting it otherwise
+func (m *mentionManager) receive(ctx context.Context, r receivedMention) {
+	if mentionStore == nil {
+		return
+	}
+	src, err := mentioner.Verify(ctx, r.source, r.target)
+	switch {
+	case errors.Is(err, webmention.ErrNoLink), errors.Is(err, webmention.ErrGone):
+		err = mentionStore.DeleteMention(ctx, r.bookmarkID, r.source)
+	case err == nil:
+		_, err = mentionStore.SaveMention(ctx, model.Mention{
+			BookmarkID: r.bookmarkID,
+			Source:     r.source,
+			Title:      truncate(src.Title, 200),
+		})
+	}
+	if err != nil {
+		log.Printf("Webmention from %s failed: %v", r.source, err)
+	}
+}
@@ -0,0 +1,224 @@
+// Package slack handles a Slack app's slash command and link unfurling,
+// so links can be saved and previewed from Slack.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiURL is the Web API endpoint; the method is appended
+const apiURL = "https://slack.com/api/"
+
+// maxRequestAge bounds how old a signed request may be, which stops
+// captured requests from being replayed later
+const maxRequestAge = 5 * time.Minute
+
+// Config enables the app; it is disabled without a signing secret
+type Config struct {
+	SigningSecret string
+	// BotToken is used to unfurl links; without it links are not unfurled
+	BotToken string
+	// CollectionID is the shared collection links are saved into
+	CollectionID string
+}
+
+// App verifies and answers requests from Slack
+type App struct {
+	secret string
+	token  string
+	client *http.Client
+}
+
+// Open returns the configured app, or nil when no signing secret is set
+func Open(cfg Config) (*App, error) {
+	if cfg.SigningSecret == "" {
+		return nil, nil
+	}
+	if cfg.BotToken != "" && !strings.HasPrefix(cfg.BotToken, "xoxb-") {
+		return nil, errors.New("Slack bot token must be a bot token (xoxb-...)")
+	}
+	return &App{
+		secret: cfg.SigningSecret,
+		token:  cfg.BotToken,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Verify reports whether body was signed by Slack with the signing secret
+// in the last few minutes
+func (a *App) Verify(header http.Header, body []byte, now time.Time) bool {
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(a.secret))
+	fmt.Fprintf(mac, "v0:%s:", ts)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature")))
+}
+
+// CanUnfurl reports whether a bot token is configured
+func (a *App) CanUnfurl() bool {
+	return a.token != ""
+}
+
+// Command is a slash command invocation
+type Command struct {
+	Command  string
+	Text     string
+	UserID   string
+	UserName string
+	TeamID   string
+}
+
+// ParseCommand reads a slash command from its form-encoded body
+func ParseCommand(body []byte) (Command, error) {
+	v, err := url.ParseQuery(string(body))
+	if err != nil {
+		return Command{}, err
+	}
+	return Command{
+		Command:  v.Get("command"),
+		Text:     v.Get("text"),
+		UserID:   v.Get("user_id"),
+		UserName: v.Get("user_name"),
+		TeamID:   v.Get("team_id"),
+	}, nil
+}
+
+// Link is what a command asks to save: its first http(s) URL, its
+// #hashtags as tags, and the rest of its words as the title
+type Link struct {
+	URL   string
+	Title string
+	Tags  []string
+}
+
+// ParseLink reads the link out of command text. Slack sends links as
+// <url> or <url|label>, and channel mentions as <#C123|name>, which are
+// not tags.
+func ParseLink(text string) (Link, bool) {
+	var (
+		link  Link
+		title []string
+	)
+	for _, word := range strings.Fields(text) {
+		if strings.HasPrefix(word, "<") && strings.HasSuffix(word, ">") {
+			target, label, _ := strings.Cut(word[1:len(word)-1], "|")
+			if link.URL == "" && isWebURL(target) {
+				link.URL = target
+				continue
+			}
+			if label != "" {
+				word = label
+			}
+		}
+		switch {
+		case strings.HasPrefix(word, "#") && len(word) > 1:
+			link.Tags = append(link.Tags, strings.TrimPrefix(word, "#"))
+		case link.URL == "" && isWebURL(word):
+			link.URL = word
+		default:
+			title = append(title, word)
+		}
+	}
+	link.Title = strings.Join(title, " ")
+	return link, link.URL != ""
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Response answers a slash command. In-channel responses are shown to
+// everyone in the channel, ephemeral ones only to the user.
+type Response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Ephemeral returns a response only the user sees
+func Ephemeral(text string) Response {
+	return Response{ResponseType: "ephemeral", Text: text}
+}
+
+// InChannel returns a response the whole channel sees
+func InChannel(text string) Response {
+	return Response{ResponseType: "in_channel", Text: text}
+}
+
+// Event is an Events API request: a URL verification challenge when the
+// app is configured, and event callbacks afterwards
+type Event struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Event     struct {
+		Type      string `json:"type"`
+		Channel   string `json:"channel"`
+		MessageTS string `json:"message_ts"`
+		Links     []struct {
+			URL string `json:"url"`
+		} `json:"links"`
+	} `json:"event"`
+}
+
+// Unfurl is the preview shown for a link
+type Unfurl struct {
+	Title     string `json:"title"`
+	TitleLink string `json:"title_link"`
+	Text      string `json:"text,omitempty"`
+}
+
+// Unfurl attaches previews to the links of a message, keyed by URL as
+// they appeared in the link_shared event
+func (a *App) Unfurl(ctx context.Context, channel, ts string, unfurls map[string]Unfurl) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"channel": channel,
+		"ts":      ts,
+		"unfurls": unfurls,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"chat.unfurl", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("chat.unfurl: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("chat.unfurl: %s", resp.Status)
+	}
+	if !result.OK {
+		return fmt.Errorf("chat.unfurl: %s", result.Error)
+	}
+	return nil
+}
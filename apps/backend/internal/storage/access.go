@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+var (
+	// ErrForbidden is returned when the actor can see a collection but
+	// lacks the access level an operation needs
+	ErrForbidden = errors.New("insufficient access to this collection")
+	// ErrShareNotFound is returned when revoking a share that does not exist
+	ErrShareNotFound = errors.New("share not found")
+)
+
+// Actor is who a collection operation is performed for. The zero Actor is
+// an anonymous caller.
+type Actor struct {
+	AccountID string
+	Admin     bool
+}
+
+// accessLevels ranks the access levels; a missing level ranks 0
+var accessLevels = map[string]int{
+	model.AccessView:  1,
+	model.AccessEdit:  2,
+	model.AccessOwner: 3,
+}
+
+// Restrict returns a view of cs that enforces actor's access to each
+// collection:
+//
+//   - admins, and the owner of a collection or of any collection above
+//     it, have owner access
+//   - shares grant view or edit access to a collection and everything
+//     below it; workspace shares apply to every signed-in account and
+//     public shares to everyone
+//   - collections without owner are open to everyone, as they were before
+//     collections had owners
+//
+// Collections the actor cannot view behave as if they did not exist.
+// Reading needs view access, changing names and bookmarks edit access, and
+// moving, merging away, deleting and sharing owner access.
+func Restrict(cs CollectionStore, actor Actor) CollectionStore {
+	return &restricted{cs: cs, actor: actor}
+}
+
+// CheckAccess returns nil when actor has at least access to collection
+// id, ErrForbidden when they can only see it and ErrCollectionNotFound
+// otherwise
+func CheckAccess(ctx context.Context, cs CollectionStore, actor Actor, id, access string) error {
+	return (&restricted{cs: cs, actor: actor}).require(ctx, id, access)
+}
+
+type restricted struct {
+	cs    CollectionStore
+	actor Actor
+}
+
+// levels computes the actor's access rank for every collection
+func (r *restricted) levels(ctx context.Context) map[string]int {
+	all := r.cs.ListCollections(ctx)
+	byID := make(map[string]model.Collection, len(all))
+	for _, c := range all {
+		byID[c.ID] = c
+	}
+	shared := make(map[string]int)
+	for _, sh := range r.cs.ListShares(ctx) {
+		signedIn := r.actor.AccountID != ""
+		if sh.Grantee == model.GranteePublic ||
+			(signedIn && (sh.Grantee == r.actor.AccountID || sh.Grantee == model.GranteeWorkspace)) {
+			shared[sh.CollectionID] = max(shared[sh.CollectionID], accessLevels[sh.Access])
+		}
+	}
+
+	owner := accessLevels[model.AccessOwner]
+	levels := make(map[string]int, len(all))
+	for _, c := range all {
+		level := 0
+		if r.actor.Admin || c.OwnerID == "" {
+			level = owner
+		}
+		// Walk up the tree; the depth bound guards against a corrupt cycle.
+		for cur, ok, depth := c, true, 0; ok && level < owner && depth <= len(all); depth++ {
+			if r.actor.AccountID != "" && cur.OwnerID == r.actor.AccountID {
+				level = owner
+			}
+			level = max(level, shared[cur.ID])
+			cur, ok = byID[cur.ParentID]
+		}
+		levels[c.ID] = level
+	}
+	return levels
+}
+
+// require checks the actor has at least access on collection id
+func (r *restricted) require(ctx context.Context, id, access string) error {
+	level := r.levels(ctx)[id]
+	switch {
+	case level == 0:
+		return ErrCollectionNotFound
+	case level < accessLevels[access]:
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (r *restricted) ListCollections(ctx context.Context) []model.Collection {
+	levels := r.levels(ctx)
+	result := []model.Collection{}
+	for _, c := range r.cs.ListCollections(ctx) {
+		if levels[c.ID] > 0 {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func (r *restricted) GetCollection(ctx context.Context, id string) (model.Collection, bool) {
+	if r.levels(ctx)[id] == 0 {
+		return model.Collection{}, false
+	}
+	return r.cs.GetCollection(ctx, id)
+}
+
+// CreateCollection makes the actor the owner of the new collection,
+// whatever ownerID says
+func (r *restricted) CreateCollection(ctx context.Context, name, parentID, _ string) (model.Collection, error) {
+	if parentID != "" {
+		if err := r.require(ctx, parentID, model.AccessEdit); err != nil {
+			return model.Collection{}, err
+		}
+	}
+	return r.cs.CreateCollection(ctx, name, parentID, r.actor.AccountID)
+}
+
+func (r *restricted) RenameCollection(ctx context.Context, id, name string) (model.Collection, error) {
+	if err := r.require(ctx, id, model.AccessEdit); err != nil {
+		return model.Collection{}, err
+	}
+	return r.cs.RenameCollection(ctx, id, name)
+}
+
+func (r *restricted) MoveCollection(ctx context.Context, id, parentID string) (model.Collection, error) {
+	if err := r.require(ctx, id, model.AccessOwner); err != nil {
+		return model.Collection{}, err
+	}
+	if parentID != "" {
+		if err := r.require(ctx, parentID, model.AccessEdit); err != nil {
+			return model.Collection{}, err
+		}
+	}
+	return r.cs.MoveCollection(ctx, id, parentID)
+}
+
+func (r *restricted) MergeCollections(ctx context.Context, sourceID, targetID string) (model.Collection, error) {
+	if err := r.require(ctx, sourceID, model.AccessOwner); err != nil {
+		return model.Collection{}, err
+	}
+	if err := r.require(ctx, targetID, model.AccessEdit); err != nil {
+		return model.Collection{}, err
+	}
+	return r.cs.MergeCollections(ctx, sourceID, targetID)
+}
+
+func (r *restricted) DeleteCollection(ctx context.Context, id string) error {
+	if err := r.require(ctx, id, model.AccessOwner); err != nil {
+		return err
+	}
+	return r.cs.DeleteCollection(ctx, id)
+}
+
+func (r *restricted) AddToCollection(ctx context.Context, id string, bookmarkIDs []string) error {
+	if err := r.require(ctx, id, model.AccessEdit); err != nil {
+		return err
+	}
+	return r.cs.AddToCollection(ctx, id, bookmarkIDs)
+}
+
+func (r *restricted) RemoveFromCollection(ctx context.Context, id, bookmarkID string) error {
+	if err := r.require(ctx, id, model.AccessEdit); err != nil {
+		return err
+	}
+	return r.cs.RemoveFromCollection(ctx, id, bookmarkID)
+}
+
+func (r *restricted) CollectionBookmarks(ctx context.Context, id string, recursive bool) ([]string, error) {
+	if err := r.require(ctx, id, model.AccessView); err != nil {
+		return nil, err
+	}
+	return r.cs.CollectionBookmarks(ctx, id, recursive)
+}
+
+func (r *restricted) ShareCollection(ctx context.Context, id, grantee, access string) error {
+	if err := r.require(ctx, id, model.AccessOwner); err != nil {
+		return err
+	}
+	return r.cs.ShareCollection(ctx, id, grantee, access)
+}
+
+func (r *restricted) UnshareCollection(ctx context.Context, id, grantee string) error {
+	if err := r.require(ctx, id, model.AccessOwner); err != nil {
+		return err
+	}
+	return r.cs.UnshareCollection(ctx, id, grantee)
+}
+
+// ListShares returns the shares of the collections the actor owns
+func (r *restricted) ListShares(ctx context.Context) []model.CollectionShare {
+	levels := r.levels(ctx)
+	result := []model.CollectionShare{}
+	for _, sh := range r.cs.ListShares(ctx) {
+		if levels[sh.CollectionID] >= accessLevels[model.AccessOwner] {
+			result = append(result, sh)
+		}
+	}
+	return result
+}
+
+// CheckBookmarkAccess returns nil when actor has at least access to b,
+// ErrForbidden when they can only see it and ErrBookmarkNotFound
+// otherwise. Bookmarks are open to admins, to their owner and, when they
+// have none, to everyone, as they were before bookmarks had owners.
+// Anyone else reaches a bookmark through the collections it is in, with
+// the access they have there: reading needs view access, changing it
+// edit access and deleting it owner access. cs may be nil when the store
+// keeps no collections.
+func CheckBookmarkAccess(ctx context.Context, cs CollectionStore, actor Actor, b model.Bookmark, access string) error {
+	return checkBookmarkAccess(ctx, cs, actor, b.OwnerID, access, func() map[string]bool {
+		return bookmarkCollections(ctx, cs, b.ID)
+	})
+}
+
+// CheckDeletedAccess is CheckBookmarkAccess for a bookmark in the trash,
+// reached through the collections it was in
+func CheckDeletedAccess(ctx context.Context, cs CollectionStore, actor Actor, d model.DeletedBookmark, access string) error {
+	return checkBookmarkAccess(ctx, cs, actor, d.OwnerID, access, func() map[string]bool {
+		in := make(map[string]bool, len(d.CollectionIDs))
+		for _, id := range d.CollectionIDs {
+			in[id] = true
+		}
+		return in
+	})
+}
+
+func checkBookmarkAccess(ctx context.Context, cs CollectionStore, actor Actor, ownerID, access string, collectionIDs func() map[string]bool) error {
+	if actor.Admin || ownerID == "" || (actor.AccountID != "" && ownerID == actor.AccountID) {
+		return nil
+	}
+	level := 0
+	if cs != nil {
+		levels := (&restricted{cs: cs, actor: actor}).levels(ctx)
+		for id := range collectionIDs() {
+			level = max(level, levels[id])
+		}
+	}
+	switch {
+	case level == 0:
+		return ErrBookmarkNotFound
+	case level < accessLevels[access]:
+		return ErrForbidden
+	}
+	return nil
+}
+
+// bookmarkCollections returns the collections bookmark id is directly in
+func bookmarkCollections(ctx context.Context, cs CollectionStore, id string) map[string]bool {
+	in := make(map[string]bool)
+	if cs == nil {
+		return in
+	}
+	for _, c := range cs.ListCollections(ctx) {
+		ids, err := cs.CollectionBookmarks(ctx, c.ID, false)
+		if err != nil {
+			continue
+		}
+		for _, bookmarkID := range ids {
+			if bookmarkID == id {
+				in[c.ID] = true
+				break
+			}
+		}
+	}
+	return in
+}
+
+// VisibleBookmarks keeps the bookmarks actor can view
+func VisibleBookmarks(ctx context.Context, cs CollectionStore, actor Actor, bookmarks []model.Bookmark) []model.Bookmark {
+	if actor.Admin {
+		return bookmarks
+	}
+	var shared map[string]bool
+	result := make([]model.Bookmark, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		if b.OwnerID == "" || (actor.AccountID != "" && b.OwnerID == actor.AccountID) {
+			result = append(result, b)
+			continue
+		}
+		if shared == nil {
+			shared = viewableBookmarkIDs(ctx, cs, actor)
+		}
+		if shared[b.ID] {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// viewableBookmarkIDs returns the bookmarks in the collections actor can
+// view
+func viewableBookmarkIDs(ctx context.Context, cs CollectionStore, actor Actor) map[string]bool {
+	ids := make(map[string]bool)
+	if cs == nil {
+		return ids
+	}
+	for id, level := range (&restricted{cs: cs, actor: actor}).levels(ctx) {
+		if level == 0 {
+			continue
+		}
+		in, err := cs.CollectionBookmarks(ctx, id, false)
+		if err != nil {
+			continue
+		}
+		for _, bookmarkID := range in {
+			ids[bookmarkID] = true
+		}
+	}
+	return ids
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+var (
+	// ErrAccountExists is returned when creating an account whose email
+	// is taken
+	ErrAccountExists = errors.New("an account with this email already exists")
+	// ErrUsernameTaken is returned when claiming another account's username
+	ErrUsernameTaken = errors.New("this username is taken")
+	// ErrAccountNotFound is returned when updating an unknown account
+	ErrAccountNotFound = errors.New("account not found")
+)
+
+// AccountStore persists login accounts. Emails are matched case-insensitively.
+type AccountStore interface {
+	CreateAccount(ctx context.Context, email, passwordHash, role string) (model.Account, error)
+	GetAccountByEmail(ctx context.Context, email string) (model.Account, bool)
+	GetAccount(ctx context.Context, id string) (model.Account, bool)
+	CountAccounts(ctx context.Context, role string) int
+	// GetAccountByUsername returns the account with a username, which is
+	// matched case-insensitively
+	GetAccountByUsername(ctx context.Context, username string) (model.Account, bool)
+	// UpdateProfile sets an account's username and whether its profile is
+	// public
+	UpdateProfile(ctx context.Context, id, username string, public bool) (model.Account, error)
+	// SetArchivePolicy sets after how many untouched days the bookmarks in
+	// an account's collections are archived; 0 turns it off
+	SetArchivePolicy(ctx context.Context, id string, afterDays int) (model.Account, error)
+	// ListAccounts returns every account in creation order
+	ListAccounts(ctx context.Context) []model.Account
+}
+
+// CreateAccount adds a new account
+func (s *MemoryStore) CreateAccount(ctx context.Context, email, passwordHash, role string) (model.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email = strings.ToLower(email)
+	for _, a := range s.accounts {
+		if a.Email == email {
+			return model.Account{}, ErrAccountExists
+		}
+	}
+	account := model.Account{
+		ID:           fmt.Sprintf("%d", len(s.accounts)+1),
+		Email:        email,
+		Role:         role,
+		PasswordHash: passwordHash,
+		CreatedAt:    time.Now(),
+	}
+	s.accounts = append(s.accounts, account)
+	return account, nil
+}
+
+// GetAccountByEmail returns the account registered under email
+func (s *MemoryStore) GetAccountByEmail(ctx context.Context, email string) (model.Account, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	email = strings.ToLower(email)
+	for _, a := range s.accounts {
+		if a.Email == email {
+			return a, true
+		}
+	}
+	return model.Account{}, false
+}
+
+// GetAccount returns the account with id
+func (s *MemoryStore) GetAccount(ctx context.Context, id string) (model.Account, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, a := range s.accounts {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return model.Account{}, false
+}
+
+// CountAccounts returns the number of accounts with role, or all accounts
+// when role is empty
+func (s *MemoryStore) CountAccounts(ctx context.Context, role string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, a := range s.accounts {
+		if role == "" || a.Role == role {
+			n++
+		}
+	}
+	return n
+}
+
+// GetAccountByUsername returns the account with username
+func (s *MemoryStore) GetAccountByUsername(ctx context.Context, username string) (model.Account, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	username = strings.ToLower(username)
+	for _, a := range s.accounts {
+		if a.Username != "" && a.Username == username {
+			return a, true
+		}
+	}
+	return model.Account{}, false
+}
+
+// UpdateProfile changes an account's public profile settings
+func (s *MemoryStore) UpdateProfile(ctx context.Context, id, username string, public bool) (model.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	username = strings.ToLower(username)
+	index := -1
+	for i, a := range s.accounts {
+		if a.ID == id {
+			index = i
+		} else if a.Username == username {
+			return model.Account{}, ErrUsernameTaken
+		}
+	}
+	if index < 0 {
+		return model.Account{}, ErrAccountNotFound
+	}
+	s.accounts[index].Username = username
+	s.accounts[index].PublicProfile = public
+	return s.accounts[index], nil
+}
+
+// SetArchivePolicy changes an account's auto-archive policy
+func (s *MemoryStore) SetArchivePolicy(ctx context.Context, id string, afterDays int) (model.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.accounts {
+		if s.accounts[i].ID == id {
+			s.accounts[i].ArchiveAfterDays = afterDays
+			return s.accounts[i], nil
+		}
+	}
+	return model.Account{}, ErrAccountNotFound
+}
+
+// ListAccounts returns every account
+func (s *MemoryStore) ListAccounts(ctx context.Context) []model.Account {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]model.Account, len(s.accounts))
+	copy(result, s.accounts)
+	return result
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ActivityStore keeps the activity feed. Entries outlive the bookmarks
+// and collections they mention.
+type ActivityStore interface {
+	// RecordActivity appends an entry, assigning its ID and, when unset,
+	// its time
+	RecordActivity(ctx context.Context, a model.Activity) (model.Activity, error)
+	// ListActivity returns up to limit entries older than before, newest
+	// first
+	ListActivity(ctx context.Context, before time.Time, limit int) ([]model.Activity, error)
+}
+
+// RecordActivity appends an entry to the feed
+func (s *MemoryStore) RecordActivity(ctx context.Context, a model.Activity) (model.Activity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.ID = fmt.Sprintf("%d", len(s.activity)+1)
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	s.activity = append(s.activity, a)
+	return a, nil
+}
+
+// ListActivity returns the newest entries older than before
+func (s *MemoryStore) ListActivity(ctx context.Context, before time.Time, limit int) ([]model.Activity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []model.Activity{}
+	for i := len(s.activity) - 1; i >= 0 && len(result) < limit; i-- {
+		if s.activity[i].CreatedAt.Before(before) {
+			result = append(result, s.activity[i])
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+var (
+	// ErrAliasTaken is returned when an alias is already the URL or an
+	// alias of a bookmark
+	ErrAliasTaken = errors.New("URL already belongs to a bookmark")
+	// ErrAliasNotFound is returned when removing an alias a bookmark does
+	// not have
+	ErrAliasNotFound = errors.New("alias not found")
+)
+
+// AliasStore attaches alternate URLs to bookmarks. An alias belongs to a
+// single bookmark and cannot be added while it is any bookmark's URL, so
+// looking a URL up finds one record rather than duplicates.
+type AliasStore interface {
+	// AddAlias attaches url to a bookmark
+	AddAlias(ctx context.Context, id, url string) (model.Bookmark, error)
+	// RemoveAlias detaches url from a bookmark
+	RemoveAlias(ctx context.Context, id, url string) (model.Bookmark, error)
+	// FindByURL returns the bookmark whose URL or alias is url, the oldest
+	// one when several bookmarks share the URL, or ErrBookmarkNotFound
+	FindByURL(ctx context.Context, url string) (model.Bookmark, error)
+}
+
+// urlOwner returns the index of the first bookmark whose URL or alias is
+// url; callers hold s.mu
+func (s *MemoryStore) urlOwner(url string) int {
+	for i, b := range s.bookmarks {
+		if b.URL == url {
+			return i
+		}
+		for _, a := range b.Aliases {
+			if a == url {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// AddAlias attaches url to a bookmark
+func (s *MemoryStore) AddAlias(ctx context.Context, id, url string) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.bookmarkIndex(id)
+	if i < 0 {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	if s.urlOwner(url) >= 0 {
+		return model.Bookmark{}, ErrAliasTaken
+	}
+	// Copy so bookmarks handed out earlier keep their alias list
+	aliases := append(append([]string{}, s.bookmarks[i].Aliases...), url)
+	sort.Strings(aliases)
+	s.bookmarks[i].Aliases = aliases
+	s.bookmarks[i].UpdatedAt = time.Now()
+	return s.bookmarks[i], nil
+}
+
+// RemoveAlias detaches url from a bookmark
+func (s *MemoryStore) RemoveAlias(ctx context.Context, id, url string) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.bookmarkIndex(id)
+	if i < 0 {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	aliases := make([]string, 0, len(s.bookmarks[i].Aliases))
+	for _, a := range s.bookmarks[i].Aliases {
+		if a != url {
+			aliases = append(aliases, a)
+		}
+	}
+	if len(aliases) == len(s.bookmarks[i].Aliases) {
+		return model.Bookmark{}, ErrAliasNotFound
+	}
+	if len(aliases) == 0 {
+		aliases = nil
+	}
+	s.bookmarks[i].Aliases = aliases
+	s.bookmarks[i].UpdatedAt = time.Now()
+	return s.bookmarks[i], nil
+}
+
+// FindByURL returns the bookmark whose URL or alias is url
+func (s *MemoryStore) FindByURL(ctx context.Context, url string) (model.Bookmark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.urlOwner(url); i >= 0 {
+		return s.bookmarks[i], nil
+	}
+	return model.Bookmark{}, ErrBookmarkNotFound
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ArchiveStore moves bookmarks in and out of the archive, which keeps them
+// out of the active list without deleting them. This is unrelated to the
+// archived page copies kept in blob storage.
+type ArchiveStore interface {
+	// SetArchived archives a bookmark or brings it back
+	SetArchived(ctx context.Context, id string, archived bool) (model.Bookmark, error)
+	// ArchiveStale archives those of ids that are not archived yet and
+	// were last updated before t, and returns them
+	ArchiveStale(ctx context.Context, ids []string, t time.Time) ([]model.Bookmark, error)
+}
+
+// SetArchived archives a bookmark or brings it back
+func (s *MemoryStore) SetArchived(ctx context.Context, id string, archived bool) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setArchived(id, archived)
+}
+
+func (s *MemoryStore) setArchived(id string, archived bool) (model.Bookmark, error) {
+	i := s.bookmarkIndex(id)
+	if i < 0 {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	s.bookmarks[i].Archived = archived
+	s.bookmarks[i].UpdatedAt = time.Now()
+	return s.bookmarks[i], nil
+}
+
+// ArchiveStale archives the bookmarks among ids untouched since t
+func (s *MemoryStore) ArchiveStale(ctx context.Context, ids []string, t time.Time) ([]model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.archiveStale(ids, t)
+}
+
+func (s *MemoryStore) archiveStale(ids []string, t time.Time) ([]model.Bookmark, error) {
+	now := time.Now()
+	result := []model.Bookmark{}
+	for _, id := range ids {
+		i := s.bookmarkIndex(id)
+		if i < 0 || s.bookmarks[i].Archived || !s.bookmarks[i].UpdatedAt.Before(t) {
+			continue
+		}
+		s.bookmarks[i].Archived = true
+		s.bookmarks[i].UpdatedAt = now
+		result = append(result, s.bookmarks[i])
+	}
+	return result, nil
+}
@@ -0,0 +1,24 @@
+package storage
+
+import "context"
+
+// BlobMove is a change of a bookmark's ID made by a migration, after which
+// the blobs kept under the old ID still have to be moved to the new one
+type BlobMove struct {
+	Seq   int64
+	OldID string
+	NewID string
+}
+
+// BlobMoveStore lists the bookmark ID changes whose blobs have not been
+// moved yet. Only backends that have changed bookmark IDs implement it.
+type BlobMoveStore interface {
+	// LockBlobMoves waits until no other server instance is moving blobs,
+	// and keeps others from starting until unlock is called
+	LockBlobMoves(ctx context.Context) (unlock func(), err error)
+	// PendingBlobMoves returns the changes left, in the order they were
+	// made, since a bookmark's ID may have changed more than once
+	PendingBlobMoves(ctx context.Context) ([]BlobMove, error)
+	// CompleteBlobMove drops a change once its blobs have moved
+	CompleteBlobMove(ctx context.Context, seq int64) error
+}
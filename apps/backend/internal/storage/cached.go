@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// CachedStore wraps another Store with a bounded in-process LRU cache for
+// GetByID. Entries expire after a TTL and are dropped when the bookmark is
+// written, so it is safe to put in front of any backend.
+type CachedStore struct {
+	Store
+
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+	// generation counts evictions. A GetByID only caches what it read if
+	// no eviction happened while it read, so a bookmark fetched before a
+	// write cannot be cached after the write evicted it.
+	generation uint64
+}
+
+type cacheEntry struct {
+	bookmark  model.Bookmark
+	expiresAt time.Time
+}
+
+// NewCachedStore wraps inner with an LRU holding at most size bookmarks
+func NewCachedStore(inner Store, size int, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		Store:   inner,
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// GetByID returns a bookmark by ID, consulting the cache first
+func (s *CachedStore) GetByID(ctx context.Context, id string) (model.Bookmark, error) {
+	b, generation, ok := s.get(id)
+	if ok {
+		return b, nil
+	}
+	b, err := s.Store.GetByID(ctx, id)
+	if err == nil {
+		s.put(b, generation)
+	}
+	return b, err
+}
+
+// Create adds a new bookmark. Like every write it runs through InTx,
+// which evicts what it wrote.
+func (s *CachedStore) Create(ctx context.Context, ownerID, title, url string, tags []string) (b model.Bookmark, err error) {
+	err = s.InTx(ctx, func(tx Store) error {
+		b, err = tx.Create(ctx, ownerID, title, url, tags)
+		return err
+	})
+	return b, err
+}
+
+// Update updates an existing bookmark
+func (s *CachedStore) Update(ctx context.Context, id, title, url string, tags []string) (b model.Bookmark, err error) {
+	err = s.InTx(ctx, func(tx Store) error {
+		b, err = tx.Update(ctx, id, title, url, tags)
+		return err
+	})
+	return b, err
+}
+
+// Delete removes a bookmark
+func (s *CachedStore) Delete(ctx context.Context, id string) error {
+	return s.InTx(ctx, func(tx Store) error {
+		return tx.Delete(ctx, id)
+	})
+}
+
+// InTx runs fn in a transaction of the wrapped store. The bookmarks fn
+// writes are evicted once it returns, whether or not they were
+// committed.
+func (s *CachedStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	var written []string
+	err := s.Store.InTx(ctx, func(tx Store) error {
+		return fn(&evictingTx{Store: tx, written: &written})
+	})
+	s.evict(written...)
+	return err
+}
+
+// evictingTx records the bookmarks written in a transaction, so
+// CachedStore can evict them when it ends
+type evictingTx struct {
+	Store
+	written *[]string
+}
+
+func (t *evictingTx) Create(ctx context.Context, ownerID, title, url string, tags []string) (model.Bookmark, error) {
+	b, err := t.Store.Create(ctx, ownerID, title, url, tags)
+	if err == nil {
+		*t.written = append(*t.written, b.ID)
+	}
+	return b, err
+}
+
+func (t *evictingTx) Update(ctx context.Context, id, title, url string, tags []string) (model.Bookmark, error) {
+	*t.written = append(*t.written, id)
+	return t.Store.Update(ctx, id, title, url, tags)
+}
+
+func (t *evictingTx) Delete(ctx context.Context, id string) error {
+	*t.written = append(*t.written, id)
+	return t.Store.Delete(ctx, id)
+}
+
+func (t *evictingTx) SetNotes(ctx context.Context, id, notes string) (model.Bookmark, error) {
+	*t.written = append(*t.written, id)
+	return t.Store.(NoteStore).SetNotes(ctx, id, notes)
+}
+
+func (t *evictingTx) SetRead(ctx context.Context, id string, read bool) (model.Bookmark, error) {
+	*t.written = append(*t.written, id)
+	return t.Store.(ReadingStore).SetRead(ctx, id, read)
+}
+
+func (t *evictingTx) SetProgress(ctx context.Context, id string, p model.ReadingProgress) (model.Bookmark, error) {
+	*t.written = append(*t.written, id)
+	return t.Store.(ReadingStore).SetProgress(ctx, id, p)
+}
+
+func (t *evictingTx) SetWordCount(ctx context.Context, id string, words int) (model.Bookmark, error) {
+	*t.written = append(*t.written, id)
+	return t.Store.(ReadingStore).SetWordCount(ctx, id, words)
+}
+
+func (t *evictingTx) SetArchived(ctx context.Context, id string, archived bool) (model.Bookmark, error) {
+	*t.written = append(*t.written, id)
+	return t.Store.(ArchiveStore).SetArchived(ctx, id, archived)
+}
+
+func (t *evictingTx) ArchiveStale(ctx context.Context, ids []string, before time.Time) ([]model.Bookmark, error) {
+	*t.written = append(*t.written, ids...)
+	return t.Store.(ArchiveStore).ArchiveStale(ctx, ids, before)
+}
+
+// InTx runs fn as part of the transaction already open
+func (t *evictingTx) InTx(ctx context.Context, fn func(tx Store) error) error {
+	return fn(t)
+}
+
+// Invalidate drops a bookmark from the cache, e.g. after another server
+// instance changed it
+func (s *CachedStore) Invalidate(id string) {
+	s.evict(id)
+}
+
+// get returns a cached bookmark, or the generation to pass to put after
+// reading it from the wrapped store
+func (s *CachedStore) get(id string) (model.Bookmark, uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[id]
+	if !ok {
+		return model.Bookmark{}, s.generation, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		s.order.Remove(el)
+		delete(s.entries, id)
+		return model.Bookmark{}, s.generation, false
+	}
+	s.order.MoveToFront(el)
+	return entry.bookmark, s.generation, true
+}
+
+// put caches b unless an eviction happened since generation was read
+func (s *CachedStore) put(b model.Bookmark, generation uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generation != generation {
+		return
+	}
+	entry := &cacheEntry{bookmark: b, expiresAt: time.Now().Add(s.ttl)}
+	if el, ok := s.entries[b.ID]; ok {
+		el.Value = entry
+		s.order.MoveToFront(el)
+		return
+	}
+	s.entries[b.ID] = s.order.PushFront(entry)
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*cacheEntry).bookmark.ID)
+	}
+}
+
+// evict drops written bookmarks from the cache. It is the only way
+// entries leave the cache other than expiry and the size bound.
+func (s *CachedStore) evict(ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	for _, id := range ids {
+		if el, ok := s.entries[id]; ok {
+			s.order.Remove(el)
+			delete(s.entries, id)
+		}
+	}
+}
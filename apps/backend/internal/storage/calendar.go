@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// CalendarTokenStore keeps each account's calendar token, which can only
+// read its calendar feed. Tokens are stored hashed; an account has at
+// most one.
+type CalendarTokenStore interface {
+	// SetCalendarToken replaces an account's token hash; "" revokes it
+	SetCalendarToken(ctx context.Context, accountID, tokenHash string) error
+	// AccountByCalendarToken returns the account a token hash belongs to
+	AccountByCalendarToken(ctx context.Context, tokenHash string) (model.Account, bool)
+}
+
+// SetCalendarToken replaces an account's token hash
+func (s *MemoryStore) SetCalendarToken(ctx context.Context, accountID, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	for _, a := range s.accounts {
+		found = found || a.ID == accountID
+	}
+	if !found {
+		return ErrAccountNotFound
+	}
+	for hash, id := range s.calendarTokens {
+		if id == accountID {
+			delete(s.calendarTokens, hash)
+		}
+	}
+	if tokenHash != "" {
+		s.calendarTokens[tokenHash] = accountID
+	}
+	return nil
+}
+
+// AccountByCalendarToken returns the account a token hash belongs to
+func (s *MemoryStore) AccountByCalendarToken(ctx context.Context, tokenHash string) (model.Account, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.calendarTokens[tokenHash]
+	if !ok {
+		return model.Account{}, false
+	}
+	for _, a := range s.accounts {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return model.Account{}, false
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// CaptureTokenStore keeps each account's capture token, which can only
+// save bookmarks. Tokens are stored hashed; an account has at most one.
+type CaptureTokenStore interface {
+	// SetCaptureToken replaces an account's token hash; "" revokes it
+	SetCaptureToken(ctx context.Context, accountID, tokenHash string) error
+	// AccountByCaptureToken returns the account a token hash belongs to
+	AccountByCaptureToken(ctx context.Context, tokenHash string) (model.Account, bool)
+}
+
+// SetCaptureToken replaces an account's token hash
+func (s *MemoryStore) SetCaptureToken(ctx context.Context, accountID, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	for _, a := range s.accounts {
+		found = found || a.ID == accountID
+	}
+	if !found {
+		return ErrAccountNotFound
+	}
+	for hash, id := range s.captureTokens {
+		if id == accountID {
+			delete(s.captureTokens, hash)
+		}
+	}
+	if tokenHash != "" {
+		s.captureTokens[tokenHash] = accountID
+	}
+	return nil
+}
+
+// AccountByCaptureToken returns the account a token hash belongs to
+func (s *MemoryStore) AccountByCaptureToken(ctx context.Context, tokenHash string) (model.Account, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.captureTokens[tokenHash]
+	if !ok {
+		return model.Account{}, false
+	}
+	for _, a := range s.accounts {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return model.Account{}, false
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ErrClipNotFound is returned when a bookmark has no clip with an ID
+var ErrClipNotFound = errors.New("clip not found")
+
+// ClipStore keeps the clips captured from bookmarked pages. Clips go with
+// their bookmark when it is deleted.
+type ClipStore interface {
+	// AddClip stores a clip of c.BookmarkID
+	AddClip(ctx context.Context, c model.Clip) (model.Clip, error)
+	// BookmarkClips returns a bookmark's clips, oldest first
+	BookmarkClips(ctx context.Context, bookmarkID string) ([]model.Clip, error)
+	// DeleteClip removes one of a bookmark's clips
+	DeleteClip(ctx context.Context, bookmarkID, clipID string) error
+}
+
+// AddClip stores a clip of c.BookmarkID
+func (s *MemoryStore) AddClip(ctx context.Context, c model.Clip) (model.Clip, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bookmarkIndex(c.BookmarkID) < 0 {
+		return model.Clip{}, ErrBookmarkNotFound
+	}
+	s.nextClipID++
+	c.ID = fmt.Sprintf("%d", s.nextClipID)
+	c.CreatedAt = time.Now()
+	s.clips[c.BookmarkID] = append(s.clips[c.BookmarkID], c)
+	return c, nil
+}
+
+// BookmarkClips returns a bookmark's clips
+func (s *MemoryStore) BookmarkClips(ctx context.Context, bookmarkID string) ([]model.Clip, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.bookmarkIndex(bookmarkID) < 0 {
+		return nil, ErrBookmarkNotFound
+	}
+	return append([]model.Clip{}, s.clips[bookmarkID]...), nil
+}
+
+// DeleteClip removes one of a bookmark's clips
+func (s *MemoryStore) DeleteClip(ctx context.Context, bookmarkID, clipID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clips := s.clips[bookmarkID]
+	for i, c := range clips {
+		if c.ID == clipID {
+			s.clips[bookmarkID] = append(clips[:i:i], clips[i+1:]...)
+			return nil
+		}
+	}
+	return ErrClipNotFound
+}
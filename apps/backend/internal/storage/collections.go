@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+var (
+	// ErrCollectionNotFound is returned when a collection, or the parent
+	// or merge target named in a request, does not exist
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrCollectionCycle is returned when a move or merge would place a
+	// collection below itself
+	ErrCollectionCycle = errors.New("a collection cannot be moved below itself")
+	// ErrBookmarkNotFound is returned for a bookmark ID that does not
+	// exist, by Store and by the stores of things attached to bookmarks
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+)
+
+// CollectionStore persists a tree of collections and which bookmarks they
+// hold. A bookmark can be in any number of collections.
+type CollectionStore interface {
+	ListCollections(ctx context.Context) []model.Collection
+	GetCollection(ctx context.Context, id string) (model.Collection, bool)
+	// CreateCollection adds a collection owned by ownerID below parentID,
+	// or at the top level when parentID is empty. An empty ownerID leaves
+	// the collection without owner.
+	CreateCollection(ctx context.Context, name, parentID, ownerID string) (model.Collection, error)
+	RenameCollection(ctx context.Context, id, name string) (model.Collection, error)
+	// MoveCollection reparents a collection along with its subtree
+	MoveCollection(ctx context.Context, id, parentID string) (model.Collection, error)
+	// MergeCollections moves the children and bookmarks of source into
+	// target and deletes source
+	MergeCollections(ctx context.Context, sourceID, targetID string) (model.Collection, error)
+	// DeleteCollection removes a collection and its subtree; the bookmarks
+	// themselves are kept
+	DeleteCollection(ctx context.Context, id string) error
+	AddToCollection(ctx context.Context, id string, bookmarkIDs []string) error
+	RemoveFromCollection(ctx context.Context, id, bookmarkID string) error
+	// CollectionBookmarks returns the IDs of the bookmarks in a collection,
+	// including its descendants when recursive is set, in creation order
+	CollectionBookmarks(ctx context.Context, id string, recursive bool) ([]string, error)
+	// ShareCollection grants or changes a grantee's access to a collection
+	ShareCollection(ctx context.Context, id, grantee, access string) error
+	// UnshareCollection revokes a grantee's access to a collection
+	UnshareCollection(ctx context.Context, id, grantee string) error
+	// ListShares returns every share of every collection
+	ListShares(ctx context.Context) []model.CollectionShare
+}
+
+// collectionIndex finds a collection by ID; callers hold s.mu
+func (s *MemoryStore) collectionIndex(id string) int {
+	for i, c := range s.collections {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// subtree returns the IDs of id and all its descendants; callers hold s.mu
+func (s *MemoryStore) subtree(id string) map[string]bool {
+	ids := map[string]bool{id: true}
+	for grew := true; grew; {
+		grew = false
+		for _, c := range s.collections {
+			if c.ParentID != "" && ids[c.ParentID] && !ids[c.ID] {
+				ids[c.ID] = true
+				grew = true
+			}
+		}
+	}
+	return ids
+}
+
+// ListCollections returns all collections in creation order
+func (s *MemoryStore) ListCollections(ctx context.Context) []model.Collection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]model.Collection, len(s.collections))
+	copy(result, s.collections)
+	return result
+}
+
+// GetCollection returns a collection by ID
+func (s *MemoryStore) GetCollection(ctx context.Context, id string) (model.Collection, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i := s.collectionIndex(id); i >= 0 {
+		return s.collections[i], true
+	}
+	return model.Collection{}, false
+}
+
+// CreateCollection adds a collection
+func (s *MemoryStore) CreateCollection(ctx context.Context, name, parentID, ownerID string) (model.Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if parentID != "" && s.collectionIndex(parentID) < 0 {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+	c := model.Collection{
+		ID:        fmt.Sprintf("%d", s.nextCollectionID),
+		Name:      name,
+		ParentID:  parentID,
+		OwnerID:   ownerID,
+		CreatedAt: time.Now(),
+	}
+	s.nextCollectionID++
+	s.collections = append(s.collections, c)
+	return c, nil
+}
+
+// RenameCollection changes a collection's name
+func (s *MemoryStore) RenameCollection(ctx context.Context, id, name string) (model.Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.collectionIndex(id)
+	if i < 0 {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+	s.collections[i].Name = name
+	return s.collections[i], nil
+}
+
+// MoveCollection reparents a collection
+func (s *MemoryStore) MoveCollection(ctx context.Context, id, parentID string) (model.Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.collectionIndex(id)
+	if i < 0 || (parentID != "" && s.collectionIndex(parentID) < 0) {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+	if parentID != "" && s.subtree(id)[parentID] {
+		return model.Collection{}, ErrCollectionCycle
+	}
+	s.collections[i].ParentID = parentID
+	return s.collections[i], nil
+}
+
+// MergeCollections folds source into target
+func (s *MemoryStore) MergeCollections(ctx context.Context, sourceID, targetID string) (model.Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, dst := s.collectionIndex(sourceID), s.collectionIndex(targetID)
+	if src < 0 || dst < 0 {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+	if s.subtree(sourceID)[targetID] {
+		return model.Collection{}, ErrCollectionCycle
+	}
+
+	for i := range s.collections {
+		if s.collections[i].ParentID == sourceID {
+			s.collections[i].ParentID = targetID
+		}
+	}
+	for bookmarkID := range s.members[sourceID] {
+		s.addMember(targetID, bookmarkID)
+	}
+	delete(s.members, sourceID)
+	s.dropShares(map[string]bool{sourceID: true})
+	s.moveReactions(sourceID, targetID)
+	target := s.collections[dst]
+	s.collections = append(s.collections[:src], s.collections[src+1:]...)
+	return target, nil
+}
+
+// DeleteCollection removes a collection and its subtree
+func (s *MemoryStore) DeleteCollection(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.collectionIndex(id) < 0 {
+		return ErrCollectionNotFound
+	}
+	doomed := s.subtree(id)
+	kept := s.collections[:0]
+	for _, c := range s.collections {
+		if doomed[c.ID] {
+			delete(s.members, c.ID)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	s.collections = kept
+	s.dropShares(doomed)
+	s.dropReactions(func(r model.Reaction) bool { return doomed[r.CollectionID] })
+	return nil
+}
+
+// addMember records a bookmark in a collection; callers hold s.mu
+func (s *MemoryStore) addMember(id, bookmarkID string) {
+	if s.members[id] == nil {
+		s.members[id] = make(map[string]bool)
+	}
+	s.members[id][bookmarkID] = true
+}
+
+// AddToCollection adds bookmarks to a collection; bookmarks already in it
+// are left alone
+func (s *MemoryStore) AddToCollection(ctx context.Context, id string, bookmarkIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.collectionIndex(id) < 0 {
+		return ErrCollectionNotFound
+	}
+	known := make(map[string]bool, len(s.bookmarks))
+	for _, b := range s.bookmarks {
+		known[b.ID] = true
+	}
+	for _, bookmarkID := range bookmarkIDs {
+		if !known[bookmarkID] {
+			return fmt.Errorf("%w: %s", ErrBookmarkNotFound, bookmarkID)
+		}
+	}
+	for _, bookmarkID := range bookmarkIDs {
+		s.addMember(id, bookmarkID)
+	}
+	return nil
+}
+
+// RemoveFromCollection takes a bookmark out of a collection
+func (s *MemoryStore) RemoveFromCollection(ctx context.Context, id, bookmarkID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.collectionIndex(id) < 0 {
+		return ErrCollectionNotFound
+	}
+	if !s.members[id][bookmarkID] {
+		return ErrBookmarkNotFound
+	}
+	delete(s.members[id], bookmarkID)
+	s.dropReactions(func(r model.Reaction) bool { return r.CollectionID == id && r.BookmarkID == bookmarkID })
+	return nil
+}
+
+// CollectionBookmarks lists the bookmarks in a collection
+func (s *MemoryStore) CollectionBookmarks(ctx context.Context, id string, recursive bool) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.collectionIndex(id) < 0 {
+		return nil, ErrCollectionNotFound
+	}
+	scope := map[string]bool{id: true}
+	if recursive {
+		scope = s.subtree(id)
+	}
+	ids := []string{}
+	for _, b := range s.bookmarks {
+		for c := range scope {
+			if s.members[c][b.ID] {
+				ids = append(ids, b.ID)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+// dropShares removes the shares of deleted collections; callers hold s.mu
+func (s *MemoryStore) dropShares(ids map[string]bool) {
+	kept := s.shares[:0]
+	for _, sh := range s.shares {
+		if !ids[sh.CollectionID] {
+			kept = append(kept, sh)
+		}
+	}
+	s.shares = kept
+}
+
+// ShareCollection grants access to a collection
+func (s *MemoryStore) ShareCollection(ctx context.Context, id, grantee, access string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.collectionIndex(id) < 0 {
+		return ErrCollectionNotFound
+	}
+	for i, sh := range s.shares {
+		if sh.CollectionID == id && sh.Grantee == grantee {
+			s.shares[i].Access = access
+			return nil
+		}
+	}
+	s.shares = append(s.shares, model.CollectionShare{CollectionID: id, Grantee: grantee, Access: access})
+	return nil
+}
+
+// UnshareCollection revokes access to a collection
+func (s *MemoryStore) UnshareCollection(ctx context.Context, id, grantee string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.collectionIndex(id) < 0 {
+		return ErrCollectionNotFound
+	}
+	for i, sh := range s.shares {
+		if sh.CollectionID == id && sh.Grantee == grantee {
+			s.shares = append(s.shares[:i], s.shares[i+1:]...)
+			return nil
+		}
+	}
+	return ErrShareNotFound
+}
+
+// ListShares returns all shares
+func (s *MemoryStore) ListShares(ctx context.Context) []model.CollectionShare {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]model.CollectionShare, len(s.shares))
+	copy(result, s.shares)
+	return result
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ErrDomainRuleNotFound is returned when removing a rule that does not
+// exist
+var ErrDomainRuleNotFound = errors.New("domain rule not found")
+
+// DomainRuleStore keeps the domain rules checked when bookmarks are
+// saved. An account ID of "" stands for the instance-wide rules.
+type DomainRuleStore interface {
+	// DomainRules returns the rules of an account, sorted by domain
+	DomainRules(ctx context.Context, accountID string) ([]model.DomainRule, error)
+	// SetDomainRule creates the rule for r.Domain or changes its mode
+	SetDomainRule(ctx context.Context, r model.DomainRule) (model.DomainRule, error)
+	// DeleteDomainRule removes an account's rule for domain
+	DeleteDomainRule(ctx context.Context, accountID, domain string) error
+}
+
+// DomainRules returns the rules of an account
+func (s *MemoryStore) DomainRules(ctx context.Context, accountID string) ([]model.DomainRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []model.DomainRule{}
+	for _, r := range s.domainRules {
+		if r.AccountID == accountID {
+			result = append(result, r)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Domain < result[j].Domain })
+	return result, nil
+}
+
+// SetDomainRule creates the rule for r.Domain or changes its mode
+func (s *MemoryStore) SetDomainRule(ctx context.Context, r model.DomainRule) (model.DomainRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.domainRules {
+		if existing.AccountID == r.AccountID && existing.Domain == r.Domain {
+			s.domainRules[i].Mode = r.Mode
+			return s.domainRules[i], nil
+		}
+	}
+	r.CreatedAt = time.Now()
+	s.domainRules = append(s.domainRules, r)
+	return r, nil
+}
+
+// DeleteDomainRule removes an account's rule for domain
+func (s *MemoryStore) DeleteDomainRule(ctx context.Context, accountID, domain string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.domainRules {
+		if r.AccountID == accountID && r.Domain == domain {
+			s.domainRules = append(s.domainRules[:i], s.domainRules[i+1:]...)
+			return nil
+		}
+	}
+	return ErrDomainRuleNotFound
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// FederationStore keeps what publishing accounts to the fediverse needs:
+// the key each account's actor signs with, and the actors following it.
+type FederationStore interface {
+	// InitActorKey stores keyPEM as an account's private key unless it
+	// already has one, and returns the key the account ends up with
+	InitActorKey(ctx context.Context, accountID, keyPEM string) (string, error)
+	// AddFollower records a follower, replacing an earlier follow by the
+	// same actor
+	AddFollower(ctx context.Context, f model.Follower) error
+	// RemoveFollower forgets an actor following an account; it is not an
+	// error if the actor was not following
+	RemoveFollower(ctx context.Context, accountID, actorID string) error
+	// Followers returns the actors following an account, oldest first
+	Followers(ctx context.Context, accountID string) ([]model.Follower, error)
+}
+
+// InitActorKey stores keyPEM unless the account already has a key
+func (s *MemoryStore) InitActorKey(ctx context.Context, accountID, keyPEM string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	for _, a := range s.accounts {
+		found = found || a.ID == accountID
+	}
+	if !found {
+		return "", ErrAccountNotFound
+	}
+	if key, ok := s.actorKeys[accountID]; ok {
+		return key, nil
+	}
+	s.actorKeys[accountID] = keyPEM
+	return keyPEM, nil
+}
+
+// AddFollower records a follower
+func (s *MemoryStore) AddFollower(ctx context.Context, f model.Follower) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now()
+	}
+	for i, existing := range s.followers {
+		if existing.AccountID == f.AccountID && existing.ActorID == f.ActorID {
+			f.CreatedAt = existing.CreatedAt
+			s.followers[i] = f
+			return nil
+		}
+	}
+	s.followers = append(s.followers, f)
+	return nil
+}
+
+// RemoveFollower forgets an actor following an account
+func (s *MemoryStore) RemoveFollower(ctx context.Context, accountID, actorID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.followers[:0]
+	for _, f := range s.followers {
+		if f.AccountID != accountID || f.ActorID != actorID {
+			kept = append(kept, f)
+		}
+	}
+	s.followers = kept
+	return nil
+}
+
+// Followers returns the actors following an account
+func (s *MemoryStore) Followers(ctx context.Context, accountID string) ([]model.Follower, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []model.Follower{}
+	for _, f := range s.followers {
+		if f.AccountID == accountID {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
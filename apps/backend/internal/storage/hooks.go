@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ErrHookNotFound is returned when an account has no hook with an ID
+var ErrHookNotFound = errors.New("hook not found")
+
+// ErrHookDeliveryNotFound is returned when an account has no hook
+// delivery with an ID
+var ErrHookDeliveryNotFound = errors.New("hook delivery not found")
+
+// HookStore keeps REST hook subscriptions and the log of their
+// deliveries. Hooks go with their account when it is deleted, and
+// deliveries with their hook.
+type HookStore interface {
+	// AddHook subscribes h.TargetURL to h.Event for h.AccountID
+	AddHook(ctx context.Context, h model.Hook) (model.Hook, error)
+	// AccountHooks returns an account's hooks, oldest first
+	AccountHooks(ctx context.Context, accountID string) ([]model.Hook, error)
+	// EventHooks returns every account's hooks for an event
+	EventHooks(ctx context.Context, event string) ([]model.Hook, error)
+	// DeleteHook unsubscribes one of an account's hooks
+	DeleteHook(ctx context.Context, accountID, id string) error
+
+	// AddHookDelivery logs a delivery to d.HookID, filling in its ID,
+	// account and target URL
+	AddHookDelivery(ctx context.Context, d model.HookDelivery) (model.HookDelivery, error)
+	// UpdateHookDelivery records the outcome of an attempt: d's status,
+	// attempts, last status and error, and next attempt
+	UpdateHookDelivery(ctx context.Context, d model.HookDelivery) error
+	// GetHookDelivery returns one of an account's deliveries
+	GetHookDelivery(ctx context.Context, accountID, id string) (model.HookDelivery, error)
+	// HookDeliveries returns up to limit of an account's deliveries,
+	// newest first, only those of hookID and in status when they are set
+	HookDeliveries(ctx context.Context, accountID, hookID, status string, limit int) ([]model.HookDelivery, error)
+	// DueHookDeliveries returns up to limit pending deliveries whose next
+	// attempt is due at now, the longest due first
+	DueHookDeliveries(ctx context.Context, now time.Time, limit int) ([]model.HookDelivery, error)
+	// PruneHookDeliveries deletes the deliveries that are no longer
+	// pending and were last updated before before, returning how many
+	PruneHookDeliveries(ctx context.Context, before time.Time) (int, error)
+}
+
+// AddHook subscribes h.TargetURL to h.Event for h.AccountID
+func (s *MemoryStore) AddHook(ctx context.Context, h model.Hook) (model.Hook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextHookID++
+	h.ID = fmt.Sprintf("%d", s.nextHookID)
+	h.CreatedAt = time.Now()
+	s.hooks = append(s.hooks, h)
+	return h, nil
+}
+
+// AccountHooks returns an account's hooks
+func (s *MemoryStore) AccountHooks(ctx context.Context, accountID string) ([]model.Hook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []model.Hook{}
+	for _, h := range s.hooks {
+		if h.AccountID == accountID {
+			result = append(result, h)
+		}
+	}
+	return result, nil
+}
+
+// EventHooks returns every account's hooks for an event
+func (s *MemoryStore) EventHooks(ctx context.Context, event string) ([]model.Hook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []model.Hook
+	for _, h := range s.hooks {
+		if h.Event == event {
+			result = append(result, h)
+		}
+	}
+	return result, nil
+}
+
+// DeleteHook unsubscribes one of an account's hooks
+func (s *MemoryStore) DeleteHook(ctx context.Context, accountID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, h := range s.hooks {
+		if h.ID == id && h.AccountID == accountID {
+			s.hooks = append(s.hooks[:i:i], s.hooks[i+1:]...)
+			kept := s.hookDeliveries[:0]
+			for _, d := range s.hookDeliveries {
+				if d.HookID != id {
+					kept = append(kept, d)
+				}
+			}
+			s.hookDeliveries = kept
+			return nil
+		}
+	}
+	return ErrHookNotFound
+}
+
+// AddHookDelivery logs a delivery to d.HookID
+func (s *MemoryStore) AddHookDelivery(ctx context.Context, d model.HookDelivery) (model.HookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	for _, h := range s.hooks {
+		if h.ID == d.HookID {
+			d.AccountID, d.TargetURL, found = h.AccountID, h.TargetURL, true
+		}
+	}
+	if !found {
+		return model.HookDelivery{}, ErrHookNotFound
+	}
+	s.nextHookDeliveryID++
+	d.ID = fmt.Sprintf("%d", s.nextHookDeliveryID)
+	d.CreatedAt = time.Now()
+	d.UpdatedAt = d.CreatedAt
+	s.hookDeliveries = append(s.hookDeliveries, d)
+	return d, nil
+}
+
+// UpdateHookDelivery records the outcome of an attempt
+func (s *MemoryStore) UpdateHookDelivery(ctx context.Context, d model.HookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.hookDeliveries {
+		if existing.ID == d.ID {
+			existing.Status, existing.Attempts = d.Status, d.Attempts
+			existing.LastStatus, existing.LastError = d.LastStatus, d.LastError
+			existing.NextAttemptAt = d.NextAttemptAt
+			existing.UpdatedAt = time.Now()
+			s.hookDeliveries[i] = existing
+			return nil
+		}
+	}
+	return ErrHookDeliveryNotFound
+}
+
+// GetHookDelivery returns one of an account's deliveries
+func (s *MemoryStore) GetHookDelivery(ctx context.Context, accountID, id string) (model.HookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, d := range s.hookDeliveries {
+		if d.ID == id && d.AccountID == accountID {
+			return d, nil
+		}
+	}
+	return model.HookDelivery{}, ErrHookDeliveryNotFound
+}
+
+// HookDeliveries returns an account's deliveries, newest first
+func (s *MemoryStore) HookDeliveries(ctx context.Context, accountID, hookID, status string, limit int) ([]model.HookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if hookID != "" {
+		found := false
+		for _, h := range s.hooks {
+			found = found || (h.ID == hookID && h.AccountID == accountID)
+		}
+		if !found {
+			return nil, ErrHookNotFound
+		}
+	}
+	result := []model.HookDelivery{}
+	for i := len(s.hookDeliveries) - 1; i >= 0 && len(result) < limit; i-- {
+		d := s.hookDeliveries[i]
+		if d.AccountID == accountID && (hookID == "" || d.HookID == hookID) && (status == "" || d.Status == status) {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+// DueHookDeliveries returns pending deliveries due at now
+func (s *MemoryStore) DueHookDeliveries(ctx context.Context, now time.Time, limit int) ([]model.HookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []model.HookDelivery
+	for _, d := range s.hookDeliveries {
+		if d.Status == model.HookDeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			result = append(result, d)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].NextAttemptAt.Before(*result[j].NextAttemptAt) })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// PruneHookDeliveries deletes settled deliveries last updated before
+// before
+func (s *MemoryStore) PruneHookDeliveries(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.hookDeliveries[:0]
+	for _, d := range s.hookDeliveries {
+		if d.Status == model.HookDeliveryPending || !d.UpdatedAt.Before(before) {
+			kept = append(kept, d)
+		}
+	}
+	pruned := len(s.hookDeliveries) - len(kept)
+	s.hookDeliveries = kept
+	return pruned, nil
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ErrIntegrationNotFound is returned when an account is not connected to
+// a service
+var ErrIntegrationNotFound = errors.New("integration not found")
+
+// IntegrationStore keeps the services accounts push their bookmarks to
+// or pull them from, at most one connection per account and service. Connections go with
+// their account when it is deleted.
+type IntegrationStore interface {
+	// SetIntegration connects i.AccountID to i.Service, replacing an
+	// earlier connection and its sync state
+	SetIntegration(ctx context.Context, i model.Integration) (model.Integration, error)
+	// GetIntegration returns an account's connection to a service
+	GetIntegration(ctx context.Context, accountID, service string) (model.Integration, error)
+	// AccountIntegrations returns an account's connections
+	AccountIntegrations(ctx context.Context, accountID string) ([]model.Integration, error)
+	// ServiceIntegrations returns every account's connection to a service
+	ServiceIntegrations(ctx context.Context, service string) ([]model.Integration, error)
+	// RecordIntegrationSync records how a push that started at at ended:
+	// it succeeded when errMsg is empty
+	RecordIntegrationSync(ctx context.Context, accountID, service string, at time.Time, errMsg string) error
+	// SetIntegrationCursor records the newest item a pull has seen
+	SetIntegrationCursor(ctx context.Context, accountID, service, cursor string) error
+	// DeleteIntegration disconnects an account from a service
+	DeleteIntegration(ctx context.Context, accountID, service string) error
+}
+
+// SetIntegration connects i.AccountID to i.Service
+func (s *MemoryStore) SetIntegration(ctx context.Context, i model.Integration) (model.Integration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	for _, a := range s.accounts {
+		found = found || a.ID == i.AccountID
+	}
+	if !found {
+		return model.Integration{}, ErrAccountNotFound
+	}
+	i.LastSyncAt, i.LastError, i.Cursor = nil, "", ""
+	i.CreatedAt = time.Now()
+	for n, existing := range s.integrations {
+		if existing.AccountID == i.AccountID && existing.Service == i.Service {
+			s.integrations[n] = i
+			return i, nil
+		}
+	}
+	s.integrations = append(s.integrations, i)
+	return i, nil
+}
+
+// GetIntegration returns an account's connection to a service
+func (s *MemoryStore) GetIntegration(ctx context.Context, accountID, service string) (model.Integration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, i := range s.integrations {
+		if i.AccountID == accountID && i.Service == service {
+			return i, nil
+		}
+	}
+	return model.Integration{}, ErrIntegrationNotFound
+}
+
+// AccountIntegrations returns an account's connections
+func (s *MemoryStore) AccountIntegrations(ctx context.Context, accountID string) ([]model.Integration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []model.Integration{}
+	for _, i := range s.integrations {
+		if i.AccountID == accountID {
+			result = append(result, i)
+		}
+	}
+	return result, nil
+}
+
+// ServiceIntegrations returns every account's connection to a service
+func (s *MemoryStore) ServiceIntegrations(ctx context.Context, service string) ([]model.Integration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []model.Integration
+	for _, i := range s.integrations {
+		if i.Service == service {
+			result = append(result, i)
+		}
+	}
+	return result, nil
+}
+
+// RecordIntegrationSync records how a push ended
+func (s *MemoryStore) RecordIntegrationSync(ctx context.Context, accountID, service string, at time.Time, errMsg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for n, i := range s.integrations {
+		if i.AccountID != accountID || i.Service != service {
+			continue
+		}
+		if errMsg == "" {
+			s.integrations[n].LastSyncAt = &at
+		}
+		s.integrations[n].LastError = errMsg
+		return nil
+	}
+	return ErrIntegrationNotFound
+}
+
+// SetIntegrationCursor records the newest item a pull has seen
+func (s *MemoryStore) SetIntegrationCursor(ctx context.Context, accountID, service, cursor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for n, i := range s.integrations {
+		if i.AccountID == accountID && i.Service == service {
+			s.integrations[n].Cursor = cursor
+			return nil
+		}
+	}
+	return ErrIntegrationNotFound
+}
+
+// DeleteIntegration disconnects an account from a service
+func (s *MemoryStore) DeleteIntegration(ctx context.Context, accountID, service string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for n, i := range s.integrations {
+		if i.AccountID == accountID && i.Service == service {
+			s.integrations = append(s.integrations[:n:n], s.integrations[n+1:]...)
+			return nil
+		}
+	}
+	return ErrIntegrationNotFound
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/ulid"
+)
+
+// MemoryStore is a simple in-memory store for bookmarks (for development)
+type MemoryStore struct {
+	mu        sync.RWMutex
+	bookmarks []model.Bookmark
+	accounts  []model.Account
+
+	collections        []model.Collection
+	members            map[string]map[string]bool // collection ID -> bookmark IDs
+	shares             []model.CollectionShare
+	reactions          []model.Reaction
+	activity           []model.Activity
+	deleted            []model.DeletedBookmark
+	domainRules        []model.DomainRule
+	urlRules           *model.URLRules             // nil until set; defaults apply
+	reminders          map[string]model.Reminder   // bookmark ID -> reminder
+	revisions          map[string][]model.Revision // bookmark ID -> history
+	clips              map[string][]model.Clip     // bookmark ID -> clips
+	mentions           map[string][]model.Mention  // bookmark ID -> mentions
+	hooks              []model.Hook
+	hookDeliveries     []model.HookDelivery
+	captureTokens      map[string]string // token hash -> account ID
+	calendarTokens     map[string]string // token hash -> account ID
+	integrations       []model.Integration
+	actorKeys          map[string]string // account ID -> private key PEM
+	followers          []model.Follower
+	nextCollectionID   int
+	nextClipID         int
+	nextHookID         int
+	nextHookDeliveryID int
+	quotas             Quotas
+}
+
+func init() {
+	Register("memory", func(cfg Config) (Store, error) {
+		s := NewMemoryStore()
+		s.quotas = cfg.Quotas
+		return s, nil
+	})
+}
+
+// NewMemoryStore creates a new, empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		bookmarks:        []model.Bookmark{},
+		collections:      []model.Collection{},
+		members:          make(map[string]map[string]bool),
+		reminders:        make(map[string]model.Reminder),
+		revisions:        make(map[string][]model.Revision),
+		clips:            make(map[string][]model.Clip),
+		mentions:         make(map[string][]model.Mention),
+		captureTokens:    make(map[string]string),
+		calendarTokens:   make(map[string]string),
+		actorKeys:        make(map[string]string),
+		nextCollectionID: 1,
+	}
+}
+
+// GetAll returns all bookmarks
+func (s *MemoryStore) GetAll(ctx context.Context) ([]model.Bookmark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.getAll(), nil
+}
+
+// getAll returns a copy of the bookmarks, to avoid data races; callers
+// hold s.mu
+func (s *MemoryStore) getAll() []model.Bookmark {
+	result := make([]model.Bookmark, len(s.bookmarks))
+	copy(result, s.bookmarks)
+	return result
+}
+
+// Create adds a new bookmark
+func (s *MemoryStore) Create(ctx context.Context, ownerID, title, url string, tags []string) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.create(ownerID, title, url, tags)
+}
+
+// create adds a new bookmark; callers hold s.mu
+func (s *MemoryStore) create(ownerID, title, url string, tags []string) (model.Bookmark, error) {
+	now := time.Now()
+	bookmark := model.Bookmark{
+		ID:        ulid.Make(now),
+		OwnerID:   ownerID,
+		Title:     title,
+		URL:       url,
+		Tags:      model.NormalizeTags(tags),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.checkQuotas(bookmark); err != nil {
+		return model.Bookmark{}, err
+	}
+	s.bookmarks = append(s.bookmarks, bookmark)
+	s.recordRevision(bookmark)
+	return bookmark, nil
+}
+
+// GetByID returns a bookmark by ID
+func (s *MemoryStore) GetByID(ctx context.Context, id string) (model.Bookmark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.getByID(id)
+}
+
+// getByID returns a bookmark by ID; callers hold s.mu
+func (s *MemoryStore) getByID(id string) (model.Bookmark, error) {
+	if i := s.bookmarkIndex(id); i >= 0 {
+		return s.bookmarks[i], nil
+	}
+	return model.Bookmark{}, ErrBookmarkNotFound
+}
+
+// Update updates an existing bookmark
+func (s *MemoryStore) Update(ctx context.Context, id, title, url string, tags []string) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(id, title, url, tags)
+}
+
+// update updates an existing bookmark; callers hold s.mu
+func (s *MemoryStore) update(id, title, url string, tags []string) (model.Bookmark, error) {
+	i := s.bookmarkIndex(id)
+	if i < 0 {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	if tags != nil {
+		tags = model.NormalizeTags(tags)
+		if err := s.quotas.checkTags(tags); err != nil {
+			return model.Bookmark{}, err
+		}
+	}
+	if title != "" {
+		s.bookmarks[i].Title = title
+	}
+	if url != "" {
+		s.bookmarks[i].URL = url
+	}
+	if tags != nil {
+		s.bookmarks[i].Tags = tags
+	}
+	s.bookmarks[i].UpdatedAt = time.Now()
+	s.recordRevision(s.bookmarks[i])
+	return s.bookmarks[i], nil
+}
+
+// bookmarkIndex finds a bookmark by ID; callers hold s.mu
+func (s *MemoryStore) bookmarkIndex(id string) int {
+	for i, b := range s.bookmarks {
+		if b.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Delete moves a bookmark to the trash, remembering its collections so
+// Restore can put it back
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(id)
+}
+
+// remove moves a bookmark to the trash; callers hold s.mu
+func (s *MemoryStore) remove(id string) error {
+	i := s.bookmarkIndex(id)
+	if i < 0 {
+		return ErrBookmarkNotFound
+	}
+	b := s.bookmarks[i]
+	s.bookmarks = append(s.bookmarks[:i], s.bookmarks[i+1:]...)
+	deleted := model.DeletedBookmark{Bookmark: b, DeletedAt: time.Now()}
+	for colID, m := range s.members {
+		if m[id] {
+			deleted.CollectionIDs = append(deleted.CollectionIDs, colID)
+			delete(m, id)
+		}
+	}
+	sort.Strings(deleted.CollectionIDs)
+	s.deleted = append(s.deleted, deleted)
+	s.dropReactions(func(r model.Reaction) bool { return r.BookmarkID == id })
+	delete(s.reminders, id)
+	delete(s.revisions, id)
+	delete(s.clips, id)
+	delete(s.mentions, id)
+	return nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// MentionStore keeps the Webmentions received for bookmarks, one per
+// bookmark and source. Mentions go with their bookmark when it is
+// deleted.
+type MentionStore interface {
+	// SaveMention records a verified mention, updating the title of an
+	// earlier one from the same source
+	SaveMention(ctx context.Context, m model.Mention) (model.Mention, error)
+	// DeleteMention forgets a mention whose source no longer links to the
+	// bookmark; it is not an error if there was none
+	DeleteMention(ctx context.Context, bookmarkID, source string) error
+	// BookmarkMentions returns a bookmark's mentions, oldest first
+	BookmarkMentions(ctx context.Context, bookmarkID string) ([]model.Mention, error)
+}
+
+// SaveMention records a verified mention
+func (s *MemoryStore) SaveMention(ctx context.Context, m model.Mention) (model.Mention, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bookmarkIndex(m.BookmarkID) < 0 {
+		return model.Mention{}, ErrBookmarkNotFound
+	}
+	now := time.Now()
+	m.UpdatedAt = now
+	mentions := s.mentions[m.BookmarkID]
+	for i, existing := range mentions {
+		if existing.Source == m.Source {
+			m.CreatedAt = existing.CreatedAt
+			mentions[i] = m
+			return m, nil
+		}
+	}
+	m.CreatedAt = now
+	s.mentions[m.BookmarkID] = append(mentions, m)
+	return m, nil
+}
+
+// DeleteMention forgets a mention
+func (s *MemoryStore) DeleteMention(ctx context.Context, bookmarkID, source string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mentions := s.mentions[bookmarkID]
+	for i, m := range mentions {
+		if m.Source == source {
+			s.mentions[bookmarkID] = append(mentions[:i:i], mentions[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// BookmarkMentions returns a bookmark's mentions
+func (s *MemoryStore) BookmarkMentions(ctx context.Context, bookmarkID string) ([]model.Mention, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.bookmarkIndex(bookmarkID) < 0 {
+		return nil, ErrBookmarkNotFound
+	}
+	return append([]model.Mention{}, s.mentions[bookmarkID]...), nil
+}
@@ -0,0 +1,658 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// migration is one versioned, reversible schema change
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// migrations lists every schema change in order. Append new entries; never
+// edit or renumber one that has shipped.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "create_bookmarks",
+		Up: `CREATE TABLE IF NOT EXISTS bookmarks (
+			id         BIGSERIAL PRIMARY KEY,
+			title      TEXT NOT NULL,
+			url        TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+		Down: `DROP TABLE IF EXISTS bookmarks`,
+	},
+	{
+		Version: 2,
+		Name:    "add_bookmark_tags",
+		Up:      `ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}'`,
+		Down:    `ALTER TABLE bookmarks DROP COLUMN IF EXISTS tags`,
+	},
+	{
+		Version: 3,
+		Name:    "create_accounts",
+		Up: `CREATE TABLE IF NOT EXISTS accounts (
+			id            BIGSERIAL PRIMARY KEY,
+			email         TEXT NOT NULL UNIQUE,
+			password_hash TEXT NOT NULL,
+			role          TEXT NOT NULL,
+			created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+		Down: `DROP TABLE IF EXISTS accounts`,
+	},
+	{
+		Version: 4,
+		Name:    "create_collections",
+		Up: `CREATE TABLE IF NOT EXISTS collections (
+			id         BIGSERIAL PRIMARY KEY,
+			name       TEXT NOT NULL,
+			parent_id  BIGINT REFERENCES collections (id) ON DELETE CASCADE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS collections_parent_id_idx ON collections (parent_id);
+		CREATE TABLE IF NOT EXISTS collection_bookmarks (
+			collection_id BIGINT NOT NULL REFERENCES collections (id) ON DELETE CASCADE,
+			bookmark_id   BIGINT NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+			PRIMARY KEY (collection_id, bookmark_id)
+		);
+		CREATE INDEX IF NOT EXISTS collection_bookmarks_bookmark_id_idx ON collection_bookmarks (bookmark_id)`,
+		Down: `DROP TABLE IF EXISTS collection_bookmarks; DROP TABLE IF EXISTS collections`,
+	},
+	{
+		Version: 5,
+		Name:    "create_collection_shares",
+		Up: `ALTER TABLE collections ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES accounts (id) ON DELETE SET NULL;
+		CREATE TABLE IF NOT EXISTS collection_shares (
+			collection_id BIGINT NOT NULL REFERENCES collections (id) ON DELETE CASCADE,
+			grantee       TEXT NOT NULL,
+			access        TEXT NOT NULL,
+			PRIMARY KEY (collection_id, grantee)
+		)`,
+		Down: `DROP TABLE IF EXISTS collection_shares; ALTER TABLE collections DROP COLUMN IF EXISTS owner_id`,
+	},
+	{
+		Version: 6,
+		Name:    "add_account_profiles",
+		Up: `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS username TEXT UNIQUE;
+		ALTER TABLE accounts ADD COLUMN IF NOT EXISTS public_profile BOOLEAN NOT NULL DEFAULT false`,
+		Down: `ALTER TABLE accounts DROP COLUMN IF EXISTS public_profile; ALTER TABLE accounts DROP COLUMN IF EXISTS username`,
+	},
+	{
+		Version: 7,
+		Name:    "create_collection_reactions",
+		Up: `CREATE TABLE IF NOT EXISTS collection_reactions (
+			collection_id BIGINT NOT NULL,
+			bookmark_id   BIGINT NOT NULL,
+			account_id    BIGINT NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+			emoji         TEXT NOT NULL,
+			created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (collection_id, bookmark_id, account_id, emoji),
+			FOREIGN KEY (collection_id, bookmark_id)
+				REFERENCES collection_bookmarks (collection_id, bookmark_id) ON DELETE CASCADE
+		)`,
+		Down: `DROP TABLE IF EXISTS collection_reactions`,
+	},
+	{
+		Version: 8,
+		Name:    "add_bookmark_read_state",
+		Up:      `ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS is_read BOOLEAN NOT NULL DEFAULT false`,
+		Down:    `ALTER TABLE bookmarks DROP COLUMN IF EXISTS is_read`,
+	},
+	{
+		Version: 9,
+		Name:    "add_bookmark_reading_progress",
+		Up: `ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS progress_percent DOUBLE PRECISION;
+		ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS progress_anchor TEXT NOT NULL DEFAULT '';
+		ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS progress_updated_at TIMESTAMPTZ`,
+		Down: `ALTER TABLE bookmarks DROP COLUMN IF EXISTS progress_updated_at;
+		ALTER TABLE bookmarks DROP COLUMN IF EXISTS progress_anchor;
+		ALTER TABLE bookmarks DROP COLUMN IF EXISTS progress_percent`,
+	},
+	{
+		Version: 10,
+		Name:    "add_bookmark_word_count",
+		Up:      `ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS word_count INTEGER NOT NULL DEFAULT 0`,
+		Down:    `ALTER TABLE bookmarks DROP COLUMN IF EXISTS word_count`,
+	},
+	{
+		Version: 11,
+		Name:    "create_reminders",
+		Up: `CREATE TABLE IF NOT EXISTS reminders (
+			bookmark_id  BIGINT PRIMARY KEY REFERENCES bookmarks (id) ON DELETE CASCADE,
+			account_id   BIGINT REFERENCES accounts (id) ON DELETE SET NULL,
+			remind_at    TIMESTAMPTZ NOT NULL,
+			delivered_at TIMESTAMPTZ
+		);
+		CREATE INDEX IF NOT EXISTS reminders_remind_at_idx ON reminders (remind_at)`,
+		Down: `DROP TABLE IF EXISTS reminders`,
+	},
+	{
+		Version: 12,
+		Name:    "add_bookmark_archive",
+		Up: `ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+		UPDATE bookmarks SET updated_at = created_at WHERE updated_at IS NULL;
+		ALTER TABLE bookmarks ALTER COLUMN updated_at SET DEFAULT now();
+		ALTER TABLE bookmarks ALTER COLUMN updated_at SET NOT NULL;
+		ALTER TABLE accounts ADD COLUMN IF NOT EXISTS archive_after_days INTEGER NOT NULL DEFAULT 0`,
+		Down: `ALTER TABLE accounts DROP COLUMN IF EXISTS archive_after_days;
+		ALTER TABLE bookmarks DROP COLUMN IF EXISTS updated_at;
+		ALTER TABLE bookmarks DROP COLUMN IF EXISTS archived`,
+	},
+	{
+		Version: 13,
+		Name:    "create_bookmark_aliases",
+		Up: `CREATE TABLE IF NOT EXISTS bookmark_aliases (
+			url         TEXT PRIMARY KEY,
+			bookmark_id BIGINT NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS bookmark_aliases_bookmark_id_idx ON bookmark_aliases (bookmark_id)`,
+		Down: `DROP TABLE IF EXISTS bookmark_aliases`,
+	},
+	{
+		Version: 14,
+		Name:    "create_bookmark_revisions",
+		Up: `CREATE TABLE IF NOT EXISTS bookmark_revisions (
+			bookmark_id BIGINT NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+			version     INTEGER NOT NULL,
+			title       TEXT NOT NULL,
+			url         TEXT NOT NULL,
+			tags        TEXT[] NOT NULL,
+			created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (bookmark_id, version)
+		);
+		INSERT INTO bookmark_revisions (bookmark_id, version, title, url, tags, created_at)
+		SELECT id, 1, title, url, tags, created_at FROM bookmarks
+		ON CONFLICT DO NOTHING;
+		CREATE OR REPLACE FUNCTION record_bookmark_revision() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND NEW.title = OLD.title AND NEW.url = OLD.url AND NEW.tags = OLD.tags THEN
+				RETURN NEW;
+			END IF;
+			INSERT INTO bookmark_revisions (bookmark_id, version, title, url, tags)
+			SELECT NEW.id, COALESCE(max(version), 0) + 1, NEW.title, NEW.url, NEW.tags
+			FROM bookmark_revisions WHERE bookmark_id = NEW.id;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql;
+		DROP TRIGGER IF EXISTS bookmarks_record_revision ON bookmarks;
+		CREATE TRIGGER bookmarks_record_revision AFTER INSERT OR UPDATE ON bookmarks
+			FOR EACH ROW EXECUTE FUNCTION record_bookmark_revision()`,
+		Down: `DROP TRIGGER IF EXISTS bookmarks_record_revision ON bookmarks;
+		DROP FUNCTION IF EXISTS record_bookmark_revision();
+		DROP TABLE IF EXISTS bookmark_revisions`,
+	},
+	{
+		Version: 15,
+		Name:    "create_activity",
+		Up: `CREATE TABLE IF NOT EXISTS activity (
+			id            BIGSERIAL PRIMARY KEY,
+			kind          TEXT NOT NULL,
+			account_id    BIGINT REFERENCES accounts (id) ON DELETE SET NULL,
+			bookmark_id   BIGINT,
+			collection_id BIGINT,
+			detail        TEXT NOT NULL DEFAULT '',
+			created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS activity_created_at_idx ON activity (created_at)`,
+		Down: `DROP TABLE IF EXISTS activity`,
+	},
+	{
+		Version: 16,
+		Name:    "create_deleted_bookmarks",
+		Up: `CREATE TABLE IF NOT EXISTS deleted_bookmarks (
+			id             BIGINT PRIMARY KEY,
+			title          TEXT NOT NULL,
+			url            TEXT NOT NULL,
+			aliases        TEXT[] NOT NULL DEFAULT '{}',
+			tags           TEXT[] NOT NULL DEFAULT '{}',
+			collection_ids BIGINT[] NOT NULL DEFAULT '{}',
+			is_read        BOOLEAN NOT NULL DEFAULT false,
+			archived       BOOLEAN NOT NULL DEFAULT false,
+			word_count     INTEGER NOT NULL DEFAULT 0,
+			created_at     TIMESTAMPTZ NOT NULL,
+			updated_at     TIMESTAMPTZ NOT NULL,
+			deleted_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS deleted_bookmarks_deleted_at_idx ON deleted_bookmarks (deleted_at)`,
+		Down: `DROP TABLE IF EXISTS deleted_bookmarks`,
+	},
+	{
+		Version: 17,
+		Name:    "create_domain_rules",
+		Up: `CREATE TABLE IF NOT EXISTS domain_rules (
+			account_id BIGINT REFERENCES accounts (id) ON DELETE CASCADE,
+			domain     TEXT NOT NULL,
+			mode       TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS domain_rules_account_domain_idx ON domain_rules ((COALESCE(account_id, 0)), domain)`,
+		Down: `DROP TABLE IF EXISTS domain_rules`,
+	},
+	{
+		Version: 18,
+		Name:    "create_url_rules",
+		Up: `CREATE TABLE IF NOT EXISTS url_rules (
+			id         BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+			rules      JSONB NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+		Down: `DROP TABLE IF EXISTS url_rules`,
+	},
+	{
+		Version: 19,
+		Name:    "add_bookmark_notes",
+		Up: `ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';
+		ALTER TABLE deleted_bookmarks ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT ''`,
+		Down: `ALTER TABLE deleted_bookmarks DROP COLUMN IF EXISTS notes;
+		ALTER TABLE bookmarks DROP COLUMN IF EXISTS notes`,
+	},
+	{
+		Version: 20,
+		Name:    "create_bookmark_clips",
+		Up: `CREATE TABLE IF NOT EXISTS bookmark_clips (
+			id          BIGSERIAL PRIMARY KEY,
+			bookmark_id BIGINT NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+			html        TEXT NOT NULL,
+			text        TEXT NOT NULL,
+			created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS bookmark_clips_bookmark_id_idx ON bookmark_clips (bookmark_id)`,
+		Down: `DROP TABLE IF EXISTS bookmark_clips`,
+	},
+	{
+		Version: 21,
+		Name:    "create_hooks",
+		Up: `CREATE TABLE IF NOT EXISTS hooks (
+			id         BIGSERIAL PRIMARY KEY,
+			account_id BIGINT NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+			event      TEXT NOT NULL,
+			target_url TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS hooks_event_idx ON hooks (event)`,
+		Down: `DROP TABLE IF EXISTS hooks`,
+	},
+	{
+		Version: 22,
+		Name:    "add_account_capture_tokens",
+		Up: `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS capture_token_hash TEXT;
+		CREATE UNIQUE INDEX IF NOT EXISTS accounts_capture_token_hash_idx ON accounts (capture_token_hash)`,
+		Down: `DROP INDEX IF EXISTS accounts_capture_token_hash_idx;
+		ALTER TABLE accounts DROP COLUMN IF EXISTS capture_token_hash`,
+	},
+	{
+		Version: 23,
+		Name:    "create_integrations",
+		Up: `CREATE TABLE IF NOT EXISTS integrations (
+			account_id     BIGINT NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+			service        TEXT NOT NULL,
+			token          TEXT NOT NULL,
+			target         TEXT NOT NULL DEFAULT '',
+			collection_ids TEXT[] NOT NULL DEFAULT '{}',
+			last_sync_at   TIMESTAMPTZ,
+			last_error     TEXT NOT NULL DEFAULT '',
+			created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (account_id, service)
+		)`,
+		Down: `DROP TABLE IF EXISTS integrations`,
+	},
+	{
+		Version: 24,
+		Name:    "add_account_calendar_tokens",
+		Up: `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS calendar_token_hash TEXT;
+		CREATE UNIQUE INDEX IF NOT EXISTS accounts_calendar_token_hash_idx ON accounts (calendar_token_hash)`,
+		Down: `DROP INDEX IF EXISTS accounts_calendar_token_hash_idx;
+		ALTER TABLE accounts DROP COLUMN IF EXISTS calendar_token_hash`,
+	},
+	{
+		Version: 25,
+		Name:    "create_followers",
+		Up: `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS actor_key TEXT;
+		CREATE TABLE IF NOT EXISTS followers (
+			account_id BIGINT NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+			actor_id   TEXT NOT NULL,
+			inbox      TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (account_id, actor_id)
+		)`,
+		Down: `DROP TABLE IF EXISTS followers;
+		ALTER TABLE accounts DROP COLUMN IF EXISTS actor_key`,
+	},
+	{
+		Version: 26,
+		Name:    "create_mentions",
+		Up: `CREATE TABLE IF NOT EXISTS mentions (
+			bookmark_id BIGINT NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+			source      TEXT NOT NULL,
+			title       TEXT NOT NULL DEFAULT '',
+			created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+			updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (bookmark_id, source)
+		)`,
+		Down: `DROP TABLE IF EXISTS mentions`,
+	},
+	{
+		Version: 27,
+		Name:    "add_integration_cursors",
+		Up:      `ALTER TABLE integrations ADD COLUMN IF NOT EXISTS cursor TEXT NOT NULL DEFAULT ''`,
+		Down:    `ALTER TABLE integrations DROP COLUMN IF EXISTS cursor`,
+	},
+	{
+		Version: 28,
+		Name:    "create_hook_deliveries",
+		Up: `CREATE TABLE IF NOT EXISTS hook_deliveries (
+			id              BIGSERIAL PRIMARY KEY,
+			hook_id         BIGINT NOT NULL REFERENCES hooks (id) ON DELETE CASCADE,
+			event           TEXT NOT NULL,
+			payload         TEXT NOT NULL,
+			status          TEXT NOT NULL DEFAULT 'pending',
+			attempts        INTEGER NOT NULL DEFAULT 0,
+			last_status     INTEGER NOT NULL DEFAULT 0,
+			last_error      TEXT NOT NULL DEFAULT '',
+			next_attempt_at TIMESTAMPTZ,
+			created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+			updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS hook_deliveries_hook_idx ON hook_deliveries (hook_id, id);
+		CREATE INDEX IF NOT EXISTS hook_deliveries_due_idx ON hook_deliveries (next_attempt_at) WHERE status = 'pending'`,
+		Down: `DROP TABLE IF EXISTS hook_deliveries`,
+	},
+	{
+		Version: 29,
+		Name:    "use_ulid_bookmark_ids",
+		// Existing bookmarks get ULIDs made from their creation time, so
+		// they keep their order. Blobs are kept under bookmark IDs, so
+		// each change is recorded for the server to move them, in both
+		// directions.
+		Up: `CREATE OR REPLACE FUNCTION make_ulid(at TIMESTAMPTZ) RETURNS TEXT AS $$
+		DECLARE
+			alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+			ms BIGINT := floor(extract(epoch FROM at) * 1000);
+			id TEXT := '';
+		BEGIN
+			FOR i IN 1..10 LOOP
+				id := substr(alphabet, (ms % 32)::INT + 1, 1) || id;
+				ms := ms / 32;
+			END LOOP;
+			FOR i IN 1..16 LOOP
+				id := id || substr(alphabet, floor(random() * 32)::INT + 1, 1);
+			END LOOP;
+			RETURN id;
+		END
+		$$ LANGUAGE plpgsql;
+		CREATE TEMPORARY TABLE bookmark_id_map (old TEXT PRIMARY KEY, new TEXT NOT NULL) ON COMMIT DROP;
+		INSERT INTO bookmark_id_map (old, new)
+		SELECT id::TEXT, make_ulid(created_at) FROM bookmarks
+		UNION ALL
+		SELECT id::TEXT, make_ulid(created_at) FROM deleted_bookmarks;
+		DROP FUNCTION make_ulid(TIMESTAMPTZ);
+		` + recordBlobMoves + `;
+		ALTER TABLE bookmarks ALTER COLUMN id DROP DEFAULT;
+		DROP SEQUENCE IF EXISTS bookmarks_id_seq;
+		` + convertBookmarkIDs("TEXT"),
+		// IDs are numbered again in the order the ULIDs sort in
+		Down: `CREATE TEMPORARY TABLE bookmark_id_map (old TEXT PRIMARY KEY, new TEXT NOT NULL) ON COMMIT DROP;
+		INSERT INTO bookmark_id_map (old, new)
+		SELECT id, (row_number() OVER (ORDER BY id))::TEXT
+		FROM (SELECT id FROM bookmarks UNION ALL SELECT id FROM deleted_bookmarks) AS b;
+		` + recordBlobMoves + `;
+		` + convertBookmarkIDs("BIGINT") + `;
+		CREATE SEQUENCE IF NOT EXISTS bookmarks_id_seq OWNED BY bookmarks.id;
+		SELECT setval('bookmarks_id_seq', (SELECT count(*) FROM bookmark_id_map) + 1, false);
+		ALTER TABLE bookmarks ALTER COLUMN id SET DEFAULT nextval('bookmarks_id_seq')`,
+	},
+	{
+		Version: 30,
+		Name:    "add_bookmark_owner",
+		// Bookmarks saved so far have no owner and count against no quota
+		Up: `ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES accounts (id) ON DELETE SET NULL;
+		CREATE INDEX IF NOT EXISTS bookmarks_owner_id_idx ON bookmarks (owner_id);
+		ALTER TABLE deleted_bookmarks ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES accounts (id) ON DELETE SET NULL`,
+		Down: `ALTER TABLE deleted_bookmarks DROP COLUMN IF EXISTS owner_id;
+		ALTER TABLE bookmarks DROP COLUMN IF EXISTS owner_id`,
+	},
+}
+
+// bookmarkIDColumns are the columns that hold bookmark IDs, with the
+// foreign key constraint on each, if any, as Postgres names and defines it
+var bookmarkIDColumns = []struct {
+	Table, Column, Constraint, ForeignKey string
+}{
+	{"bookmarks", "id", "", ""},
+	{"deleted_bookmarks", "id", "", ""},
+	{"activity", "bookmark_id", "", ""},
+	{"collection_bookmarks", "bookmark_id", "collection_bookmarks_bookmark_id_fkey",
+		"FOREIGN KEY (bookmark_id) REFERENCES bookmarks (id) ON DELETE CASCADE"},
+	{"collection_reactions", "bookmark_id", "collection_reactions_collection_id_bookmark_id_fkey",
+		"FOREIGN KEY (collection_id, bookmark_id) REFERENCES collection_bookmarks (collection_id, bookmark_id) ON DELETE CASCADE"},
+	{"reminders", "bookmark_id", "reminders_bookmark_id_fkey",
+		"FOREIGN KEY (bookmark_id) REFERENCES bookmarks (id) ON DELETE CASCADE"},
+	{"bookmark_aliases", "bookmark_id", "bookmark_aliases_bookmark_id_fkey",
+		"FOREIGN KEY (bookmark_id) REFERENCES bookmarks (id) ON DELETE CASCADE"},
+	{"bookmark_revisions", "bookmark_id", "bookmark_revisions_bookmark_id_fkey",
+		"FOREIGN KEY (bookmark_id) REFERENCES bookmarks (id) ON DELETE CASCADE"},
+	{"bookmark_clips", "bookmark_id", "bookmark_clips_bookmark_id_fkey",
+		"FOREIGN KEY (bookmark_id) REFERENCES bookmarks (id) ON DELETE CASCADE"},
+	{"mentions", "bookmark_id", "mentions_bookmark_id_fkey",
+		"FOREIGN KEY (bookmark_id) REFERENCES bookmarks (id) ON DELETE CASCADE"},
+}
+
+// recordBlobMoves copies the bookmark_id_map table of a migration that
+// changes bookmark IDs to bookmark_blob_moves, which outlives it, so the
+// blobs kept under the old IDs can be moved once the server runs. The
+// table is left for the server to empty; rolling back adds to it rather
+// than dropping it, so blobs follow the IDs through every change.
+const recordBlobMoves = `CREATE TABLE IF NOT EXISTS bookmark_blob_moves (
+			seq    BIGSERIAL PRIMARY KEY,
+			old_id TEXT NOT NULL,
+			new_id TEXT NOT NULL
+		);
+		INSERT INTO bookmark_blob_moves (old_id, new_id) SELECT old, new FROM bookmark_id_map ORDER BY old`
+
+// convertBookmarkIDs returns the statements that change every bookmark ID
+// column to typ, replacing the IDs through the bookmark_id_map table. The
+// activity of bookmarks that are gone has no new ID and loses its link.
+func convertBookmarkIDs(typ string) string {
+	var stmts []string
+	for _, col := range bookmarkIDColumns {
+		if col.Constraint != "" {
+			stmts = append(stmts, fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s`, col.Table, col.Constraint))
+		}
+	}
+	stmts = append(stmts, `UPDATE activity SET bookmark_id = NULL
+		WHERE bookmark_id::TEXT NOT IN (SELECT old FROM bookmark_id_map)`)
+	for _, col := range bookmarkIDColumns {
+		alter := fmt.Sprintf(`ALTER TABLE %[1]s ALTER COLUMN %[2]s TYPE %[3]s USING %[2]s::%[3]s`, col.Table, col.Column, typ)
+		update := fmt.Sprintf(`UPDATE %[1]s SET %[2]s = m.new FROM bookmark_id_map m WHERE %[1]s.%[2]s::TEXT = m.old`, col.Table, col.Column)
+		// ULIDs only fit a TEXT column, and only numbers cast to BIGINT
+		if typ == "TEXT" {
+			stmts = append(stmts, alter, update)
+		} else {
+			stmts = append(stmts, update, alter)
+		}
+	}
+	for _, col := range bookmarkIDColumns {
+		if col.Constraint != "" {
+			stmts = append(stmts, fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT %s %s`, col.Table, col.Constraint, col.ForeignKey))
+		}
+	}
+	return strings.Join(stmts, ";\n\t\t")
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back schema migrations, recording progress in
+// the schema_migrations table.
+type Migrator struct {
+	db *sql.DB
+}
+
+// NewMigrator returns a migrator for db
+func NewMigrator(db *sql.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+// OpenMigrator connects to dsn and returns a migrator along with the
+// underlying pool, which the caller must close.
+func OpenMigrator(dsn string) (*Migrator, *sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("connect to database: %w", err)
+	}
+	return NewMigrator(db), db, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+// applied returns the applied migration versions and when they ran
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version int
+			at      time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		result[version] = at
+	}
+	return result, rows.Err()
+}
+
+// Status lists every known migration and when it was applied, if at all
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			at := at
+			s.AppliedAt = &at
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// Pending returns the number of migrations that have not been applied
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, mig := range migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// Up applies all pending migrations in order, each in its own transaction,
+// and returns the ones it ran.
+func (m *Migrator) Up(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []MigrationStatus
+	for _, mig := range migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := m.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		now := time.Now()
+		ran = append(ran, MigrationStatus{Version: mig.Version, Name: mig.Name, AppliedAt: &now})
+	}
+	return ran, nil
+}
+
+// Down rolls back the most recently applied migration. It returns false if
+// nothing was applied.
+func (m *Migrator) Down(ctx context.Context) (MigrationStatus, bool, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return MigrationStatus{}, false, err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mig := migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		err := m.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			return err
+		})
+		if err != nil {
+			return MigrationStatus{}, false, fmt.Errorf("rollback %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		return MigrationStatus{Version: mig.Version, Name: mig.Name}, true, nil
+	}
+	return MigrationStatus{}, false, nil
+}
+
+func (m *Migrator) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// NoteStore keeps the user's notes on bookmarks
+type NoteStore interface {
+	// SetNotes replaces a bookmark's notes
+	SetNotes(ctx context.Context, id, notes string) (model.Bookmark, error)
+}
+
+// SetNotes replaces a bookmark's notes
+func (s *MemoryStore) SetNotes(ctx context.Context, id, notes string) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setNotes(id, notes)
+}
+
+func (s *MemoryStore) setNotes(id, notes string) (model.Bookmark, error) {
+	i := s.bookmarkIndex(id)
+	if i < 0 {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	s.bookmarks[i].Notes = notes
+	s.bookmarks[i].UpdatedAt = time.Now()
+	return s.bookmarks[i], nil
+}
@@ -0,0 +1,2623 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+	"github.com/hereisth/web-collector/apps/backend/internal/ulid"
+
+	"github.com/lib/pq"
+)
+
+// Config holds the settings passed to a store driver. Built-in drivers use
+// the typed fields; third-party drivers can read Options as well.
+type Config struct {
+	DSN string
+	// ReplicaDSN optionally points at a read-only replica. When set, reads
+	// are served from it and writes go to the primary.
+	ReplicaDSN      string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// QueryTimeout bounds every statement so a slow database can't pile up
+	// blocked goroutines. Zero disables the deadline.
+	QueryTimeout time.Duration
+	// AutoMigrate applies pending migrations on startup. When false, the
+	// store refuses to start until `server migrate up` has been run.
+	AutoMigrate bool
+	// Options holds driver-specific settings from DB_OPTION_* variables,
+	// keyed by the lowercased suffix (DB_OPTION_FOO_BAR -> "foo_bar")
+	Options map[string]string
+	// Quotas limit what each account keeps in the store
+	Quotas Quotas
+}
+
+func init() {
+	Register("postgres", func(cfg Config) (Store, error) {
+		return NewPostgresStore(cfg)
+	})
+}
+
+// PostgresStore persists bookmarks in PostgreSQL
+type PostgresStore struct {
+	db           *sql.DB
+	replica      *sql.DB
+	queryTimeout time.Duration
+	quotas       Quotas
+}
+
+// NewPostgresStore opens a connection pool, verifies connectivity and
+// checks that the schema is up to date.
+func NewPostgresStore(cfg Config) (*PostgresStore, error) {
+	s := &PostgresStore{queryTimeout: cfg.QueryTimeout, quotas: cfg.Quotas}
+
+	db, err := s.open(cfg.DSN, cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.db = db
+	s.replica = db
+
+	if err := checkSchema(db, cfg.AutoMigrate); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if cfg.ReplicaDSN != "" {
+		replica, err := s.open(cfg.ReplicaDSN, cfg)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+		s.replica = replica
+	}
+	return s, nil
+}
+
+// checkSchema applies pending migrations when autoMigrate is set and
+// otherwise fails if any are outstanding.
+func checkSchema(db *sql.DB, autoMigrate bool) error {
+	m := NewMigrator(db)
+	ctx := context.Background()
+	if autoMigrate {
+		ran, err := m.Up(ctx)
+		for _, mig := range ran {
+			log.Printf("postgres: applied migration %d_%s", mig.Version, mig.Name)
+		}
+		return err
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("check migrations: %w", err)
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d pending schema migration(s); run `server migrate up` or set DB_AUTO_MIGRATE=true", pending)
+	}
+	return nil
+}
+
+// open creates a connection pool for dsn and verifies connectivity
+func (s *PostgresStore) open(dsn string, cfg Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := s.queryContext(context.Background())
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	return db, nil
+}
+
+// Close releases the connection pools
+func (s *PostgresStore) Close() error {
+	if s.replica != s.db {
+		s.replica.Close()
+	}
+	return s.db.Close()
+}
+
+// queryContext returns a context carrying the per-query deadline that is
+// also done when parent is, such as when the request a query serves
+// times out or its client goes away
+func (s *PostgresStore) queryContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, s.queryTimeout)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// bookmarkColumns are the columns scanBookmark reads
+const bookmarkColumns = `id, owner_id, title, url,
+	ARRAY(SELECT url FROM bookmark_aliases WHERE bookmark_id = bookmarks.id ORDER BY url),
+	tags, notes, is_read, archived, progress_percent, progress_anchor, progress_updated_at, word_count, created_at, updated_at`
+
+func scanBookmark(row rowScanner) (model.Bookmark, error) {
+	var (
+		b          model.Bookmark
+		percent    sql.NullFloat64
+		anchor     string
+		progressAt sql.NullTime
+	)
+	var (
+		owner         sql.NullInt64
+		tags, aliases pq.StringArray
+	)
+	if err := row.Scan(&b.ID, &owner, &b.Title, &b.URL, &aliases, &tags, &b.Notes, &b.IsRead, &b.Archived, &percent, &anchor, &progressAt, &b.WordCount, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return model.Bookmark{}, err
+	}
+	if owner.Valid {
+		b.OwnerID = strconv.FormatInt(owner.Int64, 10)
+	}
+	if percent.Valid {
+		b.Progress = &model.ReadingProgress{Percent: percent.Float64, Anchor: anchor, UpdatedAt: progressAt.Time}
+	}
+	b.ReadingMinutes = model.ReadingMinutes(b.WordCount)
+	if len(aliases) > 0 {
+		b.Aliases = []string(aliases)
+	}
+	b.Tags = []string(tags)
+	if b.Tags == nil {
+		b.Tags = []string{}
+	}
+	return b, nil
+}
+
+// querier runs the bookmark queries, on the connection pools or inside a
+// transaction
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// GetAll returns all bookmarks
+func (s *PostgresStore) GetAll(ctx context.Context) ([]model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	return getAllBookmarks(ctx, s.replica)
+}
+
+func getAllBookmarks(ctx context.Context, q querier) ([]model.Bookmark, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+bookmarkColumns+` FROM bookmarks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Bookmark{}
+	for rows.Next() {
+		b, err := scanBookmark(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, rows.Err()
+}
+
+// Create adds a new bookmark, in a transaction so that the owner's quota
+// is checked and the bookmark added as one step
+func (s *PostgresStore) Create(ctx context.Context, ownerID, title, url string, tags []string) (model.Bookmark, error) {
+	var b model.Bookmark
+	err := s.InTx(ctx, func(tx Store) error {
+		var err error
+		b, err = tx.Create(ctx, ownerID, title, url, tags)
+		return err
+	})
+	return b, err
+}
+
+func createBookmark(ctx context.Context, q querier, quotas Quotas, ownerID, title, url string, tags []string) (model.Bookmark, error) {
+	owner, ok := nullableID(ownerID)
+	if !ok {
+		return model.Bookmark{}, fmt.Errorf("invalid owner ID %q", ownerID)
+	}
+	tags = model.NormalizeTags(tags)
+	if err := quotas.checkTags(tags); err != nil {
+		return model.Bookmark{}, err
+	}
+	if err := checkOwnedBookmarks(ctx, q, quotas, ownerID); err != nil {
+		return model.Bookmark{}, err
+	}
+	row := q.QueryRowContext(ctx,
+		`INSERT INTO bookmarks (id, owner_id, title, url, tags) VALUES ($1, $2, $3, $4, $5) RETURNING `+bookmarkColumns,
+		ulid.New(), owner, title, url, pq.Array(tags))
+	return scanBookmark(row)
+}
+
+// checkOwnedBookmarks returns ErrQuotaExceeded when ownerID cannot add
+// another bookmark. q must be a transaction: the owner's account row stays
+// locked until it ends, so concurrent saves by the same owner are counted
+// one after another and cannot together pass the quota.
+func checkOwnedBookmarks(ctx context.Context, q querier, quotas Quotas, ownerID string) error {
+	if ownerID == "" || quotas.MaxBookmarks <= 0 {
+		return nil
+	}
+	if _, err := q.ExecContext(ctx, `SELECT 1 FROM accounts WHERE id = $1 FOR UPDATE`, ownerID); err != nil {
+		return err
+	}
+	var owned int
+	if err := q.QueryRowContext(ctx, `SELECT count(*) FROM bookmarks WHERE owner_id = $1`, ownerID).Scan(&owned); err != nil {
+		return err
+	}
+	return quotas.checkBookmarks(ownerID, owned)
+}
+
+// GetByID returns a bookmark by ID
+func (s *PostgresStore) GetByID(ctx context.Context, id string) (model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	return getBookmark(ctx, s.replica, id)
+}
+
+func getBookmark(ctx context.Context, q querier, id string) (model.Bookmark, error) {
+	return selectBookmark(ctx, q, id, "")
+}
+
+// lockBookmark reads a bookmark and locks its row until the transaction
+// q belongs to ends
+func lockBookmark(ctx context.Context, q querier, id string) (model.Bookmark, error) {
+	return selectBookmark(ctx, q, id, " FOR UPDATE")
+}
+
+func selectBookmark(ctx context.Context, q querier, id, suffix string) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	row := q.QueryRowContext(ctx, `SELECT `+bookmarkColumns+` FROM bookmarks WHERE id = $1`+suffix, id)
+	b, err := scanBookmark(row)
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+// Update updates an existing bookmark
+func (s *PostgresStore) Update(ctx context.Context, id, title, url string, tags []string) (model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	return updateBookmark(ctx, s.db, s.quotas, id, title, url, tags)
+}
+
+func updateBookmark(ctx context.Context, q querier, quotas Quotas, id, title, url string, tags []string) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+
+	var newTags interface{}
+	if tags != nil {
+		tags = model.NormalizeTags(tags)
+		if err := quotas.checkTags(tags); err != nil {
+			return model.Bookmark{}, err
+		}
+		newTags = pq.Array(tags)
+	}
+
+	row := q.QueryRowContext(ctx, `
+		UPDATE bookmarks
+		SET title = COALESCE(NULLIF($2, ''), title),
+		    url   = COALESCE(NULLIF($3, ''), url),
+		    tags  = COALESCE($4::TEXT[], tags),
+		    updated_at = now()
+		WHERE id = $1
+		RETURNING `+bookmarkColumns, id, title, url, newTags)
+	b, err := scanBookmark(row)
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+// Delete moves a bookmark to the trash, remembering its aliases and
+// collections so Restore can put them back
+func (s *PostgresStore) Delete(ctx context.Context, id string) error {
+	return s.InTx(ctx, func(tx Store) error {
+		return tx.Delete(ctx, id)
+	})
+}
+
+func deleteBookmark(ctx context.Context, q querier, id string) error {
+	if !ulid.Valid(id) {
+		return ErrBookmarkNotFound
+	}
+
+	res, err := q.ExecContext(ctx, `
+		INSERT INTO deleted_bookmarks (id, owner_id, title, url, aliases, tags, notes, collection_ids, is_read, archived, word_count, created_at, updated_at)
+		SELECT id, owner_id, title, url,
+			ARRAY(SELECT url FROM bookmark_aliases WHERE bookmark_id = bookmarks.id ORDER BY url),
+			tags, notes,
+			ARRAY(SELECT collection_id FROM collection_bookmarks WHERE bookmark_id = bookmarks.id ORDER BY collection_id),
+			is_read, archived, word_count, created_at, updated_at
+		FROM bookmarks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrBookmarkNotFound
+	}
+	_, err = q.ExecContext(ctx, `DELETE FROM bookmarks WHERE id = $1`, id)
+	return err
+}
+
+// InTx runs fn in a database transaction, which is committed when fn
+// returns nil and rolled back otherwise. Reads in fn go to the primary so
+// they see the transaction's own writes.
+func (s *PostgresStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(postgresTx{s: s, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// postgresTx is the Store a PostgresStore transaction hands out
+type postgresTx struct {
+	s  *PostgresStore
+	tx *sql.Tx
+}
+
+func (t postgresTx) GetAll(ctx context.Context) ([]model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return getAllBookmarks(ctx, t.tx)
+}
+
+func (t postgresTx) Create(ctx context.Context, ownerID, title, url string, tags []string) (model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return createBookmark(ctx, t.tx, t.s.quotas, ownerID, title, url, tags)
+}
+
+// GetByID locks the bookmark's row until the transaction ends, so what fn
+// decides from it still holds when it writes
+func (t postgresTx) GetByID(ctx context.Context, id string) (model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return lockBookmark(ctx, t.tx, id)
+}
+
+func (t postgresTx) Update(ctx context.Context, id, title, url string, tags []string) (model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return updateBookmark(ctx, t.tx, t.s.quotas, id, title, url, tags)
+}
+
+func (t postgresTx) Delete(ctx context.Context, id string) error {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return deleteBookmark(ctx, t.tx, id)
+}
+
+func (t postgresTx) SetNotes(ctx context.Context, id, notes string) (model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return setNotes(ctx, t.tx, id, notes)
+}
+
+func (t postgresTx) SetRead(ctx context.Context, id string, read bool) (model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return setRead(ctx, t.tx, id, read)
+}
+
+func (t postgresTx) SetProgress(ctx context.Context, id string, p model.ReadingProgress) (model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return setProgress(ctx, t.tx, id, p)
+}
+
+func (t postgresTx) SetWordCount(ctx context.Context, id string, words int) (model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return setWordCount(ctx, t.tx, id, words)
+}
+
+func (t postgresTx) SetArchived(ctx context.Context, id string, archived bool) (model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return setArchived(ctx, t.tx, id, archived)
+}
+
+func (t postgresTx) ArchiveStale(ctx context.Context, ids []string, before time.Time) ([]model.Bookmark, error) {
+	ctx, cancel := t.s.queryContext(ctx)
+	defer cancel()
+	return archiveStale(ctx, t.tx, ids, before)
+}
+
+// InTx runs fn as part of the transaction already open
+func (t postgresTx) InTx(ctx context.Context, fn func(tx Store) error) error {
+	return fn(t)
+}
+
+// accountColumns are the columns scanAccount reads
+const accountColumns = `id, email, role, password_hash, username, public_profile, archive_after_days, created_at`
+
+func scanAccount(row rowScanner) (model.Account, error) {
+	var (
+		a        model.Account
+		id       int64
+		username sql.NullString
+	)
+	if err := row.Scan(&id, &a.Email, &a.Role, &a.PasswordHash, &username, &a.PublicProfile, &a.ArchiveAfterDays, &a.CreatedAt); err != nil {
+		return model.Account{}, err
+	}
+	a.ID = strconv.FormatInt(id, 10)
+	a.Username = username.String
+	return a, nil
+}
+
+// isUniqueViolation reports whether err is a duplicate key
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+// CreateAccount adds a new account
+func (s *PostgresStore) CreateAccount(ctx context.Context, email, passwordHash, role string) (model.Account, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	a, err := scanAccount(s.db.QueryRowContext(ctx, `
+		INSERT INTO accounts (email, password_hash, role) VALUES (lower($1), $2, $3)
+		RETURNING `+accountColumns, email, passwordHash, role))
+	if isUniqueViolation(err) {
+		return model.Account{}, ErrAccountExists
+	}
+	return a, err
+}
+
+// GetAccountByEmail returns the account registered under email
+func (s *PostgresStore) GetAccountByEmail(ctx context.Context, email string) (model.Account, bool) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	a, err := scanAccount(s.db.QueryRowContext(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE email = lower($1)`, email))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("postgres: get account: %v", err)
+		}
+		return model.Account{}, false
+	}
+	return a, true
+}
+
+// GetAccount returns the account with id
+func (s *PostgresStore) GetAccount(ctx context.Context, id string) (model.Account, bool) {
+	n, ok := parseID(id)
+	if !ok {
+		return model.Account{}, false
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	a, err := scanAccount(s.db.QueryRowContext(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE id = $1`, n))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("postgres: get account %s: %v", id, err)
+		}
+		return model.Account{}, false
+	}
+	return a, true
+}
+
+// GetAccountByUsername returns the account with username
+func (s *PostgresStore) GetAccountByUsername(ctx context.Context, username string) (model.Account, bool) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	a, err := scanAccount(s.replica.QueryRowContext(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE username = lower($1)`, username))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("postgres: get account: %v", err)
+		}
+		return model.Account{}, false
+	}
+	return a, true
+}
+
+// UpdateProfile changes an account's public profile settings
+func (s *PostgresStore) UpdateProfile(ctx context.Context, id, username string, public bool) (model.Account, error) {
+	n, ok := parseID(id)
+	if !ok {
+		return model.Account{}, ErrAccountNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	a, err := scanAccount(s.db.QueryRowContext(ctx, `
+		UPDATE accounts SET username = lower($2), public_profile = $3 WHERE id = $1
+		RETURNING `+accountColumns, n, username, public))
+	switch {
+	case err == sql.ErrNoRows:
+		return model.Account{}, ErrAccountNotFound
+	case isUniqueViolation(err):
+		return model.Account{}, ErrUsernameTaken
+	}
+	return a, err
+}
+
+// SetArchivePolicy changes an account's auto-archive policy
+func (s *PostgresStore) SetArchivePolicy(ctx context.Context, id string, afterDays int) (model.Account, error) {
+	n, ok := parseID(id)
+	if !ok {
+		return model.Account{}, ErrAccountNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	a, err := scanAccount(s.db.QueryRowContext(ctx, `
+		UPDATE accounts SET archive_after_days = $2 WHERE id = $1
+		RETURNING `+accountColumns, n, afterDays))
+	if err == sql.ErrNoRows {
+		return model.Account{}, ErrAccountNotFound
+	}
+	return a, err
+}
+
+// ListAccounts returns every account
+func (s *PostgresStore) ListAccounts(ctx context.Context) []model.Account {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+accountColumns+` FROM accounts ORDER BY id`)
+	if err != nil {
+		log.Printf("postgres: list accounts: %v", err)
+		return []model.Account{}
+	}
+	defer rows.Close()
+
+	result := []model.Account{}
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			log.Printf("postgres: scan account: %v", err)
+			return result
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("postgres: list accounts: %v", err)
+	}
+	return result
+}
+
+// CountAccounts returns the number of accounts with role, or all accounts
+// when role is empty
+func (s *PostgresStore) CountAccounts(ctx context.Context, role string) int {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var n int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT count(*) FROM accounts WHERE $1 = '' OR role = $1`, role).Scan(&n)
+	if err != nil {
+		log.Printf("postgres: count accounts: %v", err)
+	}
+	return n
+}
+
+// subtreeCTE selects collection $1 and all its descendants as "subtree"
+const subtreeCTE = `WITH RECURSIVE subtree (id) AS (
+	SELECT id FROM collections WHERE id = $1
+	UNION
+	SELECT c.id FROM collections c JOIN subtree s ON c.parent_id = s.id
+) `
+
+// parseID converts a string ID to the database's integer key; IDs that do
+// not parse cannot exist
+func parseID(id string) (int64, bool) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	return n, err == nil
+}
+
+// nullableID converts an optional parent or owner ID for storage
+func nullableID(id string) (sql.NullInt64, bool) {
+	if id == "" {
+		return sql.NullInt64{}, true
+	}
+	n, ok := parseID(id)
+	return sql.NullInt64{Int64: n, Valid: true}, ok
+}
+
+func scanCollection(row rowScanner) (model.Collection, error) {
+	var (
+		c      model.Collection
+		id     int64
+		parent sql.NullInt64
+		owner  sql.NullInt64
+	)
+	if err := row.Scan(&id, &c.Name, &parent, &owner, &c.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return model.Collection{}, ErrCollectionNotFound
+		}
+		return model.Collection{}, err
+	}
+	c.ID = strconv.FormatInt(id, 10)
+	if parent.Valid {
+		c.ParentID = strconv.FormatInt(parent.Int64, 10)
+	}
+	if owner.Valid {
+		c.OwnerID = strconv.FormatInt(owner.Int64, 10)
+	}
+	return c, nil
+}
+
+// isForeignKeyViolation reports whether err is a reference to a missing row
+func isForeignKeyViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23503"
+}
+
+// inTreeTx runs fn in a transaction holding a lock that serializes changes
+// to the collection tree, so concurrent moves cannot create a cycle
+func (s *PostgresStore) inTreeTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE collections IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListCollections returns all collections
+func (s *PostgresStore) ListCollections(ctx context.Context) []model.Collection {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `SELECT id, name, parent_id, owner_id, created_at FROM collections ORDER BY id`)
+	if err != nil {
+		log.Printf("postgres: list collections: %v", err)
+		return []model.Collection{}
+	}
+	defer rows.Close()
+
+	result := []model.Collection{}
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			log.Printf("postgres: scan collection: %v", err)
+			return result
+		}
+		result = append(result, c)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("postgres: list collections: %v", err)
+	}
+	return result
+}
+
+// GetCollection returns a collection by ID
+func (s *PostgresStore) GetCollection(ctx context.Context, id string) (model.Collection, bool) {
+	n, ok := parseID(id)
+	if !ok {
+		return model.Collection{}, false
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	c, err := scanCollection(s.replica.QueryRowContext(ctx,
+		`SELECT id, name, parent_id, owner_id, created_at FROM collections WHERE id = $1`, n))
+	if err != nil {
+		if err != ErrCollectionNotFound {
+			log.Printf("postgres: get collection %s: %v", id, err)
+		}
+		return model.Collection{}, false
+	}
+	return c, true
+}
+
+// CreateCollection adds a collection
+func (s *PostgresStore) CreateCollection(ctx context.Context, name, parentID, ownerID string) (model.Collection, error) {
+	parent, ok := nullableID(parentID)
+	if !ok {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+	owner, ok := nullableID(ownerID)
+	if !ok {
+		return model.Collection{}, fmt.Errorf("invalid owner ID %q", ownerID)
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	c, err := scanCollection(s.db.QueryRowContext(ctx,
+		`INSERT INTO collections (name, parent_id, owner_id) VALUES ($1, $2, $3) RETURNING id, name, parent_id, owner_id, created_at`,
+		name, parent, owner))
+	if isForeignKeyViolation(err) {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+	return c, err
+}
+
+// RenameCollection changes a collection's name
+func (s *PostgresStore) RenameCollection(ctx context.Context, id, name string) (model.Collection, error) {
+	n, ok := parseID(id)
+	if !ok {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return scanCollection(s.db.QueryRowContext(ctx,
+		`UPDATE collections SET name = $2 WHERE id = $1 RETURNING id, name, parent_id, owner_id, created_at`, n, name))
+}
+
+// MoveCollection reparents a collection
+func (s *PostgresStore) MoveCollection(ctx context.Context, id, parentID string) (model.Collection, error) {
+	n, ok := parseID(id)
+	parent, parentOK := nullableID(parentID)
+	if !ok || !parentOK {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var c model.Collection
+	err := s.inTreeTx(ctx, func(tx *sql.Tx) error {
+		if parent.Valid {
+			var cycle bool
+			err := tx.QueryRowContext(ctx, subtreeCTE+`SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`,
+				n, parent.Int64).Scan(&cycle)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return ErrCollectionCycle
+			}
+		}
+		var err error
+		c, err = scanCollection(tx.QueryRowContext(ctx,
+			`UPDATE collections SET parent_id = $2 WHERE id = $1 RETURNING id, name, parent_id, owner_id, created_at`, n, parent))
+		return err
+	})
+	if isForeignKeyViolation(err) {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+	return c, err
+}
+
+// MergeCollections folds source into target
+func (s *PostgresStore) MergeCollections(ctx context.Context, sourceID, targetID string) (model.Collection, error) {
+	src, ok := parseID(sourceID)
+	dst, dstOK := parseID(targetID)
+	if !ok || !dstOK {
+		return model.Collection{}, ErrCollectionNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var target model.Collection
+	err := s.inTreeTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		target, err = scanCollection(tx.QueryRowContext(ctx,
+			`SELECT id, name, parent_id, owner_id, created_at FROM collections WHERE id = $1`, dst))
+		if err != nil {
+			return err
+		}
+		// The target must not be the source or below it; this also
+		// fails when the source does not exist.
+		var sourceExists, cycle bool
+		err = tx.QueryRowContext(ctx, subtreeCTE+`SELECT EXISTS (SELECT 1 FROM subtree), EXISTS (SELECT 1 FROM subtree WHERE id = $2)`,
+			src, dst).Scan(&sourceExists, &cycle)
+		switch {
+		case err != nil:
+			return err
+		case !sourceExists:
+			return ErrCollectionNotFound
+		case cycle:
+			return ErrCollectionCycle
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE collections SET parent_id = $2 WHERE parent_id = $1`, src, dst); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO collection_bookmarks (collection_id, bookmark_id)
+			SELECT $2, bookmark_id FROM collection_bookmarks WHERE collection_id = $1
+			ON CONFLICT DO NOTHING`, src, dst); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO collection_reactions (collection_id, bookmark_id, account_id, emoji, created_at)
+			SELECT $2, bookmark_id, account_id, emoji, created_at FROM collection_reactions WHERE collection_id = $1
+			ON CONFLICT DO NOTHING`, src, dst); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM collections WHERE id = $1`, src)
+		return err
+	})
+	return target, err
+}
+
+// DeleteCollection removes a collection; its subtree and memberships go
+// with it through ON DELETE CASCADE
+func (s *PostgresStore) DeleteCollection(ctx context.Context, id string) error {
+	n, ok := parseID(id)
+	if !ok {
+		return ErrCollectionNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM collections WHERE id = $1`, n)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// AddToCollection adds bookmarks to a collection
+func (s *PostgresStore) AddToCollection(ctx context.Context, id string, bookmarkIDs []string) error {
+	n, ok := parseID(id)
+	if !ok {
+		return ErrCollectionNotFound
+	}
+	for _, bookmarkID := range bookmarkIDs {
+		if !ulid.Valid(bookmarkID) {
+			return fmt.Errorf("%w: %s", ErrBookmarkNotFound, bookmarkID)
+		}
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM collections WHERE id = $1)`, n).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCollectionNotFound
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO collection_bookmarks (collection_id, bookmark_id)
+		SELECT $1, unnest($2::TEXT[])
+		ON CONFLICT DO NOTHING`, n, pq.Array(bookmarkIDs))
+	if isForeignKeyViolation(err) {
+		return ErrBookmarkNotFound
+	}
+	return err
+}
+
+// RemoveFromCollection takes a bookmark out of a collection
+func (s *PostgresStore) RemoveFromCollection(ctx context.Context, id, bookmarkID string) error {
+	n, ok := parseID(id)
+	if !ok {
+		return ErrCollectionNotFound
+	}
+	if !ulid.Valid(bookmarkID) {
+		return ErrBookmarkNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM collection_bookmarks WHERE collection_id = $1 AND bookmark_id = $2`, n, bookmarkID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		if _, found := s.GetCollection(ctx, id); !found {
+			return ErrCollectionNotFound
+		}
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+// CollectionBookmarks lists the bookmarks in a collection
+func (s *PostgresStore) CollectionBookmarks(ctx context.Context, id string, recursive bool) ([]string, error) {
+	n, ok := parseID(id)
+	if !ok {
+		return nil, ErrCollectionNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	query := subtreeCTE + `SELECT EXISTS (SELECT 1 FROM subtree), COALESCE(array_agg(DISTINCT bookmark_id ORDER BY bookmark_id), '{}')
+		FROM collection_bookmarks WHERE collection_id = $1`
+	if recursive {
+		query = subtreeCTE + `SELECT EXISTS (SELECT 1 FROM subtree), COALESCE(array_agg(DISTINCT bookmark_id ORDER BY bookmark_id), '{}')
+		FROM collection_bookmarks WHERE collection_id IN (SELECT id FROM subtree)`
+	}
+	var (
+		exists bool
+		ids    pq.StringArray
+	)
+	if err := s.replica.QueryRowContext(ctx, query, n).Scan(&exists, &ids); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrCollectionNotFound
+	}
+	return []string(ids), nil
+}
+
+// UpdateTags adds and removes tags on several bookmarks. The rows are
+// locked while the new tags are computed, so concurrent edits of the same
+// bookmarks are not lost.
+func (s *PostgresStore) UpdateTags(ctx context.Context, ids, add, remove []string) ([]model.Bookmark, error) {
+	keys := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !ulid.Valid(id) {
+			return nil, fmt.Errorf("%w: %s", ErrBookmarkNotFound, id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			keys = append(keys, id)
+		}
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+bookmarkColumns+` FROM bookmarks
+		WHERE id = ANY($1) ORDER BY id FOR UPDATE`, pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]model.Bookmark, 0, len(keys))
+	for rows.Next() {
+		b, err := scanBookmark(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(result) < len(keys) {
+		for _, b := range result {
+			delete(seen, b.ID)
+		}
+		for n := range seen {
+			return nil, fmt.Errorf("%w: %s", ErrBookmarkNotFound, n)
+		}
+	}
+
+	for i := range result {
+		result[i].Tags = applyTags(result[i].Tags, add, remove)
+		if err := s.quotas.checkTags(result[i].Tags); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE bookmarks SET tags = $2, updated_at = now() WHERE id = $1`, result[i].ID, pq.Array(result[i].Tags)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ShareCollection grants access to a collection
+func (s *PostgresStore) ShareCollection(ctx context.Context, id, grantee, access string) error {
+	n, ok := parseID(id)
+	if !ok {
+		return ErrCollectionNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO collection_shares (collection_id, grantee, access) VALUES ($1, $2, $3)
+		ON CONFLICT (collection_id, grantee) DO UPDATE SET access = EXCLUDED.access`, n, grantee, access)
+	if isForeignKeyViolation(err) {
+		return ErrCollectionNotFound
+	}
+	return err
+}
+
+// UnshareCollection revokes access to a collection
+func (s *PostgresStore) UnshareCollection(ctx context.Context, id, grantee string) error {
+	n, ok := parseID(id)
+	if !ok {
+		return ErrCollectionNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM collection_shares WHERE collection_id = $1 AND grantee = $2`, n, grantee)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		if _, found := s.GetCollection(ctx, id); !found {
+			return ErrCollectionNotFound
+		}
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// ListShares returns all shares
+func (s *PostgresStore) ListShares(ctx context.Context) []model.CollectionShare {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `SELECT collection_id, grantee, access FROM collection_shares ORDER BY collection_id, grantee`)
+	if err != nil {
+		log.Printf("postgres: list shares: %v", err)
+		return []model.CollectionShare{}
+	}
+	defer rows.Close()
+
+	result := []model.CollectionShare{}
+	for rows.Next() {
+		var (
+			sh model.CollectionShare
+			id int64
+		)
+		if err := rows.Scan(&id, &sh.Grantee, &sh.Access); err != nil {
+			log.Printf("postgres: scan share: %v", err)
+			return result
+		}
+		sh.CollectionID = strconv.FormatInt(id, 10)
+		result = append(result, sh)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("postgres: list shares: %v", err)
+	}
+	return result
+}
+
+// AddReaction records a reaction. The reference to collection_bookmarks
+// rejects bookmarks that are not in the collection.
+func (s *PostgresStore) AddReaction(ctx context.Context, collectionID, bookmarkID, accountID, emoji string) error {
+	n, ok := parseID(collectionID)
+	if !ok {
+		return ErrCollectionNotFound
+	}
+	if !ulid.Valid(bookmarkID) {
+		return ErrBookmarkNotFound
+	}
+	a, ok := parseID(accountID)
+	if !ok {
+		return fmt.Errorf("invalid account ID %q", accountID)
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM collections WHERE id = $1)`, n).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCollectionNotFound
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO collection_reactions (collection_id, bookmark_id, account_id, emoji) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`, n, bookmarkID, a, emoji)
+	if isForeignKeyViolation(err) {
+		return ErrBookmarkNotFound
+	}
+	return err
+}
+
+// RemoveReaction withdraws a reaction
+func (s *PostgresStore) RemoveReaction(ctx context.Context, collectionID, bookmarkID, accountID, emoji string) error {
+	n, ok := parseID(collectionID)
+	if !ok {
+		return ErrCollectionNotFound
+	}
+	a, ok := parseID(accountID)
+	if !ok || !ulid.Valid(bookmarkID) {
+		return nil
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM collections WHERE id = $1)`, n).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCollectionNotFound
+	}
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM collection_reactions
+		WHERE collection_id = $1 AND bookmark_id = $2 AND account_id = $3 AND emoji = $4`, n, bookmarkID, a, emoji)
+	return err
+}
+
+// CollectionReactions lists the reactions in a collection
+func (s *PostgresStore) CollectionReactions(ctx context.Context, collectionID string) ([]model.Reaction, error) {
+	n, ok := parseID(collectionID)
+	if !ok {
+		return nil, ErrCollectionNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var exists bool
+	if err := s.replica.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM collections WHERE id = $1)`, n).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrCollectionNotFound
+	}
+	rows, err := s.replica.QueryContext(ctx, `
+		SELECT bookmark_id, account_id, emoji FROM collection_reactions
+		WHERE collection_id = $1 ORDER BY created_at`, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Reaction{}
+	for rows.Next() {
+		var a int64
+		r := model.Reaction{CollectionID: collectionID}
+		if err := rows.Scan(&r.BookmarkID, &a, &r.Emoji); err != nil {
+			return nil, err
+		}
+		r.AccountID = strconv.FormatInt(a, 10)
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// SetRead marks a bookmark read or unread
+func (s *PostgresStore) SetRead(ctx context.Context, id string, read bool) (model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	return setRead(ctx, s.db, id, read)
+}
+
+func setRead(ctx context.Context, q querier, id string, read bool) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+
+	b, err := scanBookmark(q.QueryRowContext(ctx,
+		`UPDATE bookmarks SET is_read = $2, updated_at = now() WHERE id = $1 RETURNING `+bookmarkColumns, id, read))
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+// SetProgress records how far a bookmark has been read
+func (s *PostgresStore) SetProgress(ctx context.Context, id string, p model.ReadingProgress) (model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	return setProgress(ctx, s.db, id, p)
+}
+
+func setProgress(ctx context.Context, q querier, id string, p model.ReadingProgress) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+
+	b, err := scanBookmark(q.QueryRowContext(ctx, `
+		UPDATE bookmarks SET progress_percent = $2, progress_anchor = $3, progress_updated_at = $4, updated_at = $4
+		WHERE id = $1 RETURNING `+bookmarkColumns, id, p.Percent, p.Anchor, p.UpdatedAt))
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+// SetWordCount records the word count of a bookmark's page
+func (s *PostgresStore) SetWordCount(ctx context.Context, id string, words int) (model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	return setWordCount(ctx, s.db, id, words)
+}
+
+func setWordCount(ctx context.Context, q querier, id string, words int) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+
+	b, err := scanBookmark(q.QueryRowContext(ctx,
+		`UPDATE bookmarks SET word_count = $2 WHERE id = $1 RETURNING `+bookmarkColumns, id, words))
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+// SetNotes replaces a bookmark's notes
+func (s *PostgresStore) SetNotes(ctx context.Context, id, notes string) (model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	return setNotes(ctx, s.db, id, notes)
+}
+
+func setNotes(ctx context.Context, q querier, id, notes string) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+
+	b, err := scanBookmark(q.QueryRowContext(ctx,
+		`UPDATE bookmarks SET notes = $2, updated_at = now() WHERE id = $1 RETURNING `+bookmarkColumns, id, notes))
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+func scanReminder(row rowScanner) (model.Reminder, error) {
+	var (
+		r         model.Reminder
+		account   sql.NullInt64
+		delivered sql.NullTime
+	)
+	if err := row.Scan(&r.BookmarkID, &account, &r.RemindAt, &delivered); err != nil {
+		return model.Reminder{}, err
+	}
+	if account.Valid {
+		r.AccountID = strconv.FormatInt(account.Int64, 10)
+	}
+	if delivered.Valid {
+		r.DeliveredAt = &delivered.Time
+	}
+	return r, nil
+}
+
+// SetReminder creates or replaces a bookmark's reminder
+func (s *PostgresStore) SetReminder(ctx context.Context, r model.Reminder) (model.Reminder, error) {
+	if !ulid.Valid(r.BookmarkID) {
+		return model.Reminder{}, ErrBookmarkNotFound
+	}
+	account, ok := nullableID(r.AccountID)
+	if !ok {
+		return model.Reminder{}, fmt.Errorf("invalid account ID %q", r.AccountID)
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	saved, err := scanReminder(s.db.QueryRowContext(ctx, `
+		INSERT INTO reminders (bookmark_id, account_id, remind_at) VALUES ($1, $2, $3)
+		ON CONFLICT (bookmark_id) DO UPDATE
+		SET account_id = EXCLUDED.account_id, remind_at = EXCLUDED.remind_at, delivered_at = NULL
+		RETURNING bookmark_id, account_id, remind_at, delivered_at`, r.BookmarkID, account, r.RemindAt))
+	if isForeignKeyViolation(err) {
+		return model.Reminder{}, ErrBookmarkNotFound
+	}
+	return saved, err
+}
+
+// DeleteReminder cancels a bookmark's reminder
+func (s *PostgresStore) DeleteReminder(ctx context.Context, bookmarkID string) error {
+	if !ulid.Valid(bookmarkID) {
+		return ErrReminderNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM reminders WHERE bookmark_id = $1`, bookmarkID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrReminderNotFound
+	}
+	return nil
+}
+
+// DueReminders returns the reminders due at or before t
+func (s *PostgresStore) DueReminders(ctx context.Context, t time.Time) ([]model.Reminder, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT bookmark_id, account_id, remind_at, delivered_at FROM reminders
+		WHERE remind_at <= $1 ORDER BY remind_at, bookmark_id`, t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Reminder{}
+	for rows.Next() {
+		r, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// MarkReminderDelivered records that a reminder was sent
+func (s *PostgresStore) MarkReminderDelivered(ctx context.Context, bookmarkID string, t time.Time) error {
+	if !ulid.Valid(bookmarkID) {
+		return ErrReminderNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE reminders SET delivered_at = $2 WHERE bookmark_id = $1`, bookmarkID, t)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrReminderNotFound
+	}
+	return nil
+}
+
+// SetArchived archives a bookmark or brings it back
+func (s *PostgresStore) SetArchived(ctx context.Context, id string, archived bool) (model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	return setArchived(ctx, s.db, id, archived)
+}
+
+func setArchived(ctx context.Context, q querier, id string, archived bool) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+
+	b, err := scanBookmark(q.QueryRowContext(ctx,
+		`UPDATE bookmarks SET archived = $2, updated_at = now() WHERE id = $1 RETURNING `+bookmarkColumns, id, archived))
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+// ArchiveStale archives the bookmarks among ids untouched since t
+func (s *PostgresStore) ArchiveStale(ctx context.Context, ids []string, t time.Time) ([]model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	return archiveStale(ctx, s.db, ids, t)
+}
+
+func archiveStale(ctx context.Context, q querier, ids []string, t time.Time) ([]model.Bookmark, error) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if ulid.Valid(id) {
+			keys = append(keys, id)
+		}
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		UPDATE bookmarks SET archived = true, updated_at = now()
+		WHERE id = ANY($1) AND NOT archived AND updated_at < $2
+		RETURNING `+bookmarkColumns, pq.Array(keys), t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Bookmark{}
+	for rows.Next() {
+		b, err := scanBookmark(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, rows.Err()
+}
+
+// AddAlias attaches url to a bookmark
+func (s *PostgresStore) AddAlias(ctx context.Context, id, url string) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO bookmark_aliases (url, bookmark_id)
+		SELECT $2, id FROM bookmarks
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM bookmarks WHERE url = $2)`, id, url)
+	if isUniqueViolation(err) {
+		return model.Bookmark{}, ErrAliasTaken
+	}
+	if err != nil {
+		return model.Bookmark{}, err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return model.Bookmark{}, err
+		}
+		return model.Bookmark{}, ErrAliasTaken
+	}
+	return s.touch(ctx, id)
+}
+
+// RemoveAlias detaches url from a bookmark
+func (s *PostgresStore) RemoveAlias(ctx context.Context, id, url string) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM bookmark_aliases WHERE bookmark_id = $1 AND url = $2`, id, url)
+	if err != nil {
+		return model.Bookmark{}, err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return model.Bookmark{}, err
+		}
+		return model.Bookmark{}, ErrAliasNotFound
+	}
+	return s.touch(ctx, id)
+}
+
+// touch bumps a bookmark's updated_at and returns it
+func (s *PostgresStore) touch(ctx context.Context, n string) (model.Bookmark, error) {
+	b, err := scanBookmark(s.db.QueryRowContext(ctx,
+		`UPDATE bookmarks SET updated_at = now() WHERE id = $1 RETURNING `+bookmarkColumns, n))
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+// FindByURL returns the bookmark whose URL or alias is url
+func (s *PostgresStore) FindByURL(ctx context.Context, url string) (model.Bookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	b, err := scanBookmark(s.replica.QueryRowContext(ctx, `
+		SELECT `+bookmarkColumns+` FROM bookmarks
+		WHERE url = $1 OR id = (SELECT bookmark_id FROM bookmark_aliases WHERE url = $1)
+		ORDER BY id LIMIT 1`, url))
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+// BookmarkHistory returns a bookmark's revisions, which a trigger records
+// on every insert or change of title, URL or tags
+func (s *PostgresStore) BookmarkHistory(ctx context.Context, id string) ([]model.Revision, error) {
+	if !ulid.Valid(id) {
+		return nil, ErrBookmarkNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `
+		SELECT version, title, url, tags, created_at FROM bookmark_revisions
+		WHERE bookmark_id = $1 ORDER BY version`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Revision{}
+	for rows.Next() {
+		var (
+			r    = model.Revision{BookmarkID: id}
+			tags pq.StringArray
+		)
+		if err := rows.Scan(&r.Version, &r.Title, &r.URL, &tags, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		r.Tags = []string(tags)
+		if r.Tags == nil {
+			r.Tags = []string{}
+		}
+		result = append(result, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// RecordActivity appends an entry to the feed
+func (s *PostgresStore) RecordActivity(ctx context.Context, a model.Activity) (model.Activity, error) {
+	account, ok := nullableID(a.AccountID)
+	if !ok {
+		return model.Activity{}, fmt.Errorf("invalid account ID %q", a.AccountID)
+	}
+	bookmark := sql.NullString{String: a.BookmarkID, Valid: a.BookmarkID != ""}
+	collection, _ := nullableID(a.CollectionID)
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO activity (kind, account_id, bookmark_id, collection_id, detail, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		a.Kind, account, bookmark, collection, a.Detail, a.CreatedAt).Scan(&id)
+	if err != nil {
+		return model.Activity{}, err
+	}
+	a.ID = strconv.FormatInt(id, 10)
+	return a, nil
+}
+
+// ListActivity returns the newest entries older than before
+func (s *PostgresStore) ListActivity(ctx context.Context, before time.Time, limit int) ([]model.Activity, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `
+		SELECT id, kind, account_id, bookmark_id, collection_id, detail, created_at FROM activity
+		WHERE created_at < $1 ORDER BY created_at DESC, id DESC LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Activity{}
+	for rows.Next() {
+		var (
+			a                   model.Activity
+			id                  int64
+			account, collection sql.NullInt64
+			bookmark            sql.NullString
+		)
+		if err := rows.Scan(&id, &a.Kind, &account, &bookmark, &collection, &a.Detail, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.ID = strconv.FormatInt(id, 10)
+		if account.Valid {
+			a.AccountID = strconv.FormatInt(account.Int64, 10)
+		}
+		if bookmark.Valid {
+			a.BookmarkID = bookmark.String
+		}
+		if collection.Valid {
+			a.CollectionID = strconv.FormatInt(collection.Int64, 10)
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// ListDeleted returns the bookmarks in the trash, most recently deleted
+// first
+func (s *PostgresStore) ListDeleted(ctx context.Context) ([]model.DeletedBookmark, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `
+		SELECT id, owner_id, title, url, aliases, tags, notes, collection_ids, is_read, archived, word_count, created_at, updated_at, deleted_at
+		FROM deleted_bookmarks ORDER BY deleted_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.DeletedBookmark{}
+	for rows.Next() {
+		var (
+			d             model.DeletedBookmark
+			owner         sql.NullInt64
+			aliases, tags pq.StringArray
+			collections   pq.Int64Array
+		)
+		if err := rows.Scan(&d.ID, &owner, &d.Title, &d.URL, &aliases, &tags, &d.Notes, &collections, &d.IsRead, &d.Archived,
+			&d.WordCount, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt); err != nil {
+			return nil, err
+		}
+		if owner.Valid {
+			d.OwnerID = strconv.FormatInt(owner.Int64, 10)
+		}
+		if len(aliases) > 0 {
+			d.Aliases = []string(aliases)
+		}
+		d.Tags = []string(tags)
+		if d.Tags == nil {
+			d.Tags = []string{}
+		}
+		for _, c := range collections {
+			d.CollectionIDs = append(d.CollectionIDs, strconv.FormatInt(c, 10))
+		}
+		d.ReadingMinutes = model.ReadingMinutes(d.WordCount)
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+// Restore moves a bookmark out of the trash. Aliases another bookmark has
+// taken since and collections deleted since are dropped.
+func (s *PostgresStore) Restore(ctx context.Context, id string) (model.Bookmark, error) {
+	if !ulid.Valid(id) {
+		return model.Bookmark{}, ErrNotInTrash
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.Bookmark{}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		d             model.DeletedBookmark
+		owner         sql.NullInt64
+		aliases, tags pq.StringArray
+		collections   pq.Int64Array
+	)
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM deleted_bookmarks WHERE id = $1
+		RETURNING owner_id, title, url, aliases, tags, notes, collection_ids, is_read, archived, word_count, created_at`, id).
+		Scan(&owner, &d.Title, &d.URL, &aliases, &tags, &d.Notes, &collections, &d.IsRead, &d.Archived, &d.WordCount, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return model.Bookmark{}, ErrNotInTrash
+	}
+	if err != nil {
+		return model.Bookmark{}, err
+	}
+	if owner.Valid {
+		if err := checkOwnedBookmarks(ctx, tx, s.quotas, strconv.FormatInt(owner.Int64, 10)); err != nil {
+			return model.Bookmark{}, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO bookmarks (id, owner_id, title, url, tags, notes, is_read, archived, word_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		id, owner, d.Title, d.URL, tags, d.Notes, d.IsRead, d.Archived, d.WordCount, d.CreatedAt); err != nil {
+		return model.Bookmark{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO bookmark_aliases (url, bookmark_id)
+		SELECT a, $1 FROM unnest($2::TEXT[]) AS a
+		WHERE NOT EXISTS (SELECT 1 FROM bookmarks WHERE url = a)
+		ON CONFLICT DO NOTHING`, id, aliases); err != nil {
+		return model.Bookmark{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO collection_bookmarks (collection_id, bookmark_id)
+		SELECT id, $1 FROM collections WHERE id = ANY($2)`, id, collections); err != nil {
+		return model.Bookmark{}, err
+	}
+	b, err := scanBookmark(tx.QueryRowContext(ctx, `SELECT `+bookmarkColumns+` FROM bookmarks WHERE id = $1`, id))
+	if err != nil {
+		return model.Bookmark{}, err
+	}
+	return b, tx.Commit()
+}
+
+// PurgeDeleted permanently removes the bookmarks deleted before t
+func (s *PostgresStore) PurgeDeleted(ctx context.Context, t time.Time) (int, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM deleted_bookmarks WHERE deleted_at < $1`, t)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := res.RowsAffected()
+	return int(purged), err
+}
+
+// DomainRules returns the rules of an account, or the instance-wide rules
+// for ""
+func (s *PostgresStore) DomainRules(ctx context.Context, accountID string) ([]model.DomainRule, error) {
+	account, ok := nullableID(accountID)
+	if !ok {
+		return []model.DomainRule{}, nil
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `
+		SELECT domain, mode, created_at FROM domain_rules
+		WHERE account_id IS NOT DISTINCT FROM $1 ORDER BY domain`, account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.DomainRule{}
+	for rows.Next() {
+		r := model.DomainRule{AccountID: accountID}
+		if err := rows.Scan(&r.Domain, &r.Mode, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// SetDomainRule creates the rule for r.Domain or changes its mode
+func (s *PostgresStore) SetDomainRule(ctx context.Context, r model.DomainRule) (model.DomainRule, error) {
+	account, ok := nullableID(r.AccountID)
+	if !ok {
+		return model.DomainRule{}, ErrAccountNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO domain_rules (account_id, domain, mode) VALUES ($1, $2, $3)
+		ON CONFLICT ((COALESCE(account_id, 0)), domain) DO UPDATE SET mode = EXCLUDED.mode
+		RETURNING created_at`, account, r.Domain, r.Mode).Scan(&r.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return model.DomainRule{}, ErrAccountNotFound
+	}
+	if err != nil {
+		return model.DomainRule{}, err
+	}
+	return r, nil
+}
+
+// DeleteDomainRule removes an account's rule for domain
+func (s *PostgresStore) DeleteDomainRule(ctx context.Context, accountID, domain string) error {
+	account, ok := nullableID(accountID)
+	if !ok {
+		return ErrDomainRuleNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM domain_rules WHERE account_id IS NOT DISTINCT FROM $1 AND domain = $2`, account, domain)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrDomainRuleNotFound
+	}
+	return nil
+}
+
+// URLRules returns the rules, or the defaults when none were set
+func (s *PostgresStore) URLRules(ctx context.Context) (model.URLRules, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var raw []byte
+	err := s.replica.QueryRowContext(ctx, `SELECT rules FROM url_rules`).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return model.DefaultURLRules(), nil
+	}
+	if err != nil {
+		return model.URLRules{}, err
+	}
+	var r model.URLRules
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return model.URLRules{}, err
+	}
+	return r, nil
+}
+
+// SetURLRules replaces the rules
+func (s *PostgresStore) SetURLRules(ctx context.Context, r model.URLRules) (model.URLRules, error) {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return model.URLRules{}, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO url_rules (rules) VALUES ($1)
+		ON CONFLICT (id) DO UPDATE SET rules = EXCLUDED.rules, updated_at = now()`, raw); err != nil {
+		return model.URLRules{}, err
+	}
+	return r, nil
+}
+
+// AddClip stores a clip of c.BookmarkID
+func (s *PostgresStore) AddClip(ctx context.Context, c model.Clip) (model.Clip, error) {
+	if !ulid.Valid(c.BookmarkID) {
+		return model.Clip{}, ErrBookmarkNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO bookmark_clips (bookmark_id, html, text) VALUES ($1, $2, $3)
+		RETURNING id, created_at`, c.BookmarkID, c.HTML, c.Text).Scan(&id, &c.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return model.Clip{}, ErrBookmarkNotFound
+	}
+	if err != nil {
+		return model.Clip{}, err
+	}
+	c.ID = strconv.FormatInt(id, 10)
+	return c, nil
+}
+
+// BookmarkClips returns a bookmark's clips, oldest first
+func (s *PostgresStore) BookmarkClips(ctx context.Context, bookmarkID string) ([]model.Clip, error) {
+	if _, err := s.GetByID(ctx, bookmarkID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `
+		SELECT id, html, text, created_at FROM bookmark_clips
+		WHERE bookmark_id = $1 ORDER BY id`, bookmarkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Clip{}
+	for rows.Next() {
+		var (
+			c  = model.Clip{BookmarkID: bookmarkID}
+			id int64
+		)
+		if err := rows.Scan(&id, &c.HTML, &c.Text, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.ID = strconv.FormatInt(id, 10)
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// DeleteClip removes one of a bookmark's clips
+func (s *PostgresStore) DeleteClip(ctx context.Context, bookmarkID, clipID string) error {
+	clip, ok := parseID(clipID)
+	if !ok || !ulid.Valid(bookmarkID) {
+		return ErrClipNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM bookmark_clips WHERE id = $1 AND bookmark_id = $2`, clip, bookmarkID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrClipNotFound
+	}
+	return nil
+}
+
+// AddHook subscribes h.TargetURL to h.Event for h.AccountID
+func (s *PostgresStore) AddHook(ctx context.Context, h model.Hook) (model.Hook, error) {
+	account, ok := parseID(h.AccountID)
+	if !ok {
+		return model.Hook{}, ErrAccountNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO hooks (account_id, event, target_url) VALUES ($1, $2, $3)
+		RETURNING id, created_at`, account, h.Event, h.TargetURL).Scan(&id, &h.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return model.Hook{}, ErrAccountNotFound
+	}
+	if err != nil {
+		return model.Hook{}, err
+	}
+	h.ID = strconv.FormatInt(id, 10)
+	return h, nil
+}
+
+// AccountHooks returns an account's hooks, oldest first
+func (s *PostgresStore) AccountHooks(ctx context.Context, accountID string) ([]model.Hook, error) {
+	account, ok := parseID(accountID)
+	if !ok {
+		return []model.Hook{}, nil
+	}
+	return s.queryHooks(ctx, `WHERE account_id = $1`, account)
+}
+
+// EventHooks returns every account's hooks for an event
+func (s *PostgresStore) EventHooks(ctx context.Context, event string) ([]model.Hook, error) {
+	return s.queryHooks(ctx, `WHERE event = $1`, event)
+}
+
+func (s *PostgresStore) queryHooks(ctx context.Context, where string, arg interface{}) ([]model.Hook, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `
+		SELECT id, account_id, event, target_url, created_at FROM hooks `+where+` ORDER BY id`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Hook{}
+	for rows.Next() {
+		var (
+			h           model.Hook
+			id, account int64
+		)
+		if err := rows.Scan(&id, &account, &h.Event, &h.TargetURL, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		h.ID = strconv.FormatInt(id, 10)
+		h.AccountID = strconv.FormatInt(account, 10)
+		result = append(result, h)
+	}
+	return result, rows.Err()
+}
+
+// DeleteHook unsubscribes one of an account's hooks
+func (s *PostgresStore) DeleteHook(ctx context.Context, accountID, id string) error {
+	account, ok := parseID(accountID)
+	hook, hookOK := parseID(id)
+	if !ok || !hookOK {
+		return ErrHookNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM hooks WHERE id = $1 AND account_id = $2`, hook, account)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrHookNotFound
+	}
+	return nil
+}
+
+// AddHookDelivery logs a delivery to d.HookID
+func (s *PostgresStore) AddHookDelivery(ctx context.Context, d model.HookDelivery) (model.HookDelivery, error) {
+	hook, ok := parseID(d.HookID)
+	if !ok {
+		return model.HookDelivery{}, ErrHookNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var id, account int64
+	err := s.db.QueryRowContext(ctx, `
+		WITH h AS (SELECT id, account_id, target_url FROM hooks WHERE id = $1),
+		d AS (
+			INSERT INTO hook_deliveries (hook_id, event, payload, status, attempts, next_attempt_at)
+			SELECT id, $2, $3, $4, $5, $6 FROM h
+			RETURNING id, created_at, updated_at
+		)
+		SELECT d.id, d.created_at, d.updated_at, h.account_id, h.target_url FROM d, h`,
+		hook, d.Event, string(d.Payload), d.Status, d.Attempts, d.NextAttemptAt,
+	).Scan(&id, &d.CreatedAt, &d.UpdatedAt, &account, &d.TargetURL)
+	if err == sql.ErrNoRows {
+		return model.HookDelivery{}, ErrHookNotFound
+	}
+	if err != nil {
+		return model.HookDelivery{}, err
+	}
+	d.ID = strconv.FormatInt(id, 10)
+	d.AccountID = strconv.FormatInt(account, 10)
+	return d, nil
+}
+
+// UpdateHookDelivery records the outcome of an attempt
+func (s *PostgresStore) UpdateHookDelivery(ctx context.Context, d model.HookDelivery) error {
+	id, ok := parseID(d.ID)
+	if !ok {
+		return ErrHookDeliveryNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE hook_deliveries SET
+			status = $2, attempts = $3, last_status = $4, last_error = $5, next_attempt_at = $6, updated_at = now()
+		WHERE id = $1`, id, d.Status, d.Attempts, d.LastStatus, d.LastError, d.NextAttemptAt)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrHookDeliveryNotFound
+	}
+	return nil
+}
+
+// GetHookDelivery returns one of an account's deliveries
+func (s *PostgresStore) GetHookDelivery(ctx context.Context, accountID, id string) (model.HookDelivery, error) {
+	account, ok := parseID(accountID)
+	delivery, deliveryOK := parseID(id)
+	if !ok || !deliveryOK {
+		return model.HookDelivery{}, ErrHookDeliveryNotFound
+	}
+	result, err := s.queryHookDeliveries(ctx, `WHERE d.id = $1 AND h.account_id = $2`, delivery, account)
+	if err != nil {
+		return model.HookDelivery{}, err
+	}
+	if len(result) == 0 {
+		return model.HookDelivery{}, ErrHookDeliveryNotFound
+	}
+	return result[0], nil
+}
+
+// HookDeliveries returns an account's deliveries, newest first
+func (s *PostgresStore) HookDeliveries(ctx context.Context, accountID, hookID, status string, limit int) ([]model.HookDelivery, error) {
+	account, ok := parseID(accountID)
+	if !ok {
+		return []model.HookDelivery{}, nil
+	}
+	var hook int64
+	if hookID != "" {
+		if hook, ok = parseID(hookID); !ok {
+			return nil, ErrHookNotFound
+		}
+		ctx, cancel := s.queryContext(ctx)
+		defer cancel()
+		var exists bool
+		err := s.replica.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM hooks WHERE id = $1 AND account_id = $2)`, hook, account).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrHookNotFound
+		}
+	}
+	return s.queryHookDeliveries(ctx, `
+		WHERE h.account_id = $1 AND ($2 = 0 OR d.hook_id = $2) AND ($3 = '' OR d.status = $3)
+		ORDER BY d.id DESC LIMIT $4`, account, hook, status, limit)
+}
+
+// DueHookDeliveries returns pending deliveries due at now
+func (s *PostgresStore) DueHookDeliveries(ctx context.Context, now time.Time, limit int) ([]model.HookDelivery, error) {
+	return s.queryHookDeliveries(ctx, `
+		WHERE d.status = 'pending' AND d.next_attempt_at <= $1
+		ORDER BY d.next_attempt_at LIMIT $2`, now, limit)
+}
+
+func (s *PostgresStore) queryHookDeliveries(ctx context.Context, where string, args ...interface{}) ([]model.HookDelivery, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT d.id, d.hook_id, h.account_id, h.target_url, d.event, d.payload, d.status, d.attempts,
+			d.last_status, d.last_error, d.next_attempt_at, d.created_at, d.updated_at
+		FROM hook_deliveries d JOIN hooks h ON h.id = d.hook_id `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.HookDelivery{}
+	for rows.Next() {
+		var (
+			d                 model.HookDelivery
+			id, hook, account int64
+			payload           string
+			next              sql.NullTime
+		)
+		if err := rows.Scan(&id, &hook, &account, &d.TargetURL, &d.Event, &payload, &d.Status, &d.Attempts,
+			&d.LastStatus, &d.LastError, &next, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		d.ID = strconv.FormatInt(id, 10)
+		d.HookID = strconv.FormatInt(hook, 10)
+		d.AccountID = strconv.FormatInt(account, 10)
+		d.Payload = json.RawMessage(payload)
+		if next.Valid {
+			d.NextAttemptAt = &next.Time
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+// PruneHookDeliveries deletes settled deliveries last updated before
+// before
+func (s *PostgresStore) PruneHookDeliveries(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM hook_deliveries WHERE status <> 'pending' AND updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// SetCaptureToken replaces an account's token hash
+func (s *PostgresStore) SetCaptureToken(ctx context.Context, accountID, tokenHash string) error {
+	n, ok := parseID(accountID)
+	if !ok {
+		return ErrAccountNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE accounts SET capture_token_hash = $2 WHERE id = $1`, n, sql.NullString{String: tokenHash, Valid: tokenHash != ""})
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// AccountByCaptureToken returns the account a token hash belongs to
+func (s *PostgresStore) AccountByCaptureToken(ctx context.Context, tokenHash string) (model.Account, bool) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	a, err := scanAccount(s.db.QueryRowContext(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE capture_token_hash = $1`, tokenHash))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("postgres: get account by capture token: %v", err)
+		}
+		return model.Account{}, false
+	}
+	return a, true
+}
+
+// SetCalendarToken replaces an account's token hash
+func (s *PostgresStore) SetCalendarToken(ctx context.Context, accountID, tokenHash string) error {
+	n, ok := parseID(accountID)
+	if !ok {
+		return ErrAccountNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE accounts SET calendar_token_hash = $2 WHERE id = $1`, n, sql.NullString{String: tokenHash, Valid: tokenHash != ""})
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// AccountByCalendarToken returns the account a token hash belongs to
+func (s *PostgresStore) AccountByCalendarToken(ctx context.Context, tokenHash string) (model.Account, bool) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	a, err := scanAccount(s.db.QueryRowContext(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE calendar_token_hash = $1`, tokenHash))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("postgres: get account by calendar token: %v", err)
+		}
+		return model.Account{}, false
+	}
+	return a, true
+}
+
+// SetIntegration connects i.AccountID to i.Service
+func (s *PostgresStore) SetIntegration(ctx context.Context, i model.Integration) (model.Integration, error) {
+	account, ok := parseID(i.AccountID)
+	if !ok {
+		return model.Integration{}, ErrAccountNotFound
+	}
+	if i.CollectionIDs == nil {
+		i.CollectionIDs = []string{}
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	i.LastSyncAt, i.LastError, i.Cursor = nil, "", ""
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO integrations (account_id, service, token, target, collection_ids) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id, service) DO UPDATE SET
+			token = EXCLUDED.token, target = EXCLUDED.target, collection_ids = EXCLUDED.collection_ids,
+			last_sync_at = NULL, last_error = '', cursor = '', created_at = now()
+		RETURNING created_at`, account, i.Service, i.Token, i.Target, pq.Array(i.CollectionIDs)).Scan(&i.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return model.Integration{}, ErrAccountNotFound
+	}
+	if err != nil {
+		return model.Integration{}, err
+	}
+	return i, nil
+}
+
+// GetIntegration returns an account's connection to a service
+func (s *PostgresStore) GetIntegration(ctx context.Context, accountID, service string) (model.Integration, error) {
+	account, ok := parseID(accountID)
+	if !ok {
+		return model.Integration{}, ErrIntegrationNotFound
+	}
+	result, err := s.queryIntegrations(ctx, `WHERE account_id = $1 AND service = $2`, account, service)
+	if err != nil {
+		return model.Integration{}, err
+	}
+	if len(result) == 0 {
+		return model.Integration{}, ErrIntegrationNotFound
+	}
+	return result[0], nil
+}
+
+// AccountIntegrations returns an account's connections
+func (s *PostgresStore) AccountIntegrations(ctx context.Context, accountID string) ([]model.Integration, error) {
+	account, ok := parseID(accountID)
+	if !ok {
+		return []model.Integration{}, nil
+	}
+	return s.queryIntegrations(ctx, `WHERE account_id = $1`, account)
+}
+
+// ServiceIntegrations returns every account's connection to a service
+func (s *PostgresStore) ServiceIntegrations(ctx context.Context, service string) ([]model.Integration, error) {
+	return s.queryIntegrations(ctx, `WHERE service = $1`, service)
+}
+
+func (s *PostgresStore) queryIntegrations(ctx context.Context, where string, args ...interface{}) ([]model.Integration, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `
+		SELECT account_id, service, token, target, collection_ids, last_sync_at, last_error, cursor, created_at
+		FROM integrations `+where+` ORDER BY account_id, service`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Integration{}
+	for rows.Next() {
+		var (
+			i           model.Integration
+			account     int64
+			collections pq.StringArray
+			lastSync    sql.NullTime
+		)
+		if err := rows.Scan(&account, &i.Service, &i.Token, &i.Target, &collections, &lastSync, &i.LastError, &i.Cursor, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		i.AccountID = strconv.FormatInt(account, 10)
+		i.CollectionIDs = []string(collections)
+		if lastSync.Valid {
+			i.LastSyncAt = &lastSync.Time
+		}
+		result = append(result, i)
+	}
+	return result, rows.Err()
+}
+
+// RecordIntegrationSync records how a push ended
+func (s *PostgresStore) RecordIntegrationSync(ctx context.Context, accountID, service string, at time.Time, errMsg string) error {
+	account, ok := parseID(accountID)
+	if !ok {
+		return ErrIntegrationNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE integrations SET
+			last_sync_at = CASE WHEN $4::text = '' THEN $3::timestamptz ELSE last_sync_at END,
+			last_error = $4
+		WHERE account_id = $1 AND service = $2`, account, service, at, errMsg)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrIntegrationNotFound
+	}
+	return nil
+}
+
+// SetIntegrationCursor records the newest item a pull has seen
+func (s *PostgresStore) SetIntegrationCursor(ctx context.Context, accountID, service, cursor string) error {
+	account, ok := parseID(accountID)
+	if !ok {
+		return ErrIntegrationNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE integrations SET cursor = $3 WHERE account_id = $1 AND service = $2`, account, service, cursor)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrIntegrationNotFound
+	}
+	return nil
+}
+
+// DeleteIntegration disconnects an account from a service
+func (s *PostgresStore) DeleteIntegration(ctx context.Context, accountID, service string) error {
+	account, ok := parseID(accountID)
+	if !ok {
+		return ErrIntegrationNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM integrations WHERE account_id = $1 AND service = $2`, account, service)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return ErrIntegrationNotFound
+	}
+	return nil
+}
+
+// InitActorKey stores keyPEM unless the account already has a key
+func (s *PostgresStore) InitActorKey(ctx context.Context, accountID, keyPEM string) (string, error) {
+	n, ok := parseID(accountID)
+	if !ok {
+		return "", ErrAccountNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	var key string
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE accounts SET actor_key = COALESCE(actor_key, $2) WHERE id = $1 RETURNING actor_key`, n, keyPEM).Scan(&key)
+	if err == sql.ErrNoRows {
+		return "", ErrAccountNotFound
+	}
+	return key, err
+}
+
+// AddFollower records a follower
+func (s *PostgresStore) AddFollower(ctx context.Context, f model.Follower) error {
+	account, ok := parseID(f.AccountID)
+	if !ok {
+		return ErrAccountNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO followers (account_id, actor_id, inbox) VALUES ($1, $2, $3)
+		ON CONFLICT (account_id, actor_id) DO UPDATE SET inbox = EXCLUDED.inbox`,
+		account, f.ActorID, f.Inbox)
+	return err
+}
+
+// RemoveFollower forgets an actor following an account
+func (s *PostgresStore) RemoveFollower(ctx context.Context, accountID, actorID string) error {
+	account, ok := parseID(accountID)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM followers WHERE account_id = $1 AND actor_id = $2`, account, actorID)
+	return err
+}
+
+// Followers returns the actors following an account
+func (s *PostgresStore) Followers(ctx context.Context, accountID string) ([]model.Follower, error) {
+	account, ok := parseID(accountID)
+	if !ok {
+		return []model.Follower{}, nil
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT actor_id, inbox, created_at FROM followers
+		WHERE account_id = $1 ORDER BY created_at, actor_id`, account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Follower{}
+	for rows.Next() {
+		f := model.Follower{AccountID: accountID}
+		if err := rows.Scan(&f.ActorID, &f.Inbox, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
+
+// SaveMention records a verified mention
+func (s *PostgresStore) SaveMention(ctx context.Context, m model.Mention) (model.Mention, error) {
+	if !ulid.Valid(m.BookmarkID) {
+		return model.Mention{}, ErrBookmarkNotFound
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO mentions (bookmark_id, source, title) VALUES ($1, $2, $3)
+		ON CONFLICT (bookmark_id, source) DO UPDATE SET title = EXCLUDED.title, updated_at = now()
+		RETURNING created_at, updated_at`, m.BookmarkID, m.Source, m.Title).Scan(&m.CreatedAt, &m.UpdatedAt)
+	if isForeignKeyViolation(err) {
+		return model.Mention{}, ErrBookmarkNotFound
+	}
+	if err != nil {
+		return model.Mention{}, err
+	}
+	return m, nil
+}
+
+// DeleteMention forgets a mention
+func (s *PostgresStore) DeleteMention(ctx context.Context, bookmarkID, source string) error {
+	if !ulid.Valid(bookmarkID) {
+		return nil
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM mentions WHERE bookmark_id = $1 AND source = $2`, bookmarkID, source)
+	return err
+}
+
+// BookmarkMentions returns a bookmark's mentions, oldest first
+func (s *PostgresStore) BookmarkMentions(ctx context.Context, bookmarkID string) ([]model.Mention, error) {
+	if _, err := s.GetByID(ctx, bookmarkID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.replica.QueryContext(ctx, `
+		SELECT source, title, created_at, updated_at FROM mentions
+		WHERE bookmark_id = $1 ORDER BY created_at, source`, bookmarkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Mention{}
+	for rows.Next() {
+		m := model.Mention{BookmarkID: bookmarkID}
+		if err := rows.Scan(&m.Source, &m.Title, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// LockBlobMoves takes a session advisory lock on a connection of its own,
+// which unlock releases and returns to the pool
+func (s *PostgresStore) LockBlobMoves(ctx context.Context) (func(), error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext('bookmark_blob_moves'))`); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext('bookmark_blob_moves'))`); err != nil {
+			// Ending the session releases the lock as well, so the
+			// connection is dropped rather than returned to the pool
+			log.Printf("postgres: release blob move lock: %v", err)
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
+}
+
+// PendingBlobMoves returns the bookmark ID changes whose blobs have not
+// been moved yet, oldest first
+func (s *PostgresStore) PendingBlobMoves(ctx context.Context) ([]BlobMove, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT seq, old_id, new_id FROM bookmark_blob_moves ORDER BY seq`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []BlobMove
+	for rows.Next() {
+		var m BlobMove
+		if err := rows.Scan(&m.Seq, &m.OldID, &m.NewID); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// CompleteBlobMove drops a bookmark ID change whose blobs have moved
+func (s *PostgresStore) CompleteBlobMove(ctx context.Context, seq int64) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM bookmark_blob_moves WHERE seq = $1`, seq)
+	return err
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ErrQuotaExceeded is returned by writes that would take an account past
+// one of its quotas. It is wrapped with the limit that was reached.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quotas limit what each account keeps in the store; zero disables a
+// limit. Bookmarks without an owner, such as imported or seeded ones,
+// count against no account.
+type Quotas struct {
+	// MaxBookmarks caps the bookmarks an account owns, trash excluded
+	MaxBookmarks int
+	// MaxTags caps the tags on a single bookmark
+	MaxTags int
+}
+
+// checkTags returns ErrQuotaExceeded when a bookmark would carry more
+// tags than q allows. tags must already be normalized.
+func (q Quotas) checkTags(tags []string) error {
+	if q.MaxTags > 0 && len(tags) > q.MaxTags {
+		return fmt.Errorf("%w: a bookmark can have at most %d tags", ErrQuotaExceeded, q.MaxTags)
+	}
+	return nil
+}
+
+// checkBookmarks returns ErrQuotaExceeded when an owner with owned
+// bookmarks cannot add another
+func (q Quotas) checkBookmarks(ownerID string, owned int) error {
+	if ownerID != "" && q.MaxBookmarks > 0 && owned >= q.MaxBookmarks {
+		return fmt.Errorf("%w: an account can have at most %d bookmarks", ErrQuotaExceeded, q.MaxBookmarks)
+	}
+	return nil
+}
+
+// ownedBy counts the bookmarks of ownerID; callers hold s.mu
+func (s *MemoryStore) ownedBy(ownerID string) int {
+	n := 0
+	for _, b := range s.bookmarks {
+		if b.OwnerID == ownerID {
+			n++
+		}
+	}
+	return n
+}
+
+// checkQuotas returns ErrQuotaExceeded when b cannot be added to the
+// store; callers hold s.mu
+func (s *MemoryStore) checkQuotas(b model.Bookmark) error {
+	if err := s.quotas.checkTags(b.Tags); err != nil {
+		return err
+	}
+	if b.OwnerID == "" || s.quotas.MaxBookmarks <= 0 {
+		return nil
+	}
+	return s.quotas.checkBookmarks(b.OwnerID, s.ownedBy(b.OwnerID))
+}
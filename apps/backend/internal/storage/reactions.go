@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ReactionStore records emoji reactions to the bookmarks in collections.
+// Reactions belong to a bookmark's place in a collection: they go when the
+// bookmark leaves the collection and follow it when collections merge.
+type ReactionStore interface {
+	// AddReaction records an account's reaction; adding it again is a no-op
+	AddReaction(ctx context.Context, collectionID, bookmarkID, accountID, emoji string) error
+	// RemoveReaction withdraws an account's reaction; removing a missing
+	// reaction is a no-op
+	RemoveReaction(ctx context.Context, collectionID, bookmarkID, accountID, emoji string) error
+	// CollectionReactions returns every reaction in a collection
+	CollectionReactions(ctx context.Context, collectionID string) ([]model.Reaction, error)
+}
+
+// dropReactions deletes the reactions matching doomed; callers hold s.mu
+func (s *MemoryStore) dropReactions(doomed func(model.Reaction) bool) {
+	kept := s.reactions[:0]
+	for _, r := range s.reactions {
+		if !doomed(r) {
+			kept = append(kept, r)
+		}
+	}
+	s.reactions = kept
+}
+
+// moveReactions carries the reactions in source over to target, where the
+// same account may already have reacted alike; callers hold s.mu
+func (s *MemoryStore) moveReactions(sourceID, targetID string) {
+	existing := make(map[model.Reaction]bool)
+	for _, r := range s.reactions {
+		if r.CollectionID == targetID {
+			existing[r] = true
+		}
+	}
+	kept := s.reactions[:0]
+	for _, r := range s.reactions {
+		if r.CollectionID == sourceID {
+			r.CollectionID = targetID
+			if existing[r] {
+				continue
+			}
+			existing[r] = true
+		}
+		kept = append(kept, r)
+	}
+	s.reactions = kept
+}
+
+// AddReaction records a reaction
+func (s *MemoryStore) AddReaction(ctx context.Context, collectionID, bookmarkID, accountID, emoji string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.collectionIndex(collectionID) < 0 {
+		return ErrCollectionNotFound
+	}
+	if !s.members[collectionID][bookmarkID] {
+		return ErrBookmarkNotFound
+	}
+	reaction := model.Reaction{CollectionID: collectionID, BookmarkID: bookmarkID, AccountID: accountID, Emoji: emoji}
+	for _, r := range s.reactions {
+		if r == reaction {
+			return nil
+		}
+	}
+	s.reactions = append(s.reactions, reaction)
+	return nil
+}
+
+// RemoveReaction withdraws a reaction
+func (s *MemoryStore) RemoveReaction(ctx context.Context, collectionID, bookmarkID, accountID, emoji string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.collectionIndex(collectionID) < 0 {
+		return ErrCollectionNotFound
+	}
+	reaction := model.Reaction{CollectionID: collectionID, BookmarkID: bookmarkID, AccountID: accountID, Emoji: emoji}
+	s.dropReactions(func(r model.Reaction) bool { return r == reaction })
+	return nil
+}
+
+// CollectionReactions lists the reactions in a collection
+func (s *MemoryStore) CollectionReactions(ctx context.Context, collectionID string) ([]model.Reaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.collectionIndex(collectionID) < 0 {
+		return nil, ErrCollectionNotFound
+	}
+	result := []model.Reaction{}
+	for _, r := range s.reactions {
+		if r.CollectionID == collectionID {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
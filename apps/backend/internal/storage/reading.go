@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ReadingStore tracks what has been read, for read-it-later use
+type ReadingStore interface {
+	// SetRead marks a bookmark read or unread
+	SetRead(ctx context.Context, id string, read bool) (model.Bookmark, error)
+	// SetProgress records how far a bookmark has been read
+	SetProgress(ctx context.Context, id string, p model.ReadingProgress) (model.Bookmark, error)
+	// SetWordCount records the word count of a bookmark's page, from which
+	// its reading time is estimated
+	SetWordCount(ctx context.Context, id string, words int) (model.Bookmark, error)
+}
+
+// SetRead marks a bookmark read or unread
+func (s *MemoryStore) SetRead(ctx context.Context, id string, read bool) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setRead(id, read)
+}
+
+func (s *MemoryStore) setRead(id string, read bool) (model.Bookmark, error) {
+	for i := range s.bookmarks {
+		if s.bookmarks[i].ID == id {
+			s.bookmarks[i].IsRead = read
+			s.bookmarks[i].UpdatedAt = time.Now()
+			return s.bookmarks[i], nil
+		}
+	}
+	return model.Bookmark{}, ErrBookmarkNotFound
+}
+
+// SetProgress records how far a bookmark has been read
+func (s *MemoryStore) SetProgress(ctx context.Context, id string, p model.ReadingProgress) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setProgress(id, p)
+}
+
+func (s *MemoryStore) setProgress(id string, p model.ReadingProgress) (model.Bookmark, error) {
+	for i := range s.bookmarks {
+		if s.bookmarks[i].ID == id {
+			s.bookmarks[i].Progress = &p
+			s.bookmarks[i].UpdatedAt = p.UpdatedAt
+			return s.bookmarks[i], nil
+		}
+	}
+	return model.Bookmark{}, ErrBookmarkNotFound
+}
+
+// SetWordCount records the word count of a bookmark's page
+func (s *MemoryStore) SetWordCount(ctx context.Context, id string, words int) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setWordCount(id, words)
+}
+
+func (s *MemoryStore) setWordCount(id string, words int) (model.Bookmark, error) {
+	for i := range s.bookmarks {
+		if s.bookmarks[i].ID == id {
+			s.bookmarks[i].WordCount = words
+			s.bookmarks[i].ReadingMinutes = model.ReadingMinutes(words)
+			return s.bookmarks[i], nil
+		}
+	}
+	return model.Bookmark{}, ErrBookmarkNotFound
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// OpenFunc creates a store from its configuration
+type OpenFunc func(cfg Config) (Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]OpenFunc)
+)
+
+// Register makes a store driver available under name, selected with
+// DB_DRIVER. Like database/sql, drivers call it from an init function, so
+// compiling one in only takes a blank import. It panics if name is
+// registered twice.
+func Register(name string, open OpenFunc) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if open == nil {
+		panic("storage: Register open func is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("storage: Register called twice for driver " + name)
+	}
+	drivers[name] = open
+}
+
+// Open creates a store with the named driver
+func Open(name string, cfg Config) (Store, error) {
+	driversMu.RLock()
+	open, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown database driver %q (available: %v)", name, Drivers())
+	}
+	return open(cfg)
+}
+
+// Drivers returns the names of the registered drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ErrReminderNotFound is returned when a bookmark has no reminder
+var ErrReminderNotFound = errors.New("reminder not found")
+
+// ReminderStore keeps read-later reminders. Reminders go with their
+// bookmark when it is deleted.
+type ReminderStore interface {
+	// SetReminder creates or replaces the reminder of r.BookmarkID, which
+	// becomes pending again
+	SetReminder(ctx context.Context, r model.Reminder) (model.Reminder, error)
+	// DeleteReminder cancels a bookmark's reminder
+	DeleteReminder(ctx context.Context, bookmarkID string) error
+	// DueReminders returns the reminders due at or before t, delivered or
+	// not, earliest first
+	DueReminders(ctx context.Context, t time.Time) ([]model.Reminder, error)
+	// MarkReminderDelivered records that a reminder was sent at t
+	MarkReminderDelivered(ctx context.Context, bookmarkID string, t time.Time) error
+}
+
+// SetReminder creates or replaces a bookmark's reminder
+func (s *MemoryStore) SetReminder(ctx context.Context, r model.Reminder) (model.Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bookmarkIndex(r.BookmarkID) < 0 {
+		return model.Reminder{}, ErrBookmarkNotFound
+	}
+	r.DeliveredAt = nil
+	s.reminders[r.BookmarkID] = r
+	return r, nil
+}
+
+// DeleteReminder cancels a bookmark's reminder
+func (s *MemoryStore) DeleteReminder(ctx context.Context, bookmarkID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.reminders[bookmarkID]; !ok {
+		return ErrReminderNotFound
+	}
+	delete(s.reminders, bookmarkID)
+	return nil
+}
+
+// DueReminders returns the reminders due at or before t
+func (s *MemoryStore) DueReminders(ctx context.Context, t time.Time) ([]model.Reminder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []model.Reminder{}
+	for _, r := range s.reminders {
+		if !r.RemindAt.After(t) {
+			result = append(result, r)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].RemindAt.Equal(result[j].RemindAt) {
+			return result[i].RemindAt.Before(result[j].RemindAt)
+		}
+		return result[i].BookmarkID < result[j].BookmarkID
+	})
+	return result, nil
+}
+
+// MarkReminderDelivered records that a reminder was sent
+func (s *MemoryStore) MarkReminderDelivered(ctx context.Context, bookmarkID string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reminders[bookmarkID]
+	if !ok {
+		return ErrReminderNotFound
+	}
+	r.DeliveredAt = &t
+	s.reminders[bookmarkID] = r
+	return nil
+}
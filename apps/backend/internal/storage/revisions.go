@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// RevisionStore keeps the history of bookmark edits. A revision is
+// recorded whenever a bookmark is created or its title, URL or tags
+// change; revisions go with their bookmark when it is deleted.
+type RevisionStore interface {
+	// BookmarkHistory returns a bookmark's revisions, oldest first
+	BookmarkHistory(ctx context.Context, id string) ([]model.Revision, error)
+}
+
+// recordRevision appends b to its history unless its title, URL and tags
+// are those of the latest revision; callers hold s.mu
+func (s *MemoryStore) recordRevision(b model.Bookmark) {
+	history := s.revisions[b.ID]
+	if n := len(history); n > 0 {
+		last := history[n-1]
+		if last.Title == b.Title && last.URL == b.URL && slices.Equal(last.Tags, b.Tags) {
+			return
+		}
+	}
+	s.revisions[b.ID] = append(history, model.Revision{
+		BookmarkID: b.ID,
+		Version:    len(history) + 1,
+		Title:      b.Title,
+		URL:        b.URL,
+		Tags:       b.Tags,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// BookmarkHistory returns a bookmark's revisions
+func (s *MemoryStore) BookmarkHistory(ctx context.Context, id string) ([]model.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.bookmarkIndex(id) < 0 {
+		return nil, ErrBookmarkNotFound
+	}
+	result := make([]model.Revision, len(s.revisions[id]))
+	copy(result, s.revisions[id])
+	return result, nil
+}
@@ -0,0 +1,36 @@
+// Package storage contains the bookmark store interface and its backends.
+package storage
+
+import (
+	"context"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// Store persists bookmarks. Backends that do I/O give up when ctx is
+// done, so a request's deadline bounds the queries made for it. Methods
+// taking an ID return ErrBookmarkNotFound when there is no such bookmark,
+// and writes that would take an account past its Quotas return
+// ErrQuotaExceeded; other errors are failures of the backend.
+type Store interface {
+	// GetAll returns all bookmarks in creation order
+	GetAll(ctx context.Context) ([]model.Bookmark, error)
+	// Create adds a new bookmark saved by ownerID, which is empty for
+	// bookmarks without an owner
+	Create(ctx context.Context, ownerID, title, url string, tags []string) (model.Bookmark, error)
+	// GetByID returns a bookmark by ID
+	GetByID(ctx context.Context, id string) (model.Bookmark, error)
+	// Update updates the non-empty fields of an existing bookmark; nil tags
+	// leave the tags unchanged
+	Update(ctx context.Context, id, title, url string, tags []string) (model.Bookmark, error)
+	// Delete removes a bookmark by ID
+	Delete(ctx context.Context, id string) error
+	// InTx runs fn with a Store whose writes take effect together: all of
+	// them when fn returns nil and none of them when it returns an error,
+	// which InTx passes on. fn must make its reads and writes through tx,
+	// not through the store, and InTx called on tx joins the transaction
+	// already open. tx is also a NoteStore, ReadingStore and ArchiveStore
+	// when the store is one, and bookmarks read from it by ID cannot be
+	// changed by others until the transaction ends.
+	InTx(ctx context.Context, fn func(tx Store) error) error
+}
@@ -0,0 +1,152 @@
+package storage_test
+
+// Store benchmarks. Run with:
+//
+//	go test -run=NONE -bench=. -benchmem ./internal/storage/
+//
+// Postgres benchmarks only run when BENCH_POSTGRES_DSN points at a
+// throwaway database; its bookmarks table is truncated before each run.
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/seed"
+	"github.com/hereisth/web-collector/apps/backend/internal/storage"
+)
+
+// benchLibrarySize is the number of bookmarks loaded before list/search runs
+const benchLibrarySize = 5000
+
+// ctx is the context benchmarked store calls run with
+var ctx = context.Background()
+
+type storeFactory struct {
+	name string
+	open func(b *testing.B) storage.Store
+}
+
+func storeFactories() []storeFactory {
+	factories := []storeFactory{
+		{"memory", func(b *testing.B) storage.Store { return storage.NewMemoryStore() }},
+		{"cached-memory", func(b *testing.B) storage.Store {
+			return storage.NewCachedStore(storage.NewMemoryStore(), 1000, time.Minute)
+		}},
+	}
+	if dsn := os.Getenv("BENCH_POSTGRES_DSN"); dsn != "" {
+		factories = append(factories, storeFactory{"postgres", func(b *testing.B) storage.Store {
+			return openBenchPostgres(b, dsn)
+		}})
+	}
+	return factories
+}
+
+func openBenchPostgres(b *testing.B, dsn string) storage.Store {
+	s, err := storage.NewPostgresStore(storage.Config{
+		DSN:          dsn,
+		MaxOpenConns: 25,
+		MaxIdleConns: 25,
+		QueryTimeout: 30 * time.Second,
+		AutoMigrate:  true,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`TRUNCATE bookmarks RESTART IDENTITY`); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { s.Close() })
+	return s
+}
+
+func loaded(b *testing.B, f storeFactory) storage.Store {
+	s := f.open(b)
+	if _, err := seed.Apply(ctx, s, seed.Generate(benchLibrarySize, 1)); err != nil {
+		b.Fatal(err)
+	}
+	return s
+}
+
+func BenchmarkCreate(b *testing.B) {
+	for _, f := range storeFactories() {
+		b.Run(f.name, func(b *testing.B) {
+			s := f.open(b)
+			var n int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := atomic.AddInt64(&n, 1)
+					s.Create(ctx, "", "Benchmark bookmark", fmt.Sprintf("https://example.com/%d", i), []string{"bench"})
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkGetByID(b *testing.B) {
+	for _, f := range storeFactories() {
+		b.Run(f.name, func(b *testing.B) {
+			s := loaded(b, f)
+			var n int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					// Cycle over a small hot set, like real traffic.
+					id := atomic.AddInt64(&n, 1)%500 + 1
+					s.GetByID(ctx, fmt.Sprint(id))
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkList(b *testing.B) {
+	for _, f := range storeFactories() {
+		b.Run(f.name, func(b *testing.B) {
+			s := loaded(b, f)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					s.GetAll(ctx)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkSearch mirrors what GET /bookmarks?q= does today: list
+// everything, then filter by substring.
+func BenchmarkSearch(b *testing.B) {
+	for _, f := range storeFactories() {
+		b.Run(f.name, func(b *testing.B) {
+			s := loaded(b, f)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					all, _ := s.GetAll(ctx)
+					matches := 0
+					for _, bm := range all {
+						if strings.Contains(strings.ToLower(bm.Title), "concurrency") {
+							matches++
+						}
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// TagStore changes the tags of many bookmarks at once
+type TagStore interface {
+	// UpdateTags adds and removes tags on every listed bookmark in a single
+	// transaction and returns the updated bookmarks. If any bookmark does
+	// not exist, nothing changes and ErrBookmarkNotFound is returned.
+	UpdateTags(ctx context.Context, ids, add, remove []string) ([]model.Bookmark, error)
+}
+
+// applyTags returns tags without remove and with add, normalized. Tags are
+// removed exactly; a tag's descendants are left alone.
+func applyTags(tags, add, remove []string) []string {
+	drop := make(map[string]bool, len(remove))
+	for _, t := range model.NormalizeTags(remove) {
+		drop[t] = true
+	}
+	result := make([]string, 0, len(tags)+len(add))
+	for _, t := range tags {
+		if !drop[t] {
+			result = append(result, t)
+		}
+	}
+	return model.NormalizeTags(append(result, add...))
+}
+
+// UpdateTags adds and removes tags on several bookmarks
+func (s *MemoryStore) UpdateTags(ctx context.Context, ids, add, remove []string) ([]model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := make(map[string]int, len(s.bookmarks))
+	for i, b := range s.bookmarks {
+		index[b.ID] = i
+	}
+	for _, id := range ids {
+		if _, ok := index[id]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrBookmarkNotFound, id)
+		}
+	}
+
+	tags := make(map[string][]string, len(ids))
+	for _, id := range ids {
+		tags[id] = applyTags(s.bookmarks[index[id]].Tags, add, remove)
+		if err := s.quotas.checkTags(tags[id]); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]model.Bookmark, 0, len(ids))
+	done := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if done[id] {
+			continue
+		}
+		done[id] = true
+		b := &s.bookmarks[index[id]]
+		b.Tags = tags[id]
+		b.UpdatedAt = time.Now()
+		s.recordRevision(*b)
+		result = append(result, *b)
+	}
+	return result, nil
+}
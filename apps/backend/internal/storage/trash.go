@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// ErrNotInTrash is returned when restoring a bookmark that is not in the
+// trash, either because it was never deleted or because it was purged
+var ErrNotInTrash = errors.New("bookmark not in trash")
+
+// TrashStore keeps deleted bookmarks restorable. Stores that implement it
+// move bookmarks to the trash on Delete instead of removing them; their
+// reminders, reactions, clips and history still go with the deletion.
+type TrashStore interface {
+	// ListDeleted returns the bookmarks in the trash, most recently
+	// deleted first
+	ListDeleted(ctx context.Context) ([]model.DeletedBookmark, error)
+	// Restore moves a bookmark out of the trash and back into the
+	// collections it was in that still exist. It returns
+	// ErrQuotaExceeded when the bookmark's owner has no room left for it.
+	Restore(ctx context.Context, id string) (model.Bookmark, error)
+	// PurgeDeleted permanently removes the bookmarks deleted before t and
+	// returns how many there were
+	PurgeDeleted(ctx context.Context, t time.Time) (int, error)
+}
+
+// ListDeleted returns the bookmarks in the trash
+func (s *MemoryStore) ListDeleted(ctx context.Context) ([]model.DeletedBookmark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]model.DeletedBookmark, 0, len(s.deleted))
+	for i := len(s.deleted) - 1; i >= 0; i-- {
+		result = append(result, s.deleted[i])
+	}
+	return result, nil
+}
+
+// Restore moves a bookmark out of the trash. Aliases another bookmark has
+// taken since are dropped.
+func (s *MemoryStore) Restore(ctx context.Context, id string) (model.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, d := range s.deleted {
+		if d.ID != id {
+			continue
+		}
+		if err := s.quotas.checkBookmarks(d.OwnerID, s.ownedBy(d.OwnerID)); err != nil {
+			return model.Bookmark{}, err
+		}
+		s.deleted = append(s.deleted[:i], s.deleted[i+1:]...)
+
+		b := d.Bookmark
+		var aliases []string
+		for _, a := range b.Aliases {
+			if s.urlOwner(a) < 0 {
+				aliases = append(aliases, a)
+			}
+		}
+		b.Aliases = aliases
+		b.UpdatedAt = time.Now()
+		// Put it back where it was in creation order
+		at := sort.Search(len(s.bookmarks), func(j int) bool { return s.bookmarks[j].CreatedAt.After(b.CreatedAt) })
+		s.bookmarks = slices.Insert(s.bookmarks, at, b)
+		for _, colID := range d.CollectionIDs {
+			if s.collectionIndex(colID) >= 0 {
+				s.addMember(colID, id)
+			}
+		}
+		s.recordRevision(b)
+		return b, nil
+	}
+	return model.Bookmark{}, ErrNotInTrash
+}
+
+// PurgeDeleted permanently removes the bookmarks deleted before t
+func (s *MemoryStore) PurgeDeleted(ctx context.Context, t time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.deleted[:0]
+	for _, d := range s.deleted {
+		if !d.DeletedAt.Before(t) {
+			kept = append(kept, d)
+		}
+	}
+	purged := len(s.deleted) - len(kept)
+	s.deleted = kept
+	return purged, nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// memoryTx is the Store a MemoryStore transaction hands out. Its methods
+// run with the store's lock already held.
+type memoryTx struct {
+	s *MemoryStore
+}
+
+// InTx runs fn while holding the store's lock, putting every bookmark
+// back as it was when fn fails. Other readers and writers wait until fn
+// returns.
+func (s *MemoryStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := s.snapshot()
+	if err := fn(memoryTx{s}); err != nil {
+		s.restore(saved)
+		return err
+	}
+	return nil
+}
+
+// memorySnapshot holds what the bookmark methods change
+type memorySnapshot struct {
+	bookmarks []model.Bookmark
+	deleted   []model.DeletedBookmark
+	members   map[string]map[string]bool
+	reactions []model.Reaction
+	reminders map[string]model.Reminder
+	revisions map[string][]model.Revision
+	clips     map[string][]model.Clip
+	mentions  map[string][]model.Mention
+}
+
+// snapshot copies the state the bookmark methods change; callers hold
+// s.mu
+func (s *MemoryStore) snapshot() memorySnapshot {
+	members := make(map[string]map[string]bool, len(s.members))
+	for id, m := range s.members {
+		members[id] = maps.Clone(m)
+	}
+	return memorySnapshot{
+		bookmarks: slices.Clone(s.bookmarks),
+		deleted:   slices.Clone(s.deleted),
+		members:   members,
+		reactions: slices.Clone(s.reactions),
+		reminders: maps.Clone(s.reminders),
+		revisions: maps.Clone(s.revisions),
+		clips:     maps.Clone(s.clips),
+		mentions:  maps.Clone(s.mentions),
+	}
+}
+
+// restore puts back a snapshot; callers hold s.mu
+func (s *MemoryStore) restore(saved memorySnapshot) {
+	s.bookmarks = saved.bookmarks
+	s.deleted = saved.deleted
+	s.members = saved.members
+	s.reactions = saved.reactions
+	s.reminders = saved.reminders
+	s.revisions = saved.revisions
+	s.clips = saved.clips
+	s.mentions = saved.mentions
+}
+
+func (t memoryTx) GetAll(ctx context.Context) ([]model.Bookmark, error) {
+	return t.s.getAll(), nil
+}
+
+func (t memoryTx) Create(ctx context.Context, ownerID, title, url string, tags []string) (model.Bookmark, error) {
+	return t.s.create(ownerID, title, url, tags)
+}
+
+func (t memoryTx) GetByID(ctx context.Context, id string) (model.Bookmark, error) {
+	return t.s.getByID(id)
+}
+
+func (t memoryTx) Update(ctx context.Context, id, title, url string, tags []string) (model.Bookmark, error) {
+	return t.s.update(id, title, url, tags)
+}
+
+func (t memoryTx) Delete(ctx context.Context, id string) error {
+	return t.s.remove(id)
+}
+
+func (t memoryTx) SetNotes(ctx context.Context, id, notes string) (model.Bookmark, error) {
+	return t.s.setNotes(id, notes)
+}
+
+func (t memoryTx) SetRead(ctx context.Context, id string, read bool) (model.Bookmark, error) {
+	return t.s.setRead(id, read)
+}
+
+func (t memoryTx) SetProgress(ctx context.Context, id string, p model.ReadingProgress) (model.Bookmark, error) {
+	return t.s.setProgress(id, p)
+}
+
+func (t memoryTx) SetWordCount(ctx context.Context, id string, words int) (model.Bookmark, error) {
+	return t.s.setWordCount(id, words)
+}
+
+func (t memoryTx) SetArchived(ctx context.Context, id string, archived bool) (model.Bookmark, error) {
+	return t.s.setArchived(id, archived)
+}
+
+func (t memoryTx) ArchiveStale(ctx context.Context, ids []string, before time.Time) ([]model.Bookmark, error) {
+	return t.s.archiveStale(ids, before)
+}
+
+// InTx runs fn as part of the transaction already open
+func (t memoryTx) InTx(ctx context.Context, fn func(tx Store) error) error {
+	return fn(t)
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"github.com/hereisth/web-collector/apps/backend/internal/model"
+)
+
+// URLRuleStore keeps the instance's URL normalization rules
+type URLRuleStore interface {
+	// URLRules returns the rules, or the defaults when none were set
+	URLRules(ctx context.Context) (model.URLRules, error)
+	// SetURLRules replaces the rules
+	SetURLRules(ctx context.Context, r model.URLRules) (model.URLRules, error)
+}
+
+// URLRules returns the rules
+func (s *MemoryStore) URLRules(ctx context.Context) (model.URLRules, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.urlRules == nil {
+		return model.DefaultURLRules(), nil
+	}
+	return *s.urlRules, nil
+}
+
+// SetURLRules replaces the rules
+func (s *MemoryStore) SetURLRules(ctx context.Context, r model.URLRules) (model.URLRules, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.urlRules = &r
+	return r, nil
+}
@@ -0,0 +1,224 @@
+// Package telegram receives links sent to a Telegram bot, so they can be
+// saved as bookmarks. Updates arrive on a webhook and replies go back in
+// the webhook response, so the Bot API is only called to register the
+// webhook.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiURL is the Bot API endpoint; the token and method are appended
+const apiURL = "https://api.telegram.org/bot"
+
+// secretHeader carries the webhook secret on every update
+const secretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Config enables the bot; it is disabled without a token
+type Config struct {
+	Token string
+	// WebhookSecret authenticates updates; Telegram allows 1-256 of
+	// A-Z, a-z, 0-9, _ and -
+	WebhookSecret string
+	// WebhookURL, when set, is registered with Telegram at startup
+	WebhookURL string
+	// AllowedChats are the comma-separated chat IDs whose links are saved
+	AllowedChats string
+}
+
+// Bot checks and answers the updates sent to the webhook
+type Bot struct {
+	token   string
+	secret  string
+	allowed map[int64]bool
+	client  *http.Client
+}
+
+var secretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// Open returns the configured bot, or nil when no token is set
+func Open(cfg Config) (*Bot, error) {
+	if cfg.Token == "" {
+		return nil, nil
+	}
+	if !secretPattern.MatchString(cfg.WebhookSecret) {
+		return nil, errors.New("the Telegram bot needs a webhook secret of 1-256 letters, digits, _ or -")
+	}
+	if cfg.WebhookURL != "" && !strings.HasPrefix(cfg.WebhookURL, "https://") {
+		return nil, fmt.Errorf("Telegram webhook URL %q must be https", cfg.WebhookURL)
+	}
+	b := &Bot{
+		token:   cfg.Token,
+		secret:  cfg.WebhookSecret,
+		allowed: make(map[int64]bool),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, field := range strings.Split(cfg.AllowedChats, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Telegram chat ID %q is not a number", field)
+		}
+		b.allowed[id] = true
+	}
+	return b, nil
+}
+
+// SetWebhook registers url with Telegram as where updates are sent
+func (b *Bot) SetWebhook(ctx context.Context, webhookURL string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"url":             webhookURL,
+		"secret_token":    b.secret,
+		"allowed_updates": []string{"message"},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+b.token+"/setWebhook", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		// The error names the request URL, which contains the token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("setWebhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("setWebhook: %s", resp.Status)
+	}
+	if !result.OK {
+		return fmt.Errorf("setWebhook: %s", result.Description)
+	}
+	return nil
+}
+
+// Authentic reports whether r carries the webhook secret
+func (b *Bot) Authentic(r *http.Request) bool {
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(b.secret)) == 1
+}
+
+// Allowed reports whether links from a chat are saved
+func (b *Bot) Allowed(chatID int64) bool {
+	return b.allowed[chatID]
+}
+
+// Update is an incoming update; only messages are handled
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+// Message is a chat message. Links sent with a photo or file arrive in
+// the caption.
+type Message struct {
+	MessageID       int64    `json:"message_id"`
+	Chat            Chat     `json:"chat"`
+	Text            string   `json:"text"`
+	Caption         string   `json:"caption"`
+	Entities        []Entity `json:"entities"`
+	CaptionEntities []Entity `json:"caption_entities"`
+}
+
+// Chat identifies the conversation a message belongs to
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// Entity marks up part of a message; text links carry their URL
+type Entity struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// Link is a bookmark a message asks to save: its first http(s) URL, its
+// #hashtags as tags, and the rest of its words as the title
+type Link struct {
+	URL   string
+	Title string
+	Tags  []string
+}
+
+// ParseLink reads the link out of a message, reporting false when the
+// message has none
+func ParseLink(m *Message) (Link, bool) {
+	text, entities := m.Text, m.Entities
+	if text == "" {
+		text, entities = m.Caption, m.CaptionEntities
+	}
+
+	var (
+		link  Link
+		title []string
+	)
+	for _, word := range strings.Fields(text) {
+		switch {
+		case strings.HasPrefix(word, "#") && len(word) > 1:
+			link.Tags = append(link.Tags, strings.TrimPrefix(word, "#"))
+		case link.URL == "" && isWebURL(word):
+			link.URL = word
+		default:
+			title = append(title, word)
+		}
+	}
+	// A link hidden behind text has no URL in the text itself
+	if link.URL == "" {
+		for _, e := range entities {
+			if e.Type == "text_link" && isWebURL(e.URL) {
+				link.URL = e.URL
+				break
+			}
+		}
+	}
+	link.Title = strings.Join(title, " ")
+	return link, link.URL != ""
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Reply answers a message. Returned as the webhook response, it is sent
+// as a sendMessage call.
+type Reply struct {
+	Method                string `json:"method"`
+	ChatID                int64  `json:"chat_id"`
+	Text                  string `json:"text"`
+	ReplyToMessageID      int64  `json:"reply_to_message_id,omitempty"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+// NewReply answers m with text
+func NewReply(m *Message, text string) Reply {
+	return Reply{
+		Method:                "sendMessage",
+		ChatID:                m.Chat.ID,
+		Text:                  text,
+		ReplyToMessageID:      m.MessageID,
+		DisableWebPagePreview: true,
+	}
+}
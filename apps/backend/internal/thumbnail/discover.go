@@ -0,0 +1,63 @@
+package thumbnail
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// imageProperties are the meta tags that name a page's preview image, in
+// order of preference
+var imageProperties = []string{"og:image:secure_url", "og:image", "twitter:image"}
+
+// FindImage scans the head of an HTML document for its preview image and
+// returns the image URL resolved against base, or "" when there is none
+func FindImage(r io.Reader, base *url.URL) string {
+	found := make(map[string]string)
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return pick(found, base)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				// Meta tags belong in the head; stop before the page body.
+				return pick(found, base)
+			case "meta":
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = strings.TrimSpace(string(v))
+					}
+				}
+				if key != "" && content != "" && found[key] == "" {
+					found[key] = content
+				}
+			}
+		}
+	}
+}
+
+func pick(found map[string]string, base *url.URL) string {
+	for _, p := range imageProperties {
+		raw, ok := found[p]
+		if !ok {
+			continue
+		}
+		u, err := base.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		return u.String()
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+// Package thumbnail renders small JPEG previews of bookmarked pages from
+// their og:image.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	// Decoders for the formats sites commonly use for og:image
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Size is a named thumbnail width; heights keep the source aspect ratio
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Sizes lists the thumbnails rendered for every image
+var Sizes = []Size{
+	{Name: "sm", Width: 160},
+	{Name: "md", Width: 320},
+	{Name: "lg", Width: 640},
+}
+
+// LookupSize returns the size with the given name
+func LookupSize(name string) (Size, bool) {
+	for _, s := range Sizes {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Size{}, false
+}
+
+// maxPixels bounds the decoded source image, so a small file claiming huge
+// dimensions cannot exhaust memory
+const maxPixels = 40_000_000
+
+// ErrTooLarge is returned for images with more than maxPixels pixels
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// Decode reads a JPEG, PNG or GIF image, checking its dimensions before
+// decoding the pixel data
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	return img, nil
+}
+
+// Render scales src down to width and encodes it as JPEG. Images narrower
+// than width are encoded at their original size.
+func Render(w io.Writer, src image.Image, width int) error {
+	return jpeg.Encode(w, Resize(src, width), &jpeg.Options{Quality: 82})
+}
+
+// Resize scales src down to width pixels wide with an area-averaging
+// filter, which avoids the aliasing of nearest-neighbour sampling
+func Resize(src image.Image, width int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	rgba := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	if width >= sw {
+		return rgba
+	}
+	height := sh * width / sw
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			var r, g, bl, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += int(row[i])
+					g += int(row[i+1])
+					bl += int(row[i+2])
+					a += int(row[i+3])
+					n++
+				}
+			}
+			off := y*dst.Stride + x*4
+			dst.Pix[off] = uint8(r / n)
+			dst.Pix[off+1] = uint8(g / n)
+			dst.Pix[off+2] = uint8(bl / n)
+			dst.Pix[off+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
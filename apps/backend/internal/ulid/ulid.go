@@ -0,0 +1,91 @@
+// Package ulid generates ULIDs, the identifiers given to bookmarks.
+//
+// A ULID is 128 bits: a 48-bit millisecond timestamp followed by 80
+// random bits, written as 26 characters of Crockford's base32. IDs sort
+// lexicographically in the order they were made, and the random part
+// keeps them from being guessed from one another. IDs made within the
+// same millisecond increment the random part of the previous one, so
+// they sort in order too.
+package ulid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"strings"
+	"sync"
+	"time"
+)
+
+// alphabet is Crockford's base32, which leaves out I, L, O and U
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Length is the number of characters in a ULID
+const Length = 26
+
+var (
+	mu   sync.Mutex
+	last [16]byte
+)
+
+// New returns a ULID for the current time
+func New() string {
+	return Make(time.Now())
+}
+
+// Make returns a ULID for t. When t falls in the same millisecond as the
+// previous ULID, the result is that ULID plus one.
+func Make(t time.Time) string {
+	ms := uint64(t.UnixMilli())
+
+	mu.Lock()
+	defer mu.Unlock()
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:], uint32(ms))
+	if [6]byte(id[:6]) == [6]byte(last[:6]) && increment(last[6:]) {
+		copy(id[6:], last[6:])
+	} else if _, err := rand.Read(id[6:]); err != nil {
+		panic("ulid: reading random bytes: " + err.Error())
+	}
+	last = id
+	return encode(id)
+}
+
+// increment adds one to the big-endian number in b, reporting false when
+// it overflows
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func encode(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [Length]byte
+	for i := Length - 1; i >= 0; i-- {
+		out[i] = alphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// Valid reports whether s is a ULID as New writes it: 26 upper-case
+// base32 characters, the first of them no greater than 7 so the value
+// fits in 128 bits
+func Valid(s string) bool {
+	if len(s) != Length || s[0] > '7' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(alphabet, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+// Package unfurl reads the preview of a page out of its HTML: the title,
+// description, image and site name that link previews show, from Open
+// Graph and Twitter card meta tags with the document itself as fallback.
+package unfurl
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/thumbnail"
+)
+
+// maxText bounds the title and description, in runes
+const maxText = 500
+
+// Preview is what a link preview shows of a page
+type Preview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	SiteName    string `json:"site_name"`
+}
+
+// Parse reads the preview of the HTML page at base. Missing parts are
+// left empty, except the site name, which falls back to the host.
+func Parse(page []byte, base *url.URL) Preview {
+	meta, title := scanHead(page)
+	p := Preview{
+		URL:         base.String(),
+		Title:       first(meta["og:title"], meta["twitter:title"], title),
+		Description: first(meta["og:description"], meta["twitter:description"], meta["description"]),
+		Image:       thumbnail.FindImage(bytes.NewReader(page), base),
+		SiteName:    first(meta["og:site_name"], strings.TrimPrefix(base.Hostname(), "www.")),
+	}
+	if canonical, err := base.Parse(meta["og:url"]); err == nil && meta["og:url"] != "" &&
+		(canonical.Scheme == "http" || canonical.Scheme == "https") {
+		p.URL = canonical.String()
+	}
+	p.Title, p.Description = clip(p.Title), clip(p.Description)
+	return p
+}
+
+// scanHead collects the meta tags and the title of a document's head
+func scanHead(page []byte) (map[string]string, string) {
+	meta := make(map[string]string)
+	var (
+		title   string
+		inTitle bool
+	)
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return meta, title
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = string(z.Text())
+			}
+		case html.EndTagToken:
+			inTitle = false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			inTitle = string(name) == "title"
+			switch string(name) {
+			case "body":
+				// Meta tags belong in the head; stop before the page body.
+				return meta, title
+			case "meta":
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+				if key != "" && meta[key] == "" {
+					meta[key] = strings.TrimSpace(content)
+				}
+			}
+		}
+	}
+}
+
+// first returns the first of values that is not blank
+func first(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clip collapses whitespace and bounds the length of text
+func clip(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxText {
+		return strings.TrimSpace(string(r[:maxText-1])) + "…"
+	}
+	return s
+}
@@ -0,0 +1,270 @@
+// Package webmention sends and receives Webmentions, the IndieWeb way for
+// a page to tell another page that it links to it. Public bookmarks with
+// notes are pages that mention what they bookmark; other sites can
+// mention them back.
+package webmention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/hereisth/web-collector/apps/backend/internal/safehttp"
+)
+
+// maxPage bounds the pages read to discover endpoints and verify sources
+const maxPage = 1 << 20
+
+var (
+	// ErrNoEndpoint is returned when a target accepts no Webmentions
+	ErrNoEndpoint = errors.New("no webmention endpoint")
+	// ErrNoLink is returned when a source does not link to its target
+	ErrNoLink = errors.New("source does not link to target")
+	// ErrGone is returned when a source has been deleted
+	ErrGone = errors.New("source is gone")
+)
+
+// Config enables Webmentions; they are disabled without a site URL
+type Config struct {
+	// SiteURL is the public address of the instance, which the pages of
+	// public bookmarks are under
+	SiteURL string
+}
+
+// Client sends Webmentions and verifies the ones received
+type Client struct {
+	site   string
+	client *http.Client
+}
+
+// Open returns the configured client, or nil when no site URL is set
+func Open(cfg Config) (*Client, error) {
+	if cfg.SiteURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(strings.TrimSuffix(cfg.SiteURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+		return nil, fmt.Errorf("webmention site URL %q must be an http(s) URL without a path", cfg.SiteURL)
+	}
+	return &Client{
+		site:   u.Scheme + "://" + u.Host,
+		client: safehttp.Client(15 * time.Second),
+	}, nil
+}
+
+// SiteURL returns the public address of the instance, without a trailing
+// slash
+func (c *Client) SiteURL() string {
+	return c.site
+}
+
+// Send tells target's endpoint that source links to it. Sending again
+// after source changed tells the endpoint to check it again.
+func (c *Client) Send(ctx context.Context, source, target string) error {
+	endpoint, err := c.discover(ctx, target)
+	if err != nil {
+		return err
+	}
+	form := url.Values{"source": {source}, "target": {target}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("send webmention to %s: %s", endpoint, resp.Status)
+	}
+	return nil
+}
+
+// discover finds target's Webmention endpoint: in a Link header, or else
+// in the first <link> or <a> element with rel="webmention"
+func (c *Client) discover(ctx context.Context, target string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html, */*;q=0.5")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("discover webmention endpoint of %s: %s", target, resp.Status)
+	}
+	// Relative endpoints are relative to where redirects ended
+	base := resp.Request.URL
+
+	href, found := linkHeader(resp.Header.Values("Link"))
+	if !found && isHTML(resp.Header.Get("Content-Type")) {
+		href, found = relLink(io.LimitReader(resp.Body, maxPage))
+	}
+	if !found {
+		return "", ErrNoEndpoint
+	}
+	endpoint, err := base.Parse(href)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return "", fmt.Errorf("webmention endpoint %q of %s is not an http(s) URL", href, target)
+	}
+	return endpoint.String(), nil
+}
+
+// linkHeader returns the target of the first webmention link in Link
+// headers
+func linkHeader(headers []string) (string, bool) {
+	for _, header := range headers {
+		for _, link := range strings.Split(header, ",") {
+			ref, params, ok := strings.Cut(link, ";")
+			ref = strings.TrimSpace(ref)
+			if !ok || !strings.HasPrefix(ref, "<") || !strings.HasSuffix(ref, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(name, "rel") && hasRel(strings.Trim(value, `"`)) {
+					return strings.TrimSuffix(strings.TrimPrefix(ref, "<"), ">"), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// relLink returns the href of the first <link> or <a> element of an HTML
+// document with rel="webmention"
+func relLink(r io.Reader) (string, bool) {
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return "", false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) != "link" && string(name) != "a" {
+				continue
+			}
+			var (
+				href       string
+				hasHref, w bool
+			)
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				switch string(k) {
+				case "href":
+					href, hasHref = string(v), true
+				case "rel":
+					w = hasRel(string(v))
+				}
+			}
+			// An empty href names the target itself
+			if w && hasHref {
+				return href, true
+			}
+		}
+	}
+}
+
+func hasRel(rel string) bool {
+	for _, r := range strings.Fields(rel) {
+		if strings.EqualFold(r, "webmention") {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTML(contentType string) bool {
+	media, _, _ := mime.ParseMediaType(contentType)
+	return media == "text/html" || media == "application/xhtml+xml"
+}
+
+// Source is a verified page mentioning a target
+type Source struct {
+	// Title is the page's title, "" when it has none
+	Title string
+}
+
+// Verify fetches source and checks that it links to target. A source
+// answering 410 Gone has been deleted, and with it the mention.
+func (c *Client) Verify(ctx context.Context, source, target string) (Source, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return Source{}, err
+	}
+	req.Header.Set("Accept", "text/html, */*;q=0.5")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Source{}, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return Source{}, ErrGone
+	case resp.StatusCode != http.StatusOK:
+		return Source{}, fmt.Errorf("fetch source %s: %s", source, resp.Status)
+	}
+
+	body := io.LimitReader(resp.Body, maxPage)
+	if !isHTML(resp.Header.Get("Content-Type")) {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return Source{}, err
+		}
+		if !strings.Contains(string(data), target) {
+			return Source{}, ErrNoLink
+		}
+		return Source{}, nil
+	}
+	return scanSource(body, target)
+}
+
+// scanSource looks through an HTML page for a link to target, and for
+// its title
+func scanSource(r io.Reader, target string) (Source, error) {
+	var (
+		src     Source
+		linked  bool
+		inTitle bool
+	)
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if !linked {
+				return Source{}, ErrNoLink
+			}
+			return src, nil
+		case html.TextToken:
+			if inTitle && src.Title == "" {
+				src.Title = strings.Join(strings.Fields(string(z.Text())), " ")
+			}
+		case html.EndTagToken:
+			inTitle = false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			inTitle = string(name) == "title"
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				if (string(k) == "href" || string(k) == "src") && string(v) == target {
+					linked = true
+				}
+			}
+		}
+	}
+}
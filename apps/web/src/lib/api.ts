@@ -11,24 +11,55 @@ interface ApiOptions {
   signal?: AbortSignal
 }
 
+// A request field that failed validation
+export interface FieldError {
+  field: string
+  rule: string
+  message: string
+}
+
 // Generic API response wrapper
 interface ApiResponse<T> {
   success: boolean
   data?: T
+  code?: string
   error?: string
   message?: string
+  details?: unknown
+  fields?: FieldError[]
+}
+
+// Error thrown for non-2xx responses, carrying the stable error code and
+// the fields that failed validation, if any
+export class ApiError extends Error {
+  status: number
+  code?: string
+  fields: FieldError[]
+
+  constructor(status: number, json: ApiResponse<unknown>) {
+    super(json.error || json.message || `HTTP ${status}`)
+    this.name = 'ApiError'
+    this.status = status
+    this.code = json.code
+    this.fields = json.fields ?? []
+  }
+
+  // The message for a field, if it failed validation
+  fieldMessage(field: string): string | undefined {
+    return this.fields.find((f) => f.field === field)?.message
+  }
 }
 
 async function handleResponse<T>(response: Response): Promise<T> {
-  const json = await response.json()
+  const json: ApiResponse<T> = await response.json()
 
   if (!response.ok) {
     console.error('API Error:', json)
-    throw new Error(json.error || json.message || `HTTP ${response.status}`)
+    throw new ApiError(response.status, json)
   }
 
   // Return the data field if it exists, otherwise return the whole response
-  return json.data !== undefined ? json.data : json
+  return json.data !== undefined ? json.data : (json as T)
 }
 
 export const api = {
@@ -93,8 +124,18 @@ export const api = {
 
 // Bookmark API functions
 export const bookmarkApi = {
-  // Get all bookmarks
-  getAll: () => api.get<Bookmark[]>('/bookmarks'),
+  // Get all bookmarks, following the list's pages
+  getAll: async () => {
+    const perPage = 200
+    const all: Bookmark[] = []
+    for (let page = 1; ; page++) {
+      const batch = await api.get<Bookmark[]>(
+        `/bookmarks?page=${page}&per_page=${perPage}`
+      )
+      all.push(...batch)
+      if (batch.length < perPage) return all
+    }
+  },
 
   // Get a single bookmark
   getById: (id: string) => api.get<Bookmark>(`/bookmarks/${id}`),
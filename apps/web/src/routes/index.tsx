@@ -1,7 +1,7 @@
 import { createFileRoute } from '@tanstack/react-router'
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query'
 import { useState } from 'react'
-import { bookmarkApi } from '@/lib/api'
+import { ApiError, bookmarkApi } from '@/lib/api'
 import type { Bookmark, CreateBookmarkRequest } from '@/types/bookmark'
 
 export const Route = createFileRoute('/')({
@@ -72,6 +72,8 @@ function BookmarksPage() {
   const handleCloseForm = () => {
     setIsFormOpen(false)
     setEditingBookmark(null)
+    createMutation.reset()
+    updateMutation.reset()
   }
 
   if (error) {
@@ -150,6 +152,7 @@ function BookmarksPage() {
           onSubmit={handleSubmit}
           onClose={handleCloseForm}
           isSubmitting={createMutation.isPending || updateMutation.isPending}
+          error={editingBookmark ? updateMutation.error : createMutation.error}
         />
       )}
     </div>
@@ -302,15 +305,24 @@ function BookmarkFormModal({
   onSubmit,
   onClose,
   isSubmitting,
+  error,
 }: {
   bookmark: Bookmark | null
   onSubmit: (data: CreateBookmarkRequest) => void
   onClose: () => void
   isSubmitting: boolean
+  error: Error | null
 }) {
   const [title, setTitle] = useState(bookmark?.title ?? '')
   const [url, setUrl] = useState(bookmark?.url ?? '')
 
+  // Server-side validation messages, shown under the fields they are about
+  const titleError =
+    error instanceof ApiError ? error.fieldMessage('title') : undefined
+  const urlError =
+    error instanceof ApiError ? error.fieldMessage('url') : undefined
+  const formError = error && !titleError && !urlError ? error.message : undefined
+
   const handleSubmit = (e: React.FormEvent) => {
     e.preventDefault()
     if (title.trim() && url.trim()) {
@@ -345,12 +357,16 @@ function BookmarkFormModal({
                 value={title}
                 onChange={(e) => setTitle(e.target.value)}
                 placeholder="例如：GitHub"
-                className="w-full px-4 py-2.5 border border-slate-200 rounded-lg
+                className={`w-full px-4 py-2.5 border rounded-lg
                            focus:outline-none focus:ring-2 focus:ring-indigo-500 focus:border-transparent
-                           placeholder:text-slate-400"
+                           placeholder:text-slate-400 ${titleError ? 'border-red-400' : 'border-slate-200'}`}
+                aria-invalid={titleError ? true : undefined}
                 required
                 autoFocus
               />
+              {titleError && (
+                <p className="text-sm text-red-600 mt-1">{titleError}</p>
+              )}
             </div>
 
             <div>
@@ -366,14 +382,22 @@ function BookmarkFormModal({
                 value={url}
                 onChange={(e) => setUrl(e.target.value)}
                 placeholder="https://github.com"
-                className="w-full px-4 py-2.5 border border-slate-200 rounded-lg
+                className={`w-full px-4 py-2.5 border rounded-lg
                            focus:outline-none focus:ring-2 focus:ring-indigo-500 focus:border-transparent
-                           placeholder:text-slate-400"
+                           placeholder:text-slate-400 ${urlError ? 'border-red-400' : 'border-slate-200'}`}
+                aria-invalid={urlError ? true : undefined}
                 required
               />
+              {urlError && (
+                <p className="text-sm text-red-600 mt-1">{urlError}</p>
+              )}
             </div>
           </div>
 
+          {formError && (
+            <p className="text-sm text-red-600 mt-4">{formError}</p>
+          )}
+
           <div className="flex gap-3 mt-6">
             <button
               type="button"
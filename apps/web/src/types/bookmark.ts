@@ -3,6 +3,7 @@ export interface Bookmark {
   id: string;
   title: string;
   url: string;
+  tags: string[];
   created_at: string;
 }
 
@@ -10,10 +11,14 @@ export interface Bookmark {
 export interface CreateBookmarkRequest {
   title: string;
   url: string;
+  tags?: string[];
 }
 
-// Request body for updating a bookmark
+// Request body for updating a bookmark; omitted fields are kept, and an
+// empty tags list or notes clears them
 export interface UpdateBookmarkRequest {
   title?: string;
   url?: string;
+  tags?: string[];
+  notes?: string;
 }